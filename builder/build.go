@@ -95,12 +95,16 @@ func Build(pkgName, outpath string, config *compileopts.Config, action func(Buil
 		CodeModel:       config.CodeModel(),
 		RelocationModel: config.RelocationModel(),
 
+		BuildMode:          config.BuildMode(),
 		Scheduler:          config.Scheduler(),
+		PanicStrategy:      config.PanicStrategy(),
 		FuncImplementation: config.FuncImplementation(),
 		AutomaticStackSize: config.AutomaticStackSize(),
 		DefaultStackSize:   config.Target.DefaultStackSize,
 		NeedsStackObjects:  config.NeedsStackObjects(),
 		Debug:              config.Debug(),
+		StackTraces:        config.StackTraces(),
+		ReflectMethods:     config.ReflectMethods(),
 		LLVMFeatures:       config.LLVMFeatures(),
 	}
 
@@ -112,14 +116,18 @@ func Build(pkgName, outpath string, config *compileopts.Config, action func(Buil
 		return err
 	}
 
-	// Load entire program AST into memory.
+	// Load entire program AST into memory. This runs go list and type checks
+	// the program, which takes as much CPU time as a compile job. Take a job
+	// slot, so that tinygo test doesn't run these steps for many packages at
+	// the same time.
+	acquireJobSlot()
 	lprogram, err := loader.Load(config, []string{pkgName}, config.ClangHeaders, types.Config{
 		Sizes: compiler.Sizes(machine),
 	})
-	if err != nil {
-		return err
+	if err == nil {
+		err = lprogram.Parse()
 	}
-	err = lprogram.Parse()
+	releaseJobSlot()
 	if err != nil {
 		return err
 	}
@@ -39,11 +39,57 @@ func NewConfig(options *compileopts.Options) (*compileopts.Config, error) {
 
 	clangHeaderPath := getClangHeaderPath(goenv.Get("TINYGOROOT"))
 
-	return &compileopts.Config{
+	config := &compileopts.Config{
 		Options:        options,
 		Target:         spec,
 		GoMinorVersion: minor,
 		ClangHeaders:   clangHeaderPath,
 		TestConfig:     options.TestConfig,
-	}, nil
+	}
+
+	if config.PanicStrategy() == "unwind" && config.Scheduler() == "coroutines" {
+		// Returning from a coroutine goes through the scheduler, which can't
+		// run while a panic is being unwound.
+		return nil, errors.New("-panic=unwind is not supported with the coroutines scheduler, use -scheduler=none or -scheduler=tasks instead")
+	}
+
+	if config.BuildMode() == "c-shared" {
+		// Only WASI has a convention for modules whose exported functions
+		// are called after initialization (reactors).
+		isWASI := false
+		for _, tag := range config.Target.BuildTags {
+			if tag == "wasi" {
+				isWASI = true
+			}
+		}
+		if !isWASI {
+			return nil, errors.New("-buildmode=c-shared is only supported on WASI")
+		}
+	}
+
+	if config.Scheduler() == "cores" {
+		// The multicore scheduler relies on the RP2040 SIO block for hardware
+		// spinlocks and for starting the second core.
+		isRP2040 := false
+		for _, tag := range config.Target.BuildTags {
+			if tag == "rp2040" {
+				isRP2040 = true
+			}
+		}
+		if !isRP2040 {
+			return nil, errors.New("-scheduler=cores is only supported on the RP2040")
+		}
+		if gc := config.GC(); gc != "conservative" && gc != "precise" {
+			// Allocations from both cores must be protected by a lock, and
+			// the other core must be stopped while scanning for pointers.
+			return nil, errors.New("-scheduler=cores requires -gc=conservative or -gc=precise")
+		}
+		if config.PanicStrategy() == "unwind" {
+			// The defer frames of the running goroutine are kept in a global,
+			// which can't be shared between cores.
+			return nil, errors.New("-panic=unwind is not supported with the cores scheduler")
+		}
+	}
+
+	return config, nil
 }
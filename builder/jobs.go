@@ -13,6 +13,22 @@ import (
 // concurrency or performance issues.
 const jobRunnerDebug = false
 
+// jobSlots limits the number of jobs that run at the same time to the number
+// of CPUs. It is shared by all job runners in this process, as tinygo test
+// builds several packages at the same time.
+var jobSlots = make(chan struct{}, runtime.NumCPU())
+
+// acquireJobSlot waits until a job may run.
+func acquireJobSlot() {
+	jobSlots <- struct{}{}
+}
+
+// releaseJobSlot allows another job to run. A job that waits for other jobs
+// releases its own slot while waiting, and acquires it again afterwards.
+func releaseJobSlot() {
+	<-jobSlots
+}
+
 type jobState uint8
 
 const (
@@ -162,7 +178,9 @@ func jobWorker(workerChan, doneChan chan *compileJob) {
 	for job := range workerChan {
 		start := time.Now()
 		if job.run != nil {
+			acquireJobSlot()
 			err := job.run(job)
+			releaseJobSlot()
 			if err != nil {
 				job.err = err
 			}
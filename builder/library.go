@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/tinygo-org/tinygo/goenv"
 )
@@ -109,7 +110,7 @@ func (l *Library) load(target, cpu, tmpdir string) (job *compileJob, err error)
 	// file is the (static) library file.
 	var objs []string
 	arpath := filepath.Join(dir, l.name+".a")
-	job = &compileJob{
+	arJob := &compileJob{
 		description: "ar " + l.name + ".a",
 		result:      arpath,
 		run: func(*compileJob) error {
@@ -130,7 +131,7 @@ func (l *Library) load(target, cpu, tmpdir string) (job *compileJob, err error)
 		srcpath := srcpath // avoid concurrency issues by redefining inside the loop
 		objpath := filepath.Join(dir, filepath.Base(srcpath)+".o")
 		objs = append(objs, objpath)
-		job.dependencies = append(job.dependencies, &compileJob{
+		arJob.dependencies = append(arJob.dependencies, &compileJob{
 			description: "compile " + srcpath,
 			run: func(*compileJob) error {
 				var compileArgs []string
@@ -145,5 +146,53 @@ func (l *Library) load(target, cpu, tmpdir string) (job *compileJob, err error)
 		})
 	}
 
+	// Other builds in this process (such as those of tinygo test) may need
+	// the same library at the same time. Only build it once: the library is
+	// built in a single job that first waits for other builds of it, and then
+	// checks the cache again.
+	job = &compileJob{
+		description: "build " + outfile,
+		run: func(job *compileJob) error {
+			// The jobs that build the library need job slots of their own.
+			releaseJobSlot()
+			defer acquireJobSlot()
+
+			lock := libraryLock(outfile)
+			lock.Lock()
+			defer lock.Unlock()
+			path, err := cacheLoad(outfile, l.sourcePaths(target))
+			if path != "" || err != nil {
+				// Built by another job while waiting for the lock.
+				job.result = path
+				return err
+			}
+			err = runJobs(append([]*compileJob{arJob}, arJob.dependencies...))
+			if err != nil {
+				return err
+			}
+			job.result = arJob.result
+			return nil
+		},
+	}
 	return job, nil
 }
+
+// libraryLocks contains a lock for each library file that is being built by
+// this process, see libraryLock.
+var (
+	libraryLocksLock sync.Mutex
+	libraryLocks     = map[string]*sync.Mutex{}
+)
+
+// libraryLock returns the lock that must be held while building the given
+// library file.
+func libraryLock(outfile string) *sync.Mutex {
+	libraryLocksLock.Lock()
+	defer libraryLocksLock.Unlock()
+	lock := libraryLocks[outfile]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		libraryLocks[outfile] = lock
+	}
+	return lock
+}
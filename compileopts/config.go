@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tinygo-org/tinygo/goenv"
 )
@@ -55,16 +57,36 @@ func (c *Config) GOARCH() string {
 
 // BuildTags returns the complete list of build tags used during this build.
 func (c *Config) BuildTags() []string {
-	tags := append(c.Target.BuildTags, []string{"tinygo", "gc." + c.GC(), "scheduler." + c.Scheduler(), "serial." + c.Serial()}...)
+	tags := append(c.Target.BuildTags, []string{"tinygo", "gc." + c.GC(), "scheduler." + c.Scheduler(), "serial." + c.Serial(), "panic." + c.PanicStrategy()}...)
 	for i := 1; i <= c.GoMinorVersion; i++ {
 		tags = append(tags, fmt.Sprintf("go1.%d", i))
 	}
+	if c.BuildMode() == "c-shared" {
+		// Build a WASI reactor instead of a command.
+		tags = append(tags, "tinygo.wasm.reactor")
+	}
+	if c.Options.PrintGCStats {
+		// Print the runtime.MemStats when the program exits.
+		tags = append(tags, "printgcstats")
+	}
 	if extraTags := strings.Fields(c.Options.Tags); len(extraTags) != 0 {
 		tags = append(tags, extraTags...)
 	}
 	return tags
 }
 
+// AcceptsArgs returns whether programs built for this target can receive
+// command line arguments. This is not the case for bare metal targets, even
+// when they're run in an emulator.
+func (c *Config) AcceptsArgs() bool {
+	for _, tag := range c.Target.BuildTags {
+		if tag == "baremetal" {
+			return false
+		}
+	}
+	return true
+}
+
 // CgoEnabled returns true if (and only if) CGo is enabled. It is true by
 // default and false if CGO_ENABLED is set to "0".
 func (c *Config) CgoEnabled() bool {
@@ -72,7 +94,7 @@ func (c *Config) CgoEnabled() bool {
 }
 
 // GC returns the garbage collection strategy in use on this platform. Valid
-// values are "none", "leaking", "extalloc", and "conservative".
+// values are "none", "leaking", "extalloc", "conservative" and "precise".
 func (c *Config) GC() string {
 	if c.Options.GC != "" {
 		return c.Options.GC
@@ -87,7 +109,7 @@ func (c *Config) GC() string {
 // that can be traced by the garbage collector.
 func (c *Config) NeedsStackObjects() bool {
 	switch c.GC() {
-	case "conservative", "extalloc":
+	case "conservative", "precise", "extalloc":
 		for _, tag := range c.BuildTags() {
 			if tag == "tinygo.wasm" {
 				return true
@@ -100,8 +122,19 @@ func (c *Config) NeedsStackObjects() bool {
 	}
 }
 
+// BuildMode returns the build mode (-buildmode flag). It is "default" for a
+// normal executable, or "c-shared" for a WebAssembly module that is
+// initialized once after which its exported functions can be called (a WASI
+// reactor).
+func (c *Config) BuildMode() string {
+	if c.Options.BuildMode != "" {
+		return c.Options.BuildMode
+	}
+	return "default"
+}
+
 // Scheduler returns the scheduler implementation. Valid values are "none",
-//"coroutines" and "tasks".
+//"coroutines", "tasks" and "cores".
 func (c *Config) Scheduler() string {
 	if c.Options.Scheduler != "" {
 		return c.Options.Scheduler
@@ -150,7 +183,7 @@ func (c *Config) OptLevels() (optLevel, sizeLevel int, inlinerThreshold uint) {
 // target.
 func (c *Config) FuncImplementation() string {
 	switch c.Scheduler() {
-	case "tasks":
+	case "tasks", "cores":
 		// A func value is implemented as a pair of pointers:
 		//     {context, function pointer}
 		// where the context may be a pointer to a heap-allocated struct
@@ -172,9 +205,13 @@ func (c *Config) FuncImplementation() string {
 }
 
 // PanicStrategy returns the panic strategy selected for this target. Valid
-// values are "print" (print the panic value, then exit) or "trap" (issue a trap
-// instruction).
+// values are "print" (print the panic value, then exit), "trap" (issue a trap
+// instruction), or "unwind" (run deferred functions while unwinding the stack,
+// so that recover() can stop the panic).
 func (c *Config) PanicStrategy() string {
+	if c.Options.PanicStrategy == "" {
+		return "print"
+	}
 	return c.Options.PanicStrategy
 }
 
@@ -182,7 +219,7 @@ func (c *Config) PanicStrategy() string {
 // automatically at compile time, if possible. If it is false, no attempt is
 // made.
 func (c *Config) AutomaticStackSize() bool {
-	if c.Target.AutoStackSize != nil && c.Scheduler() == "tasks" {
+	if c.Target.AutoStackSize != nil && (c.Scheduler() == "tasks" || c.Scheduler() == "cores") {
 		return *c.Target.AutoStackSize
 	}
 	return false
@@ -229,6 +266,10 @@ func (c *Config) LDFlags() []string {
 	if c.Target.LinkerScript != "" {
 		ldflags = append(ldflags, "-T", c.Target.LinkerScript)
 	}
+	if c.BuildMode() == "c-shared" {
+		// A reactor has no _start function, only _initialize.
+		ldflags = append(ldflags, "--no-entry")
+	}
 	return ldflags
 }
 
@@ -256,6 +297,36 @@ func (c *Config) Debug() bool {
 	return c.Options.Debug
 }
 
+// StackTraces returns whether the compiler should emit a table with call site
+// information so that panics print a stack trace and runtime.Callers works.
+// This is done by default on hosted and WebAssembly targets, unless debug
+// information is disabled with -no-debug.
+func (c *Config) StackTraces() bool {
+	if !c.Debug() {
+		return false
+	}
+	for _, tag := range c.BuildTags() {
+		if tag == "baremetal" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReflectMethods returns whether the exported methods of types should be made
+// available to the reflect package, so that Type.Method and Value.Method work.
+// This is enabled by default. It can be disabled with the -no-reflect-methods
+// flag or in the target, to save space in programs that use reflection.
+func (c *Config) ReflectMethods() bool {
+	if c.Options.NoReflectMethods {
+		return false
+	}
+	if c.Target.ReflectMethods != nil {
+		return *c.Target.ReflectMethods
+	}
+	return true
+}
+
 // BinaryFormat returns an appropriate binary format, based on the file
 // extension and the configured binary format in the target JSON file.
 func (c *Config) BinaryFormat(ext string) string {
@@ -369,5 +440,58 @@ func (c *Config) LLVMFeatures() string {
 
 type TestConfig struct {
 	CompileTestBinary bool
-	// TODO: Filter the test functions to run, include verbose flag, etc
+
+	Verbose   bool          // print all test results and logs (-v)
+	Short     bool          // run a smaller test suite (-short)
+	RunRegexp string        // regular expression of tests to run (-run)
+	Count     int           // run each test and benchmark this many times (-count)
+	FailFast  bool          // stop after the first failed test (-failfast)
+	Timeout   time.Duration // panic the test binary after this duration (-timeout)
+
+	BenchRegexp string // regular expression of benchmarks to run (-bench)
+	BenchTime   string // run each benchmark for this duration or count (-benchtime)
+	BenchMem    bool   // print memory allocation statistics (-benchmem)
+
+	CoverMode    string // coverage mode, "set" or "count" (empty if coverage is disabled)
+	CoverProfile string // write a coverage profile to this file (-coverprofile)
+}
+
+// Flags returns the flags that must be passed to the test binary, in the same
+// format as go test uses.
+func (c TestConfig) Flags() []string {
+	var flags []string
+	if c.Verbose {
+		flags = append(flags, "-test.v")
+	}
+	if c.Short {
+		flags = append(flags, "-test.short")
+	}
+	if c.RunRegexp != "" {
+		flags = append(flags, "-test.run="+c.RunRegexp)
+	}
+	if c.Count > 1 {
+		flags = append(flags, "-test.count="+strconv.Itoa(c.Count))
+	}
+	if c.FailFast {
+		flags = append(flags, "-test.failfast")
+	}
+	if c.Timeout > 0 {
+		flags = append(flags, "-test.timeout="+c.Timeout.String())
+	}
+	if c.BenchRegexp != "" {
+		flags = append(flags, "-test.bench="+c.BenchRegexp)
+	}
+	if c.BenchTime != "" {
+		flags = append(flags, "-test.benchtime="+c.BenchTime)
+	}
+	if c.BenchMem {
+		flags = append(flags, "-test.benchmem")
+	}
+	if c.CoverProfile != "" {
+		// The test binary may not have access to the host filesystem (for
+		// example in an emulator), so the profile is written to stdout and
+		// extracted by tinygo test.
+		flags = append(flags, "-test.coverprofile=-")
+	}
+	return flags
 }
@@ -0,0 +1,64 @@
+package compileopts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTestConfigFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TestConfig
+		flags  []string
+	}{
+		{
+			name:   "Empty",
+			config: TestConfig{},
+			flags:  nil,
+		},
+		{
+			name:   "CountOfOne",
+			config: TestConfig{Count: 1},
+			flags:  nil,
+		},
+		{
+			name: "All",
+			config: TestConfig{
+				CompileTestBinary: true,
+				Verbose:           true,
+				Short:             true,
+				RunRegexp:         "TestFoo/bar",
+				Count:             3,
+				FailFast:          true,
+				Timeout:           90 * time.Second,
+				BenchRegexp:       ".",
+				BenchTime:         "100x",
+				BenchMem:          true,
+				CoverMode:         "count",
+				CoverProfile:      "coverage.out",
+			},
+			flags: []string{
+				"-test.v",
+				"-test.short",
+				"-test.run=TestFoo/bar",
+				"-test.count=3",
+				"-test.failfast",
+				"-test.timeout=1m30s",
+				"-test.bench=.",
+				"-test.benchtime=100x",
+				"-test.benchmem",
+				"-test.coverprofile=-",
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			flags := tc.config.Flags()
+			if !reflect.DeepEqual(flags, tc.flags) {
+				t.Errorf("expected flags %#v, got %#v", tc.flags, flags)
+			}
+		})
+	}
+}
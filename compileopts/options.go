@@ -7,38 +7,43 @@ import (
 )
 
 var (
-	validGCOptions            = []string{"none", "leaking", "extalloc", "conservative"}
-	validSchedulerOptions     = []string{"none", "tasks", "coroutines"}
+	validGCOptions            = []string{"none", "leaking", "extalloc", "conservative", "precise"}
+	validSchedulerOptions     = []string{"none", "tasks", "coroutines", "cores"}
 	validSerialOptions        = []string{"none", "uart", "usb"}
 	validPrintSizeOptions     = []string{"none", "short", "full"}
-	validPanicStrategyOptions = []string{"print", "trap"}
+	validPanicStrategyOptions = []string{"print", "trap", "unwind"}
 	validOptOptions           = []string{"none", "0", "1", "2", "s", "z"}
+	validBuildModeOptions     = []string{"default", "c-shared"}
+	validCoverModeOptions     = []string{"set", "count"}
 )
 
 // Options contains extra options to give to the compiler. These options are
 // usually passed from the command line.
 type Options struct {
-	Target          string
-	Opt             string
-	GC              string
-	PanicStrategy   string
-	Scheduler       string
-	Serial          string
-	PrintIR         bool
-	DumpSSA         bool
-	VerifyIR        bool
-	PrintCommands   func(cmd string, args ...string)
-	Debug           bool
-	PrintSizes      string
-	PrintAllocs     *regexp.Regexp // regexp string
-	PrintStacks     bool
-	Tags            string
-	WasmAbi         string
-	GlobalValues    map[string]map[string]string // map[pkgpath]map[varname]value
-	TestConfig      TestConfig
-	Programmer      string
-	OpenOCDCommands []string
-	LLVMFeatures    string
+	Target           string
+	BuildMode        string
+	Opt              string
+	GC               string
+	PanicStrategy    string
+	Scheduler        string
+	Serial           string
+	PrintIR          bool
+	DumpSSA          bool
+	VerifyIR         bool
+	PrintCommands    func(cmd string, args ...string)
+	Debug            bool
+	PrintSizes       string
+	PrintAllocs      *regexp.Regexp // regexp string
+	PrintStacks      bool
+	PrintGCStats     bool
+	NoReflectMethods bool
+	Tags             string
+	WasmAbi          string
+	GlobalValues     map[string]map[string]string // map[pkgpath]map[varname]value
+	TestConfig       TestConfig
+	Programmer       string
+	OpenOCDCommands  []string
+	LLVMFeatures     string
 }
 
 // Verify performs a validation on the given options, raising an error if options are not valid.
@@ -88,6 +93,24 @@ func (o *Options) Verify() error {
 		}
 	}
 
+	if o.BuildMode != "" {
+		valid := isInArray(validBuildModeOptions, o.BuildMode)
+		if !valid {
+			return fmt.Errorf(`invalid buildmode option '%s': valid values are %s`,
+				o.BuildMode,
+				strings.Join(validBuildModeOptions, ", "))
+		}
+	}
+
+	if o.TestConfig.CoverMode != "" {
+		valid := isInArray(validCoverModeOptions, o.TestConfig.CoverMode)
+		if !valid {
+			return fmt.Errorf(`invalid covermode option '%s': valid values are %s`,
+				o.TestConfig.CoverMode,
+				strings.Join(validCoverModeOptions, ", "))
+		}
+	}
+
 	if o.Opt != "" {
 		if !isInArray(validOptOptions, o.Opt) {
 			return fmt.Errorf("invalid -opt=%s: valid values are %s", o.Opt, strings.Join(validOptOptions, ", "))
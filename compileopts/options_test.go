@@ -9,10 +9,12 @@ import (
 
 func TestVerifyOptions(t *testing.T) {
 
-	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, extalloc, conservative`)
-	expectedSchedulerError := errors.New(`invalid scheduler option 'incorrect': valid values are none, tasks, coroutines`)
+	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, extalloc, conservative, precise`)
+	expectedSchedulerError := errors.New(`invalid scheduler option 'incorrect': valid values are none, tasks, coroutines, cores`)
 	expectedPrintSizeError := errors.New(`invalid size option 'incorrect': valid values are none, short, full`)
-	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap`)
+	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap, unwind`)
+	expectedBuildModeError := errors.New(`invalid buildmode option 'incorrect': valid values are default, c-shared`)
+	expectedCoverModeError := errors.New(`invalid covermode option 'atomic': valid values are set, count`)
 
 	testCases := []struct {
 		name          string
@@ -54,6 +56,12 @@ func TestVerifyOptions(t *testing.T) {
 				GC: "conservative",
 			},
 		},
+		{
+			name: "GCOptionPrecise",
+			opts: compileopts.Options{
+				GC: "precise",
+			},
+		},
 		{
 			name: "InvalidSchedulerOption",
 			opts: compileopts.Options{
@@ -123,6 +131,42 @@ func TestVerifyOptions(t *testing.T) {
 				PanicStrategy: "trap",
 			},
 		},
+		{
+			name: "PanicOptionUnwind",
+			opts: compileopts.Options{
+				PanicStrategy: "unwind",
+			},
+		},
+		{
+			name: "InvalidBuildModeOption",
+			opts: compileopts.Options{
+				BuildMode: "incorrect",
+			},
+			expectedError: expectedBuildModeError,
+		},
+		{
+			name: "BuildModeOptionCShared",
+			opts: compileopts.Options{
+				BuildMode: "c-shared",
+			},
+		},
+		{
+			name: "InvalidCoverModeOption",
+			opts: compileopts.Options{
+				TestConfig: compileopts.TestConfig{
+					CoverMode: "atomic",
+				},
+			},
+			expectedError: expectedCoverModeError,
+		},
+		{
+			name: "CoverModeOptionCount",
+			opts: compileopts.Options{
+				TestConfig: compileopts.TestConfig{
+					CoverMode: "count",
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
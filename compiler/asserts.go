@@ -228,8 +228,9 @@ func (b *builder) createRuntimeAssert(assert llvm.Value, blockPrefix, assertFunc
 
 	// Fail: the assert triggered so panic.
 	b.SetInsertPointAtEnd(faultBlock)
+	b.createCallSite(b.instrPos)
 	b.createRuntimeCall(assertFunc, nil, "")
-	b.CreateUnreachable()
+	b.createUnwind()
 
 	// Ok: assert didn't trigger so continue normally.
 	b.SetInsertPointAtEnd(nextBlock)
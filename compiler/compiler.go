@@ -23,7 +23,7 @@ import (
 // Version of the compiler pacakge. Must be incremented each time the compiler
 // package changes in a way that affects the generated LLVM module.
 // This version is independent of the TinyGo version number.
-const Version = 12 // last change: implement syscall.rawSyscallNoError
+const Version = 19 // last change: create MakeFunc trampolines for all func types
 
 func init() {
 	llvm.InitializeAllTargets()
@@ -53,12 +53,16 @@ type Config struct {
 	RelocationModel string
 
 	// Various compiler options that determine how code is generated.
+	BuildMode          string
 	Scheduler          string
+	PanicStrategy      string
 	FuncImplementation string
 	AutomaticStackSize bool
 	DefaultStackSize   uint64
 	NeedsStackObjects  bool
 	Debug              bool // Whether to emit debug information in the LLVM module.
+	StackTraces        bool // Whether to emit information for stack traces.
+	ReflectMethods     bool // Whether to emit method information for reflect.
 	LLVMFeatures       string
 }
 
@@ -84,6 +88,7 @@ type compilerContext struct {
 	diagnostics      []error
 	astComments      map[string]*ast.CommentGroup
 	runtimePkg       *types.Package
+	usedGlobals      []llvm.Value // globals that must not be removed (llvm.used)
 }
 
 // newCompilerContext returns a new compiler context ready for use, most
@@ -141,6 +146,15 @@ type builder struct {
 	phis              []phiNode
 	taskHandle        llvm.Value
 	deferPtr          llvm.Value
+	deferFrame        llvm.Value
+	recoverFrame      llvm.Value
+	landingPad        llvm.BasicBlock
+	unwindBlock       llvm.BasicBlock
+	callFrame         llvm.Value
+	callFrameParent   llvm.Value
+	callSites         map[int]llvm.Value
+	funcInfo          llvm.Value
+	instrPos          token.Pos
 	difunc            llvm.Metadata
 	dilocals          map[*types.Var]llvm.Metadata
 	allDeferFuncs     []interface{}
@@ -285,6 +299,20 @@ func CompilePackage(moduleName string, pkg *loader.Package, ssaPkg *ssa.Package,
 	defer irbuilder.Dispose()
 	c.createPackage(irbuilder, ssaPkg)
 
+	// Make sure globals that are only used by the linker are not removed by
+	// the optimizer.
+	if len(c.usedGlobals) != 0 {
+		values := make([]llvm.Value, len(c.usedGlobals))
+		for i, global := range c.usedGlobals {
+			values[i] = llvm.ConstBitCast(global, c.i8ptrType)
+		}
+		usedInitializer := llvm.ConstArray(c.i8ptrType, values)
+		used := llvm.AddGlobal(c.mod, usedInitializer.Type(), "llvm.used")
+		used.SetInitializer(usedInitializer)
+		used.SetLinkage(llvm.AppendingLinkage)
+		used.SetSection("llvm.metadata")
+	}
+
 	// see: https://reviews.llvm.org/D18355
 	if c.Debug {
 		c.mod.AddNamedMetadataOperand("llvm.module.flags",
@@ -764,6 +792,11 @@ func (c *compilerContext) createPackage(irbuilder llvm.Builder, pkg *ssa.Package
 				global.SetVisibility(llvm.HiddenVisibility)
 				if info.section != "" {
 					global.SetSection(info.section)
+					if strings.HasPrefix(info.section, ".custom_section.") {
+						// WebAssembly custom sections are not referenced from
+						// code, so they need to be kept alive explicitly.
+						c.usedGlobals = append(c.usedGlobals, global)
+					}
 				}
 			}
 		}
@@ -819,9 +852,12 @@ func (b *builder) createFunction() {
 			// info to at least have *something*.
 			filename := b.fn.Package().Pkg.Path() + "/<init>"
 			b.difunc = b.attachDebugInfoRaw(b.fn, b.llvmFn, "", filename, 0)
+			b.createDebugFuncInfo(filename, 0)
 		} else if b.fn.Syntax() != nil {
 			// Create debug info file if needed.
 			b.difunc = b.attachDebugInfo(b.fn)
+			pos := b.program.Fset.Position(b.fn.Syntax().Pos())
+			b.createDebugFuncInfo(pos.Filename, pos.Line)
 		}
 		pos := b.program.Fset.Position(b.fn.Pos())
 		b.SetCurrentDebugLocation(uint(pos.Line), uint(pos.Column), b.difunc, llvm.Metadata{})
@@ -836,6 +872,12 @@ func (b *builder) createFunction() {
 	entryBlock := b.blockEntries[b.fn.Blocks[0]]
 	b.SetInsertPointAtEnd(entryBlock)
 
+	if b.PanicStrategy == "unwind" && b.fn.Synthetic == "" {
+		// Find out whether this function was called directly by a deferred
+		// call. This must be done before any other call.
+		b.createDeferredCallCheck()
+	}
+
 	// Load function parameters
 	llvmParamIndex := 0
 	for _, param := range b.fn.Params {
@@ -895,6 +937,12 @@ func (b *builder) createFunction() {
 		}
 	}
 
+	if b.useCallFrames() {
+		// Push a call frame, to be able to print a stack trace from within
+		// this function.
+		b.createCallFrame()
+	}
+
 	if b.fn.Recover != nil {
 		// This function has deferred function calls. Set some things up for
 		// them.
@@ -957,6 +1005,16 @@ func (b *builder) createFunction() {
 		}
 	}
 
+	if !b.deferFrame.IsNil() {
+		// Run deferred functions when a panic reaches this function.
+		b.createLandingPad()
+	}
+
+	if !b.callFrame.IsNil() {
+		// Pop the call frame again before returning.
+		b.createCallFramePops()
+	}
+
 	if b.NeedsStackObjects {
 		// Track phi nodes.
 		for _, phi := range b.phis {
@@ -1034,6 +1092,12 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 		b.SetCurrentDebugLocation(uint(pos.Line), uint(pos.Column), b.difunc, llvm.Metadata{})
 	}
 
+	b.instrPos = getPos(instr)
+	if _, ok := instr.(*ssa.Panic); ok || mayUnwind(instr) {
+		// Remember where this call happens, for stack traces.
+		b.createCallSite(b.instrPos)
+	}
+
 	switch instr := instr.(type) {
 	case ssa.Value:
 		if value, err := b.createExpr(instr); err != nil {
@@ -1076,8 +1140,18 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 	case *ssa.Panic:
 		value := b.getValue(instr.X)
 		b.createRuntimeCall("_panic", []llvm.Value{value}, "")
-		b.CreateUnreachable()
+		b.createUnwind()
 	case *ssa.Return:
+		if !b.deferFrame.IsNil() {
+			// Pop the defer frame of this function. This re-raises the
+			// panic if it was not recovered.
+			b.createRuntimeCall("destroyDeferFrame", []llvm.Value{b.deferFrame}, "")
+		}
+		if b.info.exported && b.BuildMode == "c-shared" && b.fn.Pkg.Pkg.Path() != "runtime" {
+			// In a WASI reactor, run the goroutines started (or woken up) by
+			// this exported function before returning to the host.
+			b.createRuntimeCall("wasmExportExit", nil, "")
+		}
 		if len(instr.Results) == 0 {
 			b.CreateRetVoid()
 		} else if len(instr.Results) == 1 {
@@ -1107,6 +1181,12 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 	default:
 		b.addError(instr.Pos(), "unknown instruction: "+instr.String())
 	}
+
+	if b.PanicStrategy == "unwind" && mayUnwind(instr) {
+		// This instruction may have started a panic, which needs to be
+		// propagated to the caller.
+		b.blockExits[b.currentBlock] = b.createUnwindCheck()
+	}
 }
 
 // createBuiltin lowers a builtin Go function (append, close, delete, etc.) to
@@ -1125,7 +1205,8 @@ func (b *builder) createBuiltin(argTypes []types.Type, argValues []llvm.Value, c
 		elemsLen := b.CreateExtractValue(elems, 1, "append.elemsLen")
 		elemType := srcBuf.Type().ElementType()
 		elemSize := llvm.ConstInt(b.uintptrType, b.targetData.TypeAllocSize(elemType), false)
-		result := b.createRuntimeCall("sliceAppend", []llvm.Value{srcPtr, elemsPtr, srcLen, srcCap, elemsLen, elemSize}, "append.new")
+		elemLayout := b.createObjectLayout(elemType)
+		result := b.createRuntimeCall("sliceAppend", []llvm.Value{srcPtr, elemsPtr, srcLen, srcCap, elemsLen, elemSize, elemLayout}, "append.new")
 		newPtr := b.CreateExtractValue(result, 0, "append.newPtr")
 		newBuf := b.CreateBitCast(newPtr, srcBuf.Type(), "append.newBuf")
 		newLen := b.CreateExtractValue(result, 1, "append.newLen")
@@ -1266,7 +1347,14 @@ func (b *builder) createBuiltin(argTypes []types.Type, argValues []llvm.Value, c
 		cplx := argValues[0]
 		return b.CreateExtractValue(cplx, 0, "real"), nil
 	case "recover":
-		return b.createRuntimeCall("_recover", nil, ""), nil
+		if b.recoverFrame.IsNil() {
+			// Deferred functions are not run while panicking without
+			// -panic=unwind, so recover() always returns nil.
+			return llvm.ConstNull(b.getLLVMRuntimeType("_interface")), nil
+		}
+		// The frame is only set when this function was called directly by a
+		// deferred call, see createDeferredCallCheck.
+		return b.createRuntimeCall("_recover", []llvm.Value{b.recoverFrame}, ""), nil
 	case "ssa:wrapnilchk":
 		// TODO: do an actual nil check?
 		return argValues[0], nil
@@ -1431,7 +1519,8 @@ func (b *builder) createExpr(expr ssa.Value) (llvm.Value, error) {
 				return llvm.Value{}, b.makeError(expr.Pos(), fmt.Sprintf("value is too big (%v bytes)", size))
 			}
 			sizeValue := llvm.ConstInt(b.uintptrType, size, false)
-			buf := b.createRuntimeCall("alloc", []llvm.Value{sizeValue}, expr.Comment)
+			layoutValue := b.createObjectLayout(typ)
+			buf := b.createRuntimeCall("alloc", []llvm.Value{sizeValue, layoutValue}, expr.Comment)
 			buf = b.CreateBitCast(buf, llvm.PointerType(typ, 0), "")
 			return buf, nil
 		} else {
@@ -1644,7 +1733,8 @@ func (b *builder) createExpr(expr ssa.Value) (llvm.Value, error) {
 			return llvm.Value{}, err
 		}
 		sliceSize := b.CreateBinOp(llvm.Mul, elemSizeValue, sliceCapCast, "makeslice.cap")
-		slicePtr := b.createRuntimeCall("alloc", []llvm.Value{sliceSize}, "makeslice.buf")
+		layoutValue := b.createObjectLayout(llvmElemType)
+		slicePtr := b.createRuntimeCall("alloc", []llvm.Value{sliceSize, layoutValue}, "makeslice.buf")
 		slicePtr = b.CreateBitCast(slicePtr, llvm.PointerType(llvmElemType, 0), "makeslice.array")
 
 		// Extend or truncate if necessary. This is safe as we've already done
@@ -73,6 +73,7 @@ func TestCompiler(t *testing.T) {
 				GOARCH:             config.GOARCH(),
 				CodeModel:          config.CodeModel(),
 				RelocationModel:    config.RelocationModel(),
+				BuildMode:          config.BuildMode(),
 				Scheduler:          config.Scheduler(),
 				FuncImplementation: config.FuncImplementation(),
 				AutomaticStackSize: config.AutomaticStackSize(),
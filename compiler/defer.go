@@ -12,8 +12,22 @@ package compiler
 //   * On return, runtime.rundefers is called which calls all deferred functions
 //     from the head of the linked list until it has gone through all defer
 //     frames.
+//
+// With -panic=unwind, panics are implemented by returning from every function
+// until a function with deferred calls is reached:
+//   * runtime._panic sets the runtime.unwinding flag and returns, if there is
+//     a function on the stack that has deferred calls.
+//   * After every instruction that may panic, the flag is checked. If it is
+//     set, the function either returns immediately (with zero values) or jumps
+//     to its landing pad if it has deferred calls.
+//   * Functions with deferred calls push a runtime.deferFrame on entry, which
+//     is popped again on return. The landing pad moves the panic into this
+//     frame, runs all deferred functions and continues at the recover block.
+//     If none of the deferred functions called recover(), the panic is raised
+//     again when the frame is popped.
 
 import (
+	"go/token"
 	"go/types"
 
 	"github.com/tinygo-org/tinygo/compiler/llvmutil"
@@ -36,6 +50,177 @@ func (b *builder) deferInitFunc() {
 	deferType := llvm.PointerType(b.getLLVMRuntimeType("_defer"), 0)
 	b.deferPtr = b.CreateAlloca(deferType, "deferPtr")
 	b.CreateStore(llvm.ConstPointerNull(deferType), b.deferPtr)
+
+	if b.PanicStrategy == "unwind" {
+		// Push a new defer frame, which makes it possible to recover from a
+		// panic in this function (or in any function called from it).
+		b.deferFrame = b.CreateAlloca(b.getLLVMRuntimeType("deferFrame"), "deferFrame")
+		if b.NeedsStackObjects {
+			b.trackPointer(b.deferFrame)
+		}
+		b.createRuntimeCall("setupDeferFrame", []llvm.Value{b.deferFrame}, "")
+		b.landingPad = b.ctx.AddBasicBlock(b.llvmFn, "lpad")
+	}
+}
+
+// getUnwindBlock returns the block to jump to when this function needs to
+// unwind because of a panic. This is the landing pad for functions with
+// deferred calls, or a block that returns zero values otherwise. It returns a
+// nil block if panics are not unwound.
+func (b *builder) getUnwindBlock() llvm.BasicBlock {
+	if b.PanicStrategy != "unwind" || b.currentBlock == nil {
+		// Not unwinding, or not inside a regular function body (for example,
+		// in a wrapper function).
+		return llvm.BasicBlock{}
+	}
+	if !b.deferFrame.IsNil() {
+		return b.landingPad
+	}
+	if b.unwindBlock.IsNil() {
+		// Create a block that returns from this function immediately. The
+		// return values do not matter, as the caller will immediately unwind
+		// as well.
+		savedBlock := b.GetInsertBlock()
+		b.unwindBlock = b.ctx.AddBasicBlock(b.llvmFn, "unwind")
+		b.SetInsertPointAtEnd(b.unwindBlock)
+		returnType := b.llvmFn.Type().ElementType().ReturnType()
+		if returnType.TypeKind() == llvm.VoidTypeKind {
+			b.CreateRetVoid()
+		} else {
+			b.CreateRet(llvm.ConstNull(returnType))
+		}
+		b.SetInsertPointAtEnd(savedBlock)
+	}
+	return b.unwindBlock
+}
+
+// createUnwindCheck checks whether the last call started a panic, and if so,
+// jumps to the unwind block. Code emitted after this check runs in a new basic
+// block, which is returned.
+func (b *builder) createUnwindCheck() llvm.BasicBlock {
+	unwindBlock := b.getUnwindBlock()
+	if unwindBlock.IsNil() {
+		return b.GetInsertBlock()
+	}
+	unwindingGlobal := b.getGlobal(b.program.ImportedPackage("runtime").Members["unwinding"].(*ssa.Global))
+	unwinding := b.CreateLoad(unwindingGlobal, "unwinding")
+	nextBlock := b.ctx.AddBasicBlock(b.llvmFn, "unwind.next")
+	b.CreateCondBr(unwinding, unwindBlock, nextBlock)
+	b.SetInsertPointAtEnd(nextBlock)
+	return nextBlock
+}
+
+// createUnwind emits a jump to the unwind block, after a call that is known
+// to only return while a panic is being unwound. If panics are not unwound, an
+// unreachable instruction is emitted instead.
+func (b *builder) createUnwind() {
+	unwindBlock := b.getUnwindBlock()
+	if unwindBlock.IsNil() {
+		b.CreateUnreachable()
+		return
+	}
+	b.CreateBr(unwindBlock)
+}
+
+// createDeferredCallCheck is called at the start of every function with
+// -panic=unwind. The Go spec only allows recover() to stop a panic when it is
+// called directly by a deferred function, not by a function that a deferred
+// function calls. To check this, runtime.deferredCall is set right before each
+// deferred call (see createRunDefers) and cleared at the start of every
+// function. A function that calls recover() reads it first: if it is set, the
+// defer frame on top of the stack is the one running this function as a
+// deferred call, and the panic in that frame may be recovered.
+//
+// Wrappers generated by the ssa package are skipped, so that the function they
+// wrap can still see the flag.
+func (b *builder) createDeferredCallCheck() {
+	deferredCallGlobal := b.getGlobal(b.program.ImportedPackage("runtime").Members["deferredCall"].(*ssa.Global))
+	if !callsRecover(b.fn) {
+		b.CreateStore(llvm.ConstInt(b.ctx.Int1Type(), 0, false), deferredCallGlobal)
+		return
+	}
+	deferredCall := b.CreateLoad(deferredCallGlobal, "deferredCall")
+	b.CreateStore(llvm.ConstInt(b.ctx.Int1Type(), 0, false), deferredCallGlobal)
+	deferFramesGlobal := b.getGlobal(b.program.ImportedPackage("runtime").Members["deferFrames"].(*ssa.Global))
+	deferFrames := b.CreateLoad(deferFramesGlobal, "deferFrames")
+	b.recoverFrame = b.CreateSelect(deferredCall, deferFrames, llvm.ConstNull(deferFrames.Type()), "recover.frame")
+}
+
+// callsRecover returns whether the function contains a call to the recover()
+// builtin. A deferred call to recover() is not included, as it never stops a
+// panic.
+func callsRecover(fn *ssa.Function) bool {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if builtin, ok := call.Call.Value.(*ssa.Builtin); ok && builtin.Name() == "recover" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setDeferredCall sets or clears runtime.deferredCall around a deferred call,
+// see createDeferredCallCheck.
+func (b *builder) setDeferredCall(value bool) {
+	if b.deferFrame.IsNil() {
+		return
+	}
+	deferredCallGlobal := b.getGlobal(b.program.ImportedPackage("runtime").Members["deferredCall"].(*ssa.Global))
+	flag := uint64(0)
+	if value {
+		flag = 1
+	}
+	b.CreateStore(llvm.ConstInt(b.ctx.Int1Type(), flag, false), deferredCallGlobal)
+}
+
+// mayUnwind returns whether the given instruction may start a panic (by
+// calling into the runtime or any other function) and therefore needs to be
+// followed by an unwind check. Bounds checks and nil checks are not included:
+// they branch to the unwind block directly.
+func mayUnwind(instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.Call, *ssa.MapUpdate, *ssa.Send, *ssa.Select:
+		return true
+	case *ssa.Lookup:
+		// Map lookups may panic while hashing an interface key.
+		_, ok := instr.X.Type().Underlying().(*types.Map)
+		return ok
+	case *ssa.TypeAssert:
+		return !instr.CommaOk
+	case *ssa.UnOp:
+		return instr.Op == token.ARROW
+	case *ssa.BinOp:
+		// Comparing interfaces may panic on uncomparable dynamic types.
+		_, ok := instr.X.Type().Underlying().(*types.Interface)
+		return ok
+	default:
+		return false
+	}
+}
+
+// createLandingPad fills in the landing pad of a function with deferred
+// calls. A panic that reaches this function is stored in the defer frame, after
+// which all deferred functions are run. Execution then continues in the
+// recover block, which returns from the function and thereby pops the defer
+// frame. If the panic was not recovered, it is raised again at that point.
+func (b *builder) createLandingPad() {
+	b.SetInsertPointAtEnd(b.landingPad)
+
+	// Calls need a debug location in functions with debug information. Use
+	// the closing bracket of the function for this purpose.
+	if b.Debug && b.fn.Syntax() != nil {
+		pos := b.program.Fset.Position(b.fn.Syntax().End())
+		b.SetCurrentDebugLocation(uint(pos.Line), uint(pos.Column), b.difunc, llvm.Metadata{})
+	}
+
+	b.createRuntimeCall("enterLandingPad", []llvm.Value{b.deferFrame}, "")
+	b.createRunDefers()
+	b.CreateBr(b.blockEntries[b.fn.Recover])
 }
 
 // isInLoop checks if there is a path from a basic block to itself.
@@ -217,7 +402,8 @@ func (b *builder) createDefer(instr *ssa.Defer) {
 		// This may be hit a variable number of times, so use a heap allocation.
 		size := b.targetData.TypeAllocSize(deferFrameType)
 		sizeValue := llvm.ConstInt(b.uintptrType, size, false)
-		allocCall := b.createRuntimeCall("alloc", []llvm.Value{sizeValue}, "defer.alloc.call")
+		layoutValue := b.createObjectLayout(deferFrameType)
+		allocCall := b.createRuntimeCall("alloc", []llvm.Value{sizeValue, layoutValue}, "defer.alloc.call")
 		alloca = b.CreateBitCast(allocCall, llvm.PointerType(deferFrameType, 0), "defer.alloc")
 	}
 	if b.NeedsStackObjects {
@@ -345,7 +531,9 @@ func (b *builder) createRunDefers() {
 			// Parent coroutine handle.
 			forwardParams = append(forwardParams, llvm.Undef(b.i8ptrType))
 
+			b.setDeferredCall(true)
 			b.createCall(fnPtr, forwardParams, "")
+			b.setDeferredCall(false)
 
 		case *ssa.Function:
 			// Direct call.
@@ -379,7 +567,9 @@ func (b *builder) createRunDefers() {
 			}
 
 			// Call real function.
+			b.setDeferredCall(true)
 			b.createCall(b.getFunction(callback), forwardParams, "")
+			b.setDeferredCall(false)
 
 		case *ssa.MakeClosure:
 			// Get the real defer struct type and cast to it.
@@ -406,9 +596,17 @@ func (b *builder) createRunDefers() {
 			forwardParams = append(forwardParams, llvm.Undef(b.i8ptrType))
 
 			// Call deferred function.
+			b.setDeferredCall(true)
 			b.createCall(b.getFunction(fn), forwardParams, "")
+			b.setDeferredCall(false)
 		case *ssa.Builtin:
 			db := b.deferBuiltinFuncs[callback]
+			if db.callName == "recover" {
+				// A deferred call to recover() is not called directly by a
+				// deferred function, so it doesn't stop a panic:
+				//     defer recover()
+				break
+			}
 
 			//Get parameter types
 			valueTypes := []llvm.Type{b.uintptrType, llvm.PointerType(b.getLLVMRuntimeType("_defer"), 0)}
@@ -439,6 +637,10 @@ func (b *builder) createRunDefers() {
 			panic("unknown deferred function type")
 		}
 
+		// A deferred function may panic. Continue running the remaining
+		// deferred functions from the landing pad in that case.
+		b.createUnwindCheck()
+
 		// Branch back to the start of the loop.
 		b.CreateBr(loophead)
 	}
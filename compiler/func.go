@@ -19,11 +19,22 @@ func (b *builder) createFuncValue(funcPtr, context llvm.Value, sig *types.Signat
 // createFuncValue creates a function value from a raw function pointer with no
 // context.
 func (c *compilerContext) createFuncValue(builder llvm.Builder, funcPtr, context llvm.Value, sig *types.Signature) llvm.Value {
-	var funcValueScalar llvm.Value
+	funcValueScalar := c.getFuncValueScalar(funcPtr, sig)
+	funcValueType := c.getFuncType(sig)
+	funcValue := llvm.Undef(funcValueType)
+	funcValue = builder.CreateInsertValue(funcValue, context, 0, "")
+	funcValue = builder.CreateInsertValue(funcValue, funcValueScalar, 1, "")
+	return funcValue
+}
+
+// getFuncValueScalar returns the constant that is stored in the second field
+// of a func value for the given function: the function pointer itself or a
+// reference that will be replaced with a function ID during func lowering.
+func (c *compilerContext) getFuncValueScalar(funcPtr llvm.Value, sig *types.Signature) llvm.Value {
 	switch c.FuncImplementation {
 	case "doubleword":
 		// Closure is: {context, function pointer}
-		funcValueScalar = funcPtr
+		return funcPtr
 	case "switch":
 		funcValueWithSignatureGlobalName := funcPtr.Name() + "$withSignature"
 		funcValueWithSignatureGlobal := c.mod.NamedGlobal(funcValueWithSignatureGlobalName)
@@ -38,15 +49,10 @@ func (c *compilerContext) createFuncValue(builder llvm.Builder, funcPtr, context
 			funcValueWithSignatureGlobal.SetGlobalConstant(true)
 			funcValueWithSignatureGlobal.SetLinkage(llvm.LinkOnceODRLinkage)
 		}
-		funcValueScalar = llvm.ConstPtrToInt(funcValueWithSignatureGlobal, c.uintptrType)
+		return llvm.ConstPtrToInt(funcValueWithSignatureGlobal, c.uintptrType)
 	default:
 		panic("unimplemented func value variant")
 	}
-	funcValueType := c.getFuncType(sig)
-	funcValue := llvm.Undef(funcValueType)
-	funcValue = builder.CreateInsertValue(funcValue, context, 0, "")
-	funcValue = builder.CreateInsertValue(funcValue, funcValueScalar, 1, "")
-	return funcValue
 }
 
 // getFuncSignatureID returns a new external global for a given signature. This
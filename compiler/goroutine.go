@@ -92,7 +92,7 @@ func (b *builder) createGo(instr *ssa.Go) {
 		switch b.Scheduler {
 		case "none", "coroutines":
 			// There are no additional parameters needed for the goroutine start operation.
-		case "tasks":
+		case "tasks", "cores":
 			// Add the function pointer as a parameter to start the goroutine.
 			params = append(params, funcPtr)
 		default:
@@ -107,7 +107,7 @@ func (b *builder) createGo(instr *ssa.Go) {
 	paramBundle := b.emitPointerPack(params)
 	var callee, stackSize llvm.Value
 	switch b.Scheduler {
-	case "none", "tasks":
+	case "none", "tasks", "cores":
 		callee = b.createGoroutineStartWrapper(funcPtr, prefix, hasContext, instr.Pos())
 		if b.AutomaticStackSize {
 			// The stack size is not known until after linking. Call a dummy
@@ -119,7 +119,7 @@ func (b *builder) createGo(instr *ssa.Go) {
 		} else {
 			// The stack size is fixed at compile time. By emitting it here as a
 			// constant, it can be optimized.
-			if b.Scheduler == "tasks" && b.DefaultStackSize == 0 {
+			if (b.Scheduler == "tasks" || b.Scheduler == "cores") && b.DefaultStackSize == 0 {
 				b.addError(instr.Pos(), "default stack size for goroutines is not set")
 			}
 			stackSize = llvm.ConstInt(b.uintptrType, b.DefaultStackSize, false)
@@ -133,6 +133,20 @@ func (b *builder) createGo(instr *ssa.Go) {
 		panic("unreachable")
 	}
 	start := b.getFunction(b.program.ImportedPackage("internal/task").Members["start"].(*ssa.Function))
+	if b.Scheduler == "coroutines" && b.useCallFrames() {
+		// The new goroutine starts running right away, but it must start with
+		// an empty call stack instead of the call stack of this goroutine.
+		// Otherwise its first call frame would point to a frame of this
+		// goroutine, which may have been popped by the time it is resumed.
+		// The call stack of the new goroutine is stored in its task when it
+		// pauses (see transform.LowerCoroutines).
+		callStack := b.getCallStackGlobal()
+		parent := b.CreateLoad(callStack, "callStack.parent")
+		b.CreateStore(llvm.ConstNull(callStack.Type().ElementType()), callStack)
+		b.createCall(start, []llvm.Value{callee, paramBundle, stackSize, llvm.Undef(b.i8ptrType), llvm.ConstPointerNull(b.i8ptrType)}, "")
+		b.CreateStore(parent, callStack)
+		return
+	}
 	b.createCall(start, []llvm.Value{callee, paramBundle, stackSize, llvm.Undef(b.i8ptrType), llvm.ConstPointerNull(b.i8ptrType)}, "")
 }
 
@@ -46,6 +46,7 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 		var references llvm.Value
 		var length int64
 		var methodSet llvm.Value
+		var methods llvm.Value
 		var ptrTo llvm.Value
 		switch typ := typ.(type) {
 		case *types.Named:
@@ -66,9 +67,25 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 		case *types.Interface:
 			methodSetGlobal := c.getInterfaceMethodSet(typ)
 			references = llvm.ConstBitCast(methodSetGlobal, global.Type())
+		case *types.Map:
+			// Take a pointer to a {key, elem} pair of typecodes.
+			mapGlobal := c.makeMapTypeKeyElem(typ)
+			references = llvm.ConstBitCast(mapGlobal, global.Type())
+		case *types.Signature:
+			// Take a pointer to the parameter and result types, and the
+			// trampolines used by reflect.Value.Call and reflect.MakeFunc.
+			funcGlobal := c.makeFuncTypeInfo(typ)
+			references = llvm.ConstBitCast(funcGlobal, global.Type())
 		}
-		if _, ok := typ.Underlying().(*types.Interface); !ok {
+		if itf, ok := typ.Underlying().(*types.Interface); ok {
+			if c.ReflectMethods {
+				methods = c.makeInterfaceMethods(itf)
+			}
+		} else {
 			methodSet = c.getTypeMethodSet(typ)
+			if c.ReflectMethods {
+				methods = c.makeTypeMethods(typ)
+			}
 		}
 		if _, ok := typ.Underlying().(*types.Pointer); !ok {
 			ptrTo = c.getTypeCode(types.NewPointer(typ))
@@ -87,6 +104,10 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 		if !ptrTo.IsNil() {
 			globalValue = llvm.ConstInsertValue(globalValue, ptrTo, []uint32{3})
 		}
+		if !methods.IsNil() {
+			methods = llvm.ConstBitCast(methods, c.i8ptrType)
+			globalValue = llvm.ConstInsertValue(globalValue, methods, []uint32{4})
+		}
 		global.SetInitializer(globalValue)
 		global.SetLinkage(llvm.LinkOnceODRLinkage)
 		global.SetGlobalConstant(true)
@@ -136,6 +157,21 @@ func (c *compilerContext) makeStructTypeFields(typ *types.Struct) llvm.Value {
 	return structGlobal
 }
 
+// makeMapTypeKeyElem creates a new global that stores the key and element type
+// of a map type, as an array of two typecodes.
+func (c *compilerContext) makeMapTypeKeyElem(typ *types.Map) llvm.Value {
+	typecodePtrType := llvm.PointerType(c.getLLVMRuntimeType("typecodeID"), 0)
+	mapGlobalValue := llvm.ConstArray(typecodePtrType, []llvm.Value{
+		c.getTypeCode(typ.Key()),
+		c.getTypeCode(typ.Elem()),
+	})
+	mapGlobal := llvm.AddGlobal(c.mod, mapGlobalValue.Type(), "reflect/types.mapKeyElem")
+	mapGlobal.SetInitializer(mapGlobalValue)
+	mapGlobal.SetUnnamedAddr(true)
+	mapGlobal.SetLinkage(llvm.PrivateLinkage)
+	return mapGlobal
+}
+
 // getTypeCodeName returns a name for this type that can be used in the
 // interface lowering pass to assign type codes as expected by the reflect
 // package. See getTypeCodeNum.
@@ -207,6 +243,9 @@ func getTypeCodeName(t types.Type) string {
 		for i := 0; i < t.Params().Len(); i++ {
 			params[i] = getTypeCodeName(t.Params().At(i).Type())
 		}
+		if t.Variadic() {
+			params[len(params)-1] = "..." + params[len(params)-1]
+		}
 		results := make([]string, t.Results().Len())
 		for i := 0; i < t.Results().Len(); i++ {
 			results[i] = getTypeCodeName(t.Results().At(i).Type())
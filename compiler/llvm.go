@@ -47,6 +47,12 @@ func (b *builder) emitPointerPack(values []llvm.Value) llvm.Value {
 	return llvmutil.EmitPointerPack(b.Builder, b.mod, b.NeedsStackObjects, values)
 }
 
+// createObjectLayout returns the layout of the given type, to be passed to
+// runtime.alloc. See llvmutil.CreateObjectLayout for details.
+func (c *compilerContext) createObjectLayout(t llvm.Type) llvm.Value {
+	return llvmutil.CreateObjectLayout(c.mod, t)
+}
+
 // emitPointerUnpack extracts a list of values packed using emitPointerPack.
 func (b *builder) emitPointerUnpack(ptr llvm.Value, valueTypes []llvm.Type) []llvm.Value {
 	return llvmutil.EmitPointerUnpack(b.Builder, b.mod, ptr, valueTypes)
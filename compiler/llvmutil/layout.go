@@ -0,0 +1,147 @@
+package llvmutil
+
+// This file creates object layouts, which are passed to runtime.alloc. They are
+// used by the precise garbage collector (see src/runtime/gc_precise.go) to only
+// scan the words of a heap object that can actually contain a pointer.
+
+import (
+	"fmt"
+	"math/big"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// CreateObjectLayout returns the layout of the given type, as an *i8 value that
+// can be passed to runtime.alloc. Objects that are bigger than the type (such
+// as the backing array of a slice) repeat this layout. The layout is encoded as
+// follows, where a word is the alignment of a pointer:
+//
+//   - A nil pointer means the layout is not known, the object must be scanned
+//     conservatively.
+//   - If the lowest bit is set, the layout is stored in the pointer value
+//     itself. The next 4 (16-bit), 5 (32-bit) or 6 (64-bit) bits store the size
+//     of the type in words. The remaining bits are a bitmap with a bit set for
+//     every word that contains a pointer.
+//   - Otherwise, it points to a global that starts with the size in words (as
+//     an uintptr), followed by the bitmap as a byte array.
+//
+// A type without pointers is encoded as an inline layout of one word with no
+// pointer bits set.
+func CreateObjectLayout(mod llvm.Module, t llvm.Type) llvm.Value {
+	ctx := mod.Context()
+	targetData := llvm.NewTargetData(mod.DataLayout())
+	defer targetData.Dispose()
+	i8ptrType := llvm.PointerType(ctx.Int8Type(), 0)
+	uintptrType := ctx.IntType(targetData.PointerSize() * 8)
+	wordSize := uint64(targetData.PrefTypeAlignment(i8ptrType))
+
+	bitmap, ok := getPointerBitmap(targetData, t, wordSize)
+	if !ok {
+		// The type contains something that can't be described in a layout,
+		// such as an unaligned pointer.
+		return llvm.ConstPointerNull(i8ptrType)
+	}
+	size := targetData.TypeAllocSize(t)
+	sizeInWords := (size + wordSize - 1) / wordSize
+	if bitmap.BitLen() == 0 {
+		// There are no pointers in this type, so it doesn't matter how big
+		// the type is.
+		sizeInWords = 1
+	} else if size%wordSize != 0 {
+		// The layout can't be repeated for arrays of this type.
+		return llvm.ConstPointerNull(i8ptrType)
+	}
+
+	// Try to store the layout in the pointer value itself.
+	pointerBits := uint64(targetData.PointerSize()) * 8
+	sizeFieldBits := 4 + pointerBits/32
+	bitmapBits := pointerBits - 1 - sizeFieldBits
+	if sizeInWords < 1<<sizeFieldBits && uint64(bitmap.BitLen()) <= bitmapBits {
+		layout := 1 | sizeInWords<<1 | bitmap.Uint64()<<(1+sizeFieldBits)
+		return llvm.ConstIntToPtr(llvm.ConstInt(uintptrType, layout, false), i8ptrType)
+	}
+
+	// The layout is too big, store it in a global. Identical layouts are
+	// merged, as they have the same name.
+	name := fmt.Sprintf("runtime/gc.layout:%d-%x", sizeInWords, bitmap)
+	global := mod.NamedGlobal(name)
+	if global.IsNil() {
+		bitmapBytes := bitmap.Bytes() // big-endian
+		bitmapValues := make([]llvm.Value, (sizeInWords+7)/8)
+		for i := range bitmapValues {
+			var b byte
+			if i < len(bitmapBytes) {
+				b = bitmapBytes[len(bitmapBytes)-i-1]
+			}
+			bitmapValues[i] = llvm.ConstInt(ctx.Int8Type(), uint64(b), false)
+		}
+		initializer := ctx.ConstStruct([]llvm.Value{
+			llvm.ConstInt(uintptrType, sizeInWords, false),
+			llvm.ConstArray(ctx.Int8Type(), bitmapValues),
+		}, false)
+		global = llvm.AddGlobal(mod, initializer.Type(), name)
+		global.SetInitializer(initializer)
+		global.SetGlobalConstant(true)
+		global.SetUnnamedAddr(true)
+		global.SetLinkage(llvm.LinkOnceODRLinkage)
+		// The lowest bit of the pointer must be zero, see above.
+		alignment := targetData.ABITypeAlignment(uintptrType)
+		if alignment < 2 {
+			alignment = 2
+		}
+		global.SetAlignment(alignment)
+	}
+	return llvm.ConstBitCast(global, i8ptrType)
+}
+
+// getPointerBitmap scans the given LLVM type for pointers and sets bits in a
+// bigint at the word offset that contains a pointer. It returns false if the
+// type contains a pointer that isn't aligned to a word or if the type is not
+// supported.
+func getPointerBitmap(targetData llvm.TargetData, t llvm.Type, wordSize uint64) (*big.Int, bool) {
+	switch t.TypeKind() {
+	case llvm.IntegerTypeKind, llvm.FloatTypeKind, llvm.DoubleTypeKind:
+		return big.NewInt(0), true
+	case llvm.PointerTypeKind:
+		return big.NewInt(1), true
+	case llvm.StructTypeKind:
+		ptrs := big.NewInt(0)
+		for i, subtyp := range t.StructElementTypes() {
+			subptrs, ok := getPointerBitmap(targetData, subtyp, wordSize)
+			if !ok {
+				return nil, false
+			}
+			if subptrs.BitLen() == 0 {
+				continue
+			}
+			offset := targetData.ElementOffset(t, i)
+			if offset%wordSize != 0 {
+				return nil, false
+			}
+			subptrs.Lsh(subptrs, uint(offset/wordSize))
+			ptrs.Or(ptrs, subptrs)
+		}
+		return ptrs, true
+	case llvm.ArrayTypeKind:
+		subtyp := t.ElementType()
+		subptrs, ok := getPointerBitmap(targetData, subtyp, wordSize)
+		if !ok {
+			return nil, false
+		}
+		ptrs := big.NewInt(0)
+		if subptrs.BitLen() == 0 {
+			return ptrs, true
+		}
+		elementSize := targetData.TypeAllocSize(subtyp)
+		if elementSize%wordSize != 0 {
+			return nil, false
+		}
+		for i := 0; i < t.ArrayLength(); i++ {
+			ptrs.Lsh(ptrs, uint(elementSize/wordSize))
+			ptrs.Or(ptrs, subptrs)
+		}
+		return ptrs, true
+	default:
+		return nil, false
+	}
+}
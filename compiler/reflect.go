@@ -0,0 +1,352 @@
+package compiler
+
+// This file implements the compiler support for reflect.Value.Call and
+// reflect.MakeFunc. Every func type that may be used in reflection gets two
+// trampolines:
+//   * A call trampoline, which calls a func value of this type with the
+//     parameters loaded from memory, and stores the results in memory.
+//   * A MakeFunc trampoline, which implements this func type by storing the
+//     parameters in memory and calling reflect.makeFuncStub, which then calls
+//     the function passed to reflect.MakeFunc.
+// A reference to the call trampoline is stored with the func type, and the
+// MakeFunc trampoline is referenced from a separate global. From there, the
+// reflect lowering pass puts them in a side table. They are removed when the
+// reflect package doesn't use them.
+//
+// Parameters and results are stored in memory in the same way as the reflect
+// package stores them: every value is aligned to its Go alignment
+// (unsafe.Alignof) and directly follows the previous value.
+//
+// Types with exported methods also get a list of these methods, so that the
+// reflect package can list and call them. Each method refers to the function
+// that implements it, with the receiver as the first parameter. This list is
+// removed by the reflect lowering pass when the reflect package doesn't use it.
+
+import (
+	"go/token"
+	"go/types"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// reflectCallSignature is the Go signature of all call trampolines.
+var reflectCallSignature = types.NewSignature(nil, types.NewTuple(
+	types.NewParam(token.NoPos, nil, "fn", types.Typ[types.UnsafePointer]),
+	types.NewParam(token.NoPos, nil, "args", types.Typ[types.UnsafePointer]),
+	types.NewParam(token.NoPos, nil, "results", types.Typ[types.UnsafePointer]),
+), nil, false)
+
+// makeFuncTypeInfo creates a new global that stores all type information
+// related to this func type, and returns the resulting global. The global is a
+// struct of the call trampoline, whether the function is variadic, and the
+// parameter and result types. It also creates the MakeFunc trampoline.
+func (c *compilerContext) makeFuncTypeInfo(typ *types.Signature) llvm.Value {
+	typecodePtrType := llvm.PointerType(c.getLLVMRuntimeType("typecodeID"), 0)
+	params := make([]llvm.Value, typ.Params().Len())
+	for i := range params {
+		params[i] = c.getTypeCode(typ.Params().At(i).Type())
+	}
+	results := make([]llvm.Value, typ.Results().Len())
+	for i := range results {
+		results[i] = c.getTypeCode(typ.Results().At(i).Type())
+	}
+	var variadic uint64
+	if typ.Variadic() {
+		variadic = 1
+	}
+	funcGlobalValue := c.ctx.ConstStruct([]llvm.Value{
+		c.getReflectFuncCode(c.getReflectCallTrampoline(typ), reflectCallSignature),
+		llvm.ConstInt(c.uintptrType, variadic, false),
+		llvm.ConstArray(typecodePtrType, params),
+		llvm.ConstArray(typecodePtrType, results),
+	}, false)
+	funcGlobal := llvm.AddGlobal(c.mod, funcGlobalValue.Type(), "reflect/types.funcInfo")
+	funcGlobal.SetInitializer(funcGlobalValue)
+	funcGlobal.SetUnnamedAddr(true)
+	funcGlobal.SetLinkage(llvm.PrivateLinkage)
+	c.createMakeFuncCode(typ)
+	return funcGlobal
+}
+
+// makeTypeMethods creates a new global with the exported methods of the given
+// type, for use in reflect, and returns the resulting global. It returns nil
+// if the type has no exported methods. The global is an array of structs with
+// the method name, the method type (without receiver), the func type with the
+// receiver as first parameter and the function implementing the method.
+func (c *compilerContext) makeTypeMethods(typ types.Type) llvm.Value {
+	ms := c.program.MethodSets.MethodSet(typ)
+	var methods []llvm.Value
+	for i := 0; i < ms.Len(); i++ {
+		method := ms.At(i)
+		if !method.Obj().Exported() {
+			continue
+		}
+		fn := c.program.MethodValue(method)
+		llvmFn := c.getFunction(fn)
+		if llvmFn.IsNil() {
+			// compiler error, so panic
+			panic("cannot find function: " + c.getFunctionInfo(fn).linkName)
+		}
+
+		// The method set is sorted by name, and because only exported methods
+		// are included here the methods stay sorted.
+		name := c.makeMethodName(method.Obj().Name())
+
+		// The method type doesn't have a receiver. The func type has the
+		// receiver as the first parameter, which matches the function that
+		// implements this method (a wrapper for promoted methods, for example).
+		sig := method.Type().(*types.Signature)
+		methodType := types.NewSignature(nil, sig.Params(), sig.Results(), sig.Variadic())
+		params := []*types.Var{types.NewParam(token.NoPos, nil, "", typ)}
+		for j := 0; j < sig.Params().Len(); j++ {
+			params = append(params, sig.Params().At(j))
+		}
+		funcType := types.NewSignature(nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+
+		methods = append(methods, c.ctx.ConstStruct([]llvm.Value{
+			name,
+			c.getTypeCode(methodType),
+			c.getTypeCode(funcType),
+			c.getReflectFuncCode(llvmFn, funcType),
+		}, false))
+	}
+	return c.makeMethodsGlobal(methods)
+}
+
+// makeInterfaceMethods is like makeTypeMethods, but for interface types. The
+// methods of an interface type have no implementation, so the func type is the
+// same as the method type and the function is nil.
+func (c *compilerContext) makeInterfaceMethods(itf *types.Interface) llvm.Value {
+	var methods []llvm.Value
+	for i := 0; i < itf.NumMethods(); i++ {
+		method := itf.Method(i)
+		if !method.Exported() {
+			continue
+		}
+		sig := method.Type().(*types.Signature)
+		methodType := c.getTypeCode(types.NewSignature(nil, sig.Params(), sig.Results(), sig.Variadic()))
+		methods = append(methods, c.ctx.ConstStruct([]llvm.Value{
+			c.makeMethodName(method.Name()),
+			methodType,
+			methodType,
+			llvm.ConstInt(c.uintptrType, 0, false),
+		}, false))
+	}
+	return c.makeMethodsGlobal(methods)
+}
+
+// makeMethodName returns a pointer to the name of a method, to be stored in the
+// global created by makeMethodsGlobal.
+func (c *compilerContext) makeMethodName(name string) llvm.Value {
+	global := c.makeGlobalArray([]byte(name), "reflect/types.methodName", c.ctx.Int8Type())
+	global.SetLinkage(llvm.PrivateLinkage)
+	global.SetUnnamedAddr(true)
+	return llvm.ConstGEP(global, []llvm.Value{
+		llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+		llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+	})
+}
+
+// makeMethodsGlobal creates the global with the methods of a type, or returns
+// nil if there are no methods.
+func (c *compilerContext) makeMethodsGlobal(methods []llvm.Value) llvm.Value {
+	if len(methods) == 0 {
+		return llvm.Value{}
+	}
+	methodsValue := llvm.ConstArray(methods[0].Type(), methods)
+	methodsGlobal := llvm.AddGlobal(c.mod, methodsValue.Type(), "reflect/types.methods")
+	methodsGlobal.SetInitializer(methodsValue)
+	methodsGlobal.SetUnnamedAddr(true)
+	methodsGlobal.SetLinkage(llvm.PrivateLinkage)
+	return methodsGlobal
+}
+
+// getReflectFuncCode returns the Code field of a func value (as used in
+// reflect) of the given function, as an uintptr.
+func (c *compilerContext) getReflectFuncCode(fn llvm.Value, sig *types.Signature) llvm.Value {
+	code := c.getFuncValueScalar(fn, sig)
+	if code.Type().TypeKind() == llvm.PointerTypeKind {
+		code = llvm.ConstPtrToInt(code, c.uintptrType)
+	}
+	return code
+}
+
+// createMakeFuncCode creates a global with the Code field of a func value (as
+// used in reflect) of the MakeFunc trampoline for the given func type, if it
+// doesn't exist yet. The reflect lowering pass finds it by name, using the name
+// of the type code.
+func (c *compilerContext) createMakeFuncCode(typ *types.Signature) {
+	name := "reflect/makefunc.code:" + getTypeCodeName(typ)
+	if !c.mod.NamedGlobal(name).IsNil() {
+		return
+	}
+	code := c.getReflectFuncCode(c.getMakeFuncTrampoline(typ), typ)
+	global := llvm.AddGlobal(c.mod, code.Type(), name)
+	global.SetInitializer(code)
+	global.SetLinkage(llvm.LinkOnceODRLinkage)
+	global.SetGlobalConstant(true)
+	global.SetUnnamedAddr(true)
+}
+
+// getReflectCallTrampoline returns the call trampoline for the given func
+// type, creating it if needed. It has the following signature:
+//     func(fn, args, results unsafe.Pointer)
+// where fn points to the func value that should be called.
+func (c *compilerContext) getReflectCallTrampoline(typ *types.Signature) llvm.Value {
+	name := "reflect/call:" + getTypeCodeName(typ)
+	fn := c.mod.NamedFunction(name)
+	if !fn.IsNil() {
+		return fn
+	}
+	fn = llvm.AddFunction(c.mod, name, c.getRawFuncType(reflectCallSignature).ElementType())
+	fn.SetLinkage(llvm.LinkOnceODRLinkage)
+	fn.SetUnnamedAddr(true)
+
+	// Create a new builder just to create this trampoline.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+	b.SetInsertPointAtEnd(c.ctx.AddBasicBlock(fn, "entry"))
+
+	// Load the func value and the parameters.
+	sizes := Sizes(c.machine)
+	funcValuePtr := b.CreateBitCast(fn.Param(0), llvm.PointerType(c.getFuncType(typ), 0), "")
+	funcValue := b.CreateLoad(funcValuePtr, "")
+	funcPtr, context := b.decodeFuncValue(funcValue, typ)
+	params := b.loadReflectValues(sizes, fn.Param(1), typ.Params())
+
+	// Do the call, and store the results.
+	params = append(params, context, fn.Param(4)) // context, parentHandle
+	result := b.createCall(funcPtr, params, "")
+	switch typ.Results().Len() {
+	case 0:
+	case 1:
+		b.storeReflectValues(sizes, fn.Param(2), typ.Results(), []llvm.Value{result})
+	default:
+		results := make([]llvm.Value, typ.Results().Len())
+		for i := range results {
+			results[i] = b.CreateExtractValue(result, i, "")
+		}
+		b.storeReflectValues(sizes, fn.Param(2), typ.Results(), results)
+	}
+	b.CreateRetVoid()
+
+	return fn
+}
+
+// getMakeFuncTrampoline returns the MakeFunc trampoline for the given func
+// type, creating it if needed. It has the given signature, and expects a
+// pointer to a reflect.makeFuncImpl as the context parameter.
+func (c *compilerContext) getMakeFuncTrampoline(typ *types.Signature) llvm.Value {
+	name := "reflect/makefunc:" + getTypeCodeName(typ)
+	fn := c.mod.NamedFunction(name)
+	if !fn.IsNil() {
+		return fn
+	}
+	fn = llvm.AddFunction(c.mod, name, c.getRawFuncType(typ).ElementType())
+	fn.SetLinkage(llvm.LinkOnceODRLinkage)
+	fn.SetUnnamedAddr(true)
+
+	// Create a new builder just to create this trampoline.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+	b.SetInsertPointAtEnd(c.ctx.AddBasicBlock(fn, "entry"))
+
+	// Collapse the expanded parameters back into Go values.
+	llvmParams := fn.Params()
+	params := make([]llvm.Value, typ.Params().Len())
+	for i := range params {
+		paramType := c.getLLVMType(typ.Params().At(i).Type())
+		numFields := len(c.expandFormalParamType(paramType, "", nil))
+		params[i] = b.collapseFormalParam(paramType, llvmParams[:numFields])
+		llvmParams = llvmParams[numFields:]
+	}
+	context, parentHandle := llvmParams[0], llvmParams[1]
+
+	// Store the parameters in memory and call makeFuncStub. Allocate these
+	// buffers on the heap, so that the garbage collector can find the
+	// pointers stored in them. They are scanned conservatively (with a nil
+	// layout), as makeFuncStub doesn't know about their layout either.
+	sizes := Sizes(c.machine)
+	nilLayout := llvm.ConstPointerNull(c.i8ptrType)
+	_, argsSize := getReflectValuesLayout(sizes, typ.Params())
+	args := b.createRuntimeCall("alloc", []llvm.Value{llvm.ConstInt(c.uintptrType, uint64(argsSize), false), nilLayout}, "args")
+	b.storeReflectValues(sizes, args, typ.Params(), params)
+	_, resultsSize := getReflectValuesLayout(sizes, typ.Results())
+	results := b.createRuntimeCall("alloc", []llvm.Value{llvm.ConstInt(c.uintptrType, uint64(resultsSize), false), nilLayout}, "results")
+	stub := c.mod.NamedFunction("reflect.makeFuncStub")
+	if stub.IsNil() {
+		// func makeFuncStub(context, args, results unsafe.Pointer)
+		stubType := llvm.FunctionType(c.ctx.VoidType(), []llvm.Type{c.i8ptrType, c.i8ptrType, c.i8ptrType, c.i8ptrType, c.i8ptrType}, false)
+		stub = llvm.AddFunction(c.mod, "reflect.makeFuncStub", stubType)
+	}
+	b.CreateCall(stub, []llvm.Value{context, args, results, llvm.Undef(c.i8ptrType), parentHandle}, "")
+
+	// Load the results and return them.
+	resultValues := b.loadReflectValues(sizes, results, typ.Results())
+	switch len(resultValues) {
+	case 0:
+		b.CreateRetVoid()
+	case 1:
+		b.CreateRet(resultValues[0])
+	default:
+		result := llvm.Undef(fn.Type().ElementType().ReturnType())
+		for i, value := range resultValues {
+			result = b.CreateInsertValue(result, value, i, "")
+		}
+		b.CreateRet(result)
+	}
+
+	return fn
+}
+
+// getReflectValuesLayout returns the offset of each value in the tuple and the
+// total size when these values are stored in memory for reflect.
+func getReflectValuesLayout(sizes types.Sizes, tuple *types.Tuple) (offsets []int64, size int64) {
+	vars := make([]*types.Var, tuple.Len())
+	for i := range vars {
+		vars[i] = tuple.At(i)
+	}
+	offsets = sizes.Offsetsof(vars)
+	if len(vars) != 0 {
+		size = offsets[len(vars)-1] + sizes.Sizeof(vars[len(vars)-1].Type())
+	}
+	return
+}
+
+// loadReflectValues loads all values in the tuple from the buffer at ptr.
+func (b *builder) loadReflectValues(sizes types.Sizes, ptr llvm.Value, tuple *types.Tuple) []llvm.Value {
+	offsets, _ := getReflectValuesLayout(sizes, tuple)
+	values := make([]llvm.Value, tuple.Len())
+	for i := range values {
+		valuePtr := b.getReflectValuePointer(ptr, offsets[i], tuple.At(i).Type())
+		values[i] = b.CreateLoad(valuePtr, "")
+		values[i].SetAlignment(int(sizes.Alignof(tuple.At(i).Type())))
+	}
+	return values
+}
+
+// storeReflectValues stores the given values (of the types in the tuple) in
+// the buffer at ptr.
+func (b *builder) storeReflectValues(sizes types.Sizes, ptr llvm.Value, tuple *types.Tuple, values []llvm.Value) {
+	offsets, _ := getReflectValuesLayout(sizes, tuple)
+	for i, value := range values {
+		valuePtr := b.getReflectValuePointer(ptr, offsets[i], tuple.At(i).Type())
+		store := b.CreateStore(value, valuePtr)
+		store.SetAlignment(int(sizes.Alignof(tuple.At(i).Type())))
+	}
+}
+
+// getReflectValuePointer returns a pointer to the value of the given type at
+// the given offset in the buffer at ptr.
+func (b *builder) getReflectValuePointer(ptr llvm.Value, offset int64, typ types.Type) llvm.Value {
+	valuePtr := b.CreateInBoundsGEP(ptr, []llvm.Value{
+		llvm.ConstInt(b.uintptrType, uint64(offset), false),
+	}, "")
+	return b.CreateBitCast(valuePtr, llvm.PointerType(b.getLLVMType(typ), 0), "")
+}
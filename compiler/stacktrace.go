@@ -0,0 +1,182 @@
+package compiler
+
+// This file emits the information necessary for stack traces, used by
+// runtime.Callers and for printing a backtrace on panic. On most targets, the
+// runtime walks the frame pointers of the call stack and looks up each return
+// address in a table of call instructions, which is created after optimization
+// from the debug information (see transform.CreatePCTable). The compiler only
+// needs to make the name of every function available to that pass.
+//
+// On WebAssembly the call stack cannot be inspected, and on Windows the frame
+// pointers do not form a linked list. On these targets, a shadow call stack is
+// used instead:
+//   * Every function pushes a runtime.callFrame on entry to the linked list of
+//     call frames of the current goroutine (runtime.callStack), and pops it
+//     again before returning.
+//   * Before every instruction that may call another function (or panic), the
+//     pc field of the call frame is updated to point to a constant call site
+//     record that contains the function name and line number.
+// In both cases, all call site records are bundled in a single table after
+// linking (see transform.CreateCallSiteTable), so that the runtime can check
+// whether a given pc value is valid.
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// useCallFrames returns whether stack traces need a shadow call stack, because
+// the runtime cannot walk the call stack on this target.
+func (c *compilerContext) useCallFrames() bool {
+	return c.StackTraces && (strings.HasPrefix(c.Triple, "wasm") || strings.Contains(c.Triple, "windows"))
+}
+
+// getCallStackGlobal returns the runtime.callStack global, which points to the
+// innermost call frame of the currently running goroutine.
+func (b *builder) getCallStackGlobal() llvm.Value {
+	return b.getGlobal(b.program.ImportedPackage("runtime").Members["callStack"].(*ssa.Global))
+}
+
+// createCallFrame pushes a new call frame for this function. It must be called
+// from within the entry block.
+func (b *builder) createCallFrame() {
+	b.callSites = make(map[int]llvm.Value)
+	callStack := b.getCallStackGlobal()
+	b.callFrame = b.CreateAlloca(b.getLLVMRuntimeType("callFrame"), "callFrame")
+	b.callFrameParent = b.CreateLoad(callStack, "callFrame.parent")
+	zero := llvm.ConstInt(b.ctx.Int32Type(), 0, false)
+	parentPtr := b.CreateInBoundsGEP(b.callFrame, []llvm.Value{zero, zero}, "")
+	b.CreateStore(b.callFrameParent, parentPtr)
+	pcPtr := b.CreateInBoundsGEP(b.callFrame, []llvm.Value{zero, llvm.ConstInt(b.ctx.Int32Type(), 1, false)}, "")
+	b.CreateStore(llvm.ConstInt(b.uintptrType, 0, false), pcPtr)
+	b.CreateStore(b.callFrame, callStack)
+}
+
+// createCallFramePops pops the call frame of this function before every
+// return instruction. It must be called after all instructions of the function
+// have been created.
+func (b *builder) createCallFramePops() {
+	callStack := b.getCallStackGlobal()
+	for bb := b.llvmFn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+		ret := bb.LastInstruction()
+		if ret.IsNil() || ret.InstructionOpcode() != llvm.Ret {
+			continue
+		}
+		b.SetInsertPointBefore(ret)
+		b.CreateStore(b.callFrameParent, callStack)
+	}
+}
+
+// createCallSite updates the pc of the current call frame to the call site at
+// the given position. It does nothing if this function has no call frame.
+func (b *builder) createCallSite(pos token.Pos) {
+	if b.callFrame.IsNil() {
+		return
+	}
+	position := b.program.Fset.Position(pos)
+	site, ok := b.callSites[position.Line]
+	if !ok {
+		// Create a new call site record for this line.
+		siteType := b.getLLVMRuntimeType("callSite")
+		site = llvm.AddGlobal(b.mod, siteType, "runtime.callsite:"+b.info.linkName+":"+strconv.Itoa(position.Line))
+		site.SetInitializer(llvm.ConstNamedStruct(siteType, []llvm.Value{
+			b.getFuncInfo(position.Filename),
+			llvm.ConstInt(b.intType, uint64(position.Line), false),
+			llvm.ConstPointerNull(llvm.PointerType(siteType, 0)),
+		}))
+		site.SetLinkage(llvm.InternalLinkage)
+		site.SetGlobalConstant(true)
+		b.callSites[position.Line] = site
+	}
+	zero := llvm.ConstInt(b.ctx.Int32Type(), 0, false)
+	pcPtr := b.CreateInBoundsGEP(b.callFrame, []llvm.Value{zero, llvm.ConstInt(b.ctx.Int32Type(), 1, false)}, "")
+	b.CreateStore(llvm.ConstPtrToInt(site, b.uintptrType), pcPtr)
+}
+
+// getFuncInfo returns the runtime.Func object for the current function,
+// creating it if needed. The filename is only used when the function itself
+// has no position, such as for package initializers.
+func (b *builder) getFuncInfo(filename string) llvm.Value {
+	if !b.funcInfo.IsNil() {
+		return b.funcInfo
+	}
+	if pos := b.program.Fset.Position(b.fn.Pos()); pos.IsValid() {
+		filename = pos.Filename
+	}
+	funcType := b.getLLVMRuntimeType("Func")
+	b.funcInfo = llvm.AddGlobal(b.mod, funcType, "runtime.funcinfo:"+b.info.linkName)
+	b.funcInfo.SetInitializer(llvm.ConstNamedStruct(funcType, []llvm.Value{
+		b.createConst(b.info.linkName+"$funcname", ssa.NewConst(constant.MakeString(goFunctionName(b.fn)), types.Typ[types.String])),
+		b.createConst(b.info.linkName+"$funcfile", ssa.NewConst(constant.MakeString(filename), types.Typ[types.String])),
+	}))
+	b.funcInfo.SetLinkage(llvm.InternalLinkage)
+	b.funcInfo.SetGlobalConstant(true)
+	return b.funcInfo
+}
+
+// createDebugFuncInfo creates the runtime.Func object for the current function,
+// which has debug information at the given file and line. It is named after
+// this location so that transform.CreatePCTable can find it for the
+// DISubprogram of the function, also when the function has been inlined.
+// Therefore it has weak_odr linkage, to keep it until that pass has run.
+func (b *builder) createDebugFuncInfo(filename string, line int) {
+	if !b.StackTraces || b.useCallFrames() {
+		return
+	}
+	name := "runtime.funcinfo:" + filepath.Clean(filename) + ":" + strconv.Itoa(line)
+	if !b.mod.NamedGlobal(name).IsNil() {
+		// Another function was declared at the same location.
+		return
+	}
+	funcType := b.getLLVMRuntimeType("Func")
+	funcInfo := llvm.AddGlobal(b.mod, funcType, name)
+	funcInfo.SetInitializer(llvm.ConstNamedStruct(funcType, []llvm.Value{
+		b.createConst(b.info.linkName+"$funcname", ssa.NewConst(constant.MakeString(goFunctionName(b.fn)), types.Typ[types.String])),
+		b.createConst(b.info.linkName+"$funcfile", ssa.NewConst(constant.MakeString(filename), types.Typ[types.String])),
+	}))
+	funcInfo.SetLinkage(llvm.WeakODRLinkage)
+	funcInfo.SetGlobalConstant(true)
+}
+
+// goFunctionName returns the name of the function as it would be reported by
+// the standard Go runtime, for example "main.main.func1" for a closure or
+// "main.(*T).String" for a method.
+func goFunctionName(fn *ssa.Function) string {
+	if parent := fn.Parent(); parent != nil {
+		// Anonymous function. These are named main$1, main$2, etc. by the ssa
+		// package.
+		num := fn.Name()[strings.LastIndexByte(fn.Name(), '$')+1:]
+		if parent.Parent() == nil {
+			return goFunctionName(parent) + ".func" + num
+		}
+		return goFunctionName(parent) + "." + num
+	}
+	if fn.Pkg == nil {
+		// Synthetic wrapper function outside of a package.
+		return fn.RelString(nil)
+	}
+	name := fn.Name()
+	if recv := fn.Signature.Recv(); recv != nil {
+		recvType := recv.Type()
+		pointer := false
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType = ptr.Elem()
+			pointer = true
+		}
+		if named, ok := recvType.(*types.Named); ok {
+			if pointer {
+				name = "(*" + named.Obj().Name() + ")." + name
+			} else {
+				name = named.Obj().Name() + "." + name
+			}
+		}
+	}
+	return fn.Pkg.Pkg.Path() + "." + name
+}
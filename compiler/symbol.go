@@ -162,6 +162,15 @@ func (c *compilerContext) getFunction(fn *ssa.Function) llvm.Value {
 	// External/exported functions may not retain pointer values.
 	// https://golang.org/cmd/cgo/#hdr-Passing_pointers
 	if info.exported {
+		if strings.HasPrefix(c.Triple, "wasm") && !strings.HasPrefix(fn.Name(), "C.") {
+			// Functions returning more than one value (such as a string, or
+			// a tuple of values) can only be exported or imported with the
+			// multi-value proposal. Without it, LLVM silently returns the
+			// values through a hidden pointer parameter instead.
+			if n := countScalarValues(retType); n > 1 && !c.hasWasmMultiValue() {
+				c.addError(fn.Pos(), "exported function "+fn.Name()+" returns "+strconv.Itoa(n)+" values, which requires the WebAssembly multi-value feature (-llvm-features=+multivalue)")
+			}
+		}
 		// Set the wasm-import-module attribute if the function's module is set.
 		if info.module != "" {
 
@@ -471,3 +480,40 @@ func hasUnsafeImport(pkg *types.Package) bool {
 	}
 	return false
 }
+
+// hasWasmMultiValue returns whether the WebAssembly multi-value feature is
+// enabled, either in the target or with the -llvm-features flag. With this
+// feature, a function can return more than one value.
+func (c *compilerContext) hasWasmMultiValue() bool {
+	enabled := false
+	features := append(append([]string{}, c.Features...), strings.Split(c.LLVMFeatures, ",")...)
+	for _, feature := range features {
+		switch strings.TrimSpace(feature) {
+		case "+multivalue":
+			enabled = true
+		case "-multivalue":
+			enabled = false
+		}
+	}
+	return enabled
+}
+
+// countScalarValues returns the number of values of the given type once all
+// structs and arrays are flattened, which is how LLVM passes them in
+// WebAssembly.
+func countScalarValues(t llvm.Type) int {
+	switch t.TypeKind() {
+	case llvm.VoidTypeKind:
+		return 0
+	case llvm.StructTypeKind:
+		n := 0
+		for _, elementType := range t.StructElementTypes() {
+			n += countScalarValues(elementType)
+		}
+		return n
+	case llvm.ArrayTypeKind:
+		return t.ArrayLength() * countScalarValues(t.ElementType())
+	default:
+		return 1
+	}
+}
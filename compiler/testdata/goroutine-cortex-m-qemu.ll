@@ -9,7 +9,7 @@ target triple = "armv7m-none-eabi"
 %"internal/task.state" = type { i32, i32* }
 %runtime.chanSelectState = type { %runtime.channel*, i8* }
 
-declare noalias nonnull i8* @runtime.alloc(i32, i8*, i8*)
+declare noalias nonnull i8* @runtime.alloc(i32, i8*, i8*, i8*)
 
 define hidden void @main.init(i8* %context, i8* %parentHandle) unnamed_addr {
 entry:
@@ -57,10 +57,10 @@ entry:
 
 define hidden void @main.closureFunctionGoroutine(i8* %context, i8* %parentHandle) unnamed_addr {
 entry:
-  %n = call i8* @runtime.alloc(i32 4, i8* undef, i8* null)
+  %n = call i8* @runtime.alloc(i32 4, i8* nonnull inttoptr (i32 3 to i8*), i8* undef, i8* null)
   %0 = bitcast i8* %n to i32*
   store i32 3, i32* %0, align 4
-  %1 = call i8* @runtime.alloc(i32 8, i8* undef, i8* null)
+  %1 = call i8* @runtime.alloc(i32 8, i8* nonnull inttoptr (i32 133 to i8*), i8* undef, i8* null)
   %2 = bitcast i8* %1 to i32*
   store i32 5, i32* %2, align 4
   %3 = getelementptr inbounds i8, i8* %1, i32 4
@@ -95,7 +95,7 @@ declare void @runtime.printint32(i32, i8*, i8*)
 
 define hidden void @main.funcGoroutine(i8* %fn.context, void (i32, i8*, i8*)* %fn.funcptr, i8* %context, i8* %parentHandle) unnamed_addr {
 entry:
-  %0 = call i8* @runtime.alloc(i32 12, i8* undef, i8* null)
+  %0 = call i8* @runtime.alloc(i32 12, i8* nonnull inttoptr (i32 391 to i8*), i8* undef, i8* null)
   %1 = bitcast i8* %0 to i32*
   store i32 5, i32* %1, align 4
   %2 = getelementptr inbounds i8, i8* %0, i32 4
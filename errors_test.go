@@ -0,0 +1,74 @@
+package main
+
+// This file tests the errors reported by the compiler, by compiling the Go
+// files in testdata/errors and comparing the errors with the expected errors
+// in the "// ERROR:" comments at the end of each file.
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		target string
+	}{
+		{"multivalue.go", "wasi"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testErrorMessages(t, TESTDATA+"/errors/"+tc.name, &compileopts.Options{
+				Target:   tc.target,
+				Opt:      "z",
+				VerifyIR: true,
+				Debug:    true,
+			})
+		})
+	}
+}
+
+// testErrorMessages compiles the given file, which must fail, and compares the
+// reported errors with the expected errors.
+func testErrorMessages(t *testing.T, filename string, options *compileopts.Options) {
+	// Parse the expected error messages.
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal("could not read input file:", err)
+	}
+	var expected []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "// ERROR: ") {
+			expected = append(expected, strings.TrimPrefix(line, "// ERROR: "))
+		}
+	}
+
+	// Try to build a binary, which must fail.
+	tmpdir, err := ioutil.TempDir("", "tinygo-test")
+	if err != nil {
+		t.Fatal("could not create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	err = runBuild("./"+filename, filepath.Join(tmpdir, "out"), options)
+	if err == nil {
+		t.Fatal("expected to get a compiler error")
+	}
+
+	// Compare the printed errors with the expected errors.
+	buf := &bytes.Buffer{}
+	printCompilerError(func(args ...interface{}) {
+		fmt.Fprintln(buf, args...)
+	}, err)
+	actual := strings.TrimRight(buf.String(), "\n")
+	if actual != strings.Join(expected, "\n") {
+		t.Errorf("expected error:\n%s\ngot:\n%s", strings.Join(expected, "\n"), actual)
+	}
+}
@@ -355,6 +355,13 @@ func (r *runner) run(fn *function, params []value, parentMem *memoryView, indent
 					elementType := llvm.ConstExtractValue(typecodeID.Initializer(), []uint32{0})
 					uintptrType := r.mod.Context().IntType(int(mem.r.pointerSize) * 8)
 					locals[inst.localIndex] = r.getValue(llvm.ConstPtrToInt(elementType, uintptrType))
+				case "map":
+					// The element type is the second entry in the {key, elem}
+					// array referenced from the typecode.
+					keyElem := llvm.ConstExtractValue(typecodeID.Initializer(), []uint32{0}).Operand(0).Initializer()
+					elementType := llvm.ConstExtractValue(keyElem, []uint32{1})
+					uintptrType := r.mod.Context().IntType(int(mem.r.pointerSize) * 8)
+					locals[inst.localIndex] = r.getValue(llvm.ConstPtrToInt(elementType, uintptrType))
 				default:
 					return nil, mem, r.errorAt(inst, fmt.Errorf("(reflect.Type).Elem() called on %s type", class))
 				}
@@ -231,6 +231,8 @@ func pathsToOverride(needsSyscallPackage bool) map[string]bool {
 		"machine/":              false,
 		"net/":                  true,
 		"os/":                   true,
+		"os/flashfs/":           false,
+		"os/ramfs/":             false,
 		"reflect/":              false,
 		"runtime/":              false,
 		"sync/":                 true,
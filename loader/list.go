@@ -1,6 +1,8 @@
 package loader
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -28,3 +30,21 @@ func List(config *compileopts.Config, extraArgs, pkgs []string) (*exec.Cmd, erro
 	cmd.Env = append(os.Environ(), "GOROOT="+goroot, "GOOS="+config.GOOS(), "GOARCH="+config.GOARCH(), "CGO_ENABLED="+cgoEnabled)
 	return cmd, nil
 }
+
+// ListPackages expands the given package patterns (such as ./...) into the
+// import paths of the matching packages, in the same way as the go tool does
+// it for the current configuration.
+func ListPackages(config *compileopts.Config, patterns []string) ([]string, error) {
+	cmd, err := List(config, []string{"-f", "{{.ImportPath}}"}, patterns)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	cmd.Stdout = buf
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run `go list`: %s", err)
+	}
+	return strings.Fields(buf.String()), nil
+}
@@ -48,9 +48,10 @@ type PackageJSON struct {
 	ForTest    string
 
 	// Source files
-	GoFiles  []string
-	CgoFiles []string
-	CFiles   []string
+	GoFiles     []string
+	CgoFiles    []string
+	CFiles      []string
+	TestGoFiles []string
 
 	// Dependency information
 	Imports   []string
@@ -299,6 +300,44 @@ func (p *Package) parseFile(path string, mode parser.Mode) (*ast.File, error) {
 	return parser.ParseFile(p.program.fset, originalPath, data, mode)
 }
 
+// parseCoverFile parses the file after instrumenting it for code coverage with
+// go tool cover, in the same way as go test -cover does. The coverage counters
+// are stored in a global with the given name.
+func (p *Package) parseCoverFile(path, varName string) (*ast.File, error) {
+	originalPath := p.program.getOriginalPath(path)
+	cmd := exec.Command("go", "tool", "cover", "-mode="+p.program.config.TestConfig.CoverMode, "-var="+varName, originalPath)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run `go tool cover` on %s: %s\n%s", originalPath, err, stderr.String())
+	}
+	data := stdout.Bytes()
+	sum := sha512.Sum512_224(data)
+	p.FileHashes[originalPath] = sum[:]
+	return parser.ParseFile(p.program.fset, originalPath, data, parser.ParseComments)
+}
+
+// coverRegisterFile returns a file that registers the coverage counters of
+// the given files (indexed by counter variable name) with the testing package.
+// It uses go:linkname instead of an import, because the package being tested
+// may itself be imported by the testing package.
+func (p *Package) coverRegisterFile(varNames, fileNames []string) (*ast.File, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package %s\n\n", p.Name)
+	buf.WriteString("import _ \"unsafe\"\n\n")
+	buf.WriteString("//go:linkname _tinygo_registerCover testing.registerCover\n")
+	buf.WriteString("func _tinygo_registerCover(mode, fileName string, counter, pos []uint32, numStmts []uint16)\n\n")
+	buf.WriteString("func init() {\n")
+	for i, varName := range varNames {
+		fmt.Fprintf(buf, "\t_tinygo_registerCover(%q, %q, %s.Count[:], %s.Pos[:], %s.NumStmt[:])\n", p.program.config.TestConfig.CoverMode, fileNames[i], varName, varName, varName)
+	}
+	buf.WriteString("}\n")
+	return parser.ParseFile(p.program.fset, filepath.Join(p.Dir, "_tinygo_cover.go"), buf.Bytes(), parser.ParseComments)
+}
+
 // Parse parses and typechecks this package.
 //
 // Idempotent.
@@ -366,12 +405,31 @@ func (p *Package) parseFiles() ([]*ast.File, error) {
 	var files []*ast.File
 	var fileErrs []error
 
+	// Instrument the package under test when building with -cover. Test files
+	// are not instrumented.
+	cover := p.program.config.TestConfig.CoverMode != "" && p.ImportPath == strings.TrimSuffix(p.program.MainPkg().ImportPath, ".test")
+	testFiles := make(map[string]bool)
+	for _, file := range p.TestGoFiles {
+		testFiles[file] = true
+	}
+	var coverVars, coverFiles []string
+
 	// Parse all files (incuding CgoFiles).
 	parseFile := func(file string) {
+		name := file
 		if !filepath.IsAbs(file) {
 			file = filepath.Join(p.Dir, file)
 		}
-		f, err := p.parseFile(file, parser.ParseComments)
+		var f *ast.File
+		var err error
+		if cover && !testFiles[name] {
+			varName := "GoCover_" + strconv.Itoa(len(coverVars))
+			coverVars = append(coverVars, varName)
+			coverFiles = append(coverFiles, p.ImportPath+"/"+filepath.Base(file))
+			f, err = p.parseCoverFile(file, varName)
+		} else {
+			f, err = p.parseFile(file, parser.ParseComments)
+		}
 		if err != nil {
 			fileErrs = append(fileErrs, err)
 			return
@@ -381,9 +439,18 @@ func (p *Package) parseFiles() ([]*ast.File, error) {
 	for _, file := range p.GoFiles {
 		parseFile(file)
 	}
+	cover = false // CGo files are not instrumented
 	for _, file := range p.CgoFiles {
 		parseFile(file)
 	}
+	if len(coverVars) != 0 {
+		f, err := p.coverRegisterFile(coverVars, coverFiles)
+		if err != nil {
+			fileErrs = append(fileErrs, err)
+		} else {
+			files = append(files, f)
+		}
+	}
 
 	// Do CGo processing.
 	if len(p.CgoFiles) != 0 {
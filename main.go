@@ -17,6 +17,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/tinygo-org/tinygo/interp"
 	"github.com/tinygo-org/tinygo/loader"
 	"github.com/tinygo-org/tinygo/transform"
+	"github.com/tinygo-org/tinygo/wit"
 	"tinygo.org/x/go-llvm"
 
 	"go.bug.st/serial"
@@ -157,13 +159,34 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 }
 
 // Test runs the tests in the given package. Returns whether the test passed and
-// possibly an error if the test failed to run.
-func Test(pkgName string, options *compileopts.Options, testCompileOnly bool, outpath string) (bool, error) {
-	options.TestConfig.CompileTestBinary = true
-	config, err := builder.NewConfig(options)
+// possibly an error if the test failed to run. The test results and the output
+// of the test binary are written to stdout and stderr.
+//
+// The options are not modified, so Test can be called in parallel for
+// different packages.
+func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options, testCompileOnly bool, outpath string) (bool, error) {
+	testOptions := *options
+	testOptions.TestConfig.CompileTestBinary = true
+	config, err := builder.NewConfig(&testOptions)
 	if err != nil {
 		return false, err
 	}
+	if flags := config.TestConfig.Flags(); !config.AcceptsArgs() && len(flags) != 0 {
+		// The test flags cannot be passed on the command line, so store them
+		// in the test binary instead. The maps are copied, as they are shared
+		// with the original options.
+		globalValues := make(map[string]map[string]string)
+		for pkgPath, values := range options.GlobalValues {
+			globalValues[pkgPath] = values
+		}
+		testingValues := make(map[string]string)
+		for name, value := range globalValues["testing"] {
+			testingValues[name] = value
+		}
+		testingValues["testFlags"] = strings.Join(flags, "\x00")
+		globalValues["testing"] = testingValues
+		testOptions.GlobalValues = globalValues
+	}
 
 	passed := true
 	err = builder.Build(pkgName, outpath, config, func(result builder.BuildResult) error {
@@ -184,7 +207,7 @@ func Test(pkgName string, options *compileopts.Options, testCompileOnly bool, ou
 		// Run the test.
 		start := time.Now()
 		var err error
-		passed, err = runPackageTest(config, result)
+		passed, err = runPackageTest(config, result, stdout, stderr)
 		if err != nil {
 			return err
 		}
@@ -193,31 +216,175 @@ func Test(pkgName string, options *compileopts.Options, testCompileOnly bool, ou
 		// Print the result.
 		importPath := strings.TrimSuffix(result.ImportPath, ".test")
 		if passed {
-			fmt.Printf("ok  \t%s\t%.3fs\n", importPath, duration.Seconds())
+			fmt.Fprintf(stdout, "ok  \t%s\t%.3fs\n", importPath, duration.Seconds())
 		} else {
-			fmt.Printf("FAIL\t%s\t%.3fs\n", importPath, duration.Seconds())
+			fmt.Fprintf(stdout, "FAIL\t%s\t%.3fs\n", importPath, duration.Seconds())
 		}
 		return nil
 	})
 	if err, ok := err.(loader.NoTestFilesError); ok {
-		fmt.Printf("?   \t%s\t[no test files]\n", err.ImportPath)
+		fmt.Fprintf(stdout, "?   \t%s\t[no test files]\n", err.ImportPath)
 		// Pretend the test passed - it at least didn't fail.
 		return true, nil
 	}
 	return passed, err
 }
 
+// expandPackagePatterns returns the packages matching the given patterns. Only
+// patterns with "..." are expanded using go list, other package names and
+// paths are returned as-is.
+func expandPackagePatterns(options *compileopts.Options, patterns []string) ([]string, error) {
+	var pkgNames []string
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "...") {
+			pkgNames = append(pkgNames, pattern)
+			continue
+		}
+		config, err := builder.NewConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := loader.ListPackages(config, []string{pattern})
+		if err != nil {
+			return nil, err
+		}
+		pkgNames = append(pkgNames, matches...)
+	}
+	return pkgNames, nil
+}
+
+// testPackages builds and runs the tests of the given packages, at most
+// parallelism packages at a time. Like go test -p, this limits both the builds
+// and the test binaries that run at the same time. The compile jobs of all
+// builds also share the CPUs (see the builder package). The output of each package is buffered and written to stdout in package
+// order, so that the output of different packages doesn't get mixed up. It
+// returns whether all tests passed. Like the serial version, it exits on the
+// first package that fails to build.
+func testPackages(pkgNames []string, stdout io.Writer, parallelism int, options *compileopts.Options, testCompileOnly bool, outpath string) bool {
+	if len(pkgNames) == 1 {
+		// No need to buffer the output.
+		passed, err := Test(pkgNames[0], stdout, os.Stderr, options, testCompileOnly, outpath)
+		handleCompilerError(err)
+		return passed
+	}
+
+	type testResult struct {
+		output lockedBuffer
+		passed bool
+		err    error
+		done   chan struct{}
+	}
+	results := make([]*testResult, len(pkgNames))
+	for i := range results {
+		results[i] = &testResult{done: make(chan struct{})}
+	}
+
+	// Start the tests in package order, limited by the semaphore.
+	semaphore := make(chan struct{}, parallelism)
+	go func() {
+		for i, pkgName := range pkgNames {
+			semaphore <- struct{}{}
+			go func(result *testResult, pkgName string) {
+				defer func() {
+					<-semaphore
+					close(result.done)
+				}()
+				result.passed, result.err = Test(pkgName, &result.output, &result.output, options, testCompileOnly, outpath)
+			}(results[i], pkgName)
+		}
+	}()
+
+	// Print the results in package order, as soon as they are available.
+	allTestsPassed := true
+	for _, result := range results {
+		<-result.done
+		stdout.Write(result.output.Bytes())
+		handleCompilerError(result.err)
+		if !result.passed {
+			allTestsPassed = false
+		}
+	}
+	return allTestsPassed
+}
+
+// lockedBuffer is a bytes.Buffer that can be written to from multiple
+// goroutines, such as the stdout and stderr of a test binary.
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+// Bytes returns the buffered output. It must only be called after all writes
+// have finished.
+func (b *lockedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
 // runPackageTest runs a test binary that was previously built. The return
 // values are whether the test passed and any errors encountered while trying to
 // run the binary.
-func runPackageTest(config *compileopts.Config, result builder.BuildResult) (bool, error) {
+func runPackageTest(config *compileopts.Config, result builder.BuildResult, stdout, stderr io.Writer) (bool, error) {
+	// Pass test flags to the test binary, the same way as go test does. Bare
+	// metal targets already have them stored in the binary, see Test.
+	var flags []string
+	if config.AcceptsArgs() {
+		flags = config.TestConfig.Flags()
+	}
+
+	// The output of emulators is checked to see whether the test passed.
+	buf := &bytes.Buffer{}
+	if len(config.Target.Emulator) != 0 {
+		stdout = io.MultiWriter(stdout, buf)
+	}
+
+	// The coverage profile is written to stdout by the test binary, so filter
+	// it out of the output.
+	var coverWriter *coverProfileWriter
+	if config.TestConfig.CoverProfile != "" {
+		coverWriter = &coverProfileWriter{w: stdout}
+		stdout = coverWriter
+	}
+
+	passed, err := runTestBinary(config, result, flags, stdout, stderr)
+	if coverWriter != nil {
+		coverWriter.Flush()
+		if err == nil {
+			err = appendCoverProfile(config.TestConfig.CoverProfile, coverWriter.profile.Bytes())
+		}
+	}
+	if err != nil || len(config.Target.Emulator) == 0 {
+		return passed, err
+	}
+
+	testOutput := string(buf.Bytes())
+	if testOutput == "PASS\n" || strings.HasSuffix(testOutput, "\nPASS\n") {
+		// Test passed.
+		return true, nil
+	} else {
+		// Test failed, either by ending with the word "FAIL" or with a
+		// panic of some sort.
+		return false, nil
+	}
+}
+
+// runTestBinary runs the test binary, directly or in an emulator. When run
+// directly, it returns whether the test passed based on the exit code. An
+// emulator doesn't always report the exit code, so the caller needs to check
+// the output instead.
+func runTestBinary(config *compileopts.Config, result builder.BuildResult, flags []string, stdout, stderr io.Writer) (bool, error) {
 	if len(config.Target.Emulator) == 0 {
 		// Run directly.
-		cmd := executeCommand(config.Options, result.Binary)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd := executeCommand(config.Options, result.Binary, flags...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 		cmd.Dir = result.MainDir
-		err := cmd.Run()
+		err := runTestCommand(cmd, config.TestConfig.Timeout)
 		if err != nil {
 			if _, ok := err.(*exec.ExitError); ok {
 				// Binary exited with a non-zero exit code, which means the test
@@ -230,28 +397,124 @@ func runPackageTest(config *compileopts.Config, result builder.BuildResult) (boo
 	} else {
 		// Run in an emulator.
 		args := append(config.Target.Emulator[1:], result.Binary)
+		args = append(args, flags...)
 		cmd := executeCommand(config.Options, config.Target.Emulator[0], args...)
-		buf := &bytes.Buffer{}
-		w := io.MultiWriter(os.Stdout, buf)
-		cmd.Stdout = w
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		err := runTestCommand(cmd, config.TestConfig.Timeout)
 		if err != nil {
 			if err, ok := err.(*exec.ExitError); !ok || !err.Exited() {
 				// Workaround for QEMU which always exits with an error.
 				return false, &commandError{"failed to run emulator with", result.Binary, err}
 			}
 		}
-		testOutput := string(buf.Bytes())
-		if testOutput == "PASS\n" || strings.HasSuffix(testOutput, "\nPASS\n") {
-			// Test passed.
-			return true, nil
-		} else {
-			// Test failed, either by ending with the word "FAIL" or with a
-			// panic of some sort.
-			return false, nil
+		return false, nil
+	}
+}
+
+// These lines surround the coverage profile in the output of a test binary.
+// They must match the ones in src/testing/cover.go.
+const (
+	coverProfileStart = "=== COVERAGE PROFILE"
+	coverProfileEnd   = "=== END COVERAGE PROFILE"
+)
+
+// coverProfileWriter passes the output of a test binary through to the
+// underlying writer, except for the coverage profile, which it stores. The
+// output is passed through line by line.
+type coverProfileWriter struct {
+	w         io.Writer
+	line      []byte // incomplete last line
+	inProfile bool
+	profile   bytes.Buffer
+}
+
+func (w *coverProfileWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) != 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
+			break
+		}
+		w.line = append(w.line, p[:i+1]...)
+		p = p[i+1:]
+		if err := w.writeLine(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Flush writes the incomplete last line, if there is one.
+func (w *coverProfileWriter) Flush() error {
+	if len(w.line) == 0 {
+		return nil
+	}
+	return w.writeLine()
+}
+
+func (w *coverProfileWriter) writeLine() error {
+	line := w.line
+	w.line = w.line[:0]
+	text := strings.TrimRight(string(line), "\r\n")
+	switch {
+	case !w.inProfile && text == coverProfileStart:
+		w.inProfile = true
+	case w.inProfile && text == coverProfileEnd:
+		w.inProfile = false
+	case w.inProfile:
+		w.profile.WriteString(text + "\n")
+	default:
+		_, err := w.w.Write(line)
+		return err
+	}
+	return nil
+}
+
+// coverProfileLock serializes writes to the coverage profile, as tests of
+// multiple packages may finish at the same time.
+var coverProfileLock sync.Mutex
+
+// appendCoverProfile appends the coverage profile of a single test binary to
+// the file at path, without the mode line. The file is created with the mode
+// line before the tests are run, so that the profiles of all packages are
+// merged like go test does.
+func appendCoverProfile(path string, profile []byte) error {
+	coverProfileLock.Lock()
+	defer coverProfileLock.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(string(profile), "\n") {
+		if line == "" || strings.HasPrefix(line, "mode: ") {
+			continue
+		}
+		if _, err := f.WriteString(line); err != nil {
+			f.Close()
+			return err
 		}
 	}
+	return f.Close()
+}
+
+// runTestCommand runs the test binary or emulator. Like go test, it kills the
+// process if it is still running a minute after the test timeout, in case the
+// test binary itself fails to stop (for example, when it hangs in an
+// emulator).
+func runTestCommand(cmd *exec.Cmd, timeout time.Duration) error {
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout+time.Minute, func() {
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+	}
+	return cmd.Wait()
 }
 
 // Flash builds and flashes the built binary to the given serial port.
@@ -557,6 +820,30 @@ func FlashGDB(pkgName string, ocdOutput bool, options *compileopts.Options) erro
 	})
 }
 
+// WitBindgen generates Go bindings for a world in a WIT file. The Go package
+// is named after the world. The output is written to outpath, or to stdout if
+// outpath is empty.
+func WitBindgen(witPath, worldName, outpath string) error {
+	doc, err := wit.ParseFile(witPath)
+	if err != nil {
+		return err
+	}
+	world, err := doc.World(worldName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", witPath, err)
+	}
+	pkgName := strings.ToLower(strings.ReplaceAll(world.Name, "-", ""))
+	src, err := wit.Generate(doc, world, pkgName, filepath.Base(witPath))
+	if err != nil {
+		return err
+	}
+	if outpath == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(outpath, src, 0666)
+}
+
 // Run compiles and runs the given program. Depending on the target provided in
 // the options, it will run the program directly on the host or will run it in
 // an emulator. For example, -target=wasm will cause the binary to be run inside
@@ -846,15 +1133,17 @@ func usage() {
 	fmt.Fprintln(os.Stderr, "version:", goenv.Version)
 	fmt.Fprintf(os.Stderr, "usage: %s command [-printir] [-target=<target>] -o <output> <input>\n", os.Args[0])
 	fmt.Fprintln(os.Stderr, "\ncommands:")
-	fmt.Fprintln(os.Stderr, "  build: compile packages and dependencies")
-	fmt.Fprintln(os.Stderr, "  run:   compile and run immediately")
-	fmt.Fprintln(os.Stderr, "  test:  test packages")
-	fmt.Fprintln(os.Stderr, "  flash: compile and flash to the device")
-	fmt.Fprintln(os.Stderr, "  gdb:   run/flash and immediately enter GDB")
-	fmt.Fprintln(os.Stderr, "  env:   list environment variables used during build")
-	fmt.Fprintln(os.Stderr, "  list:  run go list using the TinyGo root")
-	fmt.Fprintln(os.Stderr, "  clean: empty cache directory ("+goenv.Get("GOCACHE")+")")
-	fmt.Fprintln(os.Stderr, "  help:  print this help text")
+	fmt.Fprintln(os.Stderr, "  build:        compile packages and dependencies")
+	fmt.Fprintln(os.Stderr, "  run:          compile and run immediately")
+	fmt.Fprintln(os.Stderr, "  test:         test packages")
+	fmt.Fprintln(os.Stderr, "  flash:        compile and flash to the device")
+	fmt.Fprintln(os.Stderr, "  gdb:          run/flash and immediately enter GDB")
+	fmt.Fprintln(os.Stderr, "  env:          list environment variables used during build")
+	fmt.Fprintln(os.Stderr, "  list:         run go list using the TinyGo root")
+	fmt.Fprintln(os.Stderr, "  wit-bindgen:  generate Go bindings from a WIT interface definition")
+	fmt.Fprintln(os.Stderr, "  wit-validate: check WebAssembly modules against their WIT world")
+	fmt.Fprintln(os.Stderr, "  clean:        empty cache directory ("+goenv.Get("GOCACHE")+")")
+	fmt.Fprintln(os.Stderr, "  help:         print this help text")
 	fmt.Fprintln(os.Stderr, "\nflags:")
 	flag.PrintDefaults()
 }
@@ -1006,9 +1295,10 @@ func main() {
 	command := os.Args[1]
 
 	opt := flag.String("opt", "z", "optimization level: 0, 1, 2, s, z")
-	gc := flag.String("gc", "", "garbage collector to use (none, leaking, extalloc, conservative)")
-	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap)")
-	scheduler := flag.String("scheduler", "", "which scheduler to use (none, coroutines, tasks)")
+	buildMode := flag.String("buildmode", "", "build mode to use (default, c-shared)")
+	gc := flag.String("gc", "", "garbage collector to use (none, leaking, extalloc, conservative, precise)")
+	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap, unwind)")
+	scheduler := flag.String("scheduler", "", "which scheduler to use (none, coroutines, tasks, cores)")
 	serial := flag.String("serial", "", "which serial output to use (none, uart, usb)")
 	printIR := flag.Bool("printir", false, "print LLVM IR")
 	dumpSSA := flag.Bool("dumpssa", false, "dump internal Go SSA")
@@ -1017,9 +1307,11 @@ func main() {
 	target := flag.String("target", "", "LLVM target | .json file with TargetSpec")
 	printSize := flag.String("size", "", "print sizes (none, short, full)")
 	printStacks := flag.Bool("print-stacks", false, "print stack sizes of goroutines")
+	printGCStats := flag.Bool("print-gc-stats", false, "print memory statistics when the program exits")
 	printAllocsString := flag.String("print-allocs", "", "regular expression of functions for which heap allocations should be printed")
 	printCommands := flag.Bool("x", false, "Print commands")
 	nodebug := flag.Bool("no-debug", false, "disable DWARF debug symbol generation")
+	noReflectMethods := flag.Bool("no-reflect-methods", false, "omit method information for reflect (Type.Method, Value.Method) to reduce binary size")
 	ocdCommandsString := flag.String("ocd-commands", "", "OpenOCD commands, overriding target spec (can specify multiple separated by commas)")
 	ocdOutput := flag.Bool("ocd-output", false, "print OCD daemon output during debug")
 	port := flag.String("port", "", "flash port (can specify multiple candidates separated by commas)")
@@ -1035,12 +1327,32 @@ func main() {
 		flagTest = flag.Bool("test", false, "supply -test flag to go list")
 	}
 	var outpath string
-	if command == "help" || command == "build" || command == "build-library" || command == "test" {
+	if command == "help" || command == "build" || command == "build-library" || command == "test" || command == "wit-bindgen" {
 		flag.StringVar(&outpath, "o", "", "output filename")
 	}
-	var testCompileOnlyFlag *bool
+	var witWorld *string
+	if command == "help" || command == "wit-bindgen" {
+		witWorld = flag.String("world", "", "WIT world to generate bindings for (only needed if the file has multiple worlds)")
+	}
+	var testCompileOnlyFlag, testBenchMem, testVerbose, testShort, testFailFast, testCover *bool
+	var testBench, testBenchTime, testRunRegexp, testCoverMode, testCoverProfile *string
+	var testCount, testParallelism *int
+	var testTimeout *time.Duration
 	if command == "help" || command == "test" {
 		testCompileOnlyFlag = flag.Bool("c", false, "compile the test binary but do not run it")
+		testVerbose = flag.Bool("v", false, "verbose: print additional output")
+		testShort = flag.Bool("short", false, "tell long-running tests to shorten their run time")
+		testRunRegexp = flag.String("run", "", "run only those tests matching the regular expression")
+		testCount = flag.Int("count", 1, "run each test and benchmark n times")
+		testFailFast = flag.Bool("failfast", false, "do not start new tests after the first test failure")
+		testTimeout = flag.Duration("timeout", 10*time.Minute, "panic the test binary after duration d (0 means unlimited)")
+		testParallelism = flag.Int("p", runtime.GOMAXPROCS(0), "the number of packages to build and test in parallel")
+		testBench = flag.String("bench", "", "run benchmarks matching the regular expression")
+		testBenchTime = flag.String("benchtime", "", "run each benchmark for duration d or N times (Nx)")
+		testBenchMem = flag.Bool("benchmem", false, "print memory allocation statistics for benchmarks")
+		testCover = flag.Bool("cover", false, "enable coverage analysis")
+		testCoverMode = flag.String("covermode", "", "coverage mode: set or count (default set)")
+		testCoverProfile = flag.String("coverprofile", "", "write a coverage profile to the file (implies -cover)")
 	}
 
 	// Early command processing, before commands are interpreted by the Go flag
@@ -1077,30 +1389,41 @@ func main() {
 	}
 
 	options := &compileopts.Options{
-		Target:          *target,
-		Opt:             *opt,
-		GC:              *gc,
-		PanicStrategy:   *panicStrategy,
-		Scheduler:       *scheduler,
-		Serial:          *serial,
-		PrintIR:         *printIR,
-		DumpSSA:         *dumpSSA,
-		VerifyIR:        *verifyIR,
-		Debug:           !*nodebug,
-		PrintSizes:      *printSize,
-		PrintStacks:     *printStacks,
-		PrintAllocs:     printAllocs,
-		Tags:            *tags,
-		GlobalValues:    globalVarValues,
-		WasmAbi:         *wasmAbi,
-		Programmer:      *programmer,
-		OpenOCDCommands: ocdCommands,
-		LLVMFeatures:    *llvmFeatures,
+		Target:           *target,
+		BuildMode:        *buildMode,
+		Opt:              *opt,
+		GC:               *gc,
+		PanicStrategy:    *panicStrategy,
+		Scheduler:        *scheduler,
+		Serial:           *serial,
+		PrintIR:          *printIR,
+		DumpSSA:          *dumpSSA,
+		VerifyIR:         *verifyIR,
+		Debug:            !*nodebug,
+		PrintSizes:       *printSize,
+		PrintStacks:      *printStacks,
+		PrintGCStats:     *printGCStats,
+		NoReflectMethods: *noReflectMethods,
+		PrintAllocs:      printAllocs,
+		Tags:             *tags,
+		GlobalValues:     globalVarValues,
+		WasmAbi:          *wasmAbi,
+		Programmer:       *programmer,
+		OpenOCDCommands:  ocdCommands,
+		LLVMFeatures:     *llvmFeatures,
 	}
 	if *printCommands {
 		options.PrintCommands = printCommand
 	}
 
+	if command == "test" && (*testCover || *testCoverMode != "" || *testCoverProfile != "") {
+		options.TestConfig.CoverMode = *testCoverMode
+		if options.TestConfig.CoverMode == "" {
+			options.TestConfig.CoverMode = "set"
+		}
+		options.TestConfig.CoverProfile = *testCoverProfile
+	}
+
 	os.Setenv("CC", "clang -target="+*target)
 
 	err = options.Verify()
@@ -1191,22 +1514,39 @@ func main() {
 		err := Run(pkgName, options)
 		handleCompilerError(err)
 	case "test":
-		var pkgNames []string
+		options.TestConfig.Verbose = *testVerbose
+		options.TestConfig.Short = *testShort
+		options.TestConfig.RunRegexp = *testRunRegexp
+		options.TestConfig.Count = *testCount
+		options.TestConfig.FailFast = *testFailFast
+		options.TestConfig.Timeout = *testTimeout
+		options.TestConfig.BenchRegexp = *testBench
+		options.TestConfig.BenchTime = *testBenchTime
+		options.TestConfig.BenchMem = *testBenchMem
+		if *testParallelism < 1 {
+			fmt.Fprintln(os.Stderr, "-p must be at least 1")
+			usage()
+			os.Exit(1)
+		}
+		var patterns []string
 		for i := 0; i < flag.NArg(); i++ {
-			pkgNames = append(pkgNames, filepath.ToSlash(flag.Arg(i)))
+			patterns = append(patterns, filepath.ToSlash(flag.Arg(i)))
 		}
-		if len(pkgNames) == 0 {
-			pkgNames = []string{"."}
+		if len(patterns) == 0 {
+			patterns = []string{"."}
+		}
+		pkgNames, err := expandPackagePatterns(options, patterns)
+		handleCompilerError(err)
+		if outpath != "" && len(pkgNames) > 1 {
+			fmt.Fprintln(os.Stderr, "cannot use -o flag with multiple packages")
+			os.Exit(1)
 		}
-		allTestsPassed := true
-		for _, pkgName := range pkgNames {
-			// TODO: parallelize building the test binaries
-			passed, err := Test(pkgName, options, *testCompileOnlyFlag, outpath)
+		if options.TestConfig.CoverProfile != "" {
+			// The profile of each package is appended to this file.
+			err := ioutil.WriteFile(options.TestConfig.CoverProfile, []byte("mode: "+options.TestConfig.CoverMode+"\n"), 0666)
 			handleCompilerError(err)
-			if !passed {
-				allTestsPassed = false
-			}
 		}
+		allTestsPassed := testPackages(pkgNames, os.Stdout, *testParallelism, options, *testCompileOnlyFlag, outpath)
 		if !allTestsPassed {
 			fmt.Println("FAIL")
 			os.Exit(1)
@@ -1303,6 +1643,37 @@ func main() {
 			fmt.Fprintln(os.Stderr, "failed to run `go list`:", err)
 			os.Exit(1)
 		}
+	case "wit-bindgen":
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "wit-bindgen requires exactly one WIT file")
+			usage()
+			os.Exit(1)
+		}
+		err := WitBindgen(flag.Arg(0), *witWorld, outpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "wit-validate":
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "No WebAssembly module specified.")
+			usage()
+			os.Exit(1)
+		}
+		failed := false
+		for _, path := range flag.Args() {
+			data, err := ioutil.ReadFile(path)
+			if err == nil {
+				err = wit.Validate(data)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
 	case "clean":
 		// remove cache directory
 		err := os.RemoveAll(goenv.Get("GOCACHE"))
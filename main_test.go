@@ -8,10 +8,12 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -139,6 +141,26 @@ func TestCompiler(t *testing.T) {
 			}, nil, nil)
 		})
 
+		t.Run("panic=unwind", func(t *testing.T) {
+			t.Parallel()
+			runTestWithConfig("recover.go", "", t, &compileopts.Options{
+				Opt:           "z",
+				PanicStrategy: "unwind",
+			}, nil, nil)
+		})
+
+		t.Run("gc=precise", func(t *testing.T) {
+			t.Parallel()
+			runTestWithConfig("gc.go", "", t, &compileopts.Options{
+				Opt: "z",
+				GC:  "precise",
+			}, nil, nil)
+			runTestWithConfig("gcprecise.go", "", t, &compileopts.Options{
+				Opt: "z",
+				GC:  "precise",
+			}, nil, nil)
+		})
+
 		t.Run("ldflags", func(t *testing.T) {
 			t.Parallel()
 			runTestWithConfig("ldflags.go", "", t, &compileopts.Options{
@@ -168,11 +190,64 @@ func runPlatTests(target string, tests []string, t *testing.T) {
 			t.Parallel()
 			runTest("filesystem.go", target, t, nil, nil)
 		})
+		t.Run("directory.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("directory.go", target, t, nil, nil)
+		})
+		t.Run("stacktrace.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("stacktrace.go", target, t, nil, nil)
+		})
 		t.Run("env.go", func(t *testing.T) {
 			t.Parallel()
 			runTest("env.go", target, t, []string{"first", "second"}, []string{"ENV1=VALUE1", "ENV2=VALUE2"})
 		})
 	}
+	if target == "wasi" {
+		t.Run("stdin.go", func(t *testing.T) {
+			t.Parallel()
+			// Keep stdin open for a while before writing to it, so that the
+			// program has to wait for it while other goroutines run. Only
+			// WASI can wait for a file descriptor without blocking the
+			// whole program.
+			stdin, w := io.Pipe()
+			go func() {
+				time.Sleep(time.Second)
+				w.Write([]byte("hello from stdin\n"))
+				w.Close()
+			}()
+			options := &compileopts.Options{
+				Target:   target,
+				Opt:      "z",
+				VerifyIR: true,
+				Debug:    true,
+			}
+			runTestWithInput("stdin.go", target, t, options, nil, nil, stdin)
+		})
+		t.Run("reactor.go", func(t *testing.T) {
+			t.Parallel()
+			options := &compileopts.Options{
+				Target:    target,
+				BuildMode: "c-shared",
+				Opt:       "z",
+				VerifyIR:  true,
+				Debug:     true,
+			}
+			runTestWithConfig("reactor.go", target, t, options, []string{"--invoke", "run"}, nil)
+		})
+	}
+	if target == "" {
+		// The flash image is stored in os.TempDir(), which is not available
+		// under WASI.
+		t.Run("mount.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("mount.go", target, t, nil, nil)
+		})
+		t.Run("net.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("net.go", target, t, nil, nil)
+		})
+	}
 }
 
 // Due to some problems with LLD, we cannot run links in parallel, or in parallel with compiles.
@@ -202,6 +277,12 @@ func runTest(name, target string, t *testing.T, cmdArgs, environmentVars []strin
 }
 
 func runTestWithConfig(name, target string, t *testing.T, options *compileopts.Options, cmdArgs, environmentVars []string) {
+	runTestWithInput(name, target, t, options, cmdArgs, environmentVars, nil)
+}
+
+// runTestWithInput is like runTestWithConfig, but also provides stdin to the
+// test program.
+func runTestWithInput(name, target string, t *testing.T, options *compileopts.Options, cmdArgs, environmentVars []string, stdin io.Reader) {
 	// Get the expected output for this test.
 	// Note: not using filepath.Join as it strips the path separator at the end
 	// of the path.
@@ -269,6 +350,7 @@ func runTestWithConfig(name, target string, t *testing.T, options *compileopts.O
 		}
 	}
 	stdout := &bytes.Buffer{}
+	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 	err = cmd.Start()
@@ -327,6 +409,450 @@ func runTestWithConfig(name, target string, t *testing.T, options *compileopts.O
 	}
 }
 
+// durationRegexp matches the duration at the end of the result line that tinygo
+// test prints for each package.
+var durationRegexp = regexp.MustCompile(`(?m)\t[0-9]+\.[0-9]+s$`)
+
+// runTinyGoTest runs tinygo test on the given packages in testdata/testing on
+// the host and returns whether the tests passed and the output, with durations
+// replaced by 0.000s so that it can be compared with the expected output. Only
+// the test config and the panic strategy of the options are used.
+func runTinyGoTest(t *testing.T, pkgNames []string, testOptions compileopts.Options) (bool, string) {
+	options := &compileopts.Options{
+		Opt:           "z",
+		VerifyIR:      true,
+		Debug:         true,
+		PanicStrategy: testOptions.PanicStrategy,
+		TestConfig:    testOptions.TestConfig,
+	}
+	output := &bytes.Buffer{}
+	passed := true
+	for _, pkgName := range pkgNames {
+		buildLock.Lock()
+		pkgPassed, err := Test("./"+TESTDATA+"/testing/"+pkgName, output, output, options, false, "")
+		buildLock.Unlock()
+		if err != nil {
+			printCompilerError(t.Log, err)
+			t.FailNow()
+		}
+		passed = passed && pkgPassed
+	}
+	return passed, durationRegexp.ReplaceAllString(output.String(), "\t0.000s")
+}
+
+func TestTestBenchmarks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't run tests on the host on Windows")
+	}
+	passed, output := runTinyGoTest(t, []string{"benchmark"}, compileopts.Options{
+		TestConfig: compileopts.TestConfig{
+			RunRegexp:   "^$",
+			BenchRegexp: ".",
+			BenchTime:   "1x",
+		},
+	})
+	if passed {
+		t.Error("expected a failed sub-benchmark to fail the test")
+	}
+	for _, line := range []string{
+		"--- FAIL: BenchmarkFail/sub\n\tsum is 3\n",
+		"--- FAIL: BenchmarkFail\n",
+		"\nFAIL\nFAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/benchmark\t0.000s\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected %q in the output:\n%s", line, output)
+		}
+	}
+	if !strings.Contains(output, "BenchmarkPass") || strings.Contains(output, "--- FAIL: BenchmarkPass") {
+		t.Errorf("expected BenchmarkPass to pass:\n%s", output)
+	}
+}
+
+// tinyGoTestCase is a single run of tinygo test on a package in
+// testdata/testing, with the expected result and output.
+type tinyGoTestCase struct {
+	name          string
+	config        compileopts.TestConfig
+	panicStrategy string
+	passed        bool
+	output        string
+}
+
+// runTinyGoTestCases runs tinygo test on the given package for each test case,
+// and compares the result and the output.
+func runTinyGoTestCases(t *testing.T, pkgName string, tests []tinyGoTestCase) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't run tests on the host on Windows")
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			passed, output := runTinyGoTest(t, []string{pkgName}, compileopts.Options{
+				PanicStrategy: tc.panicStrategy,
+				TestConfig:    tc.config,
+			})
+			if passed != tc.passed {
+				t.Errorf("expected passed=%t, got passed=%t", tc.passed, passed)
+			}
+			if output != tc.output {
+				t.Errorf("output did not match\nexpected:\n%s\nactual:\n%s", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestTestFlags(t *testing.T) {
+	runTinyGoTestCases(t, "flags", []tinyGoTestCase{
+		{
+			name:   "Run",
+			config: compileopts.TestConfig{RunRegexp: "TestPass|TestSub/two", Verbose: true},
+			passed: true,
+			output: "=== RUN   TestPass\n" +
+				"--- PASS: TestPass\n" +
+				"\tdouble: 4\n" +
+				"\n" +
+				"=== RUN   TestSub\n" +
+				"=== RUN   TestSub/two\n" +
+				"    --- PASS: TestSub/two\n" +
+				"\tin two\n" +
+				"\n" +
+				"--- PASS: TestSub\n" +
+				"PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/flags\t0.000s\n",
+		},
+		{
+			name:   "Fail",
+			config: compileopts.TestConfig{RunRegexp: "Fail"},
+			passed: false,
+			output: "--- FAIL: TestFail\n" +
+				"\tdouble is 6\n" +
+				"--- FAIL: TestFailToo\n" +
+				"\talso failed\n" +
+				"\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/flags\t0.000s\n",
+		},
+		{
+			name:   "FailFast",
+			config: compileopts.TestConfig{RunRegexp: "Fail", FailFast: true},
+			passed: false,
+			output: "--- FAIL: TestFail\n" +
+				"\tdouble is 6\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/flags\t0.000s\n",
+		},
+		{
+			name:   "Count",
+			config: compileopts.TestConfig{RunRegexp: "TestPass", Count: 2, Verbose: true},
+			passed: true,
+			output: "=== RUN   TestPass\n" +
+				"--- PASS: TestPass\n" +
+				"\tdouble: 4\n" +
+				"\n" +
+				"=== RUN   TestPass\n" +
+				"--- PASS: TestPass\n" +
+				"\tdouble: 4\n" +
+				"\n" +
+				"PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/flags\t0.000s\n",
+		},
+		{
+			name:   "Short",
+			config: compileopts.TestConfig{RunRegexp: "TestShort", Short: true, Verbose: true},
+			passed: true,
+			output: "=== RUN   TestShort\n" +
+				"--- SKIP: TestShort\n" +
+				"\tskipped in short mode\n" +
+				"\n" +
+				"PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/flags\t0.000s\n",
+		},
+	})
+}
+
+func TestTestExamples(t *testing.T) {
+	runTinyGoTestCases(t, "example", []tinyGoTestCase{
+		{
+			name:   "Verbose",
+			config: compileopts.TestConfig{Verbose: true},
+			passed: false,
+			output: "=== RUN   ExampleGreet\n" +
+				"--- PASS: ExampleGreet\n" +
+				"=== RUN   ExampleGreet_unordered\n" +
+				"--- PASS: ExampleGreet_unordered\n" +
+				"=== RUN   ExampleGreet_fail\n" +
+				"--- FAIL: ExampleGreet_fail\n" +
+				"got:\n" +
+				"hello, world\n" +
+				"want:\n" +
+				"hello, gopher\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/example\t0.000s\n",
+		},
+		{
+			name:   "Run",
+			config: compileopts.TestConfig{RunRegexp: "ExampleGreet_unordered"},
+			passed: true,
+			output: "PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/example\t0.000s\n",
+		},
+	})
+}
+
+func TestTestSubtests(t *testing.T) {
+	runTinyGoTestCases(t, "subtests", []tinyGoTestCase{
+		{
+			// TestParallel, TestTempDir and TestSetenv check their own results,
+			// so they only print something when they fail.
+			name:   "All",
+			config: compileopts.TestConfig{},
+			passed: false,
+			output: "    --- FAIL: TestFatal/sub\n" +
+				"\tstop\n" +
+				"\n" +
+				"\tcleanup ran\n" +
+				"\n" +
+				"--- FAIL: TestFatal\n" +
+				"\tparent continues\n" +
+				"\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/subtests\t0.000s\n",
+		},
+		{
+			name:   "Cleanup",
+			config: compileopts.TestConfig{RunRegexp: "TestCleanup", Verbose: true},
+			passed: true,
+			output: "=== RUN   TestCleanup\n" +
+				"=== RUN   TestCleanup/sub\n" +
+				"    --- PASS: TestCleanup/sub\n" +
+				"--- PASS: TestCleanup\n" +
+				"=== RUN   TestCleanupOrder\n" +
+				"--- PASS: TestCleanupOrder\n" +
+				"\tsub, sub cleanup, test, cleanup 2, cleanup 1\n" +
+				"\n" +
+				"PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/subtests\t0.000s\n",
+		},
+		{
+			name:   "RunSubtest",
+			config: compileopts.TestConfig{RunRegexp: "TestSetenv/set", Verbose: true},
+			passed: true,
+			output: "=== RUN   TestSetenv\n" +
+				"=== RUN   TestSetenv/set\n" +
+				"    --- PASS: TestSetenv/set\n" +
+				"--- PASS: TestSetenv\n" +
+				"PASS\n" +
+				"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/subtests\t0.000s\n",
+		},
+	})
+}
+
+func TestTestGoexit(t *testing.T) {
+	runTinyGoTestCases(t, "goexit", []tinyGoTestCase{
+		{
+			// runtime.Goexit runs deferred calls with -panic=unwind, before
+			// the cleanup functions.
+			name:          "Unwind",
+			config:        compileopts.TestConfig{},
+			panicStrategy: "unwind",
+			passed:        false,
+			output: "    --- FAIL: TestFatalDefer/sub\n" +
+				"\tstop\n" +
+				"\n" +
+				"\tdeferred call ran\n" +
+				"\n" +
+				"\tcleanup ran\n" +
+				"\n" +
+				"--- FAIL: TestFatalDefer\n" +
+				"\tparent continues\n" +
+				"\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/goexit\t0.000s\n",
+		},
+		{
+			name:   "NoUnwind",
+			config: compileopts.TestConfig{},
+			passed: false,
+			output: "    --- FAIL: TestFatalDefer/sub\n" +
+				"\tstop\n" +
+				"\n" +
+				"\tcleanup ran\n" +
+				"\n" +
+				"--- FAIL: TestFatalDefer\n" +
+				"\tparent continues\n" +
+				"\n" +
+				"FAIL\n" +
+				"FAIL\tgithub.com/tinygo-org/tinygo/testdata/testing/goexit\t0.000s\n",
+		},
+	})
+}
+
+func TestTestCoverProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't run tests on the host on Windows")
+	}
+	tmpdir, err := ioutil.TempDir("", "tinygo-test")
+	if err != nil {
+		t.Fatal("could not create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Like tinygo test, create the profile with the mode line first.
+	profile := filepath.Join(tmpdir, "coverage.out")
+	err = ioutil.WriteFile(profile, []byte("mode: set\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passed, output := runTinyGoTest(t, []string{"cover"}, compileopts.Options{
+		TestConfig: compileopts.TestConfig{
+			CoverMode:    "set",
+			CoverProfile: profile,
+		},
+	})
+	if !passed {
+		t.Error("expected the test to pass")
+	}
+	expected := "coverage: 28.6% of statements\n" +
+		"PASS\n" +
+		"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/cover\t0.000s\n"
+	if output != expected {
+		t.Errorf("output did not match\nexpected:\n%s\nactual:\n%s", expected, output)
+	}
+
+	// The profile must be usable by go tool cover. The columns are aligned
+	// with tabs and spaces, so only compare the fields.
+	cmd := exec.Command("go", "tool", "cover", "-func="+profile)
+	result, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go tool cover failed: %v\n%s", err, result)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(result)), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	expected = "github.com/tinygo-org/tinygo/testdata/testing/cover/cover.go:4: Abs 66.7%\n" +
+		"github.com/tinygo-org/tinygo/testdata/testing/cover/cover.go:12: Sign 0.0%\n" +
+		"total: (statements) 28.6%"
+	if actual := strings.Join(lines, "\n"); actual != expected {
+		t.Errorf("go tool cover -func output did not match\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func TestCoverProfileWriter(t *testing.T) {
+	input := "=== RUN   TestAbs\n" +
+		"=== COVERAGE PROFILE\r\n" +
+		"mode: set\r\n" +
+		"example.com/cover/cover.go:5.2,5.11 1 1\n" +
+		"=== END COVERAGE PROFILE\n" +
+		"coverage: 50.0% of statements\n" +
+		"PASS"
+	output := &bytes.Buffer{}
+	w := &coverProfileWriter{w: output}
+
+	// Write the input in small pieces, so that lines are split between
+	// writes.
+	for i := 0; i < len(input); i += 7 {
+		end := i + 7
+		if end > len(input) {
+			end = len(input)
+		}
+		n, err := w.Write([]byte(input[i:end]))
+		if err != nil || n != end-i {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal("Flush failed:", err)
+	}
+
+	expectedOutput := "=== RUN   TestAbs\n" +
+		"coverage: 50.0% of statements\n" +
+		"PASS"
+	if output.String() != expectedOutput {
+		t.Errorf("expected output %q, got %q", expectedOutput, output.String())
+	}
+	expectedProfile := "mode: set\n" +
+		"example.com/cover/cover.go:5.2,5.11 1 1\n"
+	if w.profile.String() != expectedProfile {
+		t.Errorf("expected profile %q, got %q", expectedProfile, w.profile.String())
+	}
+}
+
+func TestAppendCoverProfile(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "tinygo-test")
+	if err != nil {
+		t.Fatal("could not create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	path := filepath.Join(tmpdir, "coverage.out")
+	err = ioutil.WriteFile(path, []byte("mode: count\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, profile := range []string{
+		"mode: count\nexample.com/a/a.go:3.2,3.10 1 4\n",
+		"mode: count\nexample.com/b/b.go:5.2,7.3 2 0\nexample.com/b/b.go:8.2,8.9 1 1\n",
+		"",
+	} {
+		err := appendCoverProfile(path, []byte(profile))
+		if err != nil {
+			t.Fatal("appendCoverProfile failed:", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "mode: count\n" +
+		"example.com/a/a.go:3.2,3.10 1 4\n" +
+		"example.com/b/b.go:5.2,7.3 2 0\n" +
+		"example.com/b/b.go:8.2,8.9 1 1\n"
+	if string(data) != expected {
+		t.Errorf("expected profile %q, got %q", expected, string(data))
+	}
+}
+
+func TestTestPackagePatterns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't run tests on the host on Windows")
+	}
+	options := &compileopts.Options{
+		Opt:      "z",
+		VerifyIR: true,
+		Debug:    true,
+	}
+	pkgNames, err := expandPackagePatterns(options, []string{"./" + TESTDATA + "/testing/pattern/..."})
+	if err != nil {
+		t.Fatal("could not expand package pattern:", err)
+	}
+	expectedNames := "github.com/tinygo-org/tinygo/testdata/testing/pattern/notests " +
+		"github.com/tinygo-org/tinygo/testdata/testing/pattern/one " +
+		"github.com/tinygo-org/tinygo/testdata/testing/pattern/two"
+	if names := strings.Join(pkgNames, " "); names != expectedNames {
+		t.Fatalf("expected packages %s, got %s", expectedNames, names)
+	}
+
+	// Build and run the packages in parallel. The output must still be in
+	// package order.
+	output := &bytes.Buffer{}
+	buildLock.Lock()
+	passed := testPackages(pkgNames, output, 2, options, false, "")
+	buildLock.Unlock()
+	if !passed {
+		t.Error("expected all tests to pass")
+	}
+	expected := "?   \tgithub.com/tinygo-org/tinygo/testdata/testing/pattern/notests\t[no test files]\n" +
+		"PASS\n" +
+		"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/pattern/one\t0.000s\n" +
+		"PASS\n" +
+		"ok  \tgithub.com/tinygo-org/tinygo/testdata/testing/pattern/two\t0.000s\n"
+	if actual := durationRegexp.ReplaceAllString(output.String(), "\t0.000s"); actual != expected {
+		t.Errorf("output did not match\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
 // This TestMain is necessary because TinyGo may also be invoked to run certain
 // LLVM tools in a separate process. Not capturing these invocations would lead
 // to recursive tests.
@@ -0,0 +1,227 @@
+package main
+
+// This file tests the WebAssembly multi-value feature: it checks the
+// signatures of the exported and imported functions in the binary, and runs
+// the program in a host that calls the exports and implements the import.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+func TestWasmMultiValue(t *testing.T) {
+	t.Parallel()
+
+	// wasmtime can't provide the imported function, so the host is a small
+	// Node.js script.
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found:", err)
+	}
+
+	tmpdir, err := ioutil.TempDir("", "tinygo-test")
+	if err != nil {
+		t.Fatal("could not create temporary directory:", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	binary := filepath.Join(tmpdir, "multivalue.wasm")
+	err = runBuild("./"+TESTDATA+"/multivalue.go", binary, &compileopts.Options{
+		Target:       "wasi",
+		Opt:          "z",
+		VerifyIR:     true,
+		Debug:        true,
+		LLVMFeatures: "+multivalue",
+	})
+	if err != nil {
+		printCompilerError(t.Log, err)
+		t.Fatal("failed to build")
+	}
+
+	// Check that the functions return their values directly, and not through
+	// a hidden pointer parameter.
+	data, err := ioutil.ReadFile(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatures, err := readWasmSignatures(data)
+	if err != nil {
+		t.Fatal("could not parse WebAssembly binary:", err)
+	}
+	for name, expected := range map[string]string{
+		"export divmod":          "(i32, i32) (i32, i64)",
+		"export greeting":        "(i32, i32) (i32, i32)",
+		"export makePoint":       "(i32, i32) (i32, i32, f64)",
+		"export sumPair":         "(i32) (i64)",
+		"import multivalue.pair": "(i32) (i32, i64)",
+	} {
+		if signatures[name] != expected {
+			t.Errorf("%s: expected signature %s, got %q", name, expected, signatures[name])
+		}
+	}
+
+	// Run the program, and call the exports from the host.
+	expected, err := ioutil.ReadFile(TESTDATA + "/multivalue.txt")
+	if err != nil {
+		t.Fatal("could not read expected output file:", err)
+	}
+	cmd := exec.Command(node, "--no-warnings", TESTDATA+"/multivalue.js", binary)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	actual, err := cmd.Output()
+	if err != nil {
+		t.Log("stderr:", stderr.String())
+		t.Fatal("failed to run:", err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("output did not match\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+// readWasmSignatures returns the signatures of the exported and imported
+// functions in a WebAssembly binary, such as "(i32, i32) (i64)". The keys are
+// "export <name>" and "import <module>.<name>".
+func readWasmSignatures(data []byte) (map[string]string, error) {
+	if len(data) < 8 || string(data[:4]) != "\x00asm" {
+		return nil, errors.New("not a WebAssembly binary")
+	}
+	r := &wasmReader{data: data[8:]}
+	var types []string
+	var funcTypes []uint64 // type index of each function, imports first
+	signatures := make(map[string]string)
+	for len(r.data) != 0 && r.err == nil {
+		id := r.byte()
+		section := &wasmReader{data: r.bytes(int(r.uint()))}
+		switch id {
+		case 1: // type section
+			for n := section.uint(); n != 0 && section.err == nil; n-- {
+				section.byte() // 0x60: function type
+				params := section.valueTypes()
+				results := section.valueTypes()
+				types = append(types, "("+params+") ("+results+")")
+			}
+		case 2: // import section
+			for n := section.uint(); n != 0 && section.err == nil; n-- {
+				name := section.name() + "." + section.name()
+				switch section.byte() {
+				case 0: // function
+					typeIndex := section.uint()
+					funcTypes = append(funcTypes, typeIndex)
+					if typeIndex < uint64(len(types)) {
+						signatures["import "+name] = types[typeIndex]
+					}
+				case 1: // table
+					section.byte()
+					section.limits()
+				case 2: // memory
+					section.limits()
+				case 3: // global
+					section.byte()
+					section.byte()
+				}
+			}
+		case 3: // function section
+			for n := section.uint(); n != 0 && section.err == nil; n-- {
+				funcTypes = append(funcTypes, section.uint())
+			}
+		case 7: // export section
+			for n := section.uint(); n != 0 && section.err == nil; n-- {
+				name := section.name()
+				kind := section.byte()
+				index := section.uint()
+				if kind == 0 && index < uint64(len(funcTypes)) && funcTypes[index] < uint64(len(types)) {
+					signatures["export "+name] = types[funcTypes[index]]
+				}
+			}
+		}
+		if section.err != nil {
+			return nil, section.err
+		}
+	}
+	return signatures, r.err
+}
+
+// wasmReader reads values from a WebAssembly binary. After the first error,
+// all values are zero and the error is kept in err.
+type wasmReader struct {
+	data []byte
+	err  error
+}
+
+var errWasmTruncated = errors.New("unexpected end of data")
+
+func (r *wasmReader) byte() byte {
+	if len(r.data) == 0 {
+		r.err = errWasmTruncated
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *wasmReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		r.err = errWasmTruncated
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// uint reads an unsigned LEB128 integer.
+func (r *wasmReader) uint() uint64 {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errWasmTruncated
+		r.data = nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *wasmReader) name() string {
+	return string(r.bytes(int(r.uint())))
+}
+
+// limits reads the minimum and optional maximum size of a table or memory.
+func (r *wasmReader) limits() {
+	hasMax := r.byte()&1 != 0
+	r.uint()
+	if hasMax {
+		r.uint()
+	}
+}
+
+// valueTypes reads a vector of value types, and returns them as a comma
+// separated list.
+func (r *wasmReader) valueTypes() string {
+	var names []string
+	for n := r.uint(); n != 0 && r.err == nil; n-- {
+		switch t := r.byte(); t {
+		case 0x7f:
+			names = append(names, "i32")
+		case 0x7e:
+			names = append(names, "i64")
+		case 0x7d:
+			names = append(names, "f32")
+		case 0x7c:
+			names = append(names, "f64")
+		default:
+			names = append(names, fmt.Sprintf("0x%02x", t))
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,24 @@
+// +build !scheduler.cores
+
+package task
+
+import "runtime/interrupt"
+
+// Lock protects the scheduler state (such as task queues and channels) from
+// concurrent modification. With only a single core, this only needs to guard
+// against interrupts, so it disables them. Locks may be nested.
+func Lock() interrupt.State {
+	return interrupt.Disable()
+}
+
+// Unlock releases the lock acquired by Lock.
+func Unlock(i interrupt.State) {
+	interrupt.Restore(i)
+}
+
+// PauseLocked releases the lock acquired by Lock and then pauses the current
+// task, similar to Pause. It must be called with the lock held exactly once.
+func PauseLocked(i interrupt.State) {
+	Unlock(i)
+	Pause()
+}
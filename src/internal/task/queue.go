@@ -1,7 +1,5 @@
 package task
 
-import "runtime/interrupt"
-
 const asserts = false
 
 // Queue is a FIFO container of tasks.
@@ -12,9 +10,9 @@ type Queue struct {
 
 // Push a task onto the queue.
 func (q *Queue) Push(t *Task) {
-	i := interrupt.Disable()
+	i := Lock()
 	if asserts && t.Next != nil {
-		interrupt.Restore(i)
+		Unlock(i)
 		panic("runtime: pushing a task to a queue with a non-nil Next pointer")
 	}
 	if q.tail != nil {
@@ -25,15 +23,15 @@ func (q *Queue) Push(t *Task) {
 	if q.head == nil {
 		q.head = t
 	}
-	interrupt.Restore(i)
+	Unlock(i)
 }
 
 // Pop a task off of the queue.
 func (q *Queue) Pop() *Task {
-	i := interrupt.Disable()
+	i := Lock()
 	t := q.head
 	if t == nil {
-		interrupt.Restore(i)
+		Unlock(i)
 		return nil
 	}
 	q.head = t.Next
@@ -41,13 +39,13 @@ func (q *Queue) Pop() *Task {
 		q.tail = nil
 	}
 	t.Next = nil
-	interrupt.Restore(i)
+	Unlock(i)
 	return t
 }
 
 // Append pops the contents of another queue and pushes them onto the end of this queue.
 func (q *Queue) Append(other *Queue) {
-	i := interrupt.Disable()
+	i := Lock()
 	if q.head == nil {
 		q.head = other.head
 	} else {
@@ -55,14 +53,23 @@ func (q *Queue) Append(other *Queue) {
 	}
 	q.tail = other.tail
 	other.head, other.tail = nil, nil
-	interrupt.Restore(i)
+	Unlock(i)
+}
+
+// Front returns the first task in the queue without removing it, or nil if the
+// queue is empty. The other tasks in the queue follow it through the Next field.
+func (q *Queue) Front() *Task {
+	i := Lock()
+	t := q.head
+	Unlock(i)
+	return t
 }
 
 // Empty checks if the queue is empty.
 func (q *Queue) Empty() bool {
-	i := interrupt.Disable()
+	i := Lock()
 	empty := q.head == nil
-	interrupt.Restore(i)
+	Unlock(i)
 	return empty
 }
 
@@ -75,24 +82,24 @@ type Stack struct {
 
 // Push a task onto the stack.
 func (s *Stack) Push(t *Task) {
-	i := interrupt.Disable()
+	i := Lock()
 	if asserts && t.Next != nil {
-		interrupt.Restore(i)
+		Unlock(i)
 		panic("runtime: pushing a task to a stack with a non-nil Next pointer")
 	}
 	s.top, t.Next = t, s.top
-	interrupt.Restore(i)
+	Unlock(i)
 }
 
 // Pop a task off of the stack.
 func (s *Stack) Pop() *Task {
-	i := interrupt.Disable()
+	i := Lock()
 	t := s.top
 	if t != nil {
 		s.top = t.Next
 		t.Next = nil
 	}
-	interrupt.Restore(i)
+	Unlock(i)
 	return t
 }
 
@@ -112,13 +119,13 @@ func (t *Task) tail() *Task {
 // Queue moves the contents of the stack into a queue.
 // Elements can be popped from the queue in the same order that they would be popped from the stack.
 func (s *Stack) Queue() Queue {
-	i := interrupt.Disable()
+	i := Lock()
 	head := s.top
 	s.top = nil
 	q := Queue{
 		head: head,
 		tail: head.tail(),
 	}
-	interrupt.Restore(i)
+	Unlock(i)
 	return q
 }
@@ -17,6 +17,14 @@ type Task struct {
 
 	// state is the underlying running state of the task.
 	state state
+
+	// Unwind holds the panic state of the task while it is not running. It
+	// is empty unless -panic=unwind is used.
+	Unwind UnwindState
+
+	// CallStack is the innermost call frame of the task while it is not
+	// running, used for stack traces on targets with a shadow call stack.
+	CallStack unsafe.Pointer
 }
 
 // getGoroutineStackSize is a compiler intrinsic that returns the stack size for
@@ -57,6 +57,19 @@ func (t *Task) getReturnPtr() unsafe.Pointer {
 	return t.Ptr
 }
 
+// setCallStack is used by the compiler to store the call stack of a new
+// goroutine in its task after it paused for the first time, so that the
+// scheduler can restore it when the goroutine is resumed.
+func (t *Task) setCallStack(frame unsafe.Pointer) {
+	t.CallStack = frame
+}
+
+// SavedFrame returns the frame pointer and the return address saved when the
+// task was paused. Coroutines don't have their own stack, so it returns 0.
+func (t *Task) SavedFrame() (fp, pc uintptr) {
+	return 0, 0
+}
+
 // createTask returns a new task struct initialized with a no-op state.
 func createTask() *Task {
 	return &Task{
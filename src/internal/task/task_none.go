@@ -28,6 +28,12 @@ func (t *Task) Resume() {
 	runtimePanic("scheduler is disabled")
 }
 
+// SavedFrame returns the frame pointer and the return address saved when the
+// task was paused. There are no other tasks, so it returns 0.
+func (t *Task) SavedFrame() (fp, pc uintptr) {
+	return 0, 0
+}
+
 // OnSystemStack returns whether the caller is running on the system stack.
 func OnSystemStack() bool {
 	// This scheduler does not do any stack switching.
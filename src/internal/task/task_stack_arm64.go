@@ -62,3 +62,11 @@ func (s *state) pause() {
 func SystemStack() uintptr {
 	return systemStack
 }
+
+// SavedFrame returns the frame pointer and the return address saved when the
+// task was paused, for stack traces. The frame pointer is 0 if the task has not
+// started yet.
+func (t *Task) SavedFrame() (fp, pc uintptr) {
+	r := (*calleeSavedRegs)(unsafe.Pointer(t.state.sp))
+	return r.x29, r.pc
+}
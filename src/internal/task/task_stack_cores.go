@@ -0,0 +1,96 @@
+// +build scheduler.cores
+
+package task
+
+// This file implements the parts of the task scheduler that differ when
+// goroutines run on multiple cores at the same time. All scheduler state is
+// protected by a single (recursive) spinlock that is implemented in the
+// runtime. Tasks are still never preempted: they only switch to the scheduler
+// when they pause.
+
+import "runtime/interrupt"
+
+// numCPU is the maximum number of cores that run goroutines.
+const numCPU = 2
+
+//go:linkname currentCPU runtime.currentCPU
+func currentCPU() uint32
+
+//go:linkname schedulerTryLock runtime.schedulerTryLock
+func schedulerTryLock() bool
+
+//go:linkname schedulerUnlock runtime.schedulerUnlock
+func schedulerUnlock()
+
+// currentTask is the current running task for each core, or nil if that core
+// is currently in the scheduler.
+var currentTask [numCPU]*Task
+
+// Current returns the current active task.
+func Current() *Task {
+	// A goroutine only moves to a different core when it is paused, so the
+	// core can't change while reading the current task.
+	return currentTask[currentCPU()]
+}
+
+// Lock protects the scheduler state (such as task queues and channels) from
+// concurrent modification, both by interrupts and by other cores. Locks may be
+// nested.
+//
+// While waiting for another core to release the lock, interrupts are restored
+// to the state of the caller. The other core may be waiting for this core to
+// stop for a GC cycle (see gcStopTheWorld in the runtime), which is only
+// possible when interrupts are enabled.
+func Lock() interrupt.State {
+	for {
+		i := interrupt.Disable()
+		if schedulerTryLock() {
+			return i
+		}
+		interrupt.Restore(i)
+	}
+}
+
+// Unlock releases the lock acquired by Lock.
+func Unlock(i interrupt.State) {
+	schedulerUnlock()
+	interrupt.Restore(i)
+}
+
+// Pause suspends the current task and returns to the scheduler.
+// This function may only be called when running on a goroutine stack, not when running on the system stack or in an interrupt.
+func Pause() {
+	PauseLocked(Lock())
+}
+
+// PauseLocked suspends the current task and returns to the scheduler, and
+// releases the lock acquired by Lock once the task is no longer running. This
+// makes sure another core can't resume the task (for example, after it has
+// been put in a channel wait list) before it has fully paused. It must be
+// called with the lock held exactly once.
+func PauseLocked(i interrupt.State) {
+	t := currentTask[currentCPU()]
+
+	// Check whether the canary (the lowest address of the stack) is still
+	// valid. If it is not, a stack overflow has occured.
+	if *t.state.canaryPtr != stackCanary {
+		runtimePanic("goroutine stack overflow")
+	}
+	t.state.pause()
+
+	// The scheduler that paused this task released the lock, and the
+	// scheduler that resumed it (possibly on a different core) did so without
+	// holding it. Only the interrupt state of this task needs to be restored.
+	interrupt.Restore(i)
+}
+
+// Resume the task until it pauses or completes.
+// This may only be called from the scheduler, without holding the lock. When
+// it returns, the task has paused while holding the lock, so the scheduler
+// must release it with Unlock.
+func (t *Task) Resume() {
+	cpu := currentCPU()
+	currentTask[cpu] = t
+	t.state.resume()
+	currentTask[cpu] = nil
+}
@@ -1,4 +1,4 @@
-// +build scheduler.tasks,cortexm
+// +build scheduler.tasks,cortexm scheduler.cores,cortexm
 
 package task
 
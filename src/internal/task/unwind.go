@@ -0,0 +1,12 @@
+// +build panic.unwind
+
+package task
+
+import "unsafe"
+
+// UnwindState is the state of deferred calls of a goroutine. The runtime
+// stores it here when switching to a different goroutine.
+type UnwindState struct {
+	// DeferFrame is the innermost defer frame of this goroutine.
+	DeferFrame unsafe.Pointer
+}
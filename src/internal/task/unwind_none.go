@@ -0,0 +1,6 @@
+// +build !panic.unwind
+
+package task
+
+// UnwindState is empty: panics are not unwound without -panic=unwind.
+type UnwindState struct{}
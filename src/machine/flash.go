@@ -0,0 +1,35 @@
+package machine
+
+import "io"
+
+// BlockDevice is the raw storage device of a filesystem, such as the internal
+// flash of a chip or an external SPI flash chip.
+//
+// Flash memory is organized in erase blocks. An erase block must be erased
+// before it can be written again, and can only be erased a limited number of
+// times. Writes must be aligned to (and be a multiple of) the write block
+// size. Reads can have any size and alignment.
+type BlockDevice interface {
+	// ReadAt reads len(p) bytes from the device at offset off.
+	io.ReaderAt
+
+	// WriteAt writes len(p) bytes to the device at offset off. The offset and
+	// length must be a multiple of WriteBlockSize, and the area must have been
+	// erased.
+	io.WriterAt
+
+	// Size returns the size of the device in bytes.
+	Size() int64
+
+	// WriteBlockSize returns the smallest unit that can be written to the
+	// device, in bytes.
+	WriteBlockSize() int64
+
+	// EraseBlockSize returns the smallest unit that can be erased, in bytes.
+	// It is a multiple of WriteBlockSize.
+	EraseBlockSize() int64
+
+	// EraseBlocks erases the given number of erase blocks, starting at the
+	// given erase block index (not a byte offset).
+	EraseBlocks(start, len int64) error
+}
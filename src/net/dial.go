@@ -5,21 +5,202 @@ import (
 	"time"
 )
 
+// A Dialer contains options for connecting to an address.
 type Dialer struct {
-	Timeout   time.Duration
-	Deadline  time.Time
+	// Timeout is the maximum amount of time a dial will wait for
+	// a connect to complete. If Deadline is also set, it may fail
+	// earlier.
+	Timeout time.Duration
+
+	// Deadline is the absolute point in time after which dials
+	// will fail. If Timeout is set, it may fail earlier.
+	// Zero means no deadline, or dependent on the operating system
+	// as with the Timeout option.
+	Deadline time.Time
+
+	// LocalAddr is the local address to use when dialing an
+	// address. The address must be of a compatible type for the
+	// network being dialed.
+	// If nil, a local address is automatically chosen.
+	LocalAddr Addr
+
 	DualStack bool
 	KeepAlive time.Duration
 }
 
+func minNonzeroTime(a, b time.Time) time.Time {
+	if a.IsZero() {
+		return b
+	}
+	if b.IsZero() || a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// deadline returns the earliest of:
+//   - now+Timeout
+//   - d.Deadline
+//   - the context's deadline
+// Or zero, if none of Timeout, Deadline, or context's deadline is set.
+func (d *Dialer) deadline(ctx context.Context, now time.Time) (earliest time.Time) {
+	if d.Timeout != 0 { // including negative, for historical reasons
+		earliest = now.Add(d.Timeout)
+	}
+	if d, ok := ctx.Deadline(); ok {
+		earliest = minNonzeroTime(earliest, d)
+	}
+	return minNonzeroTime(earliest, d.Deadline)
+}
+
+// Dial connects to the address on the named network.
+//
+// Known networks are "tcp", "tcp4" (IPv4-only), "tcp6" (IPv6-only),
+// "udp", "udp4" (IPv4-only) and "udp6" (IPv6-only).
+//
+// For TCP and UDP networks, the address has the form "host:port".
+// The host must be a literal IP address, or a host name that can be
+// resolved to IP addresses by the network stack.
+// The port must be a literal port number or a service name.
+// If the host is a literal IPv6 address it must be enclosed in square
+// brackets, as in "[2001:db8::1]:80" or "[fe80::1%zone]:80".
+// When using TCP, and the host resolves to multiple IP addresses, Dial
+// prefers the first IPv4 address.
+//
+// Dial uses the network stack set with UseStack.
 func Dial(network, address string) (Conn, error) {
-	return nil, ErrNotImplemented
+	var d Dialer
+	return d.Dial(network, address)
 }
 
-func Listen(network, address string) (Listener, error) {
-	return nil, ErrNotImplemented
+// DialTimeout acts like Dial but takes a timeout.
+//
+// The timeout includes name resolution, if required.
+func DialTimeout(network, address string, timeout time.Duration) (Conn, error) {
+	d := Dialer{Timeout: timeout}
+	return d.Dial(network, address)
 }
 
+// Dial connects to the address on the named network.
+//
+// See func Dial for a description of the network and address
+// parameters.
+func (d *Dialer) Dial(network, address string) (Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using
+// the provided context.
+//
+// The provided Context must be non-nil. The context is only checked before
+// connecting: cancelling it while the connection is being established has no
+// effect, but its deadline is used as a connection timeout.
+//
+// See func Dial for a description of the network and address
+// parameters.
 func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
-	return nil, ErrNotImplemented
+	if ctx == nil {
+		panic("nil context")
+	}
+	deadline := d.deadline(ctx, time.Now())
+	if err := ctx.Err(); err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		ip, port, zone, err := resolveAddr(ctx, network, address)
+		if err != nil {
+			return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		var laddr *TCPAddr
+		if d.LocalAddr != nil {
+			var ok bool
+			laddr, ok = d.LocalAddr.(*TCPAddr)
+			if !ok {
+				return nil, &OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: nil, Err: &AddrError{Err: "mismatched local address type", Addr: d.LocalAddr.String()}}
+			}
+		}
+		c, err := dialTCP(network, laddr, &TCPAddr{IP: ip, Port: port, Zone: zone}, deadline)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case "udp", "udp4", "udp6":
+		ip, port, zone, err := resolveAddr(ctx, network, address)
+		if err != nil {
+			return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		var laddr *UDPAddr
+		if d.LocalAddr != nil {
+			var ok bool
+			laddr, ok = d.LocalAddr.(*UDPAddr)
+			if !ok {
+				return nil, &OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: nil, Err: &AddrError{Err: "mismatched local address type", Addr: d.LocalAddr.String()}}
+			}
+		}
+		c, err := DialUDP(network, laddr, &UDPAddr{IP: ip, Port: port, Zone: zone})
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: UnknownNetworkError(network)}
+	}
+}
+
+// Listen announces on the local network address.
+//
+// The network must be "tcp", "tcp4" or "tcp6".
+//
+// For TCP networks, if the host in the address parameter is empty or
+// a literal unspecified IP address, Listen listens on all available
+// IP addresses of the local system.
+// If the port in the address parameter is empty or "0", as in
+// "127.0.0.1:" or "[::1]:0", a port number is automatically chosen.
+// The Addr method of Listener can be used to discover the chosen
+// port.
+func Listen(network, address string) (Listener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: UnknownNetworkError(network)}
+	}
+	ip, port, zone, err := resolveAddr(context.Background(), network, address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	l, err := ListenTCP(network, &TCPAddr{IP: ip, Port: port, Zone: zone})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ListenPacket announces on the local network address.
+//
+// The network must be "udp", "udp4" or "udp6".
+//
+// For UDP networks, if the host in the address parameter is empty or
+// a literal unspecified IP address, ListenPacket listens on all
+// available IP addresses of the local system.
+// If the port in the address parameter is empty or "0", as in
+// "127.0.0.1:" or "[::1]:0", a port number is automatically chosen.
+// The LocalAddr method of PacketConn can be used to discover the
+// chosen port.
+func ListenPacket(network, address string) (PacketConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: UnknownNetworkError(network)}
+	}
+	ip, port, zone, err := resolveAddr(context.Background(), network, address)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	c, err := ListenUDP(network, &UDPAddr{IP: ip, Port: port, Zone: zone})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
@@ -0,0 +1,233 @@
+package net
+
+import (
+	"errors"
+	"time"
+)
+
+// This file implements a minimal DNS client, for network stacks that can send
+// UDP packets but don't resolve host names themselves. It only looks up A and
+// AAAA records, and doesn't use a search list.
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+	dnsClassIN  = 1
+
+	// Time to wait for a reply from a name server, and the number of times a
+	// query is sent to each server.
+	dnsTimeout  = 3 * time.Second
+	dnsAttempts = 2
+)
+
+var (
+	errServerMisbehaving = errors.New("server misbehaving")
+	errInvalidDNSName    = errors.New("invalid domain name")
+
+	// errWrongDNSReply is returned by parseDNSReply if the packet is not a
+	// reply to the query.
+	errWrongDNSReply = errors.New("unexpected DNS reply")
+)
+
+// dnsQueryID is the ID of the last DNS query. It is incremented for every
+// query, starting at a time-dependent value.
+var dnsQueryID = uint16(time.Now().UnixNano())
+
+// lookupDNS looks up the addresses of a host by sending DNS queries over UDP
+// to the given name servers (on port 53), using stack s. The network is "ip",
+// "ip4" or "ip6".
+func lookupDNS(s Stack, servers []IP, network, host string) ([]IP, error) {
+	var qtypes []uint16
+	switch network {
+	case "ip4":
+		qtypes = []uint16{dnsTypeA}
+	case "ip6":
+		qtypes = []uint16{dnsTypeAAAA}
+	default:
+		qtypes = []uint16{dnsTypeA, dnsTypeAAAA}
+	}
+	var ips []IP
+	var lastErr error
+	for _, qtype := range qtypes {
+		result, err := queryDNS(s, servers, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ips = append(ips, result...)
+	}
+	if len(ips) == 0 {
+		if lastErr == nil {
+			lastErr = ErrNoSuchHost
+		}
+		return nil, lastErr
+	}
+	return ips, nil
+}
+
+// queryDNS sends a query of the given type to the name servers, and returns
+// the addresses in the first usable answer.
+func queryDNS(s Stack, servers []IP, host string, qtype uint16) ([]IP, error) {
+	var lastErr error = errServerMisbehaving
+	for attempt := 0; attempt < dnsAttempts; attempt++ {
+		for _, server := range servers {
+			dnsQueryID++
+			query, ok := appendDNSQuery(nil, dnsQueryID, host, qtype)
+			if !ok {
+				return nil, errInvalidDNSName
+			}
+			ips, err := exchangeDNS(s, server, query, dnsQueryID, qtype)
+			if err == nil || err == ErrNoSuchHost {
+				return ips, err
+			}
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// exchangeDNS sends a query to a name server and parses the reply.
+func exchangeDNS(s Stack, server IP, query []byte, id, qtype uint16) ([]IP, error) {
+	raddr := &UDPAddr{IP: server, Port: 53}
+	laddr := &UDPAddr{IP: IPv4zero}
+	if server.To4() == nil {
+		laddr.IP = IPv6unspecified
+	}
+	h, err := s.ListenUDP(laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	err = h.SetReadDeadline(time.Now().Add(dnsTimeout))
+	if err != nil {
+		return nil, err
+	}
+	_, err = h.Write(query)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 512)
+	for {
+		n, err := h.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := parseDNSReply(buf[:n], id, qtype)
+		if err == errWrongDNSReply {
+			// Not a reply to our query, wait for the next packet.
+			continue
+		}
+		return ips, err
+	}
+}
+
+// appendDNSQuery appends a DNS query for the given name and record type to b.
+// It returns false if the name is not a valid domain name.
+func appendDNSQuery(b []byte, id uint16, name string, qtype uint16) ([]byte, bool) {
+	b = append(b,
+		byte(id>>8), byte(id),
+		0x01, 0x00, // flags: recursion desired
+		0, 1, // one question
+		0, 0, // no answers
+		0, 0, // no authority records
+		0, 0, // no additional records
+	)
+	if len(name) != 0 && name[len(name)-1] == '.' {
+		name = name[:len(name)-1]
+	}
+	if len(name) == 0 || len(name) > 253 {
+		return nil, false
+	}
+	for len(name) != 0 {
+		i := 0
+		for i < len(name) && name[i] != '.' {
+			i++
+		}
+		if i == 0 || i > 63 {
+			return nil, false
+		}
+		b = append(b, byte(i))
+		b = append(b, name[:i]...)
+		if i < len(name) {
+			i++ // skip dot
+		}
+		name = name[i:]
+	}
+	b = append(b, 0, byte(qtype>>8), byte(qtype), 0, dnsClassIN)
+	return b, true
+}
+
+// parseDNSReply returns the addresses of the given type in the answer section
+// of a DNS reply.
+func parseDNSReply(msg []byte, id, qtype uint16) ([]IP, error) {
+	if len(msg) < 12 || uint16(msg[0])<<8|uint16(msg[1]) != id || msg[2]&0x80 == 0 {
+		return nil, errWrongDNSReply
+	}
+	switch msg[3] & 0x0f { // response code
+	case 0:
+	case 3:
+		return nil, ErrNoSuchHost
+	default:
+		return nil, errServerMisbehaving
+	}
+	questions := int(msg[4])<<8 | int(msg[5])
+	answers := int(msg[6])<<8 | int(msg[7])
+	off := 12
+	for i := 0; i < questions; i++ {
+		off = skipDNSName(msg, off)
+		if off < 0 || off+4 > len(msg) {
+			return nil, errServerMisbehaving
+		}
+		off += 4 // type and class
+	}
+	var ips []IP
+	for i := 0; i < answers; i++ {
+		off = skipDNSName(msg, off)
+		if off < 0 || off+10 > len(msg) {
+			break // possibly truncated, use the answers read so far
+		}
+		rtype := uint16(msg[off])<<8 | uint16(msg[off+1])
+		class := uint16(msg[off+2])<<8 | uint16(msg[off+3])
+		length := int(msg[off+8])<<8 | int(msg[off+9])
+		off += 10
+		if off+length > len(msg) {
+			break
+		}
+		data := msg[off : off+length]
+		off += length
+		if rtype != qtype || class != dnsClassIN {
+			continue // for example a CNAME record
+		}
+		switch {
+		case rtype == dnsTypeA && length == IPv4len:
+			ips = append(ips, IPv4(data[0], data[1], data[2], data[3]))
+		case rtype == dnsTypeAAAA && length == IPv6len:
+			ip := make(IP, IPv6len)
+			copy(ip, data)
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, ErrNoSuchHost
+	}
+	return ips, nil
+}
+
+// skipDNSName returns the offset after the (possibly compressed) domain name
+// at offset off, or -1 if it is invalid.
+func skipDNSName(msg []byte, off int) int {
+	for off < len(msg) {
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1
+		case length&0xc0 == 0xc0:
+			// A pointer to a name elsewhere in the message ends the name.
+			return off + 2
+		case length&0xc0 != 0:
+			return -1
+		}
+		off += 1 + length
+	}
+	return -1
+}
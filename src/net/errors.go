@@ -7,4 +7,11 @@ var (
 	errClosed = errors.New("use of closed network connection")
 
 	ErrNotImplemented = errors.New("operation not implemented")
+
+	// ErrNoSuchHost is returned by a Stack when a host name can't be
+	// resolved because it doesn't exist.
+	ErrNoSuchHost = errors.New("no such host")
+
+	errMissingAddress    = errors.New("missing address")
+	errNoSuitableAddress = errors.New("no suitable address found")
 )
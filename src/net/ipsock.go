@@ -6,7 +6,10 @@
 
 package net
 
-import "internal/bytealg"
+import (
+	"context"
+	"internal/bytealg"
+)
 
 // SplitHostPort splits a network address of the form "host:port",
 // "host%zone:port", "[host]:port" or "[host%zone]:port" into host or
@@ -96,3 +99,85 @@ func JoinHostPort(host, port string) string {
 	}
 	return host + ":" + port
 }
+
+// resolveAddr resolves an address of the form "host:port" for an internet
+// network such as "tcp" or "udp6". It returns a nil IP when the host is
+// empty. When the host has several addresses, it prefers IPv4 addresses
+// unless the network only allows IPv6.
+func resolveAddr(ctx context.Context, network, address string) (ip IP, port int, zone string, err error) {
+	host, service, err := SplitHostPort(address)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	port, err = DefaultResolver.LookupPort(ctx, network, service)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if host == "" {
+		return nil, port, "", nil
+	}
+	family := ipNetwork(network)
+	if ip, zone = parseIPZone(host); ip != nil {
+		if !matchFamily(family, ip) {
+			return nil, 0, "", &AddrError{Err: errNoSuitableAddress.Error(), Addr: host}
+		}
+		return ip, port, zone, nil
+	}
+	ips, err := DefaultResolver.LookupIP(ctx, family, host)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, port, "", nil
+		}
+	}
+	return ips[0], port, "", nil
+}
+
+// ipNetwork returns the IP network ("ip", "ip4" or "ip6") of a network such
+// as "tcp" or "udp4".
+func ipNetwork(network string) string {
+	switch network[len(network)-1] {
+	case '4':
+		return "ip4"
+	case '6':
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// matchFamily reports whether ip is an address of the given IP network.
+func matchFamily(network string, ip IP) bool {
+	switch network {
+	case "ip4":
+		return ip.To4() != nil
+	case "ip6":
+		return len(ip) == IPv6len && ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+// wildcardIP returns the unspecified address of a network, or nil if it can
+// be both IPv4 and IPv6.
+func wildcardIP(network string) IP {
+	switch ipNetwork(network) {
+	case "ip4":
+		return IPv4zero
+	case "ip6":
+		return IPv6unspecified
+	default:
+		return nil
+	}
+}
+
+// loopbackIP returns the loopback address of a network, which is used when
+// dialing an address without host.
+func loopbackIP(network string) IP {
+	if ipNetwork(network) == "ip6" {
+		return IPv6loopback
+	}
+	return IPv4(127, 0, 0, 1)
+}
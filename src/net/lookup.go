@@ -0,0 +1,172 @@
+package net
+
+import (
+	"context"
+	"os"
+)
+
+// services contains minimal mappings between services names and port
+// numbers, as there is no complete list of port numbers (such as
+// /etc/services) on most targets.
+//
+// See https://www.iana.org/assignments/service-names-port-numbers
+var services = map[string]map[string]int{
+	"udp": {
+		"domain": 53,
+	},
+	"tcp": {
+		"ftp":    21,
+		"ftps":   990,
+		"gopher": 70, // ʕ◔ϖ◔ʔ
+		"http":   80,
+		"https":  443,
+		"imap2":  143,
+		"imap3":  220,
+		"imaps":  993,
+		"pop3":   110,
+		"pop3s":  995,
+		"smtp":   25,
+		"ssh":    22,
+		"telnet": 23,
+	},
+}
+
+// A Resolver looks up names and numbers.
+//
+// A nil *Resolver is equivalent to a zero Resolver. Lookups are done by the
+// network stack set with UseStack.
+type Resolver struct {
+	// PreferGo is ignored: lookups are always done by the network stack.
+	PreferGo bool
+
+	// StrictErrors is ignored.
+	StrictErrors bool
+}
+
+// DefaultResolver is the resolver used by the package-level Lookup
+// functions and by Dialers without a specified Resolver.
+var DefaultResolver = &Resolver{}
+
+// LookupHost looks up the given host using the network stack.
+// It returns a slice of that host's addresses.
+func LookupHost(host string) (addrs []string, err error) {
+	return DefaultResolver.LookupHost(context.Background(), host)
+}
+
+// LookupHost looks up the given host using the network stack.
+// It returns a slice of that host's addresses.
+func (r *Resolver) LookupHost(ctx context.Context, host string) (addrs []string, err error) {
+	ips, err := r.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	addrs = make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	return addrs, nil
+}
+
+// LookupIP looks up host using the network stack.
+// It returns a slice of that host's IPv4 and IPv6 addresses.
+func LookupIP(host string) ([]IP, error) {
+	return DefaultResolver.LookupIP(context.Background(), "ip", host)
+}
+
+// LookupIPAddr looks up host using the network stack.
+// It returns a slice of that host's IPv4 and IPv6 addresses.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]IPAddr, error) {
+	ips, err := r.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = IPAddr{IP: ip}
+	}
+	return addrs, nil
+}
+
+// LookupIP looks up host for the given network using the network stack.
+// It returns a slice of that host's IP addresses of the type specified by
+// network.
+// network must be one of "ip", "ip4" or "ip6".
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]IP, error) {
+	switch network {
+	case "ip", "ip4", "ip6":
+	default:
+		return nil, UnknownNetworkError(network)
+	}
+	if host == "" {
+		return nil, &DNSError{Err: ErrNoSuchHost.Error(), Name: host, IsNotFound: true}
+	}
+	if ip, _ := parseIPZone(host); ip != nil {
+		if !matchFamily(network, ip) {
+			return nil, &AddrError{Err: errNoSuitableAddress.Error(), Addr: host}
+		}
+		return []IP{ip}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &DNSError{Err: err.Error(), Name: host, IsTimeout: err == context.DeadlineExceeded}
+	}
+	s, err := currentStack()
+	if err != nil {
+		return nil, &DNSError{Err: err.Error(), Name: host}
+	}
+	ips, err := s.LookupIP(network, host)
+	if err == nil && len(ips) == 0 {
+		err = ErrNoSuchHost
+	}
+	if err != nil {
+		return nil, &DNSError{
+			Err:        err.Error(),
+			Name:       host,
+			IsTimeout:  err == os.ErrDeadlineExceeded,
+			IsNotFound: err == ErrNoSuchHost,
+		}
+	}
+	return ips, nil
+}
+
+// LookupPort looks up the port for the given network and service.
+func LookupPort(network, service string) (port int, err error) {
+	return DefaultResolver.LookupPort(context.Background(), network, service)
+}
+
+// LookupPort looks up the port for the given network and service.
+func (r *Resolver) LookupPort(ctx context.Context, network, service string) (port int, err error) {
+	if service == "" {
+		// Lock in the legacy behavior that an empty string
+		// means port 0. See golang.org/issue/13610.
+		return 0, nil
+	}
+	if n, i, ok := dtoi(service); ok && i == len(service) {
+		if n < 0 || n > 0xffff {
+			return 0, &AddrError{Err: "invalid port", Addr: service}
+		}
+		return n, nil
+	}
+	switch network {
+	case "tcp4", "tcp6":
+		network = "tcp"
+	case "udp4", "udp6":
+		network = "udp"
+	}
+	if m, ok := services[network]; ok {
+		if port, ok := m[lowerASCII(service)]; ok {
+			return port, nil
+		}
+	}
+	return 0, &DNSError{Err: "unknown port", Name: network + "/" + service, IsNotFound: true}
+}
+
+// lowerASCII returns s with ASCII letters converted to lower case.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
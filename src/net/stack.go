@@ -0,0 +1,116 @@
+package net
+
+import "time"
+
+// stack is the network stack used by the net package, set with UseStack.
+var stack Stack
+
+// Stack is a network stack that provides TCP and UDP sockets and name
+// resolution to the net package. It is implemented for example by the driver
+// of a network coprocessor, or by a TCP/IP stack running on top of an Ethernet
+// or WiFi driver. The errors returned by a Stack are wrapped in an *OpError or
+// *DNSError by the net package, so they should not be of these types
+// themselves.
+//
+// Blocking operations must respect the deadline they are given (or set with
+// SetReadDeadline and similar methods) and return os.ErrDeadlineExceeded when
+// it expires. A zero deadline means no deadline.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type Stack interface {
+	// DialTCP opens a TCP connection to raddr. The local address laddr may be
+	// nil, in which case the stack picks one.
+	DialTCP(laddr, raddr *TCPAddr, deadline time.Time) (ConnHandle, error)
+
+	// ListenTCP listens for incoming TCP connections on laddr. If the port
+	// is zero, the stack picks a free port.
+	ListenTCP(laddr *TCPAddr) (ListenerHandle, error)
+
+	// ListenUDP opens a UDP socket on laddr. If raddr is not nil, the socket
+	// only exchanges packets with raddr and can be used with Read and Write.
+	ListenUDP(laddr, raddr *UDPAddr) (PacketHandle, error)
+
+	// LookupIP looks up the IP addresses of the given host name. The network
+	// is "ip" for IPv4 and IPv6 addresses, "ip4" for only IPv4 addresses and
+	// "ip6" for only IPv6 addresses. It returns ErrNoSuchHost if the host
+	// doesn't exist.
+	LookupIP(network, host string) ([]IP, error)
+}
+
+// ConnHandle is a connected socket of a Stack, such as a TCP connection. It
+// can also implement CloseWrite to shut down the writing side of the
+// connection.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type ConnHandle interface {
+	// Read reads up to len(b) bytes from the connection. It returns io.EOF
+	// when the other side has closed the connection.
+	Read(b []byte) (n int, err error)
+
+	// Write writes len(b) bytes to the connection.
+	Write(b []byte) (n int, err error)
+
+	// Close closes the connection. Blocked Read and Write calls return an
+	// error.
+	Close() error
+
+	// LocalAddr returns the local address of the connection.
+	LocalAddr() Addr
+
+	// RemoteAddr returns the remote address of the connection, or nil if it
+	// is not connected.
+	RemoteAddr() Addr
+
+	// SetReadDeadline sets the deadline for current and future Read calls.
+	SetReadDeadline(t time.Time) error
+
+	// SetWriteDeadline sets the deadline for current and future Write calls.
+	SetWriteDeadline(t time.Time) error
+}
+
+// ListenerHandle is a listening TCP socket of a Stack.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type ListenerHandle interface {
+	// Accept waits for and returns the next connection.
+	Accept() (ConnHandle, error)
+
+	// Close closes the listener. Blocked Accept calls return an error.
+	Close() error
+
+	// Addr returns the local address of the listener.
+	Addr() Addr
+
+	// SetDeadline sets the deadline for current and future Accept calls.
+	SetDeadline(t time.Time) error
+}
+
+// PacketHandle is a UDP socket of a Stack. Read and Write can only be used if
+// the socket has a remote address.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type PacketHandle interface {
+	ConnHandle
+
+	// ReadFromUDP reads a packet into b, and returns its size and the address
+	// it was sent from. Data that doesn't fit in b is discarded.
+	ReadFromUDP(b []byte) (n int, addr *UDPAddr, err error)
+
+	// WriteToUDP sends b as a packet to addr.
+	WriteToUDP(b []byte, addr *UDPAddr) (n int, err error)
+}
+
+// UseStack sets the network stack used by the net package, replacing the
+// previous stack. On Linux, a stack that uses the sockets of the operating
+// system is used by default.
+func UseStack(s Stack) {
+	stack = s
+}
+
+// currentStack returns the network stack, or an error if there is none.
+func currentStack() (Stack, error) {
+	if stack == nil {
+		return nil, ErrNotImplemented
+	}
+	return stack, nil
+}
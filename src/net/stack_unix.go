@@ -0,0 +1,505 @@
+// +build linux,!baremetal,!wasi,!nintendoswitch
+
+package net
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The default network stack on Linux uses the sockets of the operating
+// system. The sockets are non-blocking: blocking operations are retried with
+// an exponential backoff, so that other goroutines can run while waiting.
+func init() {
+	UseStack(unixStack{})
+}
+
+// The time between two attempts of a blocking operation starts at
+// minPollInterval and doubles after every attempt, up to maxPollInterval. This
+// keeps the latency low for operations that complete quickly, without keeping
+// the CPU busy while a socket is idle for a long time.
+const (
+	minPollInterval = 20 * time.Microsecond
+	maxPollInterval = 20 * time.Millisecond
+)
+
+// backoff sleeps before the next attempt of a blocking operation, and doubles
+// the delay for the attempt after that. A zero delay starts at
+// minPollInterval. It doesn't sleep past the deadline, if there is one.
+func backoff(delay *time.Duration, deadline time.Time) {
+	if *delay == 0 {
+		*delay = minPollInterval
+	}
+	d := *delay
+	if !deadline.IsZero() {
+		if remaining := time.Until(deadline); remaining < d {
+			d = remaining
+		}
+	}
+	time.Sleep(d)
+	*delay *= 2
+	if *delay > maxPollInterval {
+		*delay = maxPollInterval
+	}
+}
+
+// unixStack is a Stack that uses the sockets of the operating system.
+type unixStack struct{}
+
+func (unixStack) DialTCP(laddr, raddr *TCPAddr, deadline time.Time) (ConnHandle, error) {
+	var fd, family int
+	var err error
+	if laddr != nil {
+		fd, family, err = newSocket(syscall.SOCK_STREAM, laddr.IP, laddr.Port, laddr.Zone, raddr.IP)
+	} else {
+		fd, family, err = newSocket(syscall.SOCK_STREAM, nil, -1, "", raddr.IP)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sa, err := sockaddr(family, raddr.IP, raddr.Port, raddr.Zone)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	err = syscall.Connect(fd, sa)
+	var delay time.Duration
+	for err == syscall.EINPROGRESS || err == syscall.EALREADY || err == syscall.EINTR {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			syscall.Close(fd)
+			return nil, os.ErrDeadlineExceeded
+		}
+		backoff(&delay, deadline)
+		var soerr int
+		soerr, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_ERROR)
+		if err == nil && soerr != 0 {
+			err = syscall.Errno(soerr)
+			break
+		}
+		err = syscall.Connect(fd, sa)
+	}
+	if err != nil && err != syscall.EISCONN {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("connect", err)
+	}
+	return newUnixSocket(fd, true), nil
+}
+
+func (unixStack) ListenTCP(laddr *TCPAddr) (ListenerHandle, error) {
+	fd, _, err := newSocket(syscall.SOCK_STREAM, laddr.IP, laddr.Port, laddr.Zone, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Listen(fd, syscall.SOMAXCONN)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("listen", err)
+	}
+	return &unixListener{newUnixSocket(fd, true)}, nil
+}
+
+func (unixStack) ListenUDP(laddr, raddr *UDPAddr) (PacketHandle, error) {
+	var remoteIP IP
+	if raddr != nil {
+		remoteIP = raddr.IP
+	}
+	fd, family, err := newSocket(syscall.SOCK_DGRAM, laddr.IP, laddr.Port, laddr.Zone, remoteIP)
+	if err != nil {
+		return nil, err
+	}
+	if raddr != nil {
+		sa, err := sockaddr(family, raddr.IP, raddr.Port, raddr.Zone)
+		if err == nil {
+			err = syscall.Connect(fd, sa)
+			if err != nil {
+				err = os.NewSyscallError("connect", err)
+			}
+		}
+		if err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+	}
+	return newUnixSocket(fd, false), nil
+}
+
+// LookupIP looks up a host in /etc/hosts, and otherwise asks the name servers
+// listed in /etc/resolv.conf.
+func (s unixStack) LookupIP(network, host string) ([]IP, error) {
+	if ips := lookupHostsFile(network, host); len(ips) != 0 {
+		return ips, nil
+	}
+	return lookupDNS(s, readNameservers(), network, host)
+}
+
+// newSocket creates a non-blocking socket of the given type. If port is not
+// negative, the socket is bound to the given local address. The address
+// family is chosen based on the local and remote IP address: if both are
+// nil, an IPv6 socket is created that also accepts IPv4 connections.
+func newSocket(typ int, ip IP, port int, zone string, remoteIP IP) (fd, family int, err error) {
+	if ip == nil && remoteIP == nil {
+		fd, err = syscall.Socket(syscall.AF_INET6, typ|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, 0)
+		if err == nil {
+			family = syscall.AF_INET6
+			err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, 0)
+			if err != nil {
+				syscall.Close(fd)
+				return -1, 0, os.NewSyscallError("setsockopt", err)
+			}
+		}
+	}
+	if family == 0 {
+		family = syscall.AF_INET
+		if ip != nil && ip.To4() == nil || ip == nil && remoteIP != nil && remoteIP.To4() == nil {
+			family = syscall.AF_INET6
+		}
+		fd, err = syscall.Socket(family, typ|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, 0)
+		if err != nil {
+			return -1, 0, os.NewSyscallError("socket", err)
+		}
+	}
+	if port >= 0 {
+		if typ == syscall.SOCK_STREAM {
+			// Allow listening on a port again right after closing it, like
+			// the official implementation.
+			err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+		}
+		var sa syscall.Sockaddr
+		if err == nil {
+			sa, err = sockaddr(family, ip, port, zone)
+		}
+		if err == nil {
+			err = syscall.Bind(fd, sa)
+			if err != nil {
+				err = os.NewSyscallError("bind", err)
+			}
+		}
+		if err != nil {
+			syscall.Close(fd)
+			return -1, 0, err
+		}
+	}
+	return fd, family, nil
+}
+
+// sockaddr converts an IP address and port to a socket address of the given
+// address family.
+func sockaddr(family int, ip IP, port int, zone string) (syscall.Sockaddr, error) {
+	if family == syscall.AF_INET {
+		sa := &syscall.SockaddrInet4{Port: port}
+		if ip != nil {
+			ip4 := ip.To4()
+			if ip4 == nil {
+				return nil, &AddrError{Err: "non-IPv4 address", Addr: ip.String()}
+			}
+			copy(sa.Addr[:], ip4)
+		}
+		return sa, nil
+	}
+	sa := &syscall.SockaddrInet6{Port: port}
+	if ip != nil {
+		copy(sa.Addr[:], ip.To16())
+	}
+	if zone != "" {
+		// Only numeric zones (interface indices) are supported.
+		n, i, ok := dtoi(zone)
+		if !ok || i != len(zone) {
+			return nil, &AddrError{Err: "unsupported zone", Addr: zone}
+		}
+		sa.ZoneId = uint32(n)
+	}
+	return sa, nil
+}
+
+// sockaddrIP returns the IP address, port and zone of a socket address.
+func sockaddrIP(sa syscall.Sockaddr) (IP, int, string) {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return IPv4(sa.Addr[0], sa.Addr[1], sa.Addr[2], sa.Addr[3]), sa.Port, ""
+	case *syscall.SockaddrInet6:
+		ip := make(IP, IPv6len)
+		copy(ip, sa.Addr[:])
+		zone := ""
+		if sa.ZoneId != 0 {
+			zone = uitoa(uint(sa.ZoneId))
+		}
+		return ip, sa.Port, zone
+	}
+	return nil, 0, ""
+}
+
+// unixSocket is a TCP connection or UDP socket. It implements ConnHandle and
+// PacketHandle.
+type unixSocket struct {
+	fd            int // -1 when closed
+	stream        bool
+	laddr         Addr
+	raddr         Addr
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func newUnixSocket(fd int, stream bool) *unixSocket {
+	s := &unixSocket{fd: fd, stream: stream}
+	if sa, err := syscall.Getsockname(fd); err == nil {
+		s.laddr = s.addr(sa)
+	}
+	if sa, err := syscall.Getpeername(fd); err == nil {
+		s.raddr = s.addr(sa)
+	}
+	return s
+}
+
+// addr converts a socket address to a *TCPAddr or *UDPAddr.
+func (s *unixSocket) addr(sa syscall.Sockaddr) Addr {
+	ip, port, zone := sockaddrIP(sa)
+	if s.stream {
+		return &TCPAddr{IP: ip, Port: port, Zone: zone}
+	}
+	return &UDPAddr{IP: ip, Port: port, Zone: zone}
+}
+
+// wait is called when an operation would block. It returns an error if the
+// socket was closed or the deadline passed, and otherwise waits before the
+// operation is retried. The delay is kept by the caller across attempts of the
+// same operation, see backoff.
+func (s *unixSocket) wait(deadline time.Time, delay *time.Duration) error {
+	if s.fd < 0 {
+		return errClosed
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return os.ErrDeadlineExceeded
+	}
+	backoff(delay, deadline)
+	if s.fd < 0 {
+		return errClosed
+	}
+	return nil
+}
+
+func (s *unixSocket) Read(b []byte) (int, error) {
+	var delay time.Duration
+	for {
+		if s.fd < 0 {
+			return 0, errClosed
+		}
+		n, err := syscall.Read(s.fd, b)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			err = s.wait(s.readDeadline, &delay)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("read", err)
+		}
+		if n == 0 && len(b) != 0 && s.stream {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+func (s *unixSocket) Write(b []byte) (int, error) {
+	written := 0
+	var delay time.Duration
+	for {
+		if s.fd < 0 {
+			return written, errClosed
+		}
+		n, err := syscall.Write(s.fd, b[written:])
+		if n > 0 {
+			written += n
+		}
+		if err == syscall.EAGAIN || err == syscall.EINTR || err == nil && written < len(b) && s.stream {
+			err = s.wait(s.writeDeadline, &delay)
+			if err != nil {
+				return written, err
+			}
+			continue
+		}
+		if err != nil {
+			return written, os.NewSyscallError("write", err)
+		}
+		return written, nil
+	}
+}
+
+func (s *unixSocket) ReadFromUDP(b []byte) (int, *UDPAddr, error) {
+	var delay time.Duration
+	for {
+		if s.fd < 0 {
+			return 0, nil, errClosed
+		}
+		n, from, err := syscall.Recvfrom(s.fd, b, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			err = s.wait(s.readDeadline, &delay)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, nil, os.NewSyscallError("recvfrom", err)
+		}
+		var addr *UDPAddr
+		if from != nil {
+			ip, port, zone := sockaddrIP(from)
+			addr = &UDPAddr{IP: ip, Port: port, Zone: zone}
+		}
+		return n, addr, nil
+	}
+}
+
+func (s *unixSocket) WriteToUDP(b []byte, addr *UDPAddr) (int, error) {
+	family := syscall.AF_INET
+	if laddr, ok := s.laddr.(*UDPAddr); ok && laddr.IP.To4() == nil {
+		family = syscall.AF_INET6
+	}
+	sa, err := sockaddr(family, addr.IP, addr.Port, addr.Zone)
+	if err != nil {
+		return 0, err
+	}
+	var delay time.Duration
+	for {
+		if s.fd < 0 {
+			return 0, errClosed
+		}
+		err := syscall.Sendto(s.fd, b, 0, sa)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			err = s.wait(s.writeDeadline, &delay)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("sendto", err)
+		}
+		return len(b), nil
+	}
+}
+
+func (s *unixSocket) CloseWrite() error {
+	if s.fd < 0 {
+		return errClosed
+	}
+	return os.NewSyscallError("shutdown", syscall.Shutdown(s.fd, syscall.SHUT_WR))
+}
+
+func (s *unixSocket) Close() error {
+	if s.fd < 0 {
+		return errClosed
+	}
+	err := syscall.Close(s.fd)
+	s.fd = -1
+	return os.NewSyscallError("close", err)
+}
+
+func (s *unixSocket) LocalAddr() Addr {
+	return s.laddr
+}
+
+func (s *unixSocket) RemoteAddr() Addr {
+	return s.raddr
+}
+
+func (s *unixSocket) SetReadDeadline(t time.Time) error {
+	s.readDeadline = t
+	return nil
+}
+
+func (s *unixSocket) SetWriteDeadline(t time.Time) error {
+	s.writeDeadline = t
+	return nil
+}
+
+// unixListener is a listening TCP socket. It implements ListenerHandle.
+type unixListener struct {
+	*unixSocket
+}
+
+func (l *unixListener) Accept() (ConnHandle, error) {
+	var delay time.Duration
+	for {
+		if l.fd < 0 {
+			return nil, errClosed
+		}
+		fd, _, err := syscall.Accept4(l.fd, syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC)
+		if err == syscall.EAGAIN || err == syscall.EINTR || err == syscall.ECONNABORTED {
+			err = l.wait(l.readDeadline, &delay)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, os.NewSyscallError("accept", err)
+		}
+		return newUnixSocket(fd, true), nil
+	}
+}
+
+func (l *unixListener) Addr() Addr {
+	return l.laddr
+}
+
+func (l *unixListener) SetDeadline(t time.Time) error {
+	l.readDeadline = t
+	return nil
+}
+
+// lookupHostsFile returns the addresses of host listed in /etc/hosts.
+func lookupHostsFile(network, host string) []IP {
+	data, err := os.ReadFile("/etc/hosts")
+	if err != nil {
+		return nil
+	}
+	host = strings.TrimSuffix(lowerASCII(host), ".")
+	var ips []IP
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip, _ := parseIPZone(fields[0])
+		if ip == nil || !matchFamily(network, ip) {
+			continue
+		}
+		for _, name := range fields[1:] {
+			if strings.TrimSuffix(lowerASCII(name), ".") == host {
+				ips = append(ips, ip)
+				break
+			}
+		}
+	}
+	return ips
+}
+
+// readNameservers returns the name servers listed in /etc/resolv.conf, or the
+// local host if there are none.
+func readNameservers() []IP {
+	var servers []IP
+	data, err := os.ReadFile("/etc/resolv.conf")
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				if ip, _ := parseIPZone(fields[1]); ip != nil {
+					servers = append(servers, ip)
+				}
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = append(servers, IPv4(127, 0, 0, 1))
+	}
+	return servers
+}
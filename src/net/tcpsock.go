@@ -1,11 +1,216 @@
 package net
 
+import (
+	"context"
+	"syscall"
+	"time"
+)
+
+// TCPAddr represents the address of a TCP end point.
+type TCPAddr struct {
+	IP   IP
+	Port int
+	Zone string // IPv6 scoped addressing zone
+}
+
+// Network returns the address's network name, "tcp".
+func (a *TCPAddr) Network() string { return "tcp" }
+
+func (a *TCPAddr) String() string {
+	if a == nil {
+		return "<nil>"
+	}
+	ip := ipEmptyString(a.IP)
+	if a.Zone != "" {
+		return JoinHostPort(ip+"%"+a.Zone, itoa(a.Port))
+	}
+	return JoinHostPort(ip, itoa(a.Port))
+}
+
+func (a *TCPAddr) opAddr() Addr {
+	if a == nil {
+		return nil
+	}
+	return a
+}
+
+// ResolveTCPAddr returns an address of TCP end point.
+//
+// The network must be a TCP network name.
+//
+// If the host in the address parameter is not a literal IP address or
+// the port is not a literal port number, ResolveTCPAddr resolves the
+// address to an address of TCP end point.
+// Otherwise, it parses the address as a pair of literal IP address
+// and port number.
+func ResolveTCPAddr(network, address string) (*TCPAddr, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	case "": // a hint wildcard for Go 1.0 undocumented behavior
+		network = "tcp"
+	default:
+		return nil, UnknownNetworkError(network)
+	}
+	ip, port, zone, err := resolveAddr(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPAddr{IP: ip, Port: port, Zone: zone}, nil
+}
+
 // TCPConn is an implementation of the Conn interface for TCP network
 // connections.
 type TCPConn struct {
 	conn
 }
 
+// CloseWrite shuts down the writing side of the TCP connection.
+// Most callers should just use Close.
 func (c *TCPConn) CloseWrite() error {
-	return &OpError{"close", "", nil, nil, ErrNotImplemented}
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	h, ok := c.handle.(interface{ CloseWrite() error })
+	if !ok {
+		return c.opError("close", ErrNotImplemented)
+	}
+	err := h.CloseWrite()
+	if err != nil {
+		err = c.opError("close", err)
+	}
+	return err
+}
+
+// DialTCP acts like Dial for TCP networks.
+//
+// The network must be a TCP network name; see func Dial for details.
+//
+// If laddr is nil, a local address is automatically chosen.
+// If the IP field of raddr is nil or an unspecified IP address, the
+// local system is assumed.
+func DialTCP(network string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
+	}
+	return dialTCP(network, laddr, raddr, time.Time{})
+}
+
+func dialTCP(network string, laddr, raddr *TCPAddr, deadline time.Time) (*TCPConn, error) {
+	if raddr.IP == nil || raddr.IP.IsUnspecified() {
+		raddr = &TCPAddr{IP: loopbackIP(network), Port: raddr.Port}
+	}
+	s, err := currentStack()
+	if err == nil {
+		var h ConnHandle
+		h, err = s.DialTCP(laddr, raddr, deadline)
+		if err == nil {
+			return &TCPConn{conn{h, network}}, nil
+		}
+	}
+	return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
+}
+
+// TCPListener is a TCP network listener. Clients should typically
+// use variables of type Listener instead of assuming TCP.
+type TCPListener struct {
+	handle ListenerHandle
+	net    string
+}
+
+func (l *TCPListener) ok() bool { return l != nil && l.handle != nil }
+
+// AcceptTCP accepts the next incoming call and returns the new
+// connection.
+func (l *TCPListener) AcceptTCP() (*TCPConn, error) {
+	if !l.ok() {
+		return nil, syscall.EINVAL
+	}
+	h, err := l.handle.Accept()
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.net, Source: nil, Addr: l.handle.Addr(), Err: err}
+	}
+	return &TCPConn{conn{h, l.net}}, nil
+}
+
+// Accept implements the Accept method in the Listener interface; it
+// waits for the next call and returns a generic Conn.
+func (l *TCPListener) Accept() (Conn, error) {
+	c, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close stops listening on the TCP address.
+// Already Accepted connections are not closed.
+func (l *TCPListener) Close() error {
+	if !l.ok() {
+		return syscall.EINVAL
+	}
+	err := l.handle.Close()
+	if err != nil {
+		err = &OpError{Op: "close", Net: l.net, Source: nil, Addr: l.handle.Addr(), Err: err}
+	}
+	return err
+}
+
+// Addr returns the listener's network address, a *TCPAddr.
+// The Addr returned is shared by all invocations of Addr, so
+// do not modify it.
+func (l *TCPListener) Addr() Addr {
+	if !l.ok() {
+		return nil
+	}
+	return l.handle.Addr()
+}
+
+// SetDeadline sets the deadline associated with the listener.
+// A zero time value disables the deadline.
+func (l *TCPListener) SetDeadline(t time.Time) error {
+	if !l.ok() {
+		return syscall.EINVAL
+	}
+	err := l.handle.SetDeadline(t)
+	if err != nil {
+		err = &OpError{Op: "set", Net: l.net, Source: nil, Addr: l.handle.Addr(), Err: err}
+	}
+	return err
+}
+
+// ListenTCP acts like Listen for TCP networks.
+//
+// The network must be a TCP network name; see func Dial for details.
+//
+// If the IP field of laddr is nil or an unspecified IP address,
+// ListenTCP listens on all available unicast and anycast IP addresses
+// of the local system.
+// If the Port field of laddr is 0, a port number is automatically
+// chosen.
+func ListenTCP(network string, laddr *TCPAddr) (*TCPListener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: laddr.opAddr(), Err: UnknownNetworkError(network)}
+	}
+	if laddr == nil {
+		laddr = &TCPAddr{}
+	}
+	if laddr.IP == nil {
+		laddr = &TCPAddr{IP: wildcardIP(network), Port: laddr.Port}
+	}
+	s, err := currentStack()
+	if err == nil {
+		var h ListenerHandle
+		h, err = s.ListenTCP(laddr)
+		if err == nil {
+			return &TCPListener{h, network}, nil
+		}
+	}
+	return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: laddr.opAddr(), Err: err}
 }
@@ -0,0 +1,56 @@
+// +build darwin wasi
+
+package os
+
+import (
+	"io"
+	"syscall"
+)
+
+// dirInfo is the state of a directory that is being read with the directory
+// stream functions of libc.
+type dirInfo struct {
+	dir uintptr // DIR*
+}
+
+func (d *dirInfo) close(fd int) error {
+	// The file descriptor is owned by the directory stream.
+	return syscall.Closedir(d.dir)
+}
+
+// Readdirnames reads the names of up to n entries in the directory. See
+// DirFileHandle.
+func (f *unixFileHandle) Readdirnames(n int) (names []string, err error) {
+	if f.dir == nil {
+		dir, err := syscall.Fdopendir(f.fd)
+		if err != nil {
+			return nil, handleSyscallError(err)
+		}
+		f.dir = &dirInfo{dir: dir}
+	}
+
+	size := n
+	if size <= 0 {
+		size = 100
+		n = -1
+	}
+	names = make([]string, 0, size) // Empty with room to grow.
+	for n != 0 {
+		name, err := syscall.Readdir(f.dir.dir)
+		if err != nil {
+			return names, handleSyscallError(err)
+		}
+		if name == "" {
+			break // EOF
+		}
+		if name == "." || name == ".." {
+			continue
+		}
+		names = append(names, name)
+		n--
+	}
+	if n >= 0 && len(names) == 0 {
+		return names, io.EOF
+	}
+	return names, nil
+}
@@ -0,0 +1,60 @@
+// +build linux,!baremetal,!wasi freebsd,!baremetal
+
+package os
+
+import (
+	"io"
+	"syscall"
+)
+
+// dirInfo is the state of a directory that is being read with ReadDirent.
+type dirInfo struct {
+	buf  []byte // buffer for directory I/O
+	nbuf int    // length of buf; return value from ReadDirent
+	bufp int    // location of next record in buf
+}
+
+const blockSize = 8192
+
+func (d *dirInfo) close(fd int) error {
+	return syscall.Close(fd)
+}
+
+// Readdirnames reads the names of up to n entries in the directory. See
+// DirFileHandle.
+func (f *unixFileHandle) Readdirnames(n int) (names []string, err error) {
+	if f.dir == nil {
+		f.dir = &dirInfo{buf: make([]byte, blockSize)}
+	}
+	d := f.dir
+
+	size := n
+	if size <= 0 {
+		size = 100
+		n = -1
+	}
+	names = make([]string, 0, size) // Empty with room to grow.
+	for n != 0 {
+		// Refill the buffer if necessary.
+		if d.bufp >= d.nbuf {
+			d.bufp = 0
+			d.nbuf, err = syscall.ReadDirent(f.fd, d.buf)
+			if err != nil {
+				return names, handleSyscallError(err)
+			}
+			if d.nbuf <= 0 {
+				break // EOF
+			}
+		}
+
+		// Drain the buffer.
+		var nb, nc int
+		nb, nc, names = syscall.ParseDirent(d.buf[d.bufp:d.nbuf], n, names)
+		d.bufp += nb
+		n -= nc
+	}
+	if n >= 0 && len(names) == 0 {
+		return names, io.EOF
+	}
+	return names, nil
+}
@@ -12,3 +12,22 @@ func Getenv(key string) string {
 func LookupEnv(key string) (string, bool) {
 	return syscall.Getenv(key)
 }
+
+// Setenv sets the value of the environment variable named by the key. It
+// returns an error, if any.
+func Setenv(key, value string) error {
+	err := syscall.Setenv(key, value)
+	if err != nil {
+		return NewSyscallError("setenv", err)
+	}
+	return nil
+}
+
+// Unsetenv unsets a single environment variable.
+func Unsetenv(key string) error {
+	err := syscall.Unsetenv(key)
+	if err != nil {
+		return NewSyscallError("unsetenv", err)
+	}
+	return nil
+}
@@ -16,8 +16,20 @@ var (
 	ErrNotImplemented = errors.New("operation not implemented")
 	ErrNotExist       = errors.New("file not found")
 	ErrExist          = errors.New("file exists")
+
+	// ErrDeadlineExceeded is returned for an expired deadline, for example
+	// by network connections.
+	ErrDeadlineExceeded error = &deadlineExceededError{}
 )
 
+// deadlineExceededError is the type of ErrDeadlineExceeded. Like in the
+// official implementation, it reports itself as a timeout.
+type deadlineExceededError struct{}
+
+func (e *deadlineExceededError) Error() string   { return "i/o timeout" }
+func (e *deadlineExceededError) Timeout() bool   { return true }
+func (e *deadlineExceededError) Temporary() bool { return true }
+
 // The following code is copied from the official implementation.
 // https://github.com/golang/go/blob/4ce6a8e89668b87dce67e2f55802903d6eb9110a/src/os/error.go#L65-L104
 
@@ -68,6 +80,8 @@ func underlyingError(err error) error {
 	switch err := err.(type) {
 	case *PathError:
 		return err.Err
+	case *LinkError:
+		return err.Err
 	case *SyscallError:
 		return err.Err
 	}
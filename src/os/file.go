@@ -38,12 +38,93 @@ func Remove(path string) error {
 	return nil
 }
 
+// RemoveAll removes path and any children it contains. It removes everything
+// it can but returns the first error it encounters. If the path does not
+// exist, RemoveAll returns nil (no error).
+func RemoveAll(path string) error {
+	err := Remove(path)
+	if err == nil || IsNotExist(err) {
+		return nil
+	}
+
+	// The path may be a directory that is not empty.
+	f, err1 := Open(path)
+	if err1 != nil {
+		if IsNotExist(err1) {
+			return nil
+		}
+		return err
+	}
+	names, err1 := f.Readdirnames(-1)
+	f.Close()
+	if err1 != nil {
+		// Not a directory, so the original error is the real one.
+		return err
+	}
+	for _, name := range names {
+		err1 := RemoveAll(path + string(PathSeparator) + name)
+		if err1 != nil {
+			return err1
+		}
+	}
+
+	err = Remove(path)
+	if err == nil || IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Rename renames (moves) oldpath to newpath. If newpath already exists and is
+// not a directory, Rename replaces it. Both paths must be on the same mounted
+// filesystem. If the operation fails, it will return an error of type
+// *LinkError.
+func Rename(oldpath, newpath string) error {
+	oldIndex, oldSuffix := findMountIndex(oldpath)
+	newIndex, newSuffix := findMountIndex(newpath)
+	if oldIndex < 0 || newIndex < 0 {
+		return &LinkError{"rename", oldpath, newpath, ErrNotExist}
+	}
+	if oldIndex != newIndex {
+		return &LinkError{"rename", oldpath, newpath, syscall.EXDEV}
+	}
+	fs, ok := mounts[oldIndex].filesystem.(RenameFilesystem)
+	if !ok {
+		return &LinkError{"rename", oldpath, newpath, ErrNotImplemented}
+	}
+	err := fs.Rename(oldSuffix, newSuffix)
+	if err != nil {
+		return &LinkError{"rename", oldpath, newpath, err}
+	}
+	return nil
+}
+
+// Truncate changes the size of the named file. If the operation fails, it will
+// return an error of type *PathError.
+func Truncate(name string, size int64) error {
+	f, err := OpenFile(name, O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(size)
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return err
+}
+
 // File represents an open file descriptor.
 type File struct {
 	handle FileHandle
 	name   string
 }
 
+// newFile returns a File that reads from and writes to the given handle. The
+// testing package uses it to capture the output of examples.
+func newFile(handle FileHandle, name string) *File {
+	return &File{handle: handle, name: name}
+}
+
 // Name returns the name of the file with which it was opened.
 func (f *File) Name() string {
 	return f.name
@@ -83,8 +164,22 @@ func (f *File) Read(b []byte) (n int, err error) {
 	return
 }
 
+// ReadAt reads len(b) bytes from the File starting at byte offset off. It
+// returns the number of bytes read and the error, if any. ReadAt always returns
+// a non-nil error when n < len(b). At end of file, that error is io.EOF.
 func (f *File) ReadAt(b []byte, off int64) (n int, err error) {
-	return 0, ErrNotImplemented
+	if off < 0 {
+		return 0, &PathError{"readat", f.name, errNegativeOffset}
+	}
+	r, ok := f.handle.(io.ReaderAt)
+	if !ok {
+		return 0, &PathError{"readat", f.name, ErrNotImplemented}
+	}
+	n, err = r.ReadAt(b, off)
+	if err != nil && err != io.EOF {
+		err = &PathError{"readat", f.name, err}
+	}
+	return
 }
 
 // Write writes len(b) bytes to the File. It returns the number of bytes written
@@ -97,6 +192,24 @@ func (f *File) Write(b []byte) (n int, err error) {
 	return
 }
 
+// WriteAt writes len(b) bytes to the File starting at byte offset off. It
+// returns the number of bytes written and an error, if any. WriteAt returns a
+// non-nil error when n != len(b).
+func (f *File) WriteAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, &PathError{"writeat", f.name, errNegativeOffset}
+	}
+	w, ok := f.handle.(io.WriterAt)
+	if !ok {
+		return 0, &PathError{"writeat", f.name, ErrNotImplemented}
+	}
+	n, err = w.WriteAt(b, off)
+	if err != nil {
+		err = &PathError{"writeat", f.name, err}
+	}
+	return
+}
+
 // Close closes the File, rendering it unusable for I/O.
 func (f *File) Close() (err error) {
 	err = f.handle.Close()
@@ -106,29 +219,116 @@ func (f *File) Close() (err error) {
 	return
 }
 
-// Readdir is a stub, not yet implemented
+// Readdir reads the contents of the directory associated with the File and
+// returns a slice of up to n FileInfo values, as would be returned by Lstat, in
+// directory order.
+//
+// If n > 0, Readdir returns at most n FileInfo structures. In this case, if
+// Readdir returns an empty slice, it will return a non-nil error explaining
+// why. At the end of a directory, the error is io.EOF.
+//
+// If n <= 0, Readdir returns all the FileInfo from the directory in a single
+// slice. In this case, if Readdir succeeds (reads all the way to the end of
+// the directory), it returns the slice and a nil error.
 func (f *File) Readdir(n int) ([]FileInfo, error) {
-	return nil, &PathError{"readdir", f.name, ErrNotImplemented}
+	names, err := f.readdirnames("readdir", n)
+	infos := make([]FileInfo, 0, len(names))
+	for _, name := range names {
+		info, lerr := Lstat(f.name + "/" + name)
+		if lerr != nil {
+			if IsNotExist(lerr) {
+				// The file was removed after reading the directory.
+				continue
+			}
+			return infos, lerr
+		}
+		infos = append(infos, info)
+	}
+	if n > 0 && len(infos) == 0 && err == nil {
+		// All entries were removed, which is the same as reaching the end of
+		// the directory.
+		err = io.EOF
+	}
+	return infos, err
 }
 
-// Readdirnames is a stub, not yet implemented
+// Readdirnames reads the contents of the directory associated with the File and
+// returns a slice of up to n names of files in the directory, in directory
+// order. The semantics of n and the returned error are the same as for
+// Readdir.
 func (f *File) Readdirnames(n int) (names []string, err error) {
-	return nil, &PathError{"readdirnames", f.name, ErrNotImplemented}
+	return f.readdirnames("readdirnames", n)
+}
+
+func (f *File) readdirnames(op string, n int) (names []string, err error) {
+	d, ok := f.handle.(DirFileHandle)
+	if !ok {
+		return nil, &PathError{op, f.name, ErrNotImplemented}
+	}
+	names, err = d.Readdirnames(n)
+	if err != nil && err != io.EOF {
+		err = &PathError{op, f.name, err}
+	}
+	return
 }
 
-// Seek is a stub, not yet implemented
+// Seek sets the offset for the next Read or Write on file to offset,
+// interpreted according to whence: 0 means relative to the origin of the file,
+// 1 means relative to the current offset, and 2 means relative to the end. It
+// returns the new offset and an error, if any.
 func (f *File) Seek(offset int64, whence int) (ret int64, err error) {
-	return 0, &PathError{"seek", f.name, ErrNotImplemented}
+	s, ok := f.handle.(io.Seeker)
+	if !ok {
+		return 0, &PathError{"seek", f.name, ErrNotImplemented}
+	}
+	ret, err = s.Seek(offset, whence)
+	if err != nil {
+		err = &PathError{"seek", f.name, err}
+	}
+	return
 }
 
-// Stat is a stub, not yet implemented
+// Stat returns the FileInfo structure describing file. If there is an error, it
+// will be of type *PathError.
 func (f *File) Stat() (FileInfo, error) {
-	return nil, &PathError{"stat", f.name, ErrNotImplemented}
+	s, ok := f.handle.(StatFileHandle)
+	if !ok {
+		return nil, &PathError{"stat", f.name, ErrNotImplemented}
+	}
+	info, err := s.Stat()
+	if err != nil {
+		return nil, &PathError{"stat", f.name, err}
+	}
+	return withName(info, f.name), nil
 }
 
-// Sync is a stub, not yet implemented
+// Sync commits the current contents of the file to stable storage. Files that
+// don't buffer writes don't need to be synced, so this is a no-op if the
+// filesystem doesn't support it.
 func (f *File) Sync() error {
-	return ErrNotImplemented
+	s, ok := f.handle.(SyncFileHandle)
+	if !ok {
+		return nil
+	}
+	err := s.Sync()
+	if err != nil {
+		return &PathError{"sync", f.name, err}
+	}
+	return nil
+}
+
+// Truncate changes the size of the file. It does not change the I/O offset. If
+// there is an error, it will be of type *PathError.
+func (f *File) Truncate(size int64) error {
+	t, ok := f.handle.(TruncateFileHandle)
+	if !ok {
+		return &PathError{"truncate", f.name, ErrNotImplemented}
+	}
+	err := t.Truncate(size)
+	if err != nil {
+		return &PathError{"truncate", f.name, err}
+	}
+	return nil
 }
 
 func (f *File) SyscallConn() (syscall.RawConn, error) {
@@ -161,6 +361,27 @@ func (e *PathError) Error() string {
 	return e.Op + " " + e.Path + ": " + e.Err.Error()
 }
 
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// LinkError records an error during a link or symlink or rename system call and
+// the paths that caused it.
+type LinkError struct {
+	Op  string
+	Old string
+	New string
+	Err error
+}
+
+func (e *LinkError) Error() string {
+	return e.Op + " " + e.Old + " " + e.New + ": " + e.Err.Error()
+}
+
+func (e *LinkError) Unwrap() error {
+	return e.Err
+}
+
 const (
 	O_RDONLY int = syscall.O_RDONLY
 	O_WRONLY int = syscall.O_WRONLY
@@ -172,16 +393,6 @@ const (
 	O_TRUNC  int = syscall.O_TRUNC
 )
 
-// Stat is a stub, not yet implemented
-func Stat(name string) (FileInfo, error) {
-	return nil, &PathError{"stat", name, ErrNotImplemented}
-}
-
-// Lstat is a stub, not yet implemented
-func Lstat(name string) (FileInfo, error) {
-	return nil, &PathError{"lstat", name, ErrNotImplemented}
-}
-
 // Getwd is a stub (for now), always returning an empty string
 func Getwd() (string, error) {
 	return "", nil
@@ -5,6 +5,7 @@ package os
 import (
 	"io"
 	"io/fs"
+	"sort"
 )
 
 type (
@@ -13,12 +14,83 @@ type (
 	FileInfo = fs.FileInfo
 )
 
+// ReadDir reads the contents of the directory associated with the file f and
+// returns a slice of DirEntry values in directory order. The semantics of n and
+// the returned error are the same as for Readdir.
 func (f *File) ReadDir(n int) ([]DirEntry, error) {
-	return nil, &PathError{"ReadDir", f.name, ErrNotImplemented}
+	infos, err := f.Readdir(n)
+	entries := make([]DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = dirEntry{info}
+	}
+	return entries, err
 }
 
+// ReadDir reads the named directory, returning all its directory entries sorted
+// by filename. If an error occurs reading the directory, ReadDir returns the
+// entries it was able to read before the error, along with the error.
 func ReadDir(name string) ([]DirEntry, error) {
-	return nil, &PathError{"ReadDir", name, ErrNotImplemented}
+	f, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dirs, err := f.ReadDir(-1)
+	sort.Sort(dirEntriesByName(dirs))
+	return dirs, err
+}
+
+// dirEntry is a DirEntry based on the FileInfo returned by Lstat.
+type dirEntry struct {
+	info FileInfo
+}
+
+func (d dirEntry) Name() string            { return d.info.Name() }
+func (d dirEntry) IsDir() bool             { return d.info.IsDir() }
+func (d dirEntry) Type() FileMode          { return d.info.Mode().Type() }
+func (d dirEntry) Info() (FileInfo, error) { return d.info, nil }
+
+type dirEntriesByName []DirEntry
+
+func (s dirEntriesByName) Len() int           { return len(s) }
+func (s dirEntriesByName) Less(i, j int) bool { return s[i].Name() < s[j].Name() }
+func (s dirEntriesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// DirFS returns a file system (an fs.FS) for the tree of files rooted at the
+// directory dir.
+//
+// Note that DirFS("/prefix") only guarantees that the Open calls it makes to
+// the operating system will begin with "/prefix": DirFS("/prefix").Open("file")
+// is the same as os.Open("/prefix/file"). So if /prefix/file is a symbolic link
+// pointing outside the /prefix tree, then using DirFS does not stop the access
+// any more than using os.Open does.
+func DirFS(dir string) fs.FS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (dir dirFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &PathError{"open", name, ErrInvalid}
+	}
+	f, err := Open(string(dir) + "/" + name)
+	if err != nil {
+		return nil, err // nil fs.File
+	}
+	return f, nil
+}
+
+func (dir dirFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &PathError{"stat", name, ErrInvalid}
+	}
+	f, err := Stat(string(dir) + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
 // The followings are copied from Go 1.16 official implementation:
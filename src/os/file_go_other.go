@@ -40,12 +40,17 @@ const (
 	ModePerm FileMode = 0777 // Unix permission bits
 )
 
-// IsDir is a stub, always returning false
+// IsDir reports whether m describes a directory.
 func (m FileMode) IsDir() bool {
-	return false
+	return m&ModeDir != 0
 }
 
-// IsRegular is a stub, always returning false
+// IsRegular reports whether m describes a regular file.
 func (m FileMode) IsRegular() bool {
-	return false
+	return m&ModeType == 0
+}
+
+// Perm returns the Unix permission bits in m (m & ModePerm).
+func (m FileMode) Perm() FileMode {
+	return m & ModePerm
 }
@@ -16,9 +16,9 @@ func init() {
 // Stdin, Stdout, and Stderr are open Files pointing to the standard input,
 // standard output, and standard error file descriptors.
 var (
-	Stdin  = &File{unixFileHandle(0), "/dev/stdin"}
-	Stdout = &File{unixFileHandle(1), "/dev/stdout"}
-	Stderr = &File{unixFileHandle(2), "/dev/stderr"}
+	Stdin  = &File{&unixFileHandle{fd: 0}, "/dev/stdin"}
+	Stdout = &File{&unixFileHandle{fd: 1}, "/dev/stdout"}
+	Stderr = &File{&unixFileHandle{fd: 2}, "/dev/stderr"}
 )
 
 // isOS indicates whether we're running on a real operating system with
@@ -35,7 +35,44 @@ func (fs unixFilesystem) Mkdir(path string, perm FileMode) error {
 }
 
 func (fs unixFilesystem) Remove(path string) error {
-	return handleSyscallError(syscall.Unlink(path))
+	// The path may be a file or a directory, so try both.
+	err := syscall.Unlink(path)
+	if err == nil {
+		return nil
+	}
+	err1 := syscall.Rmdir(path)
+	if err1 == nil {
+		return nil
+	}
+	// Both failed. Linux and macOS differ on whether unlink(dir) returns
+	// EISDIR, but both agree that rmdir(file) returns ENOTDIR, so use that to
+	// decide which error is real.
+	if err1 != syscall.ENOTDIR {
+		err = err1
+	}
+	return handleSyscallError(err)
+}
+
+func (fs unixFilesystem) Rename(oldpath, newpath string) error {
+	return handleSyscallError(syscall.Rename(oldpath, newpath))
+}
+
+func (fs unixFilesystem) Stat(path string) (FileInfo, error) {
+	var st syscall.Stat_t
+	err := syscall.Stat(path, &st)
+	if err != nil {
+		return nil, handleSyscallError(err)
+	}
+	return fileStatFromSys(&st, basename(path)), nil
+}
+
+func (fs unixFilesystem) Lstat(path string) (FileInfo, error) {
+	var st syscall.Stat_t
+	err := syscall.Lstat(path, &st)
+	if err != nil {
+		return nil, handleSyscallError(err)
+	}
+	return fileStatFromSys(&st, basename(path)), nil
 }
 
 func (fs unixFilesystem) OpenFile(path string, flag int, perm FileMode) (FileHandle, error) {
@@ -66,17 +103,25 @@ func (fs unixFilesystem) OpenFile(path string, flag int, perm FileMode) (FileHan
 		syscallFlag |= syscall.O_TRUNC
 	}
 	fp, err := syscall.Open(path, syscallFlag, uint32(perm))
-	return unixFileHandle(fp), handleSyscallError(err)
+	if err != nil {
+		return nil, handleSyscallError(err)
+	}
+	return &unixFileHandle{fd: fp}, nil
 }
 
 // unixFileHandle is a Unix file pointer with associated methods that implement
 // the FileHandle interface.
-type unixFileHandle uintptr
+type unixFileHandle struct {
+	fd int
+
+	// dir is set once the file is read as a directory, see Readdirnames.
+	dir *dirInfo
+}
 
 // Read reads up to len(b) bytes from the File. It returns the number of bytes
 // read and any error encountered. At end of file, Read returns 0, io.EOF.
-func (f unixFileHandle) Read(b []byte) (n int, err error) {
-	n, err = syscall.Read(int(f), b)
+func (f *unixFileHandle) Read(b []byte) (n int, err error) {
+	n, err = syscall.Read(f.fd, b)
 	err = handleSyscallError(err)
 	if n == 0 && err == nil {
 		err = io.EOF
@@ -84,17 +129,111 @@ func (f unixFileHandle) Read(b []byte) (n int, err error) {
 	return
 }
 
+// ReadAt reads len(b) bytes from the File starting at byte offset off. It
+// returns the number of bytes read and the error, if any. ReadAt always returns
+// a non-nil error when n < len(b). At end of file, that error is io.EOF.
+func (f *unixFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
+	for len(b) > 0 {
+		m, e := syscall.Pread(f.fd, b, off)
+		if e != nil {
+			err = handleSyscallError(e)
+			break
+		}
+		if m == 0 {
+			err = io.EOF
+			break
+		}
+		n += m
+		b = b[m:]
+		off += int64(m)
+	}
+	return
+}
+
 // Write writes len(b) bytes to the File. It returns the number of bytes written
 // and an error, if any. Write returns a non-nil error when n != len(b).
-func (f unixFileHandle) Write(b []byte) (n int, err error) {
-	n, err = syscall.Write(int(f), b)
+func (f *unixFileHandle) Write(b []byte) (n int, err error) {
+	n, err = syscall.Write(f.fd, b)
 	err = handleSyscallError(err)
 	return
 }
 
+// WriteAt writes len(b) bytes to the File starting at byte offset off. It
+// returns the number of bytes written and an error, if any. WriteAt returns a
+// non-nil error when n != len(b).
+func (f *unixFileHandle) WriteAt(b []byte, off int64) (n int, err error) {
+	for len(b) > 0 {
+		m, e := syscall.Pwrite(f.fd, b, off)
+		if e != nil {
+			err = handleSyscallError(e)
+			break
+		}
+		n += m
+		b = b[m:]
+		off += int64(m)
+	}
+	return
+}
+
+// Seek sets the offset for the next Read or Write on the File.
+func (f *unixFileHandle) Seek(offset int64, whence int) (int64, error) {
+	ret, err := syscall.Seek(f.fd, offset, whence)
+	return ret, handleSyscallError(err)
+}
+
+// Stat returns information about the File. The name is left empty, it is
+// filled in by the os package.
+func (f *unixFileHandle) Stat() (FileInfo, error) {
+	var st syscall.Stat_t
+	err := syscall.Fstat(f.fd, &st)
+	if err != nil {
+		return nil, handleSyscallError(err)
+	}
+	return fileStatFromSys(&st, ""), nil
+}
+
+// Truncate changes the size of the File.
+func (f *unixFileHandle) Truncate(size int64) error {
+	return handleSyscallError(syscall.Ftruncate(f.fd, size))
+}
+
+// Sync commits the contents of the File to stable storage.
+func (f *unixFileHandle) Sync() error {
+	return handleSyscallError(syscall.Fsync(f.fd))
+}
+
 // Close closes the File, rendering it unusable for I/O.
-func (f unixFileHandle) Close() error {
-	return handleSyscallError(syscall.Close(int(f)))
+func (f *unixFileHandle) Close() error {
+	if f.dir != nil {
+		return handleSyscallError(f.dir.close(f.fd))
+	}
+	return handleSyscallError(syscall.Close(f.fd))
+}
+
+// fileStatFromSys converts the result of a stat system call into a FileInfo.
+func fileStatFromSys(st *syscall.Stat_t, name string) *fileStat {
+	fs := &fileStat{
+		name:    name,
+		size:    int64(st.Size),
+		mode:    FileMode(st.Mode & 0777),
+		modTime: statModTime(st),
+		sys:     st,
+	}
+	switch st.Mode & syscall.S_IFMT {
+	case syscall.S_IFBLK:
+		fs.mode |= ModeDevice
+	case syscall.S_IFCHR:
+		fs.mode |= ModeDevice | ModeCharDevice
+	case syscall.S_IFDIR:
+		fs.mode |= ModeDir
+	case syscall.S_IFIFO:
+		fs.mode |= ModeNamedPipe
+	case syscall.S_IFLNK:
+		fs.mode |= ModeSymlink
+	case syscall.S_IFSOCK:
+		fs.mode |= ModeSocket
+	}
+	return fs
 }
 
 // handleSyscallError converts syscall errors into regular os package errors.
@@ -38,9 +38,41 @@ type Filesystem interface {
 	Remove(name string) error
 }
 
+// StatFilesystem can be implemented by a Filesystem to return information about
+// a file without opening it. If it is not implemented, Stat and Lstat open the
+// file and call Stat on the FileHandle instead.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type StatFilesystem interface {
+	// Stat returns a FileInfo describing the named file, following symbolic
+	// links.
+	Stat(name string) (FileInfo, error)
+
+	// Lstat returns a FileInfo describing the named file, without following
+	// symbolic links. Filesystems without symbolic links can implement it the
+	// same way as Stat.
+	Lstat(name string) (FileInfo, error)
+}
+
+// RenameFilesystem can be implemented by a Filesystem that supports renaming
+// (moving) files and directories within the filesystem.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type RenameFilesystem interface {
+	// Rename renames (moves) oldname to newname. If newname already exists
+	// and is not a directory, Rename replaces it.
+	Rename(oldname, newname string) error
+}
+
 // FileHandle is an interface that should be implemented by filesystems
 // implementing the Filesystem interface.
 //
+// A FileHandle can also implement io.Seeker, io.ReaderAt and io.WriterAt to
+// support the Seek, ReadAt and WriteAt methods of File, and any of the
+// StatFileHandle, DirFileHandle, TruncateFileHandle and SyncFileHandle
+// interfaces. Methods of File that are not supported by the FileHandle return
+// ErrNotImplemented.
+//
 // WARNING: this interface is not finalized and may change in a future version.
 type FileHandle interface {
 	// Read reads up to len(b) bytes from the file.
@@ -53,22 +85,73 @@ type FileHandle interface {
 	Close() (err error)
 }
 
+// StatFileHandle can be implemented by a FileHandle to return information about
+// the open file.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type StatFileHandle interface {
+	// Stat returns a FileInfo describing the file. The name of the FileInfo
+	// may be empty, it is then replaced with the base name of the file.
+	Stat() (FileInfo, error)
+}
+
+// DirFileHandle can be implemented by a FileHandle to list the contents of a
+// directory.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type DirFileHandle interface {
+	// Readdirnames reads the contents of the directory and returns the names
+	// of up to n entries, in directory order. It must not return the "." and
+	// ".." entries. The semantics of n and the returned error are the same as
+	// for File.Readdirnames.
+	Readdirnames(n int) (names []string, err error)
+}
+
+// TruncateFileHandle can be implemented by a FileHandle to change the size of
+// the file.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type TruncateFileHandle interface {
+	// Truncate changes the size of the file. It does not change the I/O
+	// offset.
+	Truncate(size int64) error
+}
+
+// SyncFileHandle can be implemented by a FileHandle that buffers writes.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type SyncFileHandle interface {
+	// Sync commits the current contents of the file to stable storage.
+	Sync() error
+}
+
 // findMount returns the appropriate (mounted) filesystem to use for a given
 // filename plus the path relative to that filesystem.
 func findMount(path string) (Filesystem, string) {
+	index, suffix := findMountIndex(path)
+	if index < 0 {
+		return nil, suffix
+	}
+	return mounts[index].filesystem, suffix
+}
+
+// findMountIndex is like findMount, but returns the index of the mount point in
+// the mounts slice instead, or -1 if no filesystem was found. This makes it
+// possible to check whether two paths are on the same filesystem.
+func findMountIndex(path string) (int, string) {
 	for i := len(mounts) - 1; i >= 0; i-- {
 		mount := mounts[i]
 		if strings.HasPrefix(path, mount.prefix) {
-			return mount.filesystem, path[len(mount.prefix)-1:]
+			return i, path[len(mount.prefix)-1:]
 		}
 	}
 	if isOS {
 		// Assume that the first entry in the mounts slice is the OS filesystem
 		// at the root of the directory tree. Use it as-is, to support relative
 		// paths.
-		return mounts[0].filesystem, path
+		return 0, path
 	}
-	return nil, path
+	return -1, path
 }
 
 // Mount mounts the given filesystem in the filesystem abstraction layer of the
@@ -0,0 +1,103 @@
+package flashfs
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileDevice is a block device that is backed by a regular file, such as a
+// disk image. It implements machine.BlockDevice, and can be used to test the
+// filesystem or to prepare an image on a host system.
+type FileDevice struct {
+	file           *os.File
+	size           int64
+	writeBlockSize int64
+	eraseBlockSize int64
+}
+
+// NewFileDevice returns a block device of the given size and geometry backed
+// by the given file. The file is extended to the requested size if it is
+// smaller: the new part is filled with 0xff, like erased flash memory.
+func NewFileDevice(file *os.File, size, writeBlockSize, eraseBlockSize int64) (*FileDevice, error) {
+	if writeBlockSize <= 0 || eraseBlockSize <= 0 || eraseBlockSize%writeBlockSize != 0 || size%eraseBlockSize != 0 {
+		return nil, syscall.EINVAL
+	}
+	dev := &FileDevice{
+		file:           file,
+		size:           size,
+		writeBlockSize: writeBlockSize,
+		eraseBlockSize: eraseBlockSize,
+	}
+	st, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	current := st.Size()
+	if current < size {
+		// Round down to an erase block, and erase from there.
+		start := current / eraseBlockSize
+		err := dev.EraseBlocks(start, size/eraseBlockSize-start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dev, nil
+}
+
+// ReadAt reads from the device at the given offset.
+func (dev *FileDevice) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > dev.size {
+		return 0, syscall.EINVAL
+	}
+	return dev.file.ReadAt(p, off)
+}
+
+// WriteAt writes to the device at the given offset. Like in flash memory, bits
+// can only be cleared: writing a 1 bit to a 0 bit has no effect.
+func (dev *FileDevice) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > dev.size || off%dev.writeBlockSize != 0 || int64(len(p))%dev.writeBlockSize != 0 {
+		return 0, syscall.EINVAL
+	}
+	buf := make([]byte, len(p))
+	_, err := dev.file.ReadAt(buf, off)
+	if err != nil {
+		return 0, err
+	}
+	for i, b := range p {
+		buf[i] &= b
+	}
+	return dev.file.WriteAt(buf, off)
+}
+
+// Size returns the size of the device in bytes.
+func (dev *FileDevice) Size() int64 {
+	return dev.size
+}
+
+// WriteBlockSize returns the write block size of the device.
+func (dev *FileDevice) WriteBlockSize() int64 {
+	return dev.writeBlockSize
+}
+
+// EraseBlockSize returns the erase block size of the device.
+func (dev *FileDevice) EraseBlockSize() int64 {
+	return dev.eraseBlockSize
+}
+
+// EraseBlocks erases the given blocks, by filling them with 0xff.
+func (dev *FileDevice) EraseBlocks(start, len int64) error {
+	if start < 0 || len < 0 || (start+len)*dev.eraseBlockSize > dev.size {
+		return syscall.EINVAL
+	}
+	buf := make([]byte, dev.eraseBlockSize)
+	for i := range buf {
+		buf[i] = 0xff
+	}
+	for block := start; block < start+len; block++ {
+		_, err := dev.file.WriteAt(buf, block*dev.eraseBlockSize)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
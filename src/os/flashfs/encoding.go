@@ -0,0 +1,196 @@
+package flashfs
+
+import (
+	"os"
+	"time"
+)
+
+// commitRecord is a record in the commit log. It is encoded as follows:
+//
+//     magic      [4]byte
+//     sequence   uint32
+//     root       uint32 // first metadata block
+//     length     uint32 // length of the metadata
+//     checksum   uint32 // CRC-32 of the metadata
+//     blockSize  uint32 // erase block size
+//     blockCount uint32 // number of erase blocks
+//     crc        uint32 // CRC-32 of the preceding fields
+type commitRecord struct {
+	sequence   uint32
+	root       uint32
+	length     uint32
+	checksum   uint32
+	blockSize  uint32
+	blockCount uint32
+}
+
+// encode writes the commit record to buf, which must be at least commitSize
+// bytes long. The rest of buf is zeroed.
+func (r *commitRecord) encode(buf []byte) {
+	copy(buf, commitMagic[:])
+	putUint32(buf[4:], r.sequence)
+	putUint32(buf[8:], r.root)
+	putUint32(buf[12:], r.length)
+	putUint32(buf[16:], r.checksum)
+	putUint32(buf[20:], r.blockSize)
+	putUint32(buf[24:], r.blockCount)
+	putUint32(buf[28:], crc32(buf[:28]))
+	for i := commitSize; i < len(buf); i++ {
+		buf[i] = 0
+	}
+}
+
+// decode reads the commit record from buf. It returns false if buf doesn't
+// contain a valid commit record, for example because it was erased or only
+// partially written.
+func (r *commitRecord) decode(buf []byte) bool {
+	if len(buf) < commitSize || string(buf[:4]) != string(commitMagic[:]) {
+		return false
+	}
+	if crc32(buf[:28]) != getUint32(buf[28:]) {
+		return false
+	}
+	r.sequence = getUint32(buf[4:])
+	r.root = getUint32(buf[8:])
+	r.length = getUint32(buf[12:])
+	r.checksum = getUint32(buf[16:])
+	r.blockSize = getUint32(buf[20:])
+	r.blockCount = getUint32(buf[24:])
+	return true
+}
+
+// appendNode serializes a node and its children. The encoding is as follows:
+//
+//     nameLength uint8
+//     name       [nameLength]byte
+//     mode       uint32 // os.FileMode
+//     modTime    int64  // Unix nanoseconds
+//
+// Followed, for a directory, by:
+//
+//     count      uint32 // number of children
+//     children   [count]node
+//
+// Or, for a file, by:
+//
+//     size       int64
+//     blocks     [(size+blockSize-1)/blockSize]uint32
+func appendNode(buf []byte, n *node) []byte {
+	buf = append(buf, uint8(len(n.name)))
+	buf = append(buf, n.name...)
+	buf = appendUint32(buf, uint32(n.mode))
+	buf = appendUint64(buf, uint64(n.modTime))
+	if n.mode.IsDir() {
+		buf = appendUint32(buf, uint32(len(n.children)))
+		for _, child := range n.children {
+			buf = appendNode(buf, child)
+		}
+	} else {
+		buf = appendUint64(buf, uint64(n.size))
+		for _, block := range n.blocks {
+			buf = appendUint32(buf, block)
+		}
+	}
+	return buf
+}
+
+// parseNode parses a node serialized by appendNode. It returns the node, the
+// remaining data and whether the node could be parsed.
+func parseNode(buf []byte, blockSize int64) (*node, []byte, bool) {
+	if len(buf) < 1 {
+		return nil, nil, false
+	}
+	nameLength := int(buf[0])
+	buf = buf[1:]
+	if len(buf) < nameLength+12 {
+		return nil, nil, false
+	}
+	n := &node{
+		name:    string(buf[:nameLength]),
+		mode:    os.FileMode(getUint32(buf[nameLength:])),
+		modTime: int64(getUint64(buf[nameLength+4:])),
+	}
+	buf = buf[nameLength+12:]
+	if n.mode.IsDir() {
+		if len(buf) < 4 {
+			return nil, nil, false
+		}
+		count := getUint32(buf)
+		buf = buf[4:]
+		for i := uint32(0); i < count; i++ {
+			var child *node
+			var ok bool
+			child, buf, ok = parseNode(buf, blockSize)
+			if !ok || child.name == "" {
+				return nil, nil, false
+			}
+			if len(n.children) != 0 && n.children[len(n.children)-1].name >= child.name {
+				// Children must be sorted, without duplicates.
+				return nil, nil, false
+			}
+			n.children = append(n.children, child)
+		}
+	} else {
+		if len(buf) < 8 {
+			return nil, nil, false
+		}
+		n.size = int64(getUint64(buf))
+		buf = buf[8:]
+		// Check the size before calculating the number of blocks, to avoid
+		// overflows and huge allocations with corrupt metadata.
+		if n.size < 0 || n.size/blockSize > int64(len(buf)/4) {
+			return nil, nil, false
+		}
+		count := int((n.size + blockSize - 1) / blockSize)
+		if len(buf) < count*4 {
+			return nil, nil, false
+		}
+		n.blocks = make([]uint32, count)
+		for i := range n.blocks {
+			n.blocks[i] = getUint32(buf[i*4:])
+		}
+		buf = buf[count*4:]
+	}
+	return n, buf, true
+}
+
+// now returns the current time in Unix nanoseconds.
+func now() int64 {
+	return time.Now().UnixNano()
+}
+
+func putUint32(buf []byte, v uint32) {
+	buf[0] = byte(v)
+	buf[1] = byte(v >> 8)
+	buf[2] = byte(v >> 16)
+	buf[3] = byte(v >> 24)
+}
+
+func getUint32(buf []byte) uint32 {
+	return uint32(buf[0]) | uint32(buf[1])<<8 | uint32(buf[2])<<16 | uint32(buf[3])<<24
+}
+
+func getUint64(buf []byte) uint64 {
+	return uint64(getUint32(buf)) | uint64(getUint32(buf[4:]))<<32
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(v)), uint32(v>>32))
+}
+
+// crc32 calculates the IEEE CRC-32 checksum of data. It is implemented
+// bit-by-bit instead of with hash/crc32, to avoid the 1kB lookup table.
+func crc32(data []byte) uint32 {
+	crc := ^uint32(0)
+	for _, b := range data {
+		crc ^= uint32(b)
+		for i := 0; i < 8; i++ {
+			crc = crc>>1 ^ 0xedb88320&-(crc&1)
+		}
+	}
+	return ^crc
+}
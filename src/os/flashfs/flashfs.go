@@ -0,0 +1,754 @@
+// Package flashfs implements a small filesystem for flash memory, that can be
+// mounted in the os package:
+//
+//     fs := flashfs.New(dev)
+//     err := fs.Mount()
+//     if err != nil {
+//         // No filesystem found, create a new one.
+//         err = fs.Format()
+//     }
+//     os.Mount("/flash/", fs)
+//
+// The device is a machine.BlockDevice, such as the internal flash of a chip or
+// an external SPI flash chip. A FileDevice, backed by a disk image, can be
+// used to test the filesystem on a host system.
+//
+// The filesystem is designed to survive power loss at any time and to spread
+// erase cycles over the whole device. It never overwrites data in place:
+// modified data and metadata are always written to newly erased blocks, and a
+// change only becomes visible when a small commit record is written that
+// points to the new metadata. Blocks are allocated in a round-robin fashion so
+// that all free blocks are erased about equally often.
+//
+// Changes to a file are committed when the file is closed or synced. Changes
+// to directories (creating, removing and renaming files) are committed
+// immediately.
+//
+// The design is inspired by littlefs, but is much simpler: the complete
+// directory tree is kept in RAM and written out on every commit. This makes it
+// suitable for filesystems with a modest number of files.
+package flashfs
+
+// On-flash layout
+//
+// The device is divided into erase blocks. The first two erase blocks hold the
+// commit log. Each commit appends a commit record to the active log block.
+// When it is full, the other log block is erased and becomes the active log
+// block. The commit record with the highest sequence number and a valid
+// checksum describes the current state of the filesystem.
+//
+// All other blocks are either free, or hold file data or metadata. File data
+// is stored in whole blocks: byte i of a file is stored in the (i/blockSize)th
+// block of the file. Metadata is a serialized directory tree, stored as a
+// linked list of blocks that each start with the index of the next block.
+//
+// All integers are stored in little endian byte order.
+
+import (
+	"errors"
+	"machine"
+	"os"
+	"sync"
+	"syscall"
+)
+
+const (
+	// Number of erase blocks at the start of the device used for the commit
+	// log.
+	logBlocks = 2
+
+	// Size of a commit record, before rounding up to the write block size.
+	commitSize = 32
+
+	// Marks the end of a linked list of metadata blocks, or a hole in a file.
+	noBlock = 0xffffffff
+
+	// Size of the header of each metadata block: the next block index.
+	metaHeaderSize = 4
+)
+
+// commitMagic identifies a commit record.
+var commitMagic = [4]byte{'t', 'g', 'f', 's'}
+
+var (
+	errNoFilesystem   = errors.New("flashfs: no filesystem found")
+	errDeviceTooSmall = errors.New("flashfs: device too small")
+	errGeometry       = errors.New("flashfs: filesystem was formatted with a different block size")
+	errCorrupt        = errors.New("flashfs: corrupt metadata")
+	errNotMounted     = errors.New("flashfs: not mounted")
+	errNameTooLong    = errors.New("flashfs: file name too long")
+	errFileTooLarge   = errors.New("flashfs: file too large")
+
+	errNoSpace error = syscall.ENOSPC
+)
+
+// FS is a filesystem on a flash block device. It implements os.Filesystem,
+// os.StatFilesystem and os.RenameFilesystem. It is safe for concurrent use.
+type FS struct {
+	lock sync.Mutex
+	dev  machine.BlockDevice
+
+	blockSize  int64  // erase block size
+	writeSize  int64  // write block size
+	recordSize int64  // commit record size, rounded up to writeSize
+	blockCount uint32 // number of erase blocks on the device
+
+	mounted bool
+	root    *node
+	changed bool // the filesystem was changed since the last commit
+
+	// Commit log state.
+	sequence  uint32 // sequence number of the last commit
+	logBlock  uint32 // active log block
+	logOffset int64  // offset of the next commit record in the log block
+
+	// Blocks used by the metadata of the last commit.
+	metaBlocks []uint32
+
+	// Block allocation state. A block is in use if it is referenced by the
+	// current (in-memory) state of the filesystem. A block is busy if it is in
+	// use or if it is referenced by the last commit: such a block must not be
+	// erased, as the filesystem would be corrupted when power is lost before
+	// the next commit.
+	used   []uint8 // bitmap
+	busy   []uint8 // bitmap
+	cursor uint32  // next block to consider for allocation
+
+	// Cache of a single data block that is being written to.
+	cache cache
+}
+
+// cache holds a block of a file in RAM while it is being modified. The block
+// that it will be written to is allocated when the cache is first modified,
+// so that running out of space is reported by the write that caused it, and
+// flushing the cache never needs to allocate a block.
+type cache struct {
+	node  *node
+	index int    // index of the block in the file
+	dirty bool   // the cache was modified and must be written to block
+	block uint32 // newly allocated block, if dirty
+	data  []byte
+}
+
+// node is a file or directory in the filesystem tree.
+type node struct {
+	name     string
+	mode     os.FileMode
+	modTime  int64    // modification time in Unix nanoseconds
+	size     int64    // size of a file
+	blocks   []uint32 // data blocks of a file, or noBlock for a hole
+	children []*node  // contents of a directory, sorted by name
+	removed  bool     // the node was removed from the tree
+}
+
+// New returns a filesystem on the given device. Mount or Format it before
+// use.
+func New(dev machine.BlockDevice) *FS {
+	return &FS{dev: dev}
+}
+
+// configure reads the device geometry.
+func (fs *FS) configure() error {
+	fs.blockSize = fs.dev.EraseBlockSize()
+	fs.writeSize = fs.dev.WriteBlockSize()
+	fs.recordSize = (commitSize + fs.writeSize - 1) / fs.writeSize * fs.writeSize
+	count := fs.dev.Size() / fs.blockSize
+	if count > noBlock {
+		count = noBlock
+	}
+	fs.blockCount = uint32(count)
+	// Require at least one block for metadata, and one spare block so that
+	// it can be rewritten.
+	if fs.blockCount < logBlocks+2 || fs.blockSize < fs.recordSize*2 || fs.blockSize <= metaHeaderSize {
+		return errDeviceTooSmall
+	}
+	fs.used = make([]uint8, (fs.blockCount+7)/8)
+	fs.busy = make([]uint8, (fs.blockCount+7)/8)
+	fs.cache = cache{data: make([]byte, fs.blockSize)}
+	for i := uint32(0); i < logBlocks; i++ {
+		setBit(fs.used, i)
+		setBit(fs.busy, i)
+	}
+	return nil
+}
+
+// Format erases the device and creates an empty filesystem on it. The
+// filesystem is mounted afterwards.
+func (fs *FS) Format() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.mounted = false
+	err := fs.configure()
+	if err != nil {
+		return err
+	}
+	err = fs.dev.EraseBlocks(0, logBlocks)
+	if err != nil {
+		return err
+	}
+	fs.root = &node{mode: os.ModeDir | 0777, modTime: now()}
+	fs.sequence = 0
+	fs.logBlock = 0
+	fs.logOffset = 0
+	fs.metaBlocks = nil
+	fs.cursor = logBlocks
+	fs.mounted = true
+	fs.changed = true
+	err = fs.commit()
+	if err != nil {
+		fs.mounted = false
+	}
+	return err
+}
+
+// Mount reads the filesystem from the device. It returns an error if there is
+// no valid filesystem on the device.
+func (fs *FS) Mount() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.mounted = false
+	err := fs.configure()
+	if err != nil {
+		return err
+	}
+
+	// Find the last commit.
+	var last commitRecord
+	found := false
+	buf := make([]byte, fs.recordSize)
+	for block := uint32(0); block < logBlocks; block++ {
+		for offset := int64(0); offset+fs.recordSize <= fs.blockSize; offset += fs.recordSize {
+			_, err := fs.dev.ReadAt(buf, int64(block)*fs.blockSize+offset)
+			if err != nil {
+				return err
+			}
+			var record commitRecord
+			if !record.decode(buf) {
+				continue
+			}
+			if !found || int32(record.sequence-last.sequence) > 0 {
+				found = true
+				last = record
+				fs.logBlock = block
+				fs.logOffset = offset
+			}
+		}
+	}
+	if !found {
+		return errNoFilesystem
+	}
+	if last.blockSize != uint32(fs.blockSize) || last.blockCount != fs.blockCount {
+		return errGeometry
+	}
+
+	// Records after the last commit may have been partially written when power
+	// was lost. Continue after the last slot in the log block that isn't
+	// erased, and skip one more slot in case a write was interrupted before
+	// it changed any bits.
+	for offset := fs.logOffset + fs.recordSize; offset+fs.recordSize <= fs.blockSize; offset += fs.recordSize {
+		_, err := fs.dev.ReadAt(buf, int64(fs.logBlock)*fs.blockSize+offset)
+		if err != nil {
+			return err
+		}
+		if !isErased(buf) {
+			fs.logOffset = offset
+		}
+	}
+	fs.logOffset += fs.recordSize * 2
+	fs.sequence = last.sequence
+
+	// Read the metadata.
+	root, metaBlocks, err := fs.readMetadata(last)
+	if err != nil {
+		return err
+	}
+	fs.root = root
+	fs.metaBlocks = metaBlocks
+	for _, block := range metaBlocks {
+		fs.markUsed(block)
+	}
+	err = fs.markTree(root)
+	if err != nil {
+		return err
+	}
+	copy(fs.busy, fs.used)
+
+	// Start allocating at a different place after every mount, so that the
+	// first blocks aren't worn out faster when the device is mounted often.
+	fs.cursor = logBlocks + last.sequence*7919%(fs.blockCount-logBlocks)
+	fs.mounted = true
+	return nil
+}
+
+// markTree marks all blocks used by files in the tree as used.
+func (fs *FS) markTree(n *node) error {
+	for _, block := range n.blocks {
+		if block == noBlock {
+			continue
+		}
+		if block < logBlocks || block >= fs.blockCount || getBit(fs.used, block) {
+			return errCorrupt
+		}
+		fs.markUsed(block)
+	}
+	for _, child := range n.children {
+		err := fs.markTree(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmount writes all pending changes to the device. The filesystem can't be
+// used afterwards until it is mounted again.
+func (fs *FS) Unmount() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if !fs.mounted {
+		return errNotMounted
+	}
+	err := fs.commit()
+	fs.mounted = false
+	return err
+}
+
+// Sync writes all pending changes to the device.
+func (fs *FS) Sync() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if !fs.mounted {
+		return errNotMounted
+	}
+	return fs.commit()
+}
+
+// commit writes the current state of the filesystem to the device: it flushes
+// the cache, writes the metadata to new blocks and appends a commit record to
+// the log. It does nothing if nothing changed since the last commit. The lock
+// must be held.
+func (fs *FS) commit() error {
+	if !fs.changed {
+		return nil
+	}
+	err := fs.flushCache()
+	if err != nil {
+		return err
+	}
+
+	// Write the metadata.
+	meta := appendNode(nil, fs.root)
+	var record commitRecord
+	record.sequence = fs.sequence + 1
+	record.length = uint32(len(meta))
+	record.checksum = crc32(meta)
+	record.blockSize = uint32(fs.blockSize)
+	record.blockCount = fs.blockCount
+	metaBlocks, err := fs.writeMetadata(meta)
+	if err != nil {
+		return err
+	}
+	record.root = metaBlocks[0]
+
+	// Write the commit record. Once it has been written, the new state is the
+	// current state.
+	if fs.logOffset+fs.recordSize > fs.blockSize {
+		// The log block is full, continue in the other log block.
+		next := (fs.logBlock + 1) % logBlocks
+		err = fs.dev.EraseBlocks(int64(next), 1)
+		if err != nil {
+			fs.freeBlocks(metaBlocks)
+			return err
+		}
+		fs.logBlock = next
+		fs.logOffset = 0
+	}
+	buf := make([]byte, fs.recordSize)
+	record.encode(buf)
+	_, err = fs.dev.WriteAt(buf, int64(fs.logBlock)*fs.blockSize+fs.logOffset)
+	fs.logOffset += fs.recordSize
+	if err != nil {
+		fs.freeBlocks(metaBlocks)
+		return err
+	}
+	fs.sequence = record.sequence
+
+	// The old metadata blocks and all blocks that were freed since the last
+	// commit can now be reused.
+	for _, block := range fs.metaBlocks {
+		clearBit(fs.used, block)
+	}
+	fs.metaBlocks = metaBlocks
+	copy(fs.busy, fs.used)
+	fs.changed = false
+	return nil
+}
+
+// writeMetadata writes the serialized directory tree to newly allocated
+// blocks, and returns these blocks.
+func (fs *FS) writeMetadata(meta []byte) ([]uint32, error) {
+	payload := fs.blockSize - metaHeaderSize
+	count := (int64(len(meta)) + payload - 1) / payload
+	if count == 0 {
+		count = 1
+	}
+	blocks := make([]uint32, count)
+	for i := range blocks {
+		block, err := fs.allocBlock(0)
+		if err != nil {
+			fs.freeBlocks(blocks[:i])
+			return nil, err
+		}
+		blocks[i] = block
+	}
+	buf := fs.cache.data // the cache has been flushed
+	fs.dropCache()
+	for i, block := range blocks {
+		next := uint32(noBlock)
+		if i+1 < len(blocks) {
+			next = blocks[i+1]
+		}
+		putUint32(buf, next)
+		n := copy(buf[metaHeaderSize:], meta)
+		meta = meta[n:]
+		length := (int64(metaHeaderSize+n) + fs.writeSize - 1) / fs.writeSize * fs.writeSize
+		for j := metaHeaderSize + int64(n); j < length; j++ {
+			buf[j] = 0
+		}
+		err := fs.writeBlock(block, buf[:length])
+		if err != nil {
+			fs.freeBlocks(blocks)
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+// readMetadata reads and parses the directory tree of the given commit. It
+// also returns the blocks used by the metadata.
+func (fs *FS) readMetadata(record commitRecord) (*node, []uint32, error) {
+	meta := make([]byte, 0, record.length)
+	var blocks []uint32
+	block := record.root
+	header := make([]byte, metaHeaderSize)
+	for uint32(len(meta)) < record.length {
+		if block < logBlocks || block >= fs.blockCount || len(blocks) >= int(fs.blockCount) {
+			return nil, nil, errCorrupt
+		}
+		blocks = append(blocks, block)
+		offset := int64(block) * fs.blockSize
+		_, err := fs.dev.ReadAt(header, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		n := fs.blockSize - metaHeaderSize
+		if remaining := int64(record.length) - int64(len(meta)); n > remaining {
+			n = remaining
+		}
+		start := len(meta)
+		meta = meta[:start+int(n)]
+		_, err = fs.dev.ReadAt(meta[start:], offset+metaHeaderSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		block = getUint32(header)
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, record.root)
+	}
+	if crc32(meta) != record.checksum {
+		return nil, nil, errCorrupt
+	}
+	root, rest, ok := parseNode(meta, fs.blockSize)
+	if !ok || len(rest) != 0 || !root.mode.IsDir() {
+		return nil, nil, errCorrupt
+	}
+	return root, blocks, nil
+}
+
+// allocBlock finds a block that is not busy, marks it as used and erases it.
+// It fails if no more than reserve blocks are available: file data is
+// allocated with a reserve for the metadata, so that the filesystem can
+// always commit (and thus free blocks by removing files) when it is full.
+func (fs *FS) allocBlock(reserve uint32) (uint32, error) {
+	if reserve != 0 && fs.availableBlocks() <= reserve {
+		return 0, errNoSpace
+	}
+	dataBlocks := fs.blockCount - logBlocks
+	for i := uint32(0); i < dataBlocks; i++ {
+		block := logBlocks + (fs.cursor-logBlocks+i)%dataBlocks
+		if getBit(fs.busy, block) {
+			continue
+		}
+		err := fs.dev.EraseBlocks(int64(block), 1)
+		if err != nil {
+			return 0, err
+		}
+		fs.markUsed(block)
+		fs.cursor = block + 1
+		if fs.cursor >= fs.blockCount {
+			fs.cursor = logBlocks
+		}
+		return block, nil
+	}
+	return 0, errNoSpace
+}
+
+// availableBlocks returns the number of blocks that are not busy.
+func (fs *FS) availableBlocks() uint32 {
+	available := uint32(0)
+	for block := uint32(logBlocks); block < fs.blockCount; block++ {
+		if !getBit(fs.busy, block) {
+			available++
+		}
+	}
+	return available
+}
+
+// markUsed marks a block as used (and thus busy).
+func (fs *FS) markUsed(block uint32) {
+	setBit(fs.used, block)
+	setBit(fs.busy, block)
+}
+
+// freeBlock marks a block as unused. It stays busy until the next commit.
+func (fs *FS) freeBlock(block uint32) {
+	if block != noBlock {
+		clearBit(fs.used, block)
+	}
+}
+
+// freeBlocks frees all the given blocks.
+func (fs *FS) freeBlocks(blocks []uint32) {
+	for _, block := range blocks {
+		fs.freeBlock(block)
+	}
+}
+
+// writeBlock writes data to the start of an erased block.
+func (fs *FS) writeBlock(block uint32, data []byte) error {
+	_, err := fs.dev.WriteAt(data, int64(block)*fs.blockSize)
+	return err
+}
+
+// loadCache loads the given block of a file in the cache, flushing the block
+// that was cached before.
+func (fs *FS) loadCache(n *node, index int) error {
+	if fs.cache.node == n && fs.cache.index == index {
+		return nil
+	}
+	err := fs.flushCache()
+	if err != nil {
+		return err
+	}
+	fs.dropCache()
+	if index < len(n.blocks) && n.blocks[index] != noBlock {
+		_, err = fs.dev.ReadAt(fs.cache.data, int64(n.blocks[index])*fs.blockSize)
+		if err != nil {
+			return err
+		}
+	} else {
+		for i := range fs.cache.data {
+			fs.cache.data[i] = 0
+		}
+	}
+	fs.cache.node = n
+	fs.cache.index = index
+	fs.cache.dirty = false
+	return nil
+}
+
+// modifyCache allocates a new block for the cached block, if it wasn't
+// modified yet. It must be called before the cache is modified.
+func (fs *FS) modifyCache() error {
+	if fs.cache.dirty {
+		return nil
+	}
+	// Keep enough blocks available to write the metadata, which may grow by
+	// a block.
+	block, err := fs.allocBlock(uint32(len(fs.metaBlocks)) + 1)
+	if err != nil {
+		return err
+	}
+	fs.cache.block = block
+	fs.cache.dirty = true
+	return nil
+}
+
+// flushCache writes the cached block to its new block, if it was modified.
+func (fs *FS) flushCache() error {
+	c := &fs.cache
+	if c.node == nil || !c.dirty {
+		return nil
+	}
+	err := fs.writeBlock(c.block, c.data)
+	if err != nil {
+		// The block may have been partially written, so it can't be written
+		// again. The modification is lost.
+		fs.dropCache()
+		return err
+	}
+	n := c.node
+	fs.freeBlock(n.blocks[c.index])
+	n.blocks[c.index] = c.block
+	c.dirty = false
+	return nil
+}
+
+// dropCache empties the cache without writing it.
+func (fs *FS) dropCache() {
+	if fs.cache.dirty {
+		fs.freeBlock(fs.cache.block)
+		fs.cache.dirty = false
+	}
+	fs.cache.node = nil
+}
+
+// readAt reads from a file at the given offset. The lock must be held.
+func (fs *FS) readAt(n *node, b []byte, offset int64) (int, error) {
+	read := 0
+	for len(b) > 0 && offset < n.size {
+		index := int(offset / fs.blockSize)
+		blockOffset := offset % fs.blockSize
+		chunk := b
+		if max := fs.blockSize - blockOffset; int64(len(chunk)) > max {
+			chunk = chunk[:max]
+		}
+		if max := n.size - offset; int64(len(chunk)) > max {
+			chunk = chunk[:max]
+		}
+		if fs.cache.node == n && fs.cache.index == index {
+			copy(chunk, fs.cache.data[blockOffset:])
+		} else if block := n.blocks[index]; block == noBlock {
+			for i := range chunk {
+				chunk[i] = 0
+			}
+		} else {
+			_, err := fs.dev.ReadAt(chunk, int64(block)*fs.blockSize+blockOffset)
+			if err != nil {
+				return read, err
+			}
+		}
+		read += len(chunk)
+		b = b[len(chunk):]
+		offset += int64(len(chunk))
+	}
+	return read, nil
+}
+
+// writeAt writes to a file at the given offset. The lock must be held.
+func (fs *FS) writeAt(n *node, b []byte, offset int64) (int, error) {
+	end := offset + int64(len(b))
+	if end/fs.blockSize >= noBlock || end < offset {
+		return 0, errFileTooLarge
+	}
+	if end > n.size {
+		err := fs.resize(n, end)
+		if err != nil {
+			return 0, err
+		}
+	}
+	written := 0
+	for len(b) > 0 {
+		index := int(offset / fs.blockSize)
+		blockOffset := offset % fs.blockSize
+		err := fs.loadCache(n, index)
+		if err != nil {
+			return written, err
+		}
+		err = fs.modifyCache()
+		if err != nil {
+			return written, err
+		}
+		copied := copy(fs.cache.data[blockOffset:], b)
+		written += copied
+		b = b[copied:]
+		offset += int64(copied)
+	}
+	n.modTime = now()
+	fs.changed = true
+	return written, nil
+}
+
+// resize changes the size of a file. Blocks past the end of the file are
+// freed, new blocks are added as holes. The lock must be held.
+func (fs *FS) resize(n *node, size int64) error {
+	if size/fs.blockSize >= noBlock {
+		return errFileTooLarge
+	}
+	count := int((size + fs.blockSize - 1) / fs.blockSize)
+	if size < n.size && size%fs.blockSize != 0 {
+		// Clear the end of the last block, so that the old data doesn't
+		// reappear when the file grows again. Do this first, so that the file
+		// is left unmodified if it fails.
+		index := count - 1
+		if n.blocks[index] != noBlock || fs.cache.node == n && fs.cache.index == index {
+			err := fs.loadCache(n, index)
+			if err == nil {
+				err = fs.modifyCache()
+			}
+			if err != nil {
+				return err
+			}
+			data := fs.cache.data[size%fs.blockSize:]
+			for i := range data {
+				data[i] = 0
+			}
+		}
+	}
+	if fs.cache.node == n && fs.cache.index >= count {
+		fs.dropCache()
+	}
+	for len(n.blocks) > count {
+		last := len(n.blocks) - 1
+		fs.freeBlock(n.blocks[last])
+		n.blocks = n.blocks[:last]
+	}
+	for len(n.blocks) < count {
+		n.blocks = append(n.blocks, noBlock)
+	}
+	n.size = size
+	n.modTime = now()
+	fs.changed = true
+	return nil
+}
+
+// removeNode frees all blocks of a file that was removed from the tree. Files
+// that are still open can't be modified afterwards.
+func (fs *FS) removeNode(n *node) {
+	if fs.cache.node == n {
+		fs.dropCache()
+	}
+	fs.freeBlocks(n.blocks)
+	n.blocks = nil
+	n.size = 0
+	n.removed = true
+}
+
+// isErased returns whether all bytes in buf are in the erased state.
+func isErased(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// Bitmap helpers.
+
+func getBit(bitmap []uint8, index uint32) bool {
+	return bitmap[index/8]&(1<<(index%8)) != 0
+}
+
+func setBit(bitmap []uint8, index uint32) {
+	bitmap[index/8] |= 1 << (index % 8)
+}
+
+func clearBit(bitmap []uint8, index uint32) {
+	bitmap[index/8] &^= 1 << (index % 8)
+}
@@ -0,0 +1,529 @@
+// Package ramfs implements a filesystem that stores all files in RAM. It can be
+// mounted in the os package, for example to use the os package in tests
+// without touching real storage:
+//
+//     os.Mount("/ram/", ramfs.New())
+//     os.WriteFile("/ram/hello.txt", []byte("hello"), 0666)
+//
+// The contents of the filesystem are lost when the program exits.
+package ramfs
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FS is an in-memory filesystem. It implements os.Filesystem,
+// os.StatFilesystem and os.RenameFilesystem. It is safe for concurrent use.
+type FS struct {
+	lock sync.Mutex
+	root *node
+}
+
+// node is a file or directory in the filesystem.
+type node struct {
+	name     string
+	mode     os.FileMode
+	modTime  time.Time
+	data     []byte  // contents of a regular file
+	children []*node // contents of a directory, sorted by name
+}
+
+// New returns a new empty filesystem.
+func New() *FS {
+	return &FS{
+		root: &node{
+			mode:    os.ModeDir | 0777,
+			modTime: time.Now(),
+		},
+	}
+}
+
+// splitPath splits a path into its components. Empty components and "." are
+// removed, and ".." removes the previous component.
+func splitPath(path string) []string {
+	var parts []string
+	for len(path) != 0 {
+		i := 0
+		for i < len(path) && path[i] != '/' {
+			i++
+		}
+		part := path[:i]
+		if i < len(path) {
+			i++ // skip slash
+		}
+		path = path[i:]
+		switch part {
+		case "", ".":
+		case "..":
+			if len(parts) != 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// lookup returns the node at the given path.
+func (fs *FS) lookup(path string) (*node, error) {
+	n := fs.root
+	for _, part := range splitPath(path) {
+		if !n.mode.IsDir() {
+			return nil, syscall.ENOTDIR
+		}
+		_, child := n.child(part)
+		if child == nil {
+			return nil, os.ErrNotExist
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// lookupParent returns the directory that contains path, and the name of the
+// last path component. It returns syscall.EINVAL for the root directory.
+func (fs *FS) lookupParent(path string) (*node, string, error) {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return nil, "", syscall.EINVAL
+	}
+	dir := fs.root
+	for _, part := range parts[:len(parts)-1] {
+		_, child := dir.child(part)
+		if child == nil {
+			return nil, "", os.ErrNotExist
+		}
+		if !child.mode.IsDir() {
+			return nil, "", syscall.ENOTDIR
+		}
+		dir = child
+	}
+	return dir, parts[len(parts)-1], nil
+}
+
+// child returns the index and node of the child with the given name in a
+// directory. If there is no such child, it returns nil and the index where it
+// would be inserted.
+func (n *node) child(name string) (int, *node) {
+	// Binary search, as the children are sorted by name.
+	low, high := 0, len(n.children)
+	for low < high {
+		mid := int(uint(low+high) >> 1)
+		if n.children[mid].name < name {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	if low < len(n.children) && n.children[low].name == name {
+		return low, n.children[low]
+	}
+	return low, nil
+}
+
+// insert adds a new child to the directory, at the index returned by child.
+func (n *node) insert(index int, child *node) {
+	n.children = append(n.children, nil)
+	copy(n.children[index+1:], n.children[index:])
+	n.children[index] = child
+	n.modTime = time.Now()
+}
+
+// remove removes the child at the given index from the directory.
+func (n *node) remove(index int) {
+	copy(n.children[index:], n.children[index+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	n.modTime = time.Now()
+}
+
+// info returns a snapshot of the file information of the node.
+func (n *node) info() *fileInfo {
+	return &fileInfo{
+		name:    n.name,
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+// OpenFile opens the named file. It is created if it doesn't exist and
+// os.O_CREATE is set. Directories can only be opened for reading.
+func (fs *FS) OpenFile(path string, flag int, perm os.FileMode) (os.FileHandle, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f := &file{
+		fs:       fs,
+		readable: flag&os.O_WRONLY == 0 || flag&os.O_RDWR == os.O_RDWR,
+		writable: flag&os.O_WRONLY != 0 || flag&os.O_RDWR != 0,
+		append:   flag&os.O_APPEND != 0,
+	}
+	if len(splitPath(path)) == 0 {
+		f.node = fs.root
+	} else {
+		dir, name, err := fs.lookupParent(path)
+		if err != nil {
+			return nil, err
+		}
+		index, n := dir.child(name)
+		if n == nil {
+			if flag&os.O_CREATE == 0 {
+				return nil, os.ErrNotExist
+			}
+			n = &node{
+				name:    name,
+				mode:    perm & os.ModePerm,
+				modTime: time.Now(),
+			}
+			dir.insert(index, n)
+		} else if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, os.ErrExist
+		}
+		f.node = n
+	}
+
+	if f.node.mode.IsDir() {
+		if f.writable {
+			return nil, syscall.EISDIR
+		}
+	} else if flag&os.O_TRUNC != 0 && f.writable {
+		f.node.data = nil
+		f.node.modTime = time.Now()
+	}
+	return f, nil
+}
+
+// Mkdir creates a new directory.
+func (fs *FS) Mkdir(path string, perm os.FileMode) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, name, err := fs.lookupParent(path)
+	if err == syscall.EINVAL {
+		return os.ErrExist // the root directory
+	}
+	if err != nil {
+		return err
+	}
+	index, n := dir.child(name)
+	if n != nil {
+		return os.ErrExist
+	}
+	dir.insert(index, &node{
+		name:    name,
+		mode:    os.ModeDir | perm&os.ModePerm,
+		modTime: time.Now(),
+	})
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+func (fs *FS) Remove(path string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, name, err := fs.lookupParent(path)
+	if err != nil {
+		return err
+	}
+	index, n := dir.child(name)
+	if n == nil {
+		return os.ErrNotExist
+	}
+	if len(n.children) != 0 {
+		return syscall.ENOTEMPTY
+	}
+	dir.remove(index)
+	return nil
+}
+
+// Rename moves the file or directory at oldpath to newpath. If newpath exists,
+// it is replaced if it is a file or an empty directory.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	oldDir, oldName, err := fs.lookupParent(oldpath)
+	if err != nil {
+		return err
+	}
+	oldIndex, n := oldDir.child(oldName)
+	if n == nil {
+		return os.ErrNotExist
+	}
+	newDir, newName, err := fs.lookupParent(newpath)
+	if err != nil {
+		return err
+	}
+	if n.mode.IsDir() {
+		// A directory can't be moved inside itself.
+		parts := splitPath(newpath)
+		for p := fs.root; len(parts) > 1; parts = parts[1:] {
+			_, p = p.child(parts[0])
+			if p == n {
+				return syscall.EINVAL
+			}
+		}
+	}
+	newIndex, existing := newDir.child(newName)
+	if existing == n {
+		return nil
+	}
+	if existing != nil {
+		if existing.mode.IsDir() {
+			if !n.mode.IsDir() {
+				return syscall.EISDIR
+			}
+			if len(existing.children) != 0 {
+				return syscall.ENOTEMPTY
+			}
+		} else if n.mode.IsDir() {
+			return syscall.ENOTDIR
+		}
+		newDir.remove(newIndex)
+		if newDir == oldDir && newIndex < oldIndex {
+			oldIndex--
+		}
+	}
+	oldDir.remove(oldIndex)
+	n.name = newName
+	newIndex, _ = newDir.child(newName)
+	newDir.insert(newIndex, n)
+	return nil
+}
+
+// Stat returns information about the named file.
+func (fs *FS) Stat(path string) (os.FileInfo, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	n, err := fs.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(), nil
+}
+
+// Lstat is the same as Stat, as there are no symbolic links in this
+// filesystem.
+func (fs *FS) Lstat(path string) (os.FileInfo, error) {
+	return fs.Stat(path)
+}
+
+// file is an open file or directory. It implements os.FileHandle and the
+// optional file handle interfaces of the os package.
+type file struct {
+	fs       *FS
+	node     *node
+	offset   int64
+	readable bool
+	writable bool
+	append   bool
+	closed   bool
+	dirIndex int // next entry returned by Readdirnames
+}
+
+func (f *file) Read(b []byte) (n int, err error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	n, err = f.readAt(b, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+func (f *file) ReadAt(b []byte, offset int64) (n int, err error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	return f.readAt(b, offset)
+}
+
+func (f *file) readAt(b []byte, offset int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.node.mode.IsDir() {
+		return 0, syscall.EISDIR
+	}
+	if !f.readable {
+		return 0, syscall.EBADF
+	}
+	if offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.node.data[offset:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *file) Write(b []byte) (n int, err error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	n, err = f.writeAt(b, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+func (f *file) WriteAt(b []byte, offset int64) (n int, err error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	return f.writeAt(b, offset)
+}
+
+func (f *file) writeAt(b []byte, offset int64) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if !f.writable {
+		return 0, syscall.EBADF
+	}
+	end := offset + int64(len(b))
+	if end > int64(len(f.node.data)) {
+		f.node.resize(end)
+	}
+	copy(f.node.data[offset:], b)
+	f.node.modTime = time.Now()
+	return len(b), nil
+}
+
+// resize changes the size of a file, filling new space with zeroes.
+func (n *node) resize(size int64) {
+	if size <= int64(cap(n.data)) {
+		old := len(n.data)
+		n.data = n.data[:size]
+		for i := old; i < len(n.data); i++ {
+			n.data[i] = 0
+		}
+		return
+	}
+	// Grow the buffer at least twice its size, to make appending cheap.
+	newCap := int64(cap(n.data)) * 2
+	if newCap < size {
+		newCap = size
+	}
+	data := make([]byte, size, newCap)
+	copy(data, n.data)
+	n.data = data
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Truncate(size int64) error {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.node.mode.IsDir() {
+		return syscall.EISDIR
+	}
+	if !f.writable || size < 0 {
+		return syscall.EINVAL
+	}
+	f.node.resize(size)
+	f.node.modTime = time.Now()
+	return nil
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	return f.node.info(), nil
+}
+
+func (f *file) Readdirnames(n int) (names []string, err error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.node.mode.IsDir() {
+		return nil, syscall.ENOTDIR
+	}
+	children := f.node.children
+	if f.dirIndex > len(children) {
+		f.dirIndex = len(children)
+	}
+	children = children[f.dirIndex:]
+	if n > 0 {
+		if len(children) == 0 {
+			return nil, io.EOF
+		}
+		if len(children) > n {
+			children = children[:n]
+		}
+	}
+	names = make([]string, len(children))
+	for i, child := range children {
+		names[i] = child.name
+	}
+	f.dirIndex += len(names)
+	return names, nil
+}
+
+func (f *file) Close() error {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// fileInfo implements os.FileInfo.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
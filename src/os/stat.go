@@ -0,0 +1,110 @@
+package os
+
+import (
+	"errors"
+	"time"
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// Stat returns a FileInfo describing the named file. If there is an error, it
+// will be of type *PathError.
+func Stat(name string) (FileInfo, error) {
+	return stat("stat", name, false)
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the symbolic link. Lstat makes
+// no attempt to follow the link. If there is an error, it will be of type
+// *PathError.
+func Lstat(name string) (FileInfo, error) {
+	return stat("lstat", name, true)
+}
+
+func stat(op, name string, lstat bool) (FileInfo, error) {
+	fs, suffix := findMount(name)
+	if fs == nil {
+		return nil, &PathError{op, name, ErrNotExist}
+	}
+	if sfs, ok := fs.(StatFilesystem); ok {
+		var info FileInfo
+		var err error
+		if lstat {
+			info, err = sfs.Lstat(suffix)
+		} else {
+			info, err = sfs.Stat(suffix)
+		}
+		if err != nil {
+			return nil, &PathError{op, name, err}
+		}
+		return withName(info, name), nil
+	}
+
+	// The filesystem doesn't support stat directly, so try to open the file
+	// and get the information from the file handle.
+	handle, err := fs.OpenFile(suffix, O_RDONLY, 0)
+	if err != nil {
+		return nil, &PathError{op, name, err}
+	}
+	defer handle.Close()
+	s, ok := handle.(StatFileHandle)
+	if !ok {
+		return nil, &PathError{op, name, ErrNotImplemented}
+	}
+	info, err := s.Stat()
+	if err != nil {
+		return nil, &PathError{op, name, err}
+	}
+	return withName(info, name), nil
+}
+
+// withName returns info with the base name of path as its name, if the
+// filesystem didn't provide a name.
+func withName(info FileInfo, path string) FileInfo {
+	if info.Name() != "" {
+		return info
+	}
+	return &namedFileInfo{info, basename(path)}
+}
+
+// namedFileInfo overrides the name of a FileInfo.
+type namedFileInfo struct {
+	FileInfo
+	name string
+}
+
+func (fi *namedFileInfo) Name() string { return fi.name }
+
+// basename removes trailing slashes and the leading directory name from path.
+func basename(path string) string {
+	i := len(path) - 1
+	// Remove trailing slashes.
+	for ; i > 0 && path[i] == '/'; i-- {
+		path = path[:i]
+	}
+	// Remove leading directory name.
+	for i--; i >= 0; i-- {
+		if path[i] == '/' {
+			path = path[i+1:]
+			break
+		}
+	}
+	return path
+}
+
+// fileStat is a FileInfo that can be returned by filesystem implementations in
+// the os package.
+type fileStat struct {
+	name    string
+	size    int64
+	mode    FileMode
+	modTime time.Time
+	sys     interface{}
+}
+
+func (fs *fileStat) Name() string       { return fs.name }
+func (fs *fileStat) Size() int64        { return fs.size }
+func (fs *fileStat) Mode() FileMode     { return fs.mode }
+func (fs *fileStat) ModTime() time.Time { return fs.modTime }
+func (fs *fileStat) IsDir() bool        { return fs.mode.IsDir() }
+func (fs *fileStat) Sys() interface{}   { return fs.sys }
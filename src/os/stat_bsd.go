@@ -0,0 +1,12 @@
+// +build darwin freebsd,!baremetal
+
+package os
+
+import (
+	"syscall"
+	"time"
+)
+
+func statModTime(st *syscall.Stat_t) time.Time {
+	return time.Unix(int64(st.Mtimespec.Sec), int64(st.Mtimespec.Nsec))
+}
@@ -1,5 +1,76 @@
 package os
 
+import (
+	"errors"
+	_ "unsafe" // for go:linkname
+)
+
 func CreateTemp(dir, pattern string) (*File, error) {
 	return nil, &PathError{"createtemp", pattern, ErrNotImplemented}
 }
+
+var errPatternHasSeparator = errors.New("pattern contains path separator")
+
+// MkdirTemp creates a new temporary directory in the directory dir and returns
+// the pathname of the new directory. The new directory's name is generated by
+// adding a random string to the end of pattern. If pattern includes a "*", the
+// random string replaces the last "*" instead. If dir is the empty string,
+// MkdirTemp uses the default directory for temporary files, as returned by
+// TempDir.
+func MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = TempDir()
+	}
+	prefix, suffix, err := prefixAndSuffix(pattern)
+	if err != nil {
+		return "", &PathError{"mkdirtemp", pattern, err}
+	}
+	if len(dir) != 0 && !IsPathSeparator(dir[len(dir)-1]) {
+		prefix = string(PathSeparator) + prefix
+	}
+	prefix = dir + prefix
+
+	for try := 0; try < 10000; try++ {
+		name := prefix + uitoa(uint(runtime_fastrand())) + suffix
+		err := Mkdir(name, 0700)
+		if err == nil {
+			return name, nil
+		}
+		if !IsExist(err) {
+			return "", err
+		}
+	}
+	return "", &PathError{"mkdirtemp", prefix + "*" + suffix, ErrExist}
+}
+
+// prefixAndSuffix splits pattern by the last wildcard "*", if applicable,
+// returning prefix as the part before "*" and suffix as the part after "*".
+func prefixAndSuffix(pattern string) (prefix, suffix string, err error) {
+	for i := 0; i < len(pattern); i++ {
+		if IsPathSeparator(pattern[i]) {
+			return "", "", errPatternHasSeparator
+		}
+	}
+	for i := len(pattern) - 1; i >= 0; i-- {
+		if pattern[i] == '*' {
+			return pattern[:i], pattern[i+1:], nil
+		}
+	}
+	return pattern, "", nil
+}
+
+// uitoa converts an unsigned integer to its decimal representation.
+func uitoa(val uint) string {
+	var buf [20]byte
+	i := len(buf) - 1
+	for val >= 10 {
+		buf[i] = byte(val%10 + '0')
+		i--
+		val /= 10
+	}
+	buf[i] = byte(val + '0')
+	return string(buf[i:])
+}
+
+//go:linkname runtime_fastrand runtime.fastrand
+func runtime_fastrand() uint32
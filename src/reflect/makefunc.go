@@ -1,5 +1,80 @@
 package reflect
 
+import (
+	"unsafe"
+)
+
+// makeFuncImpl is the context of a func value created by MakeFunc.
+type makeFuncImpl struct {
+	typ rawType
+	fn  func(args []Value) (results []Value)
+}
+
+// MakeFunc returns a new function of the given Type that wraps the function
+// fn. When called, that new function does the following:
+//
+//	- converts its arguments to a slice of Values.
+//	- runs results := fn(args).
+//	- returns the results as a slice of Values, one per formal result.
+//
+// The Value.Call method allows the caller to invoke a typed function in terms
+// of Values; in contrast, MakeFunc allows the caller to implement a typed
+// function in terms of Values.
 func MakeFunc(typ Type, fn func(args []Value) (results []Value)) Value {
-	panic("unimplemented: reflect.MakeFunc()")
+	t := typ.(rawType)
+	info := t.funcInfo("MakeFunc")
+	code := readTrampolineSidetable(unsafe.Pointer(&makeFuncTrampolinesSidetable), info.trampolineIndex)
+	if code == nil {
+		panic("reflect: MakeFunc of func type that is not available for reflection")
+	}
+	impl := &makeFuncImpl{
+		typ: t,
+		fn:  fn,
+	}
+	return Value{
+		typecode: t,
+		value: unsafe.Pointer(&funcHeader{
+			Context: unsafe.Pointer(impl),
+			Code:    code,
+		}),
+		flags: valueFlagExported,
+	}
+}
+
+// makeFuncStub is called by the MakeFunc trampoline of a func type (see
+// compiler/reflect.go), with the arguments stored in memory. It calls the
+// function passed to MakeFunc, and stores its results in memory.
+func makeFuncStub(context, args, results unsafe.Pointer) {
+	impl := (*makeFuncImpl)(context)
+	info := impl.typ.funcInfo("MakeFunc")
+
+	// Load the arguments. Values that are bigger than a pointer are copied, so
+	// that the called function may keep them.
+	in := make([]Value, info.numIn)
+	offset := uintptr(0)
+	for i := range in {
+		t := info.typeAt(i)
+		offset = align(offset, uintptr(t.Align()))
+		ptr := unsafe.Pointer(uintptr(args) + offset)
+		if size := t.Size(); size > unsafe.Sizeof(uintptr(0)) {
+			buf := alloc(size, nil)
+			memcpy(buf, ptr, size)
+			ptr = buf
+		}
+		in[i] = loadValueAt(t, ptr, valueFlagExported)
+		offset += t.Size()
+	}
+
+	// Call the function and store the results.
+	out := impl.fn(in)
+	if len(out) != info.numOut {
+		panic("reflect: wrong return count from function created by MakeFunc")
+	}
+	offset = 0
+	for i, result := range out {
+		t := info.typeAt(info.numIn + i)
+		offset = align(offset, uintptr(t.Align()))
+		memcpy(unsafe.Pointer(uintptr(results)+offset), result.assignTo(t), t.Size())
+		offset += t.Size()
+	}
 }
@@ -0,0 +1,275 @@
+package reflect
+
+// This file implements map support for the reflect package, on top of the
+// hashmap implementation in the runtime (see src/runtime/hashmap.go).
+
+import (
+	"unsafe"
+)
+
+//go:linkname mapmake runtime.hashmapMakeUnsafePointer
+func mapmake(keySize, valueSize uint8, sizeHint uintptr) unsafe.Pointer
+
+//go:linkname mapnext runtime.hashmapNextUnsafePointer
+func mapnext(m unsafe.Pointer, it unsafe.Pointer, key, value unsafe.Pointer) bool
+
+//go:linkname mapbinaryset runtime.hashmapBinarySetUnsafePointer
+func mapbinaryset(m unsafe.Pointer, key, value unsafe.Pointer)
+
+//go:linkname mapbinaryget runtime.hashmapBinaryGetUnsafePointer
+func mapbinaryget(m unsafe.Pointer, key, value unsafe.Pointer, valueSize uintptr) bool
+
+//go:linkname mapbinarydelete runtime.hashmapBinaryDeleteUnsafePointer
+func mapbinarydelete(m unsafe.Pointer, key unsafe.Pointer)
+
+//go:linkname mapstringset runtime.hashmapStringSetUnsafePointer
+func mapstringset(m unsafe.Pointer, key string, value unsafe.Pointer)
+
+//go:linkname mapstringget runtime.hashmapStringGetUnsafePointer
+func mapstringget(m unsafe.Pointer, key string, value unsafe.Pointer, valueSize uintptr) bool
+
+//go:linkname mapstringdelete runtime.hashmapStringDeleteUnsafePointer
+func mapstringdelete(m unsafe.Pointer, key string)
+
+//go:linkname mapinterfaceset runtime.hashmapInterfaceSetUnsafePointer
+func mapinterfaceset(m unsafe.Pointer, key interface{}, value unsafe.Pointer)
+
+//go:linkname mapinterfaceget runtime.hashmapInterfaceGetUnsafePointer
+func mapinterfaceget(m unsafe.Pointer, key interface{}, value unsafe.Pointer, valueSize uintptr) bool
+
+//go:linkname mapinterfacedelete runtime.hashmapInterfaceDeleteUnsafePointer
+func mapinterfacedelete(m unsafe.Pointer, key interface{})
+
+// hashmapIterator has the same layout as runtime.hashmapIterator.
+type hashmapIterator struct {
+	buckets      unsafe.Pointer
+	numBuckets   uintptr
+	bucketNumber uintptr
+	bucket       unsafe.Pointer
+	bucketIndex  uint8
+}
+
+// The runtime hashmap stores keys in one of three ways, depending on the key
+// type. This must be kept in sync with the compiler, see createMakeMap in
+// compiler/map.go.
+const (
+	mapKeyBinary    = iota // plain bytes, compared with memequal
+	mapKeyString           // string keys
+	mapKeyInterface        // all other keys, stored as an interface{}
+)
+
+// mapKeyMode returns how keys of type t are stored in a runtime hashmap.
+func (t rawType) mapKeyMode() int {
+	if t.Kind() == String {
+		return mapKeyString
+	}
+	if isBinaryMapKey(t) {
+		return mapKeyBinary
+	}
+	return mapKeyInterface
+}
+
+// isBinaryMapKey returns true if this key type does not contain strings,
+// interfaces etc., so can be hashed and compared as plain bytes. It is the
+// equivalent of hashmapIsBinaryKey in the compiler.
+func isBinaryMapKey(t rawType) bool {
+	switch t.Kind() {
+	case Bool, Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr:
+		return true
+	case Ptr:
+		return true
+	case Struct:
+		numField := t.NumField()
+		for i := 0; i < numField; i++ {
+			if !isBinaryMapKey(t.rawField(i).Type) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		return isBinaryMapKey(t.elem())
+	default:
+		return false
+	}
+}
+
+// mapKeySize returns the size of a key of type t as stored in a runtime
+// hashmap.
+func (t rawType) mapKeySize() uintptr {
+	if t.mapKeyMode() == mapKeyInterface {
+		return unsafe.Sizeof(interface{}(nil))
+	}
+	return t.Size()
+}
+
+// mapInterfaceKey returns the given key as an interface value, for use in
+// maps that store their keys as an interface{}.
+func (v Value) mapInterfaceKey(keyType rawType) interface{} {
+	if keyType.Kind() == Interface {
+		return *(*interface{})(v.assignTo(keyType))
+	}
+	if v.typecode != keyType {
+		panic("reflect: value of wrong type")
+	}
+	return valueInterfaceUnsafe(v)
+}
+
+// MakeMap creates a new map with the specified type.
+func MakeMap(typ Type) Value {
+	t := typ.(rawType)
+	if t.Kind() != Map {
+		panic(&TypeError{"MakeMap"})
+	}
+	keySize := t.key().mapKeySize()
+	valueSize := t.elem().Size()
+	if keySize > 255 || valueSize > 255 {
+		panic("reflect.MakeMap: key or element type too big")
+	}
+	return Value{
+		typecode: t,
+		value:    mapmake(uint8(keySize), uint8(valueSize), 8),
+		flags:    valueFlagExported,
+	}
+}
+
+// MapIndex returns the value associated with key in the map v. It returns the
+// zero Value if key is not found in the map or if v represents a nil map.
+func (v Value) MapIndex(key Value) Value {
+	if v.Kind() != Map {
+		panic(&ValueError{"MapIndex"})
+	}
+	m := v.pointer()
+	if m == nil {
+		return Value{}
+	}
+	keyType := v.typecode.key()
+	elemType := v.typecode.elem()
+	elemSize := elemType.Size()
+	elem := alloc(elemSize, nil)
+	var ok bool
+	switch keyType.mapKeyMode() {
+	case mapKeyString:
+		ok = mapstringget(m, *(*string)(key.assignTo(keyType)), elem, elemSize)
+	case mapKeyBinary:
+		ok = mapbinaryget(m, key.assignTo(keyType), elem, elemSize)
+	default:
+		ok = mapinterfaceget(m, key.mapInterfaceKey(keyType), elem, elemSize)
+	}
+	if !ok {
+		return Value{}
+	}
+	return loadValueAt(elemType, elem, v.flags&valueFlagExported)
+}
+
+// SetMapIndex sets the element associated with key in the map v to elem. If
+// elem is the zero Value, SetMapIndex deletes the key from the map.
+func (v Value) SetMapIndex(key, elem Value) {
+	if v.Kind() != Map {
+		panic(&ValueError{"SetMapIndex"})
+	}
+	if !v.isExported() || !key.isExported() || (elem.IsValid() && !elem.isExported()) {
+		panic("reflect.Value.SetMapIndex: unexported")
+	}
+	m := v.pointer()
+	keyType := v.typecode.key()
+	if !elem.IsValid() {
+		// Deleting from a nil map is a no-op.
+		if m == nil {
+			return
+		}
+		switch keyType.mapKeyMode() {
+		case mapKeyString:
+			mapstringdelete(m, *(*string)(key.assignTo(keyType)))
+		case mapKeyBinary:
+			mapbinarydelete(m, key.assignTo(keyType))
+		default:
+			mapinterfacedelete(m, key.mapInterfaceKey(keyType))
+		}
+		return
+	}
+	value := elem.assignTo(v.typecode.elem())
+	switch keyType.mapKeyMode() {
+	case mapKeyString:
+		mapstringset(m, *(*string)(key.assignTo(keyType)), value)
+	case mapKeyBinary:
+		mapbinaryset(m, key.assignTo(keyType), value)
+	default:
+		mapinterfaceset(m, key.mapInterfaceKey(keyType), value)
+	}
+}
+
+// MapKeys returns a slice containing all the keys present in the map, in
+// unspecified order.
+func (v Value) MapKeys() []Value {
+	if v.Kind() != Map {
+		panic(&ValueError{"MapKeys"})
+	}
+	keys := make([]Value, 0, v.Len())
+	it := v.MapRange()
+	for it.Next() {
+		keys = append(keys, it.key)
+	}
+	return keys
+}
+
+// MapRange returns a range iterator for a map.
+func (v Value) MapRange() *MapIter {
+	if v.Kind() != Map {
+		panic(&ValueError{"MapRange"})
+	}
+	return &MapIter{
+		m: v,
+	}
+}
+
+// A MapIter is an iterator for ranging over a map. See Value.MapRange.
+type MapIter struct {
+	m     Value
+	it    hashmapIterator
+	key   Value
+	value Value
+}
+
+// Key returns the key of the iterator's current map entry.
+func (it *MapIter) Key() Value {
+	if !it.key.IsValid() {
+		panic("reflect.MapIter.Key called before Next")
+	}
+	return it.key
+}
+
+// Value returns the value of the iterator's current map entry.
+func (it *MapIter) Value() Value {
+	if !it.key.IsValid() {
+		panic("reflect.MapIter.Value called before Next")
+	}
+	return it.value
+}
+
+// Next advances the map iterator and reports whether there is another entry.
+// It returns false when the iterator is exhausted.
+func (it *MapIter) Next() bool {
+	keyType := it.m.typecode.key()
+	elemType := it.m.typecode.elem()
+	key := alloc(keyType.mapKeySize(), nil)
+	elem := alloc(elemType.Size(), nil)
+	if !mapnext(it.m.pointer(), unsafe.Pointer(&it.it), key, elem) {
+		it.key = Value{}
+		it.value = Value{}
+		return false
+	}
+	flags := it.m.flags & valueFlagExported
+	if keyType.mapKeyMode() == mapKeyInterface && keyType.Kind() != Interface {
+		// The key is stored as an interface{} in the hashmap, so unpack it.
+		typecode, value := decomposeInterface(*(*interface{})(key))
+		it.key = Value{
+			typecode: typecode,
+			value:    value,
+			flags:    flags,
+		}
+	} else {
+		it.key = loadValueAt(keyType, key, flags)
+	}
+	it.value = loadValueAt(elemType, elem, flags)
+	return true
+}
@@ -0,0 +1,232 @@
+package reflect
+
+// This file implements support for listing and calling the exported methods of
+// a type. The compiler stores these methods in sidetables (see getMethodsNum
+// in transform/reflect.go), unless they have been omitted using the
+// -no-reflect-methods flag in which case no type has any methods.
+
+import (
+	"unsafe"
+)
+
+// Method represents a single method.
+type Method struct {
+	// Name is the method name.
+	Name string
+
+	// PkgPath is the package path that qualifies a lower case (unexported)
+	// method name. It is empty for upper case (exported) method names.
+	// Only exported methods are available, so it is always empty.
+	PkgPath string
+
+	Type  Type  // method type
+	Func  Value // func with receiver as first argument
+	Index int   // index for Type.Method
+}
+
+// methodInfo is the information about a single method that is stored in the
+// methods sidetable.
+type methodInfo struct {
+	name      string
+	typ       rawType // method type, without receiver
+	funcType  rawType // func type with the receiver as first parameter
+	funcIndex uintptr // index into the method funcs sidetable
+}
+
+// methodSet returns the number of exported methods of this type and a pointer
+// to the first method in the methods sidetable. For interface types, these are
+// the methods of the interface.
+func (t rawType) methodSet() (int, unsafe.Pointer) {
+	// The method sets sidetable starts with the number of entries, followed by
+	// {type code, methods sidetable offset} pairs sorted by type code.
+	table := unsafe.Pointer(&methodSetsSidetable)
+	wordSize := unsafe.Sizeof(uintptr(0))
+	low, high := uintptr(0), *(*uintptr)(table)
+	for low < high {
+		mid := (low + high) / 2
+		entry := unsafe.Pointer(uintptr(table) + (1+mid*2)*wordSize)
+		typecode := *(*rawType)(entry)
+		if typecode == t {
+			offset := *(*uintptr)(unsafe.Pointer(uintptr(entry) + wordSize))
+			n, p := readVarint(unsafe.Pointer(uintptr(unsafe.Pointer(&methodsSidetable)) + offset))
+			return int(n), p
+		}
+		if typecode < t {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return 0, nil
+}
+
+// readMethod reads a single method from the methods sidetable, and returns it
+// together with a pointer to the next method.
+func readMethod(p unsafe.Pointer) (methodInfo, unsafe.Pointer) {
+	var info methodInfo
+	var n uintptr
+	n, p = readVarint(p)
+	info.name = readStringSidetable(unsafe.Pointer(&structNamesSidetable), n)
+	n, p = readVarint(p)
+	info.typ = rawType(n)
+	n, p = readVarint(p)
+	info.funcType = rawType(n)
+	info.funcIndex, p = readVarint(p)
+	return info, p
+}
+
+// method returns the i'th exported method of this type. It panics if i is out
+// of range.
+func (t rawType) method(i int) methodInfo {
+	n, p := t.methodSet()
+	if uint(i) >= uint(n) {
+		panic("reflect: Method index out of range")
+	}
+	var info methodInfo
+	for ; i >= 0; i-- {
+		info, p = readMethod(p)
+	}
+	return info
+}
+
+// methodByName returns the exported method of this type with the given name
+// and its index, or false if there is no such method.
+func (t rawType) methodByName(name string) (methodInfo, int, bool) {
+	n, p := t.methodSet()
+	for i := 0; i < n; i++ {
+		var info methodInfo
+		info, p = readMethod(p)
+		if info.name == name {
+			return info, i, true
+		}
+	}
+	return methodInfo{}, 0, false
+}
+
+// funcValue returns the function implementing this method, with the receiver
+// as first parameter.
+func (info methodInfo) funcValue() Value {
+	code := readTrampolineSidetable(unsafe.Pointer(&methodFuncsSidetable), info.funcIndex)
+	return Value{
+		typecode: info.funcType,
+		value: unsafe.Pointer(&funcHeader{
+			Code: code,
+		}),
+		flags: valueFlagExported,
+	}
+}
+
+// NumMethod returns the number of exported methods in the type's method set.
+func (t rawType) NumMethod() int {
+	n, _ := t.methodSet()
+	return n
+}
+
+// Method returns the i'th exported method in the type's method set, sorted by
+// name. It panics if i is not in the range [0, NumMethod()).
+//
+// For an interface type, the returned Method's Type field gives the method
+// signature, without a receiver, and the Func field is the zero Value.
+func (t rawType) Method(i int) Method {
+	return t.makeMethod(t.method(i), i)
+}
+
+// MethodByName returns the exported method with the given name in the type's
+// method set, and a boolean indicating whether the method was found.
+func (t rawType) MethodByName(name string) (Method, bool) {
+	info, i, ok := t.methodByName(name)
+	if !ok {
+		return Method{}, false
+	}
+	return t.makeMethod(info, i), true
+}
+
+// makeMethod returns the Method for the given method of this type.
+func (t rawType) makeMethod(info methodInfo, i int) Method {
+	if t.Kind() == Interface {
+		// Methods of an interface type have no implementation.
+		return Method{
+			Name:  info.name,
+			Type:  info.typ,
+			Index: i,
+		}
+	}
+	return Method{
+		Name:  info.name,
+		Type:  info.funcType,
+		Func:  info.funcValue(),
+		Index: i,
+	}
+}
+
+// NumMethod returns the number of exported methods in the value's method set.
+func (v Value) NumMethod() int {
+	if v.typecode == 0 {
+		panic(&ValueError{"NumMethod"})
+	}
+	return v.typecode.NumMethod()
+}
+
+// Method returns a function value corresponding to v's i'th method. The
+// arguments to a Call on the returned function should not include a receiver;
+// the returned function will always use v as the receiver. Method panics if i
+// is out of range.
+func (v Value) Method(i int) Value {
+	if v.typecode == 0 {
+		panic(&ValueError{"Method"})
+	}
+	info := v.typecode.method(i)
+	if v.Kind() == Interface {
+		return v.interfaceMethod(info.name)
+	}
+	return v.bindMethod(info)
+}
+
+// MethodByName returns a function value corresponding to the method of v with
+// the given name. It returns the zero Value if no method was found.
+func (v Value) MethodByName(name string) Value {
+	if v.typecode == 0 {
+		panic(&ValueError{"MethodByName"})
+	}
+	info, _, ok := v.typecode.methodByName(name)
+	if !ok {
+		return Value{}
+	}
+	if v.Kind() == Interface {
+		return v.interfaceMethod(info.name)
+	}
+	return v.bindMethod(info)
+}
+
+// interfaceMethod returns the method with the given name of the value stored
+// in the interface v, bound to that value.
+func (v Value) interfaceMethod(name string) Value {
+	if v.IsNil() {
+		panic("reflect: Method on nil interface value")
+	}
+	elem := v.Elem()
+	info, _, ok := elem.typecode.methodByName(name)
+	if !ok {
+		// The dynamic type implements the interface, so this can only happen
+		// when its methods have been removed.
+		panic("reflect: method " + name + " not found")
+	}
+	return elem.bindMethod(info)
+}
+
+// bindMethod returns a func value (of the method type) that calls the given
+// method with v as the receiver.
+func (v Value) bindMethod(info methodInfo) Value {
+	fn := info.funcValue()
+	variadic := info.typ.IsVariadic()
+	bound := MakeFunc(info.typ, func(args []Value) []Value {
+		in := append([]Value{v}, args...)
+		if variadic {
+			return fn.CallSlice(in)
+		}
+		return fn.Call(in)
+	})
+	// Methods of values obtained using unexported fields cannot be called.
+	bound.flags &= v.flags | ^valueFlagExported
+	return bound
+}
@@ -19,6 +19,32 @@ var structNamesSidetable byte
 //go:extern reflect.arrayTypesSidetable
 var arrayTypesSidetable byte
 
+//go:extern reflect.mapTypesSidetable
+var mapTypesSidetable byte
+
+//go:extern reflect.funcTypesSidetable
+var funcTypesSidetable byte
+
+// The trampolines used by Value.Call and MakeFunc, one for each func type. They
+// are indexed by the trampoline index stored in the func types sidetable. See
+// compiler/reflect.go for details.
+//go:extern reflect.funcCallTrampolinesSidetable
+var funcCallTrampolinesSidetable uintptr
+
+//go:extern reflect.makeFuncTrampolinesSidetable
+var makeFuncTrampolinesSidetable uintptr
+
+// The exported methods of types, see getMethodsNum in transform/reflect.go for
+// the format of these sidetables.
+//go:extern reflect.methodSetsSidetable
+var methodSetsSidetable uintptr
+
+//go:extern reflect.methodsSidetable
+var methodsSidetable byte
+
+//go:extern reflect.methodFuncsSidetable
+var methodFuncsSidetable uintptr
+
 // readStringSidetable reads a string from the given table (like
 // structNamesSidetable) and returns this string. No heap allocation is
 // necessary because it makes the string point directly to the raw bytes of the
@@ -31,6 +57,13 @@ func readStringSidetable(table unsafe.Pointer, index uintptr) string {
 	}))
 }
 
+// readTrampolineSidetable returns the function pointer (or function ID) stored
+// at the given index in a trampoline sidetable, for use as the Code field of a
+// func value.
+func readTrampolineSidetable(table unsafe.Pointer, index uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(uintptr(table) + index*unsafe.Sizeof(uintptr(0))))
+}
+
 // readVarint decodes a varint as used in the encoding/binary package.
 // It has an input pointer and returns the read varint and the pointer
 // incremented to the next field in the data structure, just after the varint.
@@ -163,7 +163,7 @@ type Type interface {
 	//
 	// Only exported methods are accessible and they are sorted in
 	// lexicographic order.
-	//Method(int) Method
+	Method(int) Method
 
 	// MethodByName returns the method with that name in the type's
 	// method set and a boolean indicating if the method was found.
@@ -173,7 +173,7 @@ type Type interface {
 	//
 	// For an interface type, the returned Method's Type field gives the
 	// method signature, without a receiver, and the Func field is nil.
-	//MethodByName(string) (Method, bool)
+	MethodByName(string) (Method, bool)
 
 	// NumMethod returns the number of exported methods in the type's method set.
 	NumMethod() int
@@ -248,7 +248,7 @@ type Type interface {
 	//	t.IsVariadic() == true
 	//
 	// IsVariadic panics if the type's Kind is not Func.
-	//IsVariadic() bool
+	IsVariadic() bool
 
 	// Elem returns a type's element type.
 	// It panics if the type's Kind is not Array, Chan, Map, Ptr, or Slice.
@@ -286,7 +286,7 @@ type Type interface {
 	// In returns the type of a function type's i'th input parameter.
 	// It panics if the type's Kind is not Func.
 	// It panics if i is not in the range [0, NumIn()).
-	//In(i int) Type
+	In(i int) Type
 
 	// Key returns a map type's key type.
 	// It panics if the type's Kind is not Map.
@@ -302,16 +302,16 @@ type Type interface {
 
 	// NumIn returns a function type's input parameter count.
 	// It panics if the type's Kind is not Func.
-	//NumIn() int
+	NumIn() int
 
 	// NumOut returns a function type's output parameter count.
 	// It panics if the type's Kind is not Func.
-	//NumOut() int
+	NumOut() int
 
 	// Out returns the type of a function type's i'th output parameter.
 	// It panics if the type's Kind is not Func.
 	// It panics if i is not in the range [0, NumOut()).
-	//Out(i int) Type
+	Out(i int) Type
 }
 
 // The typecode as used in an interface{}.
@@ -345,8 +345,8 @@ func (t rawType) Kind() Kind {
 	}
 }
 
-// Elem returns the element type for channel, slice and array types, the
-// pointed-to value for pointer types, and the key type for map types.
+// Elem returns the element type for channel, slice, array and map types, and
+// the pointed-to value for pointer types.
 func (t rawType) Elem() Type {
 	return t.elem()
 }
@@ -359,11 +359,24 @@ func (t rawType) elem() rawType {
 		index := t.stripPrefix()
 		elem, _ := readVarint(unsafe.Pointer(uintptr(unsafe.Pointer(&arrayTypesSidetable)) + uintptr(index)))
 		return rawType(elem)
-	default: // not implemented: Map
-		panic("unimplemented: (reflect.Type).Elem()")
+	case Map:
+		_, p := t.mapKeyElem()
+		elem, _ := readVarint(p)
+		return rawType(elem)
+	default:
+		panic(&TypeError{"Elem"})
 	}
 }
 
+// mapKeyElem reads the key type of a map type from the map types sidetable. It
+// also returns a pointer to the element type, which directly follows the key
+// type in the sidetable.
+func (t rawType) mapKeyElem() (rawType, unsafe.Pointer) {
+	index := t.stripPrefix()
+	key, p := readVarint(unsafe.Pointer(uintptr(unsafe.Pointer(&mapTypesSidetable)) + uintptr(index)))
+	return rawType(key), p
+}
+
 // stripPrefix removes the "prefix" (the first 5 bytes of the type code) from
 // the type code. If this is a named type, it will resolve the underlying type
 // (which is the data for this named type). If it is not, the lower bits are
@@ -554,7 +567,7 @@ func (t rawType) Size() uintptr {
 			return 0
 		}
 		lastField := t.rawField(numField - 1)
-		return lastField.Offset + lastField.Type.Size()
+		return align(lastField.Offset+lastField.Type.Size(), uintptr(t.Align()))
 	default:
 		panic("unimplemented: size of type")
 	}
@@ -621,20 +634,55 @@ func (t rawType) FieldAlign() int {
 // AssignableTo returns whether a value of type t can be assigned to a variable
 // of type u.
 func (t rawType) AssignableTo(u Type) bool {
-	if t == u.(rawType) {
+	return t.assignableTo(u.(rawType))
+}
+
+// assignableTo returns whether a value of type t can be assigned to a variable
+// of type u: the types are identical, u is an interface type implemented by t,
+// or they have the same underlying type and at least one of them is not a
+// named type.
+func (t rawType) assignableTo(u rawType) bool {
+	if t == u {
 		return true
 	}
 	if u.Kind() == Interface {
-		panic("reflect: unimplemented: AssignableTo with interface")
+		return t.implements(u)
+	}
+	if t%2 == 0 || u%2 == 0 {
+		// Basic types are always named.
+		return false
+	}
+	if (t>>4)%2 != 0 && (u>>4)%2 != 0 {
+		// Both are named types.
+		return false
 	}
-	return false
+	// Compare the underlying types: the type kind in the lower bits and the
+	// contents of the type (see stripPrefix).
+	return t%16 == u%16 && t.stripPrefix() == u.stripPrefix()
 }
 
+// Implements returns whether the type t implements the interface type u.
 func (t rawType) Implements(u Type) bool {
 	if u.Kind() != Interface {
 		panic("reflect: non-interface type passed to Type.Implements")
 	}
-	return t.AssignableTo(u)
+	return t.implements(u.(rawType))
+}
+
+// implements returns whether t has all the methods of the interface type u.
+// Only exported methods are known to reflect, so unexported methods are not
+// checked.
+func (t rawType) implements(u rawType) bool {
+	n, p := u.methodSet()
+	for i := 0; i < n; i++ {
+		var method methodInfo
+		method, p = readMethod(p)
+		info, _, ok := t.methodByName(method.name)
+		if !ok || info.typ != method.typ {
+			return false
+		}
+	}
+	return true
 }
 
 // Comparable returns whether values of this type can be compared to each other.
@@ -679,16 +727,101 @@ func (t rawType) ConvertibleTo(u Type) bool {
 	panic("unimplemented: (reflect.Type).ConvertibleTo()")
 }
 
-func (t rawType) NumMethod() int {
-	panic("unimplemented: (reflect.Type).NumMethod()")
-}
-
 func (t rawType) Name() string {
 	panic("unimplemented: (reflect.Type).Name()")
 }
 
+// Key returns the key type of a map type. It panics if t is not a map type.
 func (t rawType) Key() Type {
-	panic("unimplemented: (reflect.Type).Key()")
+	return t.key()
+}
+
+func (t rawType) key() rawType {
+	if t.Kind() != Map {
+		panic(&TypeError{"Key"})
+	}
+	key, _ := t.mapKeyElem()
+	return key
+}
+
+// funcTypeInfo is the information about a func type that is stored in the
+// func types sidetable.
+type funcTypeInfo struct {
+	trampolineIndex uintptr // index into the trampoline sidetables
+	numIn           int
+	numOut          int
+	variadic        bool
+	types           unsafe.Pointer // varints of the param types, followed by the result types
+}
+
+// funcInfo reads the information about this func type from the func types
+// sidetable. It panics with a TypeError for the given method if t is not a
+// func type.
+func (t rawType) funcInfo(method string) funcTypeInfo {
+	if t.Kind() != Func {
+		panic(&TypeError{method})
+	}
+	funcIdentifier := t.stripPrefix()
+	p := unsafe.Pointer(uintptr(unsafe.Pointer(&funcTypesSidetable)) + uintptr(funcIdentifier))
+	var info funcTypeInfo
+	var n uintptr
+	info.trampolineIndex, p = readVarint(p)
+	n, p = readVarint(p)
+	info.numIn = int(n >> 1)
+	info.variadic = n&1 != 0
+	n, p = readVarint(p)
+	info.numOut = int(n)
+	info.types = p
+	return info
+}
+
+// typeAt returns the i'th type of the param types followed by the result
+// types.
+func (info funcTypeInfo) typeAt(i int) rawType {
+	p := info.types
+	var t uintptr
+	for ; i >= 0; i-- {
+		t, p = readVarint(p)
+	}
+	return rawType(t)
+}
+
+// IsVariadic returns whether the final input parameter of this func type is a
+// "..." parameter. It panics if t is not a func type.
+func (t rawType) IsVariadic() bool {
+	return t.funcInfo("IsVariadic").variadic
+}
+
+// NumIn returns the number of input parameters of a func type. It panics if t
+// is not a func type.
+func (t rawType) NumIn() int {
+	return t.funcInfo("NumIn").numIn
+}
+
+// NumOut returns the number of output parameters of a func type. It panics if
+// t is not a func type.
+func (t rawType) NumOut() int {
+	return t.funcInfo("NumOut").numOut
+}
+
+// In returns the type of the i'th input parameter of a func type. It panics if
+// t is not a func type or i is out of range.
+func (t rawType) In(i int) Type {
+	info := t.funcInfo("In")
+	if uint(i) >= uint(info.numIn) {
+		panic("reflect: Function index out of range")
+	}
+	return info.typeAt(i)
+}
+
+// Out returns the type of the i'th output parameter of a func type. It panics
+// if t is not a func type or i is out of range.
+func (t rawType) Out(i int) Type {
+	info := t.funcInfo("Out")
+	if uint(i) >= uint(info.numOut) {
+		panic("reflect: Function index out of range")
+	}
+	return info.typeAt(info.numIn + i)
 }
 
 // A StructField describes a single field in a struct.
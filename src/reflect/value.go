@@ -120,8 +120,8 @@ func (v Value) IsNil() bool {
 		if v.value == nil {
 			return true
 		}
-		_, val := decomposeInterface(*(*interface{})(v.value))
-		return val == nil
+		typecode, _ := decomposeInterface(*(*interface{})(v.value))
+		return typecode == 0
 	default:
 		panic(&ValueError{"IsNil"})
 	}
@@ -146,6 +146,43 @@ func (v Value) Pointer() uintptr {
 	}
 }
 
+// pointer returns the underlying pointer of a chan, map, pointer or
+// unsafe.Pointer value.
+func (v Value) pointer() unsafe.Pointer {
+	if v.isIndirect() {
+		return *(*unsafe.Pointer)(v.value)
+	}
+	return v.value
+}
+
+// valuePointer returns a pointer to the underlying value. If the value is
+// stored directly in the Value, it is first copied to a new allocation.
+func (v Value) valuePointer() unsafe.Pointer {
+	size := v.typecode.Size()
+	if v.isIndirect() || size > unsafe.Sizeof(uintptr(0)) {
+		return v.value
+	}
+	ptr := alloc(unsafe.Sizeof(uintptr(0)), nil)
+	*(*unsafe.Pointer)(ptr) = v.value
+	return ptr
+}
+
+// assignTo returns a pointer to the value of v, converted to type t if t is an
+// interface type. It panics if v cannot be assigned to t.
+func (v Value) assignTo(t rawType) unsafe.Pointer {
+	if !v.typecode.assignableTo(t) {
+		panic("reflect: value of wrong type")
+	}
+	if t.Kind() == Interface {
+		if v.Kind() == Interface {
+			return v.value
+		}
+		itf := valueInterfaceUnsafe(v)
+		return unsafe.Pointer(&itf)
+	}
+	return v.valuePointer()
+}
+
 func (v Value) IsValid() bool {
 	return v.typecode != 0
 }
@@ -343,7 +380,7 @@ func (v Value) Len() int {
 	case Chan:
 		return chanlen(v.value)
 	case Map:
-		return maplen(v.value)
+		return maplen(v.pointer())
 	case Slice:
 		return int((*sliceHeader)(v.value).len)
 	case String:
@@ -392,7 +429,16 @@ func (v Value) Elem() Value {
 			value:    ptr,
 			flags:    v.flags | valueFlagIndirect,
 		}
-	default: // not implemented: Interface
+	case Interface:
+		// Return the value stored in the interface, the same way it would be
+		// returned by ValueOf.
+		typecode, value := decomposeInterface(*(*interface{})(v.value))
+		return Value{
+			typecode: typecode,
+			value:    value,
+			flags:    v.flags &^ valueFlagIndirect,
+		}
+	default:
 		panic(&ValueError{"Elem"})
 	}
 }
@@ -549,52 +595,45 @@ func loadValue(ptr unsafe.Pointer, size uintptr) uintptr {
 	return loadedValue
 }
 
+// loadValueAt returns a Value for a value of type t that was copied to the
+// buffer at ptr (for example, a map element or a function result). Values that
+// fit in a pointer are stored directly in the Value, like they would be in an
+// interface.
+func loadValueAt(t rawType, ptr unsafe.Pointer, flags valueFlags) Value {
+	size := t.Size()
+	if size > unsafe.Sizeof(uintptr(0)) {
+		return Value{
+			typecode: t,
+			value:    ptr,
+			flags:    flags,
+		}
+	}
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(loadValue(ptr, size)),
+		flags:    flags,
+	}
+}
+
 // maskAndShift cuts out a part of a uintptr. Note that the offset may not be 0.
 func maskAndShift(value, offset, size uintptr) uintptr {
 	mask := ^uintptr(0) >> ((unsafe.Sizeof(uintptr(0)) - size) * 8)
 	return (uintptr(value) >> (offset * 8)) & mask
 }
 
-func (v Value) NumMethod() int {
-	return v.typecode.NumMethod()
-}
-
 func (v Value) OverflowFloat(x float64) bool {
 	panic("unimplemented: (reflect.Value).OverflowFloat()")
 }
 
-func (v Value) MapKeys() []Value {
-	panic("unimplemented: (reflect.Value).MapKeys()")
-}
-
-func (v Value) MapIndex(key Value) Value {
-	panic("unimplemented: (reflect.Value).MapIndex()")
-}
-
-func (v Value) MapRange() *MapIter {
-	panic("unimplemented: (reflect.Value).MapRange()")
-}
-
-type MapIter struct {
-}
-
-func (it *MapIter) Key() Value {
-	panic("unimplemented: (*reflect.MapIter).Key()")
-}
-
-func (it *MapIter) Value() Value {
-	panic("unimplemented: (*reflect.MapIter).Value()")
-}
-
-func (it *MapIter) Next() bool {
-	panic("unimplemented: (*reflect.MapIter).Next()")
-}
-
 func (v Value) Set(x Value) {
 	v.checkAddressable()
-	if !v.typecode.AssignableTo(x.typecode) {
+	if !x.typecode.assignableTo(v.typecode) {
 		panic("reflect: cannot set")
 	}
+	if v.Kind() == Interface && x.Kind() != Interface {
+		*(*interface{})(v.value) = valueInterfaceUnsafe(x)
+		return
+	}
 	size := v.typecode.Size()
 	xptr := x.value
 	if size <= unsafe.Sizeof(uintptr(0)) && !x.isIndirect() {
@@ -729,7 +768,7 @@ func Zero(typ Type) Value {
 func New(typ Type) Value {
 	return Value{
 		typecode: PtrTo(typ).(rawType),
-		value:    alloc(typ.Size()),
+		value:    alloc(typ.Size(), nil),
 		flags:    valueFlagExported,
 	}
 }
@@ -778,10 +817,10 @@ func (e *ValueError) Error() string {
 func memcpy(dst, src unsafe.Pointer, size uintptr)
 
 //go:linkname alloc runtime.alloc
-func alloc(size uintptr) unsafe.Pointer
+func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
 
 //go:linkname sliceAppend runtime.sliceAppend
-func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen uintptr, elemSize uintptr) (unsafe.Pointer, uintptr, uintptr)
+func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen uintptr, elemSize uintptr, layout unsafe.Pointer) (unsafe.Pointer, uintptr, uintptr)
 
 // Copy copies the contents of src into dst until either
 // dst has been filled or src has been exhausted.
@@ -810,7 +849,7 @@ func AppendSlice(s, t Value) Value {
 	sSlice := (*sliceHeader)(s.value)
 	tSlice := (*sliceHeader)(t.value)
 	elemSize := s.typecode.elem().Size()
-	ptr, len, cap := sliceAppend(sSlice.data, tSlice.data, sSlice.len, sSlice.cap, tSlice.len, elemSize)
+	ptr, len, cap := sliceAppend(sSlice.data, tSlice.data, sSlice.len, sSlice.cap, tSlice.len, elemSize, nil)
 	result := &sliceHeader{
 		data: ptr,
 		len:  len,
@@ -823,10 +862,6 @@ func AppendSlice(s, t Value) Value {
 	}
 }
 
-func (v Value) SetMapIndex(key, elem Value) {
-	panic("unimplemented: (reflect.Value).SetMapIndex()")
-}
-
 // FieldByIndex returns the nested field corresponding to index.
 func (v Value) FieldByIndex(index []int) Value {
 	panic("unimplemented: (reflect.Value).FieldByIndex()")
@@ -836,11 +871,115 @@ func (v Value) FieldByName(name string) Value {
 	panic("unimplemented: (reflect.Value).FieldByName()")
 }
 
-// MakeMap creates a new map with the specified type.
-func MakeMap(typ Type) Value {
-	panic("unimplemented: reflect.MakeMap()")
+// Call calls the function v with the input arguments in. For example, if
+// len(in) == 3, v.Call(in) represents the Go call v(in[0], in[1], in[2]). Call
+// panics if v's Kind is not Func. It returns the output results as Values. As
+// in Go, each input argument must be assignable to the type of the function's
+// corresponding input parameter. If v is a variadic function, Call creates the
+// variadic slice parameter itself, copying in the corresponding values.
+func (v Value) Call(in []Value) []Value {
+	return v.call("Call", in)
 }
 
-func (v Value) Call(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).Call()")
+// CallSlice calls the variadic function v with the input arguments in,
+// assigning the slice in[len(in)-1] to v's final variadic argument. For
+// example, if len(in) == 3, v.CallSlice(in) represents the Go call
+// v(in[0], in[1], in[2]...). CallSlice panics if v's Kind is not Func or if v
+// is not variadic.
+func (v Value) CallSlice(in []Value) []Value {
+	return v.call("CallSlice", in)
+}
+
+func (v Value) call(op string, in []Value) []Value {
+	if v.Kind() != Func {
+		panic(&ValueError{op})
+	}
+	if !v.isExported() {
+		panic("reflect: " + op + " using value obtained using unexported field")
+	}
+	fn := (*funcHeader)(v.value)
+	if fn.Code == nil {
+		panic("reflect: call of nil function")
+	}
+
+	// Check the number of arguments, and put variadic arguments in a slice.
+	info := v.typecode.funcInfo(op)
+	if op == "CallSlice" {
+		if !info.variadic {
+			panic("reflect: CallSlice of non-variadic function")
+		}
+		if len(in) != info.numIn {
+			panic("reflect: CallSlice with wrong argument count")
+		}
+	} else if info.variadic {
+		if len(in) < info.numIn-1 {
+			panic("reflect: Call with too few input arguments")
+		}
+		variadic := makeVariadicSlice(info.typeAt(info.numIn-1), in[info.numIn-1:])
+		in = append(in[:info.numIn-1:info.numIn-1], variadic)
+	} else if len(in) != info.numIn {
+		panic("reflect: Call with wrong argument count")
+	}
+
+	// The trampoline for this func type knows how to call the function with
+	// the arguments stored in memory.
+	code := readTrampolineSidetable(unsafe.Pointer(&funcCallTrampolinesSidetable), info.trampolineIndex)
+	if code == nil {
+		panic("reflect: " + op + " of func type that is not available for reflection")
+	}
+	trampoline := *(*func(fn, args, results unsafe.Pointer))(unsafe.Pointer(&funcHeader{Code: code}))
+
+	// Store the arguments in memory.
+	args := alloc(info.valuesSize(0, info.numIn), nil)
+	offset := uintptr(0)
+	for i, arg := range in {
+		t := info.typeAt(i)
+		offset = align(offset, uintptr(t.Align()))
+		memcpy(unsafe.Pointer(uintptr(args)+offset), arg.assignTo(t), t.Size())
+		offset += t.Size()
+	}
+
+	// Do the call, and read the results.
+	results := alloc(info.valuesSize(info.numIn, info.numOut), nil)
+	trampoline(v.value, args, results)
+	out := make([]Value, info.numOut)
+	offset = 0
+	for i := range out {
+		t := info.typeAt(info.numIn + i)
+		offset = align(offset, uintptr(t.Align()))
+		out[i] = loadValueAt(t, unsafe.Pointer(uintptr(results)+offset), valueFlagExported)
+		offset += t.Size()
+	}
+	return out
+}
+
+// valuesSize returns the size of the given range of param and result types
+// when stored in memory, for calls through Value.Call and MakeFunc.
+func (info funcTypeInfo) valuesSize(start, n int) uintptr {
+	size := uintptr(0)
+	for i := start; i < start+n; i++ {
+		t := info.typeAt(i)
+		size = align(size, uintptr(t.Align())) + t.Size()
+	}
+	return size
+}
+
+// makeVariadicSlice returns a slice of type t with the given values, for use
+// as a variadic argument.
+func makeVariadicSlice(t rawType, values []Value) Value {
+	elem := t.elem()
+	elemSize := elem.Size()
+	buf := alloc(elemSize*uintptr(len(values)), nil)
+	for i, value := range values {
+		memcpy(unsafe.Pointer(uintptr(buf)+elemSize*uintptr(i)), value.assignTo(elem), elemSize)
+	}
+	return Value{
+		typecode: t,
+		value: unsafe.Pointer(&sliceHeader{
+			data: buf,
+			len:  uintptr(len(values)),
+			cap:  uintptr(len(values)),
+		}),
+		flags: valueFlagExported,
+	}
 }
@@ -0,0 +1,29 @@
+package runtime
+
+// This file implements various core algorithms used in the runtime package and
+// standard library.
+
+// fastrandState is the state of the pseudo-random number generator used by
+// fastrand. It must never be zero.
+var fastrandState uint32 = 0x12345678
+
+// fastrand returns a pseudo-random number, using a 32-bit xorshift generator as
+// described by George Marsaglia in "Xorshift RNGs". It is cheap on all targets
+// (including 8-bit AVR) but is not suitable for anything security related.
+//
+// The state is not protected by a lock. When it is raced on, the worst that
+// can happen is that the same number is returned twice.
+func fastrand() uint32 {
+	x := fastrandState
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	fastrandState = x
+	return x
+}
+
+// fastrandn returns a pseudo-random number in the range [0, n).
+func fastrandn(n uint32) uint32 {
+	// This is Lemire's multiply-shift reduction, which avoids a division.
+	return uint32(uint64(fastrand()) * uint64(n) >> 32)
+}
@@ -28,12 +28,19 @@ func getCurrentStackPointer() uintptr {
 // others are emitted as libcalls. How many are emitted as libcalls depends on
 // the MCU core variant (M3 and higher support some 32-bit atomic operations
 // while M0 and M0+ do not).
+//
+// Disabling interrupts is enough to make these operations atomic on a single
+// core. When goroutines run on multiple cores, atomicsLock is needed as well.
+
+var atomicsLock spinLock
 
 //export __sync_fetch_and_add_4
 func __sync_fetch_and_add_4(ptr *uint32, value uint32) uint32 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	*ptr = oldValue + value
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
@@ -41,8 +48,10 @@ func __sync_fetch_and_add_4(ptr *uint32, value uint32) uint32 {
 //export __sync_fetch_and_add_8
 func __sync_fetch_and_add_8(ptr *uint64, value uint64) uint64 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	*ptr = oldValue + value
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
@@ -50,8 +59,10 @@ func __sync_fetch_and_add_8(ptr *uint64, value uint64) uint64 {
 //export __sync_lock_test_and_set_4
 func __sync_lock_test_and_set_4(ptr *uint32, value uint32) uint32 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	*ptr = value
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
@@ -59,8 +70,10 @@ func __sync_lock_test_and_set_4(ptr *uint32, value uint32) uint32 {
 //export __sync_lock_test_and_set_8
 func __sync_lock_test_and_set_8(ptr *uint64, value uint64) uint64 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	*ptr = value
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
@@ -68,10 +81,12 @@ func __sync_lock_test_and_set_8(ptr *uint64, value uint64) uint64 {
 //export __sync_val_compare_and_swap_4
 func __sync_val_compare_and_swap_4(ptr *uint32, expected, desired uint32) uint32 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	if oldValue == expected {
 		*ptr = desired
 	}
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
@@ -79,26 +94,33 @@ func __sync_val_compare_and_swap_4(ptr *uint32, expected, desired uint32) uint32
 //export __sync_val_compare_and_swap_8
 func __sync_val_compare_and_swap_8(ptr *uint64, expected, desired uint64) uint64 {
 	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
 	oldValue := *ptr
 	if oldValue == expected {
 		*ptr = desired
 	}
+	atomicsLock.Unlock()
 	arm.EnableInterrupts(mask)
 	return oldValue
 }
 
 // The safest thing to do here would just be to disable interrupts for
 // procPin/procUnpin. Note that a global variable is safe in this case, as any
-// access to procPinnedMask will happen with interrupts disabled.
+// access to procPinnedMask will happen with interrupts disabled and with
+// atomicsLock held.
 
 var procPinnedMask uintptr
 
 //go:linkname procPin sync/atomic.runtime_procPin
 func procPin() {
-	procPinnedMask = arm.DisableInterrupts()
+	mask := arm.DisableInterrupts()
+	atomicsLock.Lock()
+	procPinnedMask = mask
 }
 
 //go:linkname procUnpin sync/atomic.runtime_procUnpin
 func procUnpin() {
-	arm.EnableInterrupts(procPinnedMask)
+	mask := procPinnedMask
+	atomicsLock.Unlock()
+	arm.EnableInterrupts(mask)
 }
@@ -38,7 +38,7 @@ func growHeap() bool {
 
 //export malloc
 func libc_malloc(size uintptr) unsafe.Pointer {
-	return alloc(size)
+	return alloc(size, nil)
 }
 
 //export free
@@ -48,6 +48,7 @@ func libc_free(ptr unsafe.Pointer) {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	printMemStats()
 	abort()
 }
 
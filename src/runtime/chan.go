@@ -25,7 +25,6 @@ package runtime
 
 import (
 	"internal/task"
-	"runtime/interrupt"
 	"unsafe"
 )
 
@@ -133,7 +132,7 @@ func chanMake(elementSize uintptr, bufSize uintptr) *channel {
 	return &channel{
 		elementSize: elementSize,
 		bufSize:     bufSize,
-		buf:         alloc(elementSize * bufSize),
+		buf:         alloc(elementSize*bufSize, nil),
 	}
 }
 
@@ -309,17 +308,17 @@ func (ch *channel) trySend(value unsafe.Pointer) bool {
 		return false
 	}
 
-	i := interrupt.Disable()
+	i := task.Lock()
 
 	switch ch.state {
 	case chanStateEmpty, chanStateBuf:
 		// try to dump the value directly into the buffer
 		if ch.push(value) {
 			ch.state = chanStateBuf
-			interrupt.Restore(i)
+			task.Unlock(i)
 			return true
 		}
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return false
 	case chanStateRecv:
 		// unblock reciever
@@ -333,21 +332,21 @@ func (ch *channel) trySend(value unsafe.Pointer) bool {
 			ch.state = chanStateEmpty
 		}
 
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return true
 	case chanStateSend:
 		// something else is already waiting to send
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return false
 	case chanStateClosed:
-		interrupt.Restore(i)
+		task.Unlock(i)
 		runtimePanic("send on closed channel")
 	default:
-		interrupt.Restore(i)
+		task.Unlock(i)
 		runtimePanic("invalid channel state")
 	}
 
-	interrupt.Restore(i)
+	task.Unlock(i)
 	return false
 }
 
@@ -361,7 +360,7 @@ func (ch *channel) tryRecv(value unsafe.Pointer) (bool, bool) {
 		return false, false
 	}
 
-	i := interrupt.Disable()
+	i := task.Lock()
 
 	switch ch.state {
 	case chanStateBuf, chanStateSend:
@@ -385,7 +384,7 @@ func (ch *channel) tryRecv(value unsafe.Pointer) (bool, bool) {
 				ch.state = chanStateEmpty
 			}
 
-			interrupt.Restore(i)
+			task.Unlock(i)
 			return true, true
 		} else if ch.blocked != nil {
 			// unblock next sender if applicable
@@ -399,24 +398,24 @@ func (ch *channel) tryRecv(value unsafe.Pointer) (bool, bool) {
 				ch.state = chanStateEmpty
 			}
 
-			interrupt.Restore(i)
+			task.Unlock(i)
 			return true, true
 		}
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return false, false
 	case chanStateRecv, chanStateEmpty:
 		// something else is already waiting to recieve
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return false, false
 	case chanStateClosed:
 		if ch.pop(value) {
-			interrupt.Restore(i)
+			task.Unlock(i)
 			return true, true
 		}
 
 		// channel closed - nothing to recieve
 		memzero(value, ch.elementSize)
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return true, false
 	default:
 		runtimePanic("invalid channel state")
@@ -426,6 +425,45 @@ func (ch *channel) tryRecv(value unsafe.Pointer) (bool, bool) {
 	return false, false
 }
 
+// canSend returns whether trySend would complete (or panic) right away. The
+// scheduler lock must be held.
+func (ch *channel) canSend() bool {
+	if ch == nil {
+		return false
+	}
+	switch ch.state {
+	case chanStateEmpty, chanStateBuf:
+		// There must be space in the buffer.
+		return ch.bufUsed < ch.bufSize
+	case chanStateRecv:
+		// A receiver is waiting.
+		return true
+	case chanStateClosed:
+		// Sending will panic, which counts as proceeding.
+		return true
+	default:
+		return false
+	}
+}
+
+// canRecv returns whether tryRecv would receive a value right away. The
+// scheduler lock must be held.
+func (ch *channel) canRecv() bool {
+	if ch == nil {
+		return false
+	}
+	switch ch.state {
+	case chanStateBuf, chanStateSend:
+		// There must be a value in the buffer, or a waiting sender.
+		return ch.bufUsed != 0 || ch.blocked != nil
+	case chanStateClosed:
+		// Receiving from a closed channel never blocks.
+		return true
+	default:
+		return false
+	}
+}
+
 type chanState uint8
 
 const (
@@ -465,18 +503,18 @@ type chanSelectState struct {
 // This operation will block unless a value is immediately available.
 // May panic if the channel is closed.
 func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList) {
-	i := interrupt.Disable()
+	i := task.Lock()
 
 	if ch.trySend(value) {
 		// value immediately sent
 		chanDebug(ch)
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return
 	}
 
 	if ch == nil {
 		// A nil channel blocks forever. Do not schedule this goroutine again.
-		interrupt.Restore(i)
+		task.Unlock(i)
 		deadlock()
 	}
 
@@ -490,8 +528,7 @@ func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 	}
 	ch.blocked = blockedlist
 	chanDebug(ch)
-	interrupt.Restore(i)
-	task.Pause()
+	task.PauseLocked(i)
 	sender.Ptr = nil
 }
 
@@ -500,18 +537,18 @@ func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 // The recieved value is copied into the value pointer.
 // Returns the comma-ok value.
 func chanRecv(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList) bool {
-	i := interrupt.Disable()
+	i := task.Lock()
 
 	if rx, ok := ch.tryRecv(value); rx {
 		// value immediately available
 		chanDebug(ch)
-		interrupt.Restore(i)
+		task.Unlock(i)
 		return ok
 	}
 
 	if ch == nil {
 		// A nil channel blocks forever. Do not schedule this goroutine again.
-		interrupt.Restore(i)
+		task.Unlock(i)
 		deadlock()
 	}
 
@@ -525,8 +562,7 @@ func chanRecv(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 	}
 	ch.blocked = blockedlist
 	chanDebug(ch)
-	interrupt.Restore(i)
-	task.Pause()
+	task.PauseLocked(i)
 	ok := receiver.Data == 1
 	receiver.Ptr, receiver.Data = nil, 0
 	return ok
@@ -539,18 +575,18 @@ func chanClose(ch *channel) {
 		// Not allowed by the language spec.
 		runtimePanic("close of nil channel")
 	}
-	i := interrupt.Disable()
+	i := task.Lock()
 	switch ch.state {
 	case chanStateClosed:
 		// Not allowed by the language spec.
-		interrupt.Restore(i)
+		task.Unlock(i)
 		runtimePanic("close of closed channel")
 	case chanStateSend:
 		// This panic should ideally on the sending side, not in this goroutine.
 		// But when a goroutine tries to send while the channel is being closed,
 		// that is clearly invalid: the send should have been completed already
 		// before the close.
-		interrupt.Restore(i)
+		task.Unlock(i)
 		runtimePanic("close channel during send")
 	case chanStateRecv:
 		// unblock all receivers with the zero value
@@ -562,7 +598,7 @@ func chanClose(ch *channel) {
 		// Easy case. No available sender or receiver.
 	}
 	ch.state = chanStateClosed
-	interrupt.Restore(i)
+	task.Unlock(i)
 	chanDebug(ch)
 }
 
@@ -570,14 +606,14 @@ func chanClose(ch *channel) {
 // perhaps the most complicated statement in the Go spec. It returns the
 // selected index and the 'comma-ok' value.
 //
-// TODO: do this in a round-robin fashion (as specified in the Go spec) instead
-// of picking the first one that can proceed.
+// If more than one case can proceed immediately, one is chosen pseudo-randomly
+// (see tryChanSelect). Otherwise the case that is resumed first is selected.
 func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelBlockedList) (uintptr, bool) {
-	istate := interrupt.Disable()
+	istate := task.Lock()
 
 	if selected, ok := tryChanSelect(recvbuf, states); selected != ^uintptr(0) {
 		// one channel was immediately ready
-		interrupt.Restore(istate)
+		task.Unlock(istate)
 		return selected, ok
 	}
 
@@ -605,7 +641,7 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelB
 			case chanStateRecv:
 				// already in correct state
 			default:
-				interrupt.Restore(istate)
+				task.Unlock(istate)
 				runtimePanic("invalid channel state")
 			}
 		} else {
@@ -618,7 +654,7 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelB
 			case chanStateBuf:
 				// already in correct state
 			default:
-				interrupt.Restore(istate)
+				task.Unlock(istate)
 				runtimePanic("invalid channel state")
 			}
 		}
@@ -631,36 +667,54 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelB
 	t.Data = 1
 
 	// wait for one case to fire
-	interrupt.Restore(istate)
-	task.Pause()
+	task.PauseLocked(istate)
 
 	// figure out which one fired and return the ok value
 	return (uintptr(t.Ptr) - uintptr(unsafe.Pointer(&states[0]))) / unsafe.Sizeof(chanSelectState{}), t.Data != 0
 }
 
 // tryChanSelect is like chanSelect, but it does a non-blocking select operation.
+// When more than one case can proceed, one of them is chosen uniformly at
+// random as required by the Go spec.
 func tryChanSelect(recvbuf unsafe.Pointer, states []chanSelectState) (uintptr, bool) {
-	istate := interrupt.Disable()
+	istate := task.Lock()
 
-	// See whether we can receive from one of the channels.
+	// Pick one of the cases that can proceed, using reservoir sampling: the
+	// n-th ready case replaces the previously picked case with probability 1/n.
+	// This avoids allocating a permuted list of cases.
+	selected := -1
+	numReady := uint32(0)
 	for i, state := range states {
+		var ready bool
 		if state.value == nil {
-			// A receive operation.
-			if rx, ok := state.ch.tryRecv(recvbuf); rx {
-				chanDebug(state.ch)
-				interrupt.Restore(istate)
-				return uintptr(i), ok
-			}
+			ready = state.ch.canRecv()
 		} else {
-			// A send operation: state.value is not nil.
-			if state.ch.trySend(state.value) {
-				chanDebug(state.ch)
-				interrupt.Restore(istate)
-				return uintptr(i), true
+			ready = state.ch.canSend()
+		}
+		if ready {
+			numReady++
+			if fastrandn(numReady) == 0 {
+				selected = i
 			}
 		}
 	}
-
-	interrupt.Restore(istate)
-	return ^uintptr(0), false
+	if selected < 0 {
+		task.Unlock(istate)
+		return ^uintptr(0), false
+	}
+
+	// Do the selected operation. It can't block, as the scheduler lock is
+	// still held.
+	state := states[selected]
+	ok := true
+	if state.value == nil {
+		// A receive operation.
+		_, ok = state.ch.tryRecv(recvbuf)
+	} else {
+		// A send operation: state.value is not nil.
+		state.ch.trySend(state.value)
+	}
+	chanDebug(state.ch)
+	task.Unlock(istate)
+	return uintptr(selected), ok
 }
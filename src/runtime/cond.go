@@ -73,10 +73,14 @@ func (c *Cond) Wait() {
 		case nil:
 			// Condition variable has not been notified.
 			// Block the current task on the condition variable.
+			// The lock makes sure the task can't be resumed by Notify
+			// before it has fully paused.
+			i := task.Lock()
 			if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&c.t)), nil, unsafe.Pointer(cur)) {
-				task.Pause()
+				task.PauseLocked(i)
 				return
 			}
+			task.Unlock(i)
 		case &notifiedPlaceholder:
 			// A notification arrived and there is no waiting goroutine.
 			// Clear the notification and return.
@@ -6,3 +6,13 @@ type Error interface {
 
 	RuntimeError()
 }
+
+// runtimeError is the panic value of a runtime panic, such as a nil pointer
+// dereference. It is only observable when the panic is recovered.
+type runtimeError string
+
+func (e runtimeError) RuntimeError() {}
+
+func (e runtimeError) Error() string {
+	return "runtime error: " + string(e)
+}
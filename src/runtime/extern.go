@@ -1,5 +1,8 @@
 package runtime
 
+// Callers fills pc with the program counters of the calling goroutine's stack,
+// after skipping skip frames: 0 means the frame of Callers itself and 1 the
+// caller of Callers. It returns the number of entries written to pc.
 func Callers(skip int, pc []uintptr) int {
-	return 0
+	return callers(skip, pc)
 }
@@ -378,6 +378,57 @@ func (n *memTreapNode) scan() {
 	scan(start, end)
 }
 
+// readHeapStats fills in the heap statistics of m.
+func readHeapStats(m *MemStats) {
+	// Every allocation is preceded by a treap node, which is counted as GC
+	// metadata.
+	m.GCSys = (gcMallocs - gcFrees) * uint64(unsafe.Sizeof(memTreapNode{}))
+	m.HeapInuse = uint64(usedMem) - m.GCSys
+	m.HeapAlloc = m.HeapInuse
+	m.HeapSys = m.HeapInuse
+	m.Sys = uint64(usedMem)
+}
+
+// finishMark scans all queued allocations, building a new treap with marked
+// allocations. The marking process deletes the allocations from the old
+// allocations treap, so they are only queued once.
+func finishMark() {
+	for !scanQueue.empty() {
+		// Pop a marked node off of the scan queue.
+		n := scanQueue.pop()
+
+		// Scan and mark all nodes that this references.
+		n.scan()
+
+		// Insert this node into the active memory queue.
+		activeMem.push(n)
+	}
+}
+
+// isHeapObject returns whether ptr points into an allocation.
+func isHeapObject(ptr uintptr) bool {
+	return allocations.lookupAddr(ptr) != nil
+}
+
+// isMarked returns whether the allocation at ptr has been marked. Marked
+// allocations are removed from the allocations treap.
+func isMarked(ptr uintptr) bool {
+	return allocations.lookupAddr(ptr) == nil
+}
+
+// markReferenced marks all allocations referenced by the allocation at ptr, but
+// not the allocation itself (unless it references itself).
+func markReferenced(ptr uintptr) {
+	allocations.lookupAddr(ptr).scan()
+	finishMark()
+}
+
+// markObject marks the allocation at ptr and all allocations it references.
+func markObject(ptr uintptr) {
+	mark(ptr)
+	finishMark()
+}
+
 // destroy removes and frees all allocations in the treap.
 func (t *memTreap) destroy() {
 	n := t.root
@@ -403,6 +454,7 @@ func (t *memTreap) destroy() {
 
 			// Update used memory.
 			usedMem -= unsafe.Sizeof(memTreapNode{}) + n.size
+			gcFrees++
 			if gcDebug {
 				println("collecting:", &n.base, "size:", n.size)
 				println("used memory:", usedMem)
@@ -425,7 +477,19 @@ var gcrunning bool
 // This is only used when the garbage collector is running.
 var activeMem memScanQueue
 
+// gcLock protects the finalizer table and the memory statistics. This
+// collector doesn't support multiple cores, so it is only a lock for
+// consistency with the other collectors.
+var gcLock spinLock
+
+// GC performs a garbage collection cycle.
 func GC() {
+	runGC()
+	startFinalizers()
+}
+
+// runGC performs a garbage collection cycle.
+func runGC() {
 	if gcDebug {
 		println("running GC")
 	}
@@ -443,6 +507,7 @@ func GC() {
 		}
 		gcrunning = true
 	}
+	start := ticks()
 
 	if gcDebug {
 		println("pre-GC allocations:")
@@ -474,18 +539,8 @@ runqueueScan:
 		markedTaskQueue.Push(t)
 	}
 
-	// Scan all referenced allocations, building a new treap with marked allocations.
-	// The marking process deletes the allocations from the old allocations treap, so they are only queued once.
-	for !scanQueue.empty() {
-		// Pop a marked node off of the scan queue.
-		n := scanQueue.pop()
-
-		// Scan and mark all nodes that this references.
-		n.scan()
-
-		// Insert this node into the active memory queue.
-		activeMem.push(n)
-	}
+	// Scan all referenced allocations.
+	finishMark()
 
 	i := interrupt.Disable()
 	if !runqueue.Empty() {
@@ -496,6 +551,10 @@ runqueueScan:
 	runqueue = markedTaskQueue
 	interrupt.Restore(i)
 
+	// Keep unreachable objects with a finalizer alive until their finalizer
+	// has run.
+	markFinalizers()
+
 	// The allocations treap now only contains unreferenced nodes. Destroy them all.
 	allocations.destroy()
 	if gcAsserts && !allocations.empty() {
@@ -507,6 +566,8 @@ runqueueScan:
 		allocations.insert(activeMem.pop())
 	}
 
+	gcStatsCycle(start)
+
 	if gcDebug {
 		println("GC finished")
 	}
@@ -527,7 +588,7 @@ var zeroSizedAlloc uint8
 // alloc tries to find some free space on the heap, possibly doing a garbage
 // collection cycle if needed. If no space is free, it panics.
 //go:noinline
-func alloc(size uintptr) unsafe.Pointer {
+func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 	if size == 0 {
 		return unsafe.Pointer(&zeroSizedAlloc)
 	}
@@ -554,7 +615,7 @@ func alloc(size uintptr) unsafe.Pointer {
 				if gcDebug {
 					println("heap reached size limit")
 				}
-				GC()
+				runGC()
 				gcRan = true
 				continue
 			} else {
@@ -584,7 +645,7 @@ func alloc(size uintptr) unsafe.Pointer {
 				runtimePanic("out of memory")
 			} else {
 				// Run the garbage collector and try again.
-				GC()
+				runGC()
 				gcRan = true
 				continue
 			}
@@ -620,12 +681,20 @@ func alloc(size uintptr) unsafe.Pointer {
 
 		// Update used memory.
 		usedMem += allocSize
+		gcTotalAlloc += uint64(size)
+		gcMallocs++
 
 		if gcDebug {
 			println("allocated:", uintptr(ptr), "size:", size)
 			println("used memory:", usedMem)
 		}
 
+		if hasScheduler && gcRan {
+			// A GC cycle was run, which may have found objects that need to
+			// be finalized.
+			startFinalizers()
+		}
+
 		return ptr
 	}
 }
@@ -633,11 +702,3 @@ func alloc(size uintptr) unsafe.Pointer {
 func free(ptr unsafe.Pointer) {
 	// Currently unimplemented due to bugs in coroutine lowering.
 }
-
-func KeepAlive(x interface{}) {
-	// Unimplemented. Only required with SetFinalizer().
-}
-
-func SetFinalizer(obj interface{}, finalizer interface{}) {
-	// Unimplemented.
-}
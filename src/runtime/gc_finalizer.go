@@ -0,0 +1,230 @@
+// +build gc.conservative gc.precise gc.extalloc
+
+package runtime
+
+// This file implements finalizers for the garbage collectors that free memory.
+//
+// Objects with a finalizer are stored in a table that is checked after the mark
+// phase of each GC cycle. Objects that are not reachable anymore are marked
+// again (so that they are not freed) and are moved to a queue, from where a
+// separate goroutine runs their finalizers. Once the finalizer has run, the
+// object can be collected in a following GC cycle like any other object.
+//
+// The collector must provide the following functions, which are called while
+// the world is stopped after all reachable objects have been marked:
+//
+//   - isHeapObject(ptr) returns whether ptr points into a heap object.
+//   - isMarked(ptr) returns whether the object at ptr has been marked.
+//   - markReferenced(ptr) marks all objects referenced by the object at ptr,
+//     but not the object itself.
+//   - markObject(ptr) marks the object at ptr and all objects it references.
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// finalizerEntry is an entry in the finalizer table or queue.
+type finalizerEntry struct {
+	next *finalizerEntry
+
+	// hiddenPtr is the address of the object, inverted so that the GC doesn't
+	// treat it as a pointer. The table must not keep the object alive.
+	hiddenPtr uintptr
+
+	// typecode is the type of the object, as passed to SetFinalizer.
+	typecode uintptr
+
+	// ptr is the object address once the entry has been queued. It keeps the
+	// object alive until the finalizer has run.
+	ptr unsafe.Pointer
+
+	// fn is the finalizer func, as passed to SetFinalizer.
+	fn interface{}
+}
+
+var (
+	// finalizers is the table of objects that have a finalizer.
+	finalizers *finalizerEntry
+
+	// finalizerQueue contains the entries of objects that were found to be
+	// unreachable, and whose finalizers still need to run.
+	finalizerQueue *finalizerEntry
+
+	// finalizerRunning is set while runFinalizers is running.
+	finalizerRunning bool
+
+	// finalizerCall calls a finalizer. It is set by SetFinalizer, so that the
+	// reflection needed to call a finalizer is only included in programs that
+	// actually use finalizers.
+	finalizerCall func(fn, obj interface{})
+
+	// keepAliveSink is stored to by KeepAlive, to make sure calls to
+	// KeepAlive are not optimized away.
+	keepAliveSink uintptr
+)
+
+// SetFinalizer sets the finalizer associated with obj to the provided
+// finalizer function. When the garbage collector finds an unreachable object
+// with an associated finalizer, it clears the association and runs
+// finalizer(obj) in a separate goroutine. This makes obj reachable again, but
+// now without an associated finalizer. Assuming that SetFinalizer is not called
+// again, the next time the garbage collector sees that obj is unreachable, it
+// will free obj.
+//
+// The finalizer must be a func with a single parameter to which obj can be
+// assigned. Any results are ignored. SetFinalizer(obj, nil) clears the
+// finalizer associated with obj.
+//
+// If A points at B and both have a finalizer, only the finalizer of A runs
+// when both become unreachable. Once A is freed, the finalizer of B can run.
+// Objects in a cycle that contains an object with a finalizer are never freed.
+//
+// Finalizers of objects that are not allocated on the heap, such as globals,
+// never run. Without a scheduler, finalizers only run when runtime.GC is
+// called.
+func SetFinalizer(obj interface{}, finalizer interface{}) {
+	typecode, value := decomposeInterface(*(*_interface)(unsafe.Pointer(&obj)))
+	if typecode == 0 {
+		runtimePanic("SetFinalizer: first argument is nil")
+	}
+	objType := reflect.TypeOf(obj)
+	if objType.Kind() != reflect.Ptr {
+		runtimePanic("SetFinalizer: first argument is not a pointer")
+	}
+	if finalizer != nil {
+		fnType := reflect.TypeOf(finalizer)
+		if fnType.Kind() != reflect.Func {
+			runtimePanic("SetFinalizer: second argument is not a func")
+		}
+		if fnType.NumIn() != 1 {
+			runtimePanic("SetFinalizer: finalizer must have exactly one argument")
+		}
+		if in := fnType.In(0); in.Kind() != reflect.Interface && !objType.AssignableTo(in) {
+			runtimePanic("SetFinalizer: cannot pass object to finalizer")
+		}
+		finalizerCall = callFinalizer
+	}
+
+	ptr := uintptr(value)
+	if !isHeapObject(ptr) {
+		// Objects outside the heap are never freed, so the finalizer would
+		// never run.
+		return
+	}
+
+	// Allocate the new entry before taking the lock, as alloc may run a GC
+	// cycle.
+	var entry *finalizerEntry
+	if finalizer != nil {
+		entry = &finalizerEntry{
+			hiddenPtr: ^ptr,
+			typecode:  typecode,
+			fn:        finalizer,
+		}
+	}
+
+	gcLock.Lock()
+	for prev := &finalizers; *prev != nil; prev = &(*prev).next {
+		if (*prev).hiddenPtr == ^ptr {
+			if entry != nil {
+				gcLock.Unlock()
+				runtimePanic("SetFinalizer: finalizer already set")
+			}
+			// Clear the finalizer.
+			*prev = (*prev).next
+			break
+		}
+	}
+	if entry != nil {
+		entry.next = finalizers
+		finalizers = entry
+	}
+	gcLock.Unlock()
+}
+
+// KeepAlive marks its argument as currently reachable. This ensures that the
+// object is not freed, and its finalizer is not run, before the point in the
+// program where KeepAlive is called.
+//go:noinline
+func KeepAlive(x interface{}) {
+	// Passing x to this function is enough to keep it alive. The store makes
+	// sure the call isn't removed as it would otherwise have no side effects.
+	_, value := decomposeInterface(*(*_interface)(unsafe.Pointer(&x)))
+	keepAliveSink = ^uintptr(value)
+}
+
+// markFinalizers moves the entries of unreachable objects from the finalizer
+// table to the finalizer queue, and marks these objects so that they are not
+// freed before their finalizer has run. It must be called after all reachable
+// objects have been marked, before the sweep phase.
+func markFinalizers() {
+	// Objects referenced by an unreachable object with a finalizer must stay
+	// alive, as they may be used by that finalizer. This also makes sure the
+	// finalizers of such objects run after the finalizer of the object that
+	// references them.
+	for f := finalizers; f != nil; f = f.next {
+		if ptr := ^f.hiddenPtr; !isMarked(ptr) {
+			markReferenced(ptr)
+		}
+	}
+
+	// Objects that are still not marked are only reachable from the finalizer
+	// table. Queue their finalizers, and mark them so they stay alive until
+	// the finalizer has run.
+	prev := &finalizers
+	for f := *prev; f != nil; f = *prev {
+		ptr := ^f.hiddenPtr
+		if isMarked(ptr) {
+			prev = &f.next
+			continue
+		}
+		*prev = f.next
+		markObject(ptr)
+		f.ptr = unsafe.Pointer(ptr)
+		f.next = finalizerQueue
+		finalizerQueue = f
+	}
+}
+
+// startFinalizers runs the queued finalizers in a new goroutine, unless they
+// are already running. Without a scheduler, they run on the current stack.
+func startFinalizers() {
+	gcLock.Lock()
+	start := finalizerQueue != nil && !finalizerRunning
+	if start {
+		finalizerRunning = true
+	}
+	gcLock.Unlock()
+	if !start {
+		return
+	}
+	if hasScheduler {
+		go runFinalizers()
+	} else {
+		runFinalizers()
+	}
+}
+
+// runFinalizers runs queued finalizers until the queue is empty.
+func runFinalizers() {
+	for {
+		gcLock.Lock()
+		f := finalizerQueue
+		if f == nil {
+			finalizerRunning = false
+			gcLock.Unlock()
+			return
+		}
+		finalizerQueue = f.next
+		gcLock.Unlock()
+
+		obj := *(*interface{})(unsafe.Pointer(&_interface{f.typecode, f.ptr}))
+		finalizerCall(f.fn, obj)
+	}
+}
+
+// callFinalizer calls the finalizer fn with the object obj as its argument.
+func callFinalizer(fn, obj interface{}) {
+	reflect.ValueOf(fn).Call([]reflect.Value{reflect.ValueOf(obj)})
+}
@@ -1,4 +1,4 @@
-// +build gc.conservative gc.extalloc
+// +build gc.conservative gc.precise gc.extalloc
 // +build baremetal tinygo.wasm
 
 package runtime
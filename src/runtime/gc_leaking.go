@@ -13,11 +13,18 @@ import (
 // Ever-incrementing pointer: no memory is freed.
 var heapptr = heapStart
 
-func alloc(size uintptr) unsafe.Pointer {
+// gcLock protects the memory statistics. This collector doesn't support
+// multiple cores, so it is only a lock for consistency with the other
+// collectors.
+var gcLock spinLock
+
+func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 	// TODO: this can be optimized by not casting between pointers and ints so
 	// much. And by using platform-native data types (e.g. *uint8 for 8-bit
 	// systems).
 	size = align(size)
+	gcTotalAlloc += uint64(size)
+	gcMallocs++
 	addr := heapptr
 	heapptr += size
 	for heapptr >= heapEnd {
@@ -51,6 +58,15 @@ func SetFinalizer(obj interface{}, finalizer interface{}) {
 	// Unimplemented.
 }
 
+// readHeapStats fills in the heap statistics of m.
+func readHeapStats(m *MemStats) {
+	m.HeapInuse = uint64(heapptr - heapStart)
+	m.HeapAlloc = m.HeapInuse
+	m.HeapIdle = uint64(heapEnd - heapptr)
+	m.HeapSys = m.HeapInuse + m.HeapIdle
+	m.Sys = m.HeapSys
+}
+
 func initHeap() {
 	// Nothing to initialize.
 }
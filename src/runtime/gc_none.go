@@ -10,7 +10,10 @@ import (
 	"unsafe"
 )
 
-func alloc(size uintptr) unsafe.Pointer
+func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
+
+// gcLock protects the memory statistics, which are always zero with this GC.
+var gcLock spinLock
 
 func free(ptr unsafe.Pointer) {
 	// Nothing to free when nothing gets allocated.
@@ -28,6 +31,11 @@ func SetFinalizer(obj interface{}, finalizer interface{}) {
 	// Unimplemented.
 }
 
+// readHeapStats fills in the heap statistics of m. There is no heap, so they
+// are all zero.
+func readHeapStats(m *MemStats) {
+}
+
 func initHeap() {
 	// Nothing to initialize.
 }
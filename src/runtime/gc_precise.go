@@ -0,0 +1,95 @@
+// +build gc.precise
+
+package runtime
+
+// This file implements the precise part of the block-based GC (see
+// gc_blocks.go). Every heap object starts with a header that stores the layout
+// that was passed to alloc. The layout describes which words in the object can
+// contain a pointer, so that other words (integers, floats, string bytes) are
+// never mistaken for a pointer and objects without pointers are not scanned at
+// all.
+//
+// The layout is encoded by the compiler (see compiler/llvmutil/layout.go) in
+// one of these forms:
+//
+//   - A nil pointer means the layout is not known. Every word of the object is
+//     scanned conservatively.
+//   - If the lowest bit is set, the layout is stored in the pointer value
+//     itself. The next 4 (16-bit), 5 (32-bit) or 6 (64-bit) bits are the size
+//     of the layout in words, the remaining bits are a bitmap with a bit set
+//     for every word that may contain a pointer.
+//   - Otherwise, it points to a struct with the size in words (an uintptr)
+//     followed by the bitmap as a byte array.
+//
+// Objects that are bigger than the layout (such as the backing array of a
+// slice) repeat the layout.
+//
+// The stacks and globals are still scanned conservatively.
+
+import "unsafe"
+
+// preciseHeap is true for the precise GC: objects store their layout in a
+// header.
+const preciseHeap = true
+
+// sizeFieldBits is the number of bits used to store the size of an inline
+// layout.
+const sizeFieldBits = 4 + unsafe.Sizeof(uintptr(0))/4
+
+// gcObjectScanner is used by the GC to determine which words in a heap object
+// are pointers.
+type gcObjectScanner struct {
+	index      uintptr        // word index in the current layout
+	size       uintptr        // size of the layout in words
+	bitmap     uintptr        // inline bitmap, if bitmapAddr is nil
+	bitmapAddr unsafe.Pointer // bitmap stored in a global
+}
+
+func newGCObjectScanner(block gcBlock) gcObjectScanner {
+	layout := *(*uintptr)(block.pointer())
+	if layout == 0 {
+		// Unknown layout: treat every word as a possible pointer.
+		return gcObjectScanner{
+			size:   1,
+			bitmap: 1,
+		}
+	}
+	if layout&1 != 0 {
+		// The layout is stored in the pointer value itself.
+		return gcObjectScanner{
+			size:   (layout >> 1) & (1<<sizeFieldBits - 1),
+			bitmap: layout >> (1 + sizeFieldBits),
+		}
+	}
+	// The layout is stored in a global.
+	return gcObjectScanner{
+		size:       *(*uintptr)(unsafe.Pointer(layout)),
+		bitmapAddr: unsafe.Pointer(layout + unsafe.Sizeof(uintptr(0))),
+	}
+}
+
+// pointerFree returns whether the object doesn't contain any pointers, and
+// therefore doesn't need to be scanned.
+func (scanner *gcObjectScanner) pointerFree() bool {
+	return scanner.bitmapAddr == nil && scanner.bitmap == 0
+}
+
+// nextIsPointer returns whether the next word in the object should be treated
+// as a pointer.
+func (scanner *gcObjectScanner) nextIsPointer(word uintptr) bool {
+	index := scanner.index
+	scanner.index++
+	if scanner.index == scanner.size {
+		scanner.index = 0
+	}
+
+	if !looksLikePointer(word) {
+		return false
+	}
+
+	if scanner.bitmapAddr != nil {
+		b := *(*uint8)(unsafe.Pointer(uintptr(scanner.bitmapAddr) + index/8))
+		return (b>>(index%8))&1 != 0
+	}
+	return (scanner.bitmap>>index)&1 != 0
+}
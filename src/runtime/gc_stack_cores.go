@@ -0,0 +1,61 @@
+// +build gc.conservative,scheduler.cores gc.precise,scheduler.cores
+
+package runtime
+
+import "internal/task"
+
+// markStack marks all root pointers found on the stacks of all cores.
+//
+// This is the same as the implementation in gc_stack_raw.go, except that it
+// also marks the system stacks of the other cores. These cores have been
+// stopped by gcStopTheWorld while their registers were stored on the stack.
+// Their goroutine stacks don't need to be marked separately: they are heap
+// allocations that are reachable through the current task of each core.
+func markStack() {
+	// Scan the current stack, and all current registers.
+	scanCurrentStack()
+
+	cpu := currentCPU()
+	if !task.OnSystemStack() {
+		// Mark system stack.
+		markRoots(getSystemStackPointer(), systemStackTop(cpu))
+	}
+
+	// Mark the system stacks of the stopped cores.
+	for i := uint32(0); i < numCPU; i++ {
+		if i == cpu {
+			continue
+		}
+		if sp := gcStoppedStackPointer(i); sp != 0 {
+			markRoots(sp, systemStackTop(i))
+		}
+	}
+}
+
+//go:export tinygo_scanCurrentStack
+func scanCurrentStack()
+
+//go:export tinygo_scanstack
+func scanstack(sp uintptr) {
+	cpu := currentCPU()
+	if cpu != gcCPU {
+		// This core was stopped for a garbage collection cycle that is
+		// running on a different core. All registers are now stored on the
+		// stack, so wait here until the collection has finished.
+		gcWaitUntilStarted(cpu, sp)
+		return
+	}
+
+	// Mark current stack.
+	// This function is called by scanCurrentStack, after pushing all registers onto the stack.
+	// Callee-saved registers have been pushed onto stack by tinygo_localscan, so this will scan them too.
+	if task.OnSystemStack() {
+		// This is the system stack.
+		// Scan all words on the stack.
+		markRoots(sp, systemStackTop(cpu))
+	} else {
+		// This is a goroutine stack.
+		// It is an allocation, so scan it as if it were a value in a global.
+		markRoot(0, sp)
+	}
+}
@@ -1,5 +1,6 @@
-// +build gc.conservative gc.extalloc
+// +build gc.conservative gc.precise gc.extalloc
 // +build !tinygo.wasm
+// +build !scheduler.cores
 
 package runtime
 
@@ -12,14 +12,36 @@ import (
 
 // The underlying hashmap structure for Go.
 type hashmap struct {
-	next       *hashmap       // hashmap after evacuate (for iterators)
-	buckets    unsafe.Pointer // pointer to array of buckets
-	count      uintptr
-	keySize    uint8 // maybe this can store the key type as well? E.g. keysize == 5 means string?
-	valueSize  uint8
-	bucketBits uint8
+	buckets       unsafe.Pointer // pointer to array of buckets
+	oldBuckets    unsafe.Pointer // previous array of buckets while resizing, or nil
+	count         uintptr
+	evacuated     uintptr // number of buckets in oldBuckets that have been moved to buckets
+	keySize       uint8
+	valueSize     uint8
+	bucketBits    uint8
+	oldBucketBits uint8
+	keyAlg        hashmapAlgorithm
 }
 
+// hashmapAlgorithm describes how keys are hashed and compared. It is set by the
+// hashmapBinary*, hashmapString* and hashmapInterface* functions, so that keys
+// can be rehashed when the map is resized.
+type hashmapAlgorithm uint8
+
+const (
+	hashmapAlgorithmBinary hashmapAlgorithm = iota
+	hashmapAlgorithmString
+	hashmapAlgorithmInterface
+)
+
+// The maximum average number of entries per bucket. When a map grows beyond
+// this load factor, the number of buckets is doubled. When it drops below
+// hashmapMinLoad entries per bucket, the number of buckets is halved.
+const (
+	hashmapMaxLoad = 6
+	hashmapMinLoad = 1
+)
+
 // A hashmap bucket. A bucket is a container of 8 key/value pairs: first the
 // following two entries, then the 8 keys, then the 8 values. This somewhat odd
 // ordering is to make sure the keys and values are well aligned when one of
@@ -32,6 +54,8 @@ type hashmapBucket struct {
 }
 
 type hashmapIterator struct {
+	buckets      unsafe.Pointer // array of buckets that is being iterated over
+	numBuckets   uintptr
 	bucketNumber uintptr
 	bucket       *hashmapBucket
 	bucketIndex  uint8
@@ -62,14 +86,14 @@ func hashmapTopHash(hash uint32) uint8 {
 
 // Create a new hashmap with the given keySize and valueSize.
 func hashmapMake(keySize, valueSize uint8, sizeHint uintptr) *hashmap {
-	numBuckets := sizeHint / 8
+	numBuckets := sizeHint / hashmapMaxLoad
 	bucketBits := uint8(0)
 	for numBuckets != 0 {
 		numBuckets /= 2
 		bucketBits++
 	}
 	bucketBufSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(keySize)*8 + uintptr(valueSize)*8
-	buckets := alloc(bucketBufSize * (1 << bucketBits))
+	buckets := alloc(bucketBufSize*(1<<bucketBits), nil)
 	return &hashmap{
 		buckets:    buckets,
 		keySize:    keySize,
@@ -78,6 +102,11 @@ func hashmapMake(keySize, valueSize uint8, sizeHint uintptr) *hashmap {
 	}
 }
 
+// wrapper for use in reflect
+func hashmapMakeUnsafePointer(keySize, valueSize uint8, sizeHint uintptr) unsafe.Pointer {
+	return unsafe.Pointer(hashmapMake(keySize, valueSize, sizeHint))
+}
+
 // Return the number of entries in this hashmap, called from the len builtin.
 // A nil hashmap is defined as having length 0.
 //go:inline
@@ -94,86 +123,212 @@ func hashmapLenUnsafePointer(p unsafe.Pointer) int {
 	return hashmapLen(m)
 }
 
+// Return the size of a single bucket, including the keys and values.
+func hashmapBucketSize(m *hashmap) uintptr {
+	return unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
+}
+
+// Return the first bucket in the chain with the given number, in the given
+// array of buckets.
+func hashmapBucketAddr(m *hashmap, buckets unsafe.Pointer, bucketNumber uintptr) *hashmapBucket {
+	return (*hashmapBucket)(unsafe.Pointer(uintptr(buckets) + hashmapBucketSize(m)*bucketNumber))
+}
+
+// Return a pointer to the key in the given slot of the bucket.
+func hashmapSlotKey(m *hashmap, bucket *hashmapBucket, i uintptr) unsafe.Pointer {
+	slotKeyOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*i
+	return unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotKeyOffset)
+}
+
+// Return a pointer to the value in the given slot of the bucket.
+func hashmapSlotValue(m *hashmap, bucket *hashmapBucket, i uintptr) unsafe.Pointer {
+	slotValueOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*i
+	return unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotValueOffset)
+}
+
+// Hash a key that is stored in the map, using the algorithm of this map.
+func hashmapKeyHash(m *hashmap, key unsafe.Pointer) uint32 {
+	switch m.keyAlg {
+	case hashmapAlgorithmString:
+		return hashmapStringHash(*(*string)(key))
+	case hashmapAlgorithmInterface:
+		return hashmapInterfaceHash(*(*interface{})(key))
+	default:
+		return hashmapHash(key, uintptr(m.keySize))
+	}
+}
+
+// Compare two keys, using the algorithm of this map.
+func hashmapKeyEqual(m *hashmap, x, y unsafe.Pointer) bool {
+	switch m.keyAlg {
+	case hashmapAlgorithmString:
+		return hashmapStringEqual(x, y, uintptr(m.keySize))
+	case hashmapAlgorithmInterface:
+		return hashmapInterfaceEqual(x, y, uintptr(m.keySize))
+	default:
+		return memequal(x, y, uintptr(m.keySize))
+	}
+}
+
+// Find the slot where the given key is stored. It returns a nil bucket if the
+// key is not in the map.
+//go:nobounds
+func hashmapFind(m *hashmap, key unsafe.Pointer, hash uint32) (*hashmapBucket, uintptr) {
+	tophash := hashmapTopHash(hash)
+
+	// Look in the current array of buckets, and in the old array of buckets if
+	// the key might not have been moved yet.
+	if m.oldBuckets != nil {
+		oldBucketNumber := uintptr(hash) & (uintptr(1)<<m.oldBucketBits - 1)
+		if oldBucketNumber >= m.evacuated {
+			oldBucket := hashmapBucketAddr(m, m.oldBuckets, oldBucketNumber)
+			if bucket, i := hashmapFindInChain(m, oldBucket, key, tophash); bucket != nil {
+				return bucket, i
+			}
+		}
+	}
+	bucket := hashmapBucketAddr(m, m.buckets, uintptr(hash)&(uintptr(1)<<m.bucketBits-1))
+	return hashmapFindInChain(m, bucket, key, tophash)
+}
+
+// Find the given key in a single chain of buckets.
+//go:nobounds
+func hashmapFindInChain(m *hashmap, bucket *hashmapBucket, key unsafe.Pointer, tophash uint8) (*hashmapBucket, uintptr) {
+	for bucket != nil {
+		for i := uintptr(0); i < 8; i++ {
+			if bucket.tophash[i] == tophash {
+				// This could be the key we're looking for.
+				if hashmapKeyEqual(m, key, hashmapSlotKey(m, bucket, i)) {
+					return bucket, i
+				}
+			}
+		}
+		bucket = bucket.next
+	}
+	return nil, 0
+}
+
 // Set a specified key to a given value. Grow the map if necessary.
 //go:nobounds
-func hashmapSet(m *hashmap, key unsafe.Pointer, value unsafe.Pointer, hash uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) {
+func hashmapSet(m *hashmap, key unsafe.Pointer, value unsafe.Pointer, hash uint32, alg hashmapAlgorithm) {
+	m.keyAlg = alg
 	tophash := hashmapTopHash(hash)
 
 	if m.buckets == nil {
 		// No bucket was allocated yet, do so now.
 		m.buckets = unsafe.Pointer(hashmapInsertIntoNewBucket(m, key, value, tophash))
+		m.count++
 		return
 	}
 
-	numBuckets := uintptr(1) << m.bucketBits
-	bucketNumber := (uintptr(hash) & (numBuckets - 1))
-	bucketSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
-	bucketAddr := uintptr(m.buckets) + bucketSize*bucketNumber
-	bucket := (*hashmapBucket)(unsafe.Pointer(bucketAddr))
-	var lastBucket *hashmapBucket
+	if m.oldBuckets != nil {
+		// Move a few more buckets while the map is being resized.
+		hashmapResizeWork(m)
+	}
 
 	// See whether the key already exists somewhere.
-	var emptySlotKey unsafe.Pointer
-	var emptySlotValue unsafe.Pointer
-	var emptySlotTophash *byte
+	if bucket, i := hashmapFind(m, key, hash); bucket != nil {
+		// found same key, replace it
+		memcpy(hashmapSlotValue(m, bucket, i), value, uintptr(m.valueSize))
+		return
+	}
+
+	if m.oldBuckets == nil && m.count >= hashmapMaxLoad<<m.bucketBits {
+		// There are too many entries per bucket, which makes lookups slow.
+		// Double the number of buckets. Entries are moved to the new buckets
+		// a few at a time by each following insert or delete, so that a
+		// single insert doesn't need to move the entire map.
+		hashmapStartResize(m, m.bucketBits+1)
+		hashmapResizeWork(m)
+	}
+
+	hashmapInsert(m, key, value, hash, tophash)
+	m.count++
+}
+
+// Insert a key that is not yet in the map into the current array of buckets.
+// It does not update the number of entries in the map.
+//go:nobounds
+func hashmapInsert(m *hashmap, key, value unsafe.Pointer, hash uint32, tophash uint8) {
+	bucket := hashmapBucketAddr(m, m.buckets, uintptr(hash)&(uintptr(1)<<m.bucketBits-1))
+	var lastBucket *hashmapBucket
 	for bucket != nil {
 		for i := uintptr(0); i < 8; i++ {
-			slotKeyOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*uintptr(i)
-			slotKey := unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotKeyOffset)
-			slotValueOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*uintptr(i)
-			slotValue := unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotValueOffset)
-			if bucket.tophash[i] == 0 && emptySlotKey == nil {
-				// Found an empty slot, store it for if we couldn't find an
-				// existing slot.
-				emptySlotKey = slotKey
-				emptySlotValue = slotValue
-				emptySlotTophash = &bucket.tophash[i]
-			}
-			if bucket.tophash[i] == tophash {
-				// Could be an existing key that's the same.
-				if keyEqual(key, slotKey, uintptr(m.keySize)) {
-					// found same key, replace it
-					memcpy(slotValue, value, uintptr(m.valueSize))
-					return
-				}
+			if bucket.tophash[i] == 0 {
+				// Found an empty slot.
+				memcpy(hashmapSlotKey(m, bucket, i), key, uintptr(m.keySize))
+				memcpy(hashmapSlotValue(m, bucket, i), value, uintptr(m.valueSize))
+				bucket.tophash[i] = tophash
+				return
 			}
 		}
 		lastBucket = bucket
 		bucket = bucket.next
 	}
-	if emptySlotKey == nil {
-		// Add a new bucket to the bucket chain.
-		// TODO: rebalance if necessary to avoid O(n) insert and lookup time.
-		lastBucket.next = (*hashmapBucket)(hashmapInsertIntoNewBucket(m, key, value, tophash))
-		return
-	}
-	m.count++
-	memcpy(emptySlotKey, key, uintptr(m.keySize))
-	memcpy(emptySlotValue, value, uintptr(m.valueSize))
-	*emptySlotTophash = tophash
+
+	// Add a new bucket to the bucket chain.
+	lastBucket.next = hashmapInsertIntoNewBucket(m, key, value, tophash)
 }
 
 // hashmapInsertIntoNewBucket creates a new bucket, inserts the given key and
 // value into the bucket, and returns a pointer to this bucket.
 func hashmapInsertIntoNewBucket(m *hashmap, key, value unsafe.Pointer, tophash uint8) *hashmapBucket {
-	bucketBufSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
-	bucketBuf := alloc(bucketBufSize)
+	bucket := (*hashmapBucket)(alloc(hashmapBucketSize(m), nil))
 	// Insert into the first slot, which is empty as it has just been allocated.
-	slotKeyOffset := unsafe.Sizeof(hashmapBucket{})
-	slotKey := unsafe.Pointer(uintptr(bucketBuf) + slotKeyOffset)
-	slotValueOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8
-	slotValue := unsafe.Pointer(uintptr(bucketBuf) + slotValueOffset)
-	m.count++
-	memcpy(slotKey, key, uintptr(m.keySize))
-	memcpy(slotValue, value, uintptr(m.valueSize))
-	bucket := (*hashmapBucket)(bucketBuf)
+	memcpy(hashmapSlotKey(m, bucket, 0), key, uintptr(m.keySize))
+	memcpy(hashmapSlotValue(m, bucket, 0), value, uintptr(m.valueSize))
 	bucket.tophash[0] = tophash
 	return bucket
 }
 
+// Start resizing the map to the given number of buckets (as a power of two).
+// Until all entries have been moved by hashmapResizeWork, lookups need to check
+// both the old and the new array of buckets.
+func hashmapStartResize(m *hashmap, bucketBits uint8) {
+	m.oldBuckets = m.buckets
+	m.oldBucketBits = m.bucketBits
+	m.evacuated = 0
+	m.buckets = alloc(hashmapBucketSize(m)<<bucketBits, nil)
+	m.bucketBits = bucketBits
+}
+
+// Move the entries in the next two old buckets to the new array of buckets.
+// Moving two buckets for every insert makes sure the resize has finished before
+// the map needs to be resized again.
+func hashmapResizeWork(m *hashmap) {
+	numOldBuckets := uintptr(1) << m.oldBucketBits
+	for n := 0; n < 2 && m.evacuated < numOldBuckets; n++ {
+		hashmapEvacuate(m, m.evacuated)
+		m.evacuated++
+	}
+	if m.evacuated == numOldBuckets {
+		// All entries have been moved, so the old buckets can be freed.
+		m.oldBuckets = nil
+	}
+}
+
+// Move all entries in the given bucket chain of the old array of buckets to the
+// new array of buckets. The old buckets are left as they are, as an iterator
+// might still be iterating over them.
+//go:nobounds
+func hashmapEvacuate(m *hashmap, oldBucketNumber uintptr) {
+	bucket := hashmapBucketAddr(m, m.oldBuckets, oldBucketNumber)
+	for bucket != nil {
+		for i := uintptr(0); i < 8; i++ {
+			if bucket.tophash[i] == 0 {
+				continue
+			}
+			key := hashmapSlotKey(m, bucket, i)
+			hash := hashmapKeyHash(m, key)
+			hashmapInsert(m, key, hashmapSlotValue(m, bucket, i), hash, bucket.tophash[i])
+		}
+		bucket = bucket.next
+	}
+}
+
 // Get the value of a specified key, or zero the value if not found.
 //go:nobounds
-func hashmapGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr, hash uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) bool {
+func hashmapGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr, hash uint32) bool {
 	if m == nil {
 		// Getting a value out of a nil map is valid. From the spec:
 		// > if the map is nil or does not contain such an entry, a[x] is the
@@ -181,35 +336,12 @@ func hashmapGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr, hash u
 		memzero(value, uintptr(valueSize))
 		return false
 	}
-	numBuckets := uintptr(1) << m.bucketBits
-	bucketNumber := (uintptr(hash) & (numBuckets - 1))
-	bucketSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
-	bucketAddr := uintptr(m.buckets) + bucketSize*bucketNumber
-	bucket := (*hashmapBucket)(unsafe.Pointer(bucketAddr))
-
-	tophash := uint8(hash >> 24)
-	if tophash < 1 {
-		// 0 means empty slot, so make it bigger.
-		tophash += 1
-	}
 
 	// Try to find the key.
-	for bucket != nil {
-		for i := uintptr(0); i < 8; i++ {
-			slotKeyOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*uintptr(i)
-			slotKey := unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotKeyOffset)
-			slotValueOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*uintptr(i)
-			slotValue := unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotValueOffset)
-			if bucket.tophash[i] == tophash {
-				// This could be the key we're looking for.
-				if keyEqual(key, slotKey, uintptr(m.keySize)) {
-					// Found the key, copy it.
-					memcpy(value, slotValue, uintptr(m.valueSize))
-					return true
-				}
-			}
-		}
-		bucket = bucket.next
+	if bucket, i := hashmapFind(m, key, hash); bucket != nil {
+		// Found the key, copy it.
+		memcpy(value, hashmapSlotValue(m, bucket, i), uintptr(m.valueSize))
+		return true
 	}
 
 	// Did not find the key.
@@ -220,41 +352,36 @@ func hashmapGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr, hash u
 // Delete a given key from the map. No-op when the key does not exist in the
 // map.
 //go:nobounds
-func hashmapDelete(m *hashmap, key unsafe.Pointer, hash uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) {
+func hashmapDelete(m *hashmap, key unsafe.Pointer, hash uint32, alg hashmapAlgorithm) {
 	if m == nil {
 		// The delete builtin is defined even when the map is nil. From the spec:
 		// > If the map m is nil or the element m[k] does not exist, delete is a
 		// > no-op.
 		return
 	}
-	numBuckets := uintptr(1) << m.bucketBits
-	bucketNumber := (uintptr(hash) & (numBuckets - 1))
-	bucketSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
-	bucketAddr := uintptr(m.buckets) + bucketSize*bucketNumber
-	bucket := (*hashmapBucket)(unsafe.Pointer(bucketAddr))
+	m.keyAlg = alg
 
-	tophash := uint8(hash >> 24)
-	if tophash < 1 {
-		// 0 means empty slot, so make it bigger.
-		tophash += 1
+	if m.oldBuckets != nil {
+		// Move a few more buckets while the map is being resized.
+		hashmapResizeWork(m)
 	}
 
 	// Try to find the key.
-	for bucket != nil {
-		for i := uintptr(0); i < 8; i++ {
-			slotKeyOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*uintptr(i)
-			slotKey := unsafe.Pointer(uintptr(unsafe.Pointer(bucket)) + slotKeyOffset)
-			if bucket.tophash[i] == tophash {
-				// This could be the key we're looking for.
-				if keyEqual(key, slotKey, uintptr(m.keySize)) {
-					// Found the key, delete it.
-					bucket.tophash[i] = 0
-					m.count--
-					return
-				}
-			}
-		}
-		bucket = bucket.next
+	bucket, i := hashmapFind(m, key, hash)
+	if bucket == nil {
+		return
+	}
+
+	// Found the key, delete it.
+	bucket.tophash[i] = 0
+	m.count--
+
+	if m.oldBuckets == nil && m.bucketBits != 0 && m.count < hashmapMinLoad<<m.bucketBits {
+		// Most buckets are empty, halve the number of buckets. This also
+		// removes long bucket chains that are left after deleting many
+		// entries.
+		hashmapStartResize(m, m.bucketBits-1)
+		hashmapResizeWork(m)
 	}
 }
 
@@ -268,7 +395,16 @@ func hashmapNext(m *hashmap, it *hashmapIterator, key, value unsafe.Pointer) boo
 		return false
 	}
 
-	numBuckets := uintptr(1) << m.bucketBits
+	if it.buckets == nil {
+		// First call: iterate over the current array of buckets. Make sure
+		// no entries are left in the old buckets of a resize.
+		for m.oldBuckets != nil {
+			hashmapResizeWork(m)
+		}
+		it.buckets = m.buckets
+		it.numBuckets = uintptr(1) << m.bucketBits
+	}
+
 	for {
 		if it.bucketIndex >= 8 {
 			// end of bucket, move to the next in the chain
@@ -276,13 +412,11 @@ func hashmapNext(m *hashmap, it *hashmapIterator, key, value unsafe.Pointer) boo
 			it.bucket = it.bucket.next
 		}
 		if it.bucket == nil {
-			if it.bucketNumber >= numBuckets {
+			if it.bucketNumber >= it.numBuckets {
 				// went through all buckets
 				return false
 			}
-			bucketSize := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*8
-			bucketAddr := uintptr(m.buckets) + bucketSize*it.bucketNumber
-			it.bucket = (*hashmapBucket)(unsafe.Pointer(bucketAddr))
+			it.bucket = hashmapBucketAddr(m, it.buckets, it.bucketNumber)
 			it.bucketNumber++ // next bucket
 		}
 		if it.bucket.tophash[it.bucketIndex] == 0 {
@@ -291,34 +425,60 @@ func hashmapNext(m *hashmap, it *hashmapIterator, key, value unsafe.Pointer) boo
 			continue
 		}
 
-		bucketAddr := uintptr(unsafe.Pointer(it.bucket))
-		slotKeyOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*uintptr(it.bucketIndex)
-		slotKey := unsafe.Pointer(bucketAddr + slotKeyOffset)
-		slotValueOffset := unsafe.Sizeof(hashmapBucket{}) + uintptr(m.keySize)*8 + uintptr(m.valueSize)*uintptr(it.bucketIndex)
-		slotValue := unsafe.Pointer(bucketAddr + slotValueOffset)
+		slotKey := hashmapSlotKey(m, it.bucket, uintptr(it.bucketIndex))
+		slotValue := hashmapSlotValue(m, it.bucket, uintptr(it.bucketIndex))
+		it.bucketIndex++
+		if it.buckets != m.buckets {
+			// The map was resized after the iteration started. The buckets
+			// that are iterated over are not updated anymore, so look up the
+			// current value (or skip the key if it was deleted).
+			bucket, i := hashmapFind(m, slotKey, hashmapKeyHash(m, slotKey))
+			if bucket == nil {
+				continue
+			}
+			slotValue = hashmapSlotValue(m, bucket, i)
+		}
 		memcpy(key, slotKey, uintptr(m.keySize))
 		memcpy(value, slotValue, uintptr(m.valueSize))
-		it.bucketIndex++
 
 		return true
 	}
 }
 
+// wrapper for use in reflect
+func hashmapNextUnsafePointer(m unsafe.Pointer, it unsafe.Pointer, key, value unsafe.Pointer) bool {
+	return hashmapNext((*hashmap)(m), (*hashmapIterator)(it), key, value)
+}
+
 // Hashmap with plain binary data keys (not containing strings etc.).
 
 func hashmapBinarySet(m *hashmap, key, value unsafe.Pointer) {
 	hash := hashmapHash(key, uintptr(m.keySize))
-	hashmapSet(m, key, value, hash, memequal)
+	hashmapSet(m, key, value, hash, hashmapAlgorithmBinary)
 }
 
 func hashmapBinaryGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr) bool {
 	hash := hashmapHash(key, uintptr(m.keySize))
-	return hashmapGet(m, key, value, valueSize, hash, memequal)
+	return hashmapGet(m, key, value, valueSize, hash)
 }
 
 func hashmapBinaryDelete(m *hashmap, key unsafe.Pointer) {
 	hash := hashmapHash(key, uintptr(m.keySize))
-	hashmapDelete(m, key, hash, memequal)
+	hashmapDelete(m, key, hash, hashmapAlgorithmBinary)
+}
+
+// wrappers for use in reflect
+
+func hashmapBinarySetUnsafePointer(m unsafe.Pointer, key, value unsafe.Pointer) {
+	hashmapBinarySet((*hashmap)(m), key, value)
+}
+
+func hashmapBinaryGetUnsafePointer(m unsafe.Pointer, key, value unsafe.Pointer, valueSize uintptr) bool {
+	return hashmapBinaryGet((*hashmap)(m), key, value, valueSize)
+}
+
+func hashmapBinaryDeleteUnsafePointer(m unsafe.Pointer, key unsafe.Pointer) {
+	hashmapBinaryDelete((*hashmap)(m), key)
 }
 
 // Hashmap with string keys (a common case).
@@ -334,17 +494,31 @@ func hashmapStringHash(s string) uint32 {
 
 func hashmapStringSet(m *hashmap, key string, value unsafe.Pointer) {
 	hash := hashmapStringHash(key)
-	hashmapSet(m, unsafe.Pointer(&key), value, hash, hashmapStringEqual)
+	hashmapSet(m, unsafe.Pointer(&key), value, hash, hashmapAlgorithmString)
 }
 
 func hashmapStringGet(m *hashmap, key string, value unsafe.Pointer, valueSize uintptr) bool {
 	hash := hashmapStringHash(key)
-	return hashmapGet(m, unsafe.Pointer(&key), value, valueSize, hash, hashmapStringEqual)
+	return hashmapGet(m, unsafe.Pointer(&key), value, valueSize, hash)
 }
 
 func hashmapStringDelete(m *hashmap, key string) {
 	hash := hashmapStringHash(key)
-	hashmapDelete(m, unsafe.Pointer(&key), hash, hashmapStringEqual)
+	hashmapDelete(m, unsafe.Pointer(&key), hash, hashmapAlgorithmString)
+}
+
+// wrappers for use in reflect
+
+func hashmapStringSetUnsafePointer(m unsafe.Pointer, key string, value unsafe.Pointer) {
+	hashmapStringSet((*hashmap)(m), key, value)
+}
+
+func hashmapStringGetUnsafePointer(m unsafe.Pointer, key string, value unsafe.Pointer, valueSize uintptr) bool {
+	return hashmapStringGet((*hashmap)(m), key, value, valueSize)
+}
+
+func hashmapStringDeleteUnsafePointer(m unsafe.Pointer, key string) {
+	hashmapStringDelete((*hashmap)(m), key)
 }
 
 // Hashmap with interface keys (for everything else).
@@ -412,15 +586,29 @@ func hashmapInterfaceEqual(x, y unsafe.Pointer, n uintptr) bool {
 
 func hashmapInterfaceSet(m *hashmap, key interface{}, value unsafe.Pointer) {
 	hash := hashmapInterfaceHash(key)
-	hashmapSet(m, unsafe.Pointer(&key), value, hash, hashmapInterfaceEqual)
+	hashmapSet(m, unsafe.Pointer(&key), value, hash, hashmapAlgorithmInterface)
 }
 
 func hashmapInterfaceGet(m *hashmap, key interface{}, value unsafe.Pointer, valueSize uintptr) bool {
 	hash := hashmapInterfaceHash(key)
-	return hashmapGet(m, unsafe.Pointer(&key), value, valueSize, hash, hashmapInterfaceEqual)
+	return hashmapGet(m, unsafe.Pointer(&key), value, valueSize, hash)
 }
 
 func hashmapInterfaceDelete(m *hashmap, key interface{}) {
 	hash := hashmapInterfaceHash(key)
-	hashmapDelete(m, unsafe.Pointer(&key), hash, hashmapInterfaceEqual)
+	hashmapDelete(m, unsafe.Pointer(&key), hash, hashmapAlgorithmInterface)
+}
+
+// wrappers for use in reflect
+
+func hashmapInterfaceSetUnsafePointer(m unsafe.Pointer, key interface{}, value unsafe.Pointer) {
+	hashmapInterfaceSet((*hashmap)(m), key, value)
+}
+
+func hashmapInterfaceGetUnsafePointer(m unsafe.Pointer, key interface{}, value unsafe.Pointer, valueSize uintptr) bool {
+	return hashmapInterfaceGet((*hashmap)(m), key, value, valueSize)
+}
+
+func hashmapInterfaceDeleteUnsafePointer(m unsafe.Pointer, key interface{}) {
+	hashmapInterfaceDelete((*hashmap)(m), key)
 }
@@ -105,7 +105,9 @@ type typecodeID struct {
 	// * interface: null
 	// * chan/pointer/slice/array: the element type
 	// * struct: bitcast of global with structField array
-	// * func/map: TODO
+	// * map: bitcast of global with the key and element type
+	// * func: bitcast of global with the parameter and result types and the
+	//   reflect call trampolines
 	references *typecodeID
 
 	// The array length, for array types.
@@ -117,6 +119,11 @@ type typecodeID struct {
 	// Keeping the type struct alive here is important so that values from
 	// reflect.New (which uses reflect.PtrTo) can be used in type asserts etc.
 	ptrTo *typecodeID
+
+	// The exported methods of this type for reflect, nil if there are none or
+	// if this information has been omitted. See makeTypeMethods in the
+	// compiler.
+	methods *uint8
 }
 
 // structField is used by the compiler to pass information to the interface
@@ -1,16 +1,29 @@
-// +build gc.conservative
-
 package runtime
 
 // Memory statistics
 
-// Subset of memory statistics from upstream Go.
-// Works with conservative gc only.
+// Subset of memory statistics from upstream Go. The general statistics are
+// tracked here for every garbage collector, the heap statistics are provided
+// by the garbage collector in use (see readHeapStats).
+
+// Statistics updated by the garbage collector. They are protected by gcLock.
+var (
+	gcTotalAlloc   uint64 // total number of bytes allocated
+	gcMallocs      uint64 // total number of allocations
+	gcFrees        uint64 // total number of freed objects
+	gcNumGC        uint32 // number of completed GC cycles
+	gcPauseTotalNs uint64 // total time spent in GC cycles
+)
 
 // A MemStats records statistics about the memory allocator.
 type MemStats struct {
 	// General statistics.
 
+	// Alloc is bytes of allocated heap objects.
+	//
+	// This is the same as HeapAlloc (see below).
+	Alloc uint64
+
 	// Sys is the total bytes of memory obtained from the OS.
 	//
 	// Sys is the sum of the XSys fields below. Sys measures the
@@ -18,8 +31,29 @@ type MemStats struct {
 	// heap, stacks, and other internal data structures.
 	Sys uint64
 
+	// TotalAlloc is cumulative bytes allocated for heap objects.
+	//
+	// TotalAlloc increases as heap objects are allocated, but
+	// unlike HeapInuse, it does not decrease when objects are
+	// freed.
+	TotalAlloc uint64
+
+	// Mallocs is the cumulative count of heap objects allocated.
+	// The number of live objects is Mallocs - Frees.
+	Mallocs uint64
+
+	// Frees is the cumulative count of heap objects freed.
+	Frees uint64
+
 	// Heap memory statistics.
 
+	// HeapAlloc is bytes of allocated heap objects.
+	//
+	// "Allocated" heap objects include all reachable objects, as
+	// well as unreachable objects that the garbage collector has
+	// not yet freed.
+	HeapAlloc uint64
+
 	// HeapSys is bytes of heap memory, total.
 	//
 	// In TinyGo unlike upstream Go, we make no distinction between
@@ -45,6 +79,15 @@ type MemStats struct {
 
 	// GCSys is bytes of memory in garbage collection metadata.
 	GCSys uint64
+
+	// Garbage collector statistics.
+
+	// PauseTotalNs is the cumulative nanoseconds in GC
+	// stop-the-world pauses since the program started.
+	PauseTotalNs uint64
+
+	// NumGC is the number of completed GC cycles.
+	NumGC uint32
 }
 
 // ReadMemStats populates m with memory statistics.
@@ -52,18 +95,41 @@ type MemStats struct {
 // The returned memory statistics are up to date as of the
 // call to ReadMemStats. This would not do GC implicitly for you.
 func ReadMemStats(m *MemStats) {
-	m.HeapIdle = 0
-	m.HeapInuse = 0
-	for block := gcBlock(0); block < endBlock; block++ {
-		bstate := block.state()
-		if bstate == blockStateFree {
-			m.HeapIdle += uint64(bytesPerBlock)
-		} else {
-			m.HeapInuse += uint64(bytesPerBlock)
-		}
+	gcLock.Lock()
+	*m = MemStats{
+		TotalAlloc:   gcTotalAlloc,
+		Mallocs:      gcMallocs,
+		Frees:        gcFrees,
+		PauseTotalNs: gcPauseTotalNs,
+		NumGC:        gcNumGC,
+	}
+	readHeapStats(m)
+	m.Alloc = m.HeapAlloc
+	gcLock.Unlock()
+}
+
+// gcStatsCycle updates the GC statistics after a GC cycle that started at the
+// given time. The caller must hold gcLock.
+func gcStatsCycle(start timeUnit) {
+	gcNumGC++
+	gcPauseTotalNs += uint64(ticksToNanoseconds(ticks() - start))
+}
+
+// printMemStats prints the memory statistics when the program exits, if the
+// program was built with -print-gc-stats.
+func printMemStats() {
+	if !printGCStats {
+		return
 	}
-	m.HeapReleased = 0 // always 0, we don't currently release memory back to the OS.
-	m.HeapSys = m.HeapInuse + m.HeapIdle
-	m.GCSys = uint64(heapEnd - uintptr(metadataStart))
-	m.Sys = uint64(heapEnd - heapStart)
+	var m MemStats
+	ReadMemStats(&m)
+	println("gc stats:")
+	println("  Mallocs:     ", m.Mallocs)
+	println("  Frees:       ", m.Frees)
+	println("  TotalAlloc:  ", m.TotalAlloc)
+	println("  HeapAlloc:   ", m.HeapAlloc)
+	println("  HeapInuse:   ", m.HeapInuse)
+	println("  HeapIdle:    ", m.HeapIdle)
+	println("  NumGC:       ", m.NumGC)
+	println("  PauseTotalNs:", m.PauseTotalNs)
 }
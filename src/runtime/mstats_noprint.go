@@ -0,0 +1,7 @@
+// +build !printgcstats
+
+package runtime
+
+// printGCStats is true when the program was built with -print-gc-stats, to
+// print the memory statistics when the program exits.
+const printGCStats = false
@@ -5,28 +5,39 @@ package runtime
 //export llvm.trap
 func trap()
 
+// goexitPanic is unwound by Goexit to run the deferred calls of a goroutine
+// with -panic=unwind. It cannot be recovered.
+type goexitPanic struct{}
+
 // Builtin function panic(msg), used as a compiler intrinsic.
 func _panic(message interface{}) {
+	if startUnwinding(message) {
+		// The panic will be handled by a deferred call (-panic=unwind).
+		return
+	}
+	if _, ok := message.(goexitPanic); ok {
+		// All deferred calls have run after Goexit, stop the goroutine.
+		deadlock()
+	}
 	printstring("panic: ")
 	printitf(message)
 	printnl()
+	printStackTrace()
 	abort()
 }
 
-// Cause a runtime panic, which is (currently) always a string.
+// Cause a runtime panic, with a message that is (currently) always a string.
 func runtimePanic(msg string) {
+	if startUnwinding(runtimeError(msg)) {
+		// The panic will be handled by a deferred call (-panic=unwind).
+		return
+	}
 	printstring("panic: runtime error: ")
 	println(msg)
+	printStackTrace()
 	abort()
 }
 
-// Try to recover a panicking goroutine.
-func _recover() interface{} {
-	// Deferred functions are currently not executed during panic, so there is
-	// no way this can return anything besides nil.
-	return nil
-}
-
 // Panic when trying to dereference a nil pointer.
 func nilPanic() {
 	runtimePanic("nil pointer dereference")
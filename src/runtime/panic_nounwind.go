@@ -0,0 +1,20 @@
+// +build !panic.unwind
+
+package runtime
+
+import "internal/task"
+
+// startUnwinding always returns false: without -panic=unwind, panics cannot be
+// recovered and always abort the program.
+//go:inline
+func startUnwinding(message interface{}) bool {
+	return false
+}
+
+//go:inline
+func saveUnwindState(t *task.Task) {
+}
+
+//go:inline
+func restoreUnwindState(t *task.Task) {
+}
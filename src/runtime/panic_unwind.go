@@ -0,0 +1,120 @@
+// +build panic.unwind
+
+package runtime
+
+// This file implements panic unwinding (-panic=unwind). A panic is propagated
+// by returning from each function on the stack until a function with deferred
+// calls is reached. See compiler/defer.go for the code the compiler emits to
+// make this work.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// deferFrame is allocated on the stack by every function that contains a defer
+// statement. These frames form a linked list of all functions on the stack of
+// the current goroutine that can run deferred calls while panicking.
+type deferFrame struct {
+	previous  *deferFrame
+	panicking bool        // set while the deferred calls of this frame handle a panic
+	value     interface{} // the panic value, if panicking
+}
+
+var (
+	// deferFrames is the innermost defer frame of the running goroutine.
+	deferFrames *deferFrame
+
+	// unwinding is set while a panic propagates to the nearest function with
+	// deferred calls. It is checked by the compiler after every instruction
+	// that may panic.
+	unwinding bool
+
+	// unwindValue is the panic value while unwinding.
+	unwindValue interface{}
+
+	// deferredCall is set right before a deferred function is called, and
+	// cleared at the start of every function. A function that calls
+	// recover() reads it first, to find out whether it was called directly
+	// by a deferred call. See compiler/defer.go.
+	deferredCall bool
+)
+
+// startUnwinding starts propagating a panic to the nearest function with
+// deferred calls. If there is no such function, the panic cannot be recovered
+// and false is returned.
+func startUnwinding(message interface{}) bool {
+	if deferFrames == nil {
+		return false
+	}
+	unwinding = true
+	unwindValue = message
+	return true
+}
+
+// setupDeferFrame is called at the start of each function that contains a
+// defer statement. The frame is not yet initialized at that point.
+func setupDeferFrame(frame *deferFrame) {
+	frame.previous = deferFrames
+	frame.panicking = false
+	frame.value = nil
+	deferFrames = frame
+}
+
+// enterLandingPad is called when a panic reaches a function with deferred
+// calls, right before these calls are run. The panic is moved into the defer
+// frame, so that the deferred calls can run normally and recover() can find
+// it. A panic in one of the deferred calls replaces the previous panic.
+func enterLandingPad(frame *deferFrame) {
+	frame.panicking = true
+	frame.value = unwindValue
+	unwinding = false
+	unwindValue = nil
+}
+
+// destroyDeferFrame is called right before a function with deferred calls
+// returns. If the panic handled by this frame was not recovered, it continues
+// to propagate to the caller.
+func destroyDeferFrame(frame *deferFrame) {
+	deferFrames = frame.previous
+	if frame.panicking {
+		_panic(frame.value)
+	}
+}
+
+// Try to recover a panicking goroutine. The frame is the defer frame that
+// called the function calling recover() as a deferred call, or nil if that
+// function was not called directly by a deferred call. In that case, recover()
+// returns nil as the Go spec requires.
+func _recover(frame *deferFrame) interface{} {
+	if frame == nil || !frame.panicking {
+		// Not panicking, so return a nil interface.
+		return nil
+	}
+	if _, ok := frame.value.(goexitPanic); ok {
+		// Goexit is running the deferred calls, which recover() can't stop.
+		return nil
+	}
+	// Only the first call to recover() returns the panic value. It also stops
+	// the panic.
+	value := frame.value
+	frame.panicking = false
+	frame.value = nil
+	return value
+}
+
+// saveUnwindState is called by the scheduler after a goroutine was paused. It
+// stores the defer frames of the goroutine so that they are not visible to
+// other goroutines. Goroutines are never paused while unwinding, so the panic
+// itself does not need to be stored.
+func saveUnwindState(t *task.Task) {
+	t.Unwind.DeferFrame = unsafe.Pointer(deferFrames)
+	deferFrames = nil
+}
+
+// restoreUnwindState is called by the scheduler right before a goroutine is
+// resumed.
+func restoreUnwindState(t *task.Task) {
+	deferFrames = (*deferFrame)(t.Unwind.DeferFrame)
+	t.Unwind.DeferFrame = nil
+}
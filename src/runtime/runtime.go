@@ -13,9 +13,15 @@ func initAll()
 //go:linkname callMain main.main
 func callMain()
 
+// layoutNoPointers is the object layout (see alloc) of an object that doesn't
+// contain any pointers, such as the bytes of a string. See
+// compiler/llvmutil/layout.go for how layouts are encoded.
+var layoutNoPointers = unsafe.Pointer(uintptr(3))
+
 func GOMAXPROCS(n int) int {
-	// Note: setting GOMAXPROCS is ignored.
-	return 1
+	// Note: setting GOMAXPROCS is ignored. It is the number of cores that run
+	// goroutines, which is only more than one with the cores scheduler.
+	return numCPU
 }
 
 func GOROOT() string {
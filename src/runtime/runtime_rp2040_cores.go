@@ -0,0 +1,255 @@
+// +build rp2040,scheduler.cores
+
+package runtime
+
+// This file implements the hardware specific parts of the cores scheduler on
+// the RP2040: the second core is started through the boot ROM, and the SIO
+// block provides the core number, hardware spinlocks and the inter-core FIFOs
+// that are used to stop the other core during a GC cycle.
+
+import (
+	"device/arm"
+	"device/rp"
+	"internal/task"
+	"runtime/interrupt"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// numCPU is the number of cores that run goroutines.
+const numCPU = 2
+
+// sioType is the part of the SIO block that is used for multicore support.
+type sioType struct {
+	cpuid    volatile.Register32     // 0x000
+	_        [19]volatile.Register32 // GPIO registers
+	fifoST   volatile.Register32     // 0x050
+	fifoWR   volatile.Register32     // 0x054
+	fifoRD   volatile.Register32     // 0x058
+	_        [41]volatile.Register32 // spinlock state, divider and interpolators
+	spinlock [32]volatile.Register32 // 0x100
+}
+
+var sio = (*sioType)(unsafe.Pointer(rp.SIO))
+
+// Bits in the FIFO_ST register.
+const (
+	sioFifoValid = 1 << 0 // the RX FIFO of this core is not empty
+	sioFifoReady = 1 << 1 // the TX FIFO of this core is not full
+)
+
+// currentCPU returns the number of the core this code is running on (0 or 1).
+func currentCPU() uint32 {
+	return sio.cpuid.Get()
+}
+
+// hardwareSpinlock is one of the 32 spinlocks in the SIO block.
+type hardwareSpinlock uint8
+
+// Hardware spinlocks used by the runtime. The Pico SDK leaves the spinlocks
+// starting at 24 unused, so these are less likely to conflict with C code.
+const (
+	schedulerSpinlock hardwareSpinlock = 24 // protects the scheduler state, see schedulerTryLock
+	runtimeSpinlock   hardwareSpinlock = 25 // protects the state of each spinLock
+)
+
+// lock waits until the spinlock can be claimed. Interrupts must be disabled,
+// or the lock might never be released.
+func (l hardwareSpinlock) lock() {
+	for !l.tryLock() {
+	}
+}
+
+// tryLock claims the spinlock if it is not already claimed, and reports whether
+// it succeeded. Interrupts must be disabled.
+func (l hardwareSpinlock) tryLock() bool {
+	// Reading the spinlock register claims the lock and returns a non-zero
+	// value, or returns zero when it is already claimed.
+	if sio.spinlock[l].Get() == 0 {
+		return false
+	}
+	// Make sure memory accesses protected by the lock happen after claiming
+	// it.
+	arm.Asm("dmb")
+	return true
+}
+
+// unlock releases the spinlock.
+func (l hardwareSpinlock) unlock() {
+	// Make sure memory accesses protected by the lock are finished before
+	// releasing it.
+	arm.Asm("dmb")
+	sio.spinlock[l].Set(0)
+}
+
+// wakeOtherCores wakes up the other cores when they're waiting for an event
+// (see waitForEvents).
+func wakeOtherCores() {
+	arm.Asm("sev")
+}
+
+// core1StackSize is the size of the system stack of the second core. It is
+// used by the scheduler and by interrupts that run on the second core.
+const core1StackSize = 2048
+
+// core1Stack is the system stack of the second core. It is declared as uint64
+// to align it to 8 bytes, as required by the ABI.
+var core1Stack [core1StackSize / 8]uint64
+
+// systemStackTop returns the top of the system stack of the given core.
+func systemStackTop(cpu uint32) uintptr {
+	if cpu == 0 {
+		return stackTop
+	}
+	return uintptr(unsafe.Pointer(&core1Stack)) + unsafe.Sizeof(core1Stack)
+}
+
+// core1Running is set by the second core once it can be stopped for a GC cycle.
+var core1Running uint32
+
+// startSecondaryCores starts the second core, using the boot ROM handshake
+// described in section 2.8.2 of the RP2040 datasheet.
+func startSecondaryCores() {
+	entry := core1Entry
+	commands := [...]uint32{
+		0,
+		0,
+		1,
+		arm.SCB.VTOR.Get(), // same vector table as this core
+		uint32(systemStackTop(1)),
+		uint32((*funcValue)(unsafe.Pointer(&entry)).id),
+	}
+	for i := 0; i < len(commands); {
+		command := commands[i]
+		if command == 0 {
+			// Drain the FIFO before sending a zero, and wake up the other
+			// core in case it is waiting for an event.
+			for sio.fifoST.Get()&sioFifoValid != 0 {
+				sio.fifoRD.Get()
+			}
+			arm.Asm("sev")
+		}
+		fifoPush(command)
+		if fifoPop() == command {
+			i++
+		} else {
+			// Something went wrong, start again.
+			i = 0
+		}
+	}
+
+	// The FIFO is now only used to stop this core for a GC cycle.
+	intr := interrupt.New(rp.IRQ_SIO_IRQ_PROC0, handleFifoInterrupt)
+	intr.SetPriority(0x00)
+	intr.Enable()
+}
+
+// core1Entry is the first Go code that runs on the second core.
+func core1Entry() {
+	intr := interrupt.New(rp.IRQ_SIO_IRQ_PROC1, handleFifoInterrupt)
+	intr.SetPriority(0x00)
+	intr.Enable()
+	volatile.StoreUint32(&core1Running, 1)
+
+	runScheduler()
+
+	// The program has exited.
+	abort()
+}
+
+// fifoPush sends a value to the other core, waiting until there is space in
+// the FIFO.
+func fifoPush(value uint32) {
+	for sio.fifoST.Get()&sioFifoReady == 0 {
+	}
+	sio.fifoWR.Set(value)
+	arm.Asm("sev")
+}
+
+// fifoPop receives a value from the other core, waiting until one arrives.
+func fifoPop() uint32 {
+	for sio.fifoST.Get()&sioFifoValid == 0 {
+		arm.Asm("wfe")
+	}
+	return sio.fifoRD.Get()
+}
+
+var (
+	// gcCPU is the core that is running a GC cycle.
+	gcCPU uint32
+
+	// gcStopping is set while a GC cycle is running on gcCPU.
+	gcStopping uint32
+
+	// gcStackPointers contains the stack pointer of each stopped core, or 0
+	// if the core is not (yet) stopped.
+	gcStackPointers [numCPU]uint32
+
+	// gcSchedulerState is the interrupt state returned by task.Lock in
+	// gcStopTheWorld.
+	gcSchedulerState interrupt.State
+)
+
+// handleFifoInterrupt is called when the other core sends a value through the
+// FIFO. This only happens when the other core wants to run a GC cycle.
+func handleFifoInterrupt(intr interrupt.Interrupt) {
+	for sio.fifoST.Get()&sioFifoValid != 0 {
+		sio.fifoRD.Get()
+	}
+	// Clear the error flags, which would otherwise keep the interrupt active.
+	sio.fifoST.Set(0xff)
+
+	// Store all registers on the stack, and wait until the GC cycle has
+	// finished. See scanstack.
+	scanCurrentStack()
+}
+
+// gcStopTheWorld stops the other core before a GC cycle, so that it doesn't
+// modify the heap while it is scanned. The caller must hold gcLock.
+//
+// The scheduler lock is held until gcStartTheWorld, so that the other core is
+// never stopped while it holds this lock (the GC needs it to scan the
+// runqueue). Because task.Lock enables interrupts while waiting for the lock,
+// the other core can always be stopped. This means that gcLock must never be
+// acquired while holding the scheduler lock, or both cores would wait for each
+// other.
+func gcStopTheWorld() {
+	gcSchedulerState = task.Lock()
+	cpu := currentCPU()
+	gcCPU = cpu
+	if volatile.LoadUint32(&core1Running) == 0 {
+		// The second core hasn't been started yet.
+		return
+	}
+	other := 1 - cpu
+	volatile.StoreUint32(&gcStopping, 1)
+	fifoPush(0)
+	for volatile.LoadUint32(&gcStackPointers[other]) == 0 {
+	}
+}
+
+// gcStartTheWorld resumes the core stopped by gcStopTheWorld.
+func gcStartTheWorld() {
+	if volatile.LoadUint32(&core1Running) != 0 {
+		other := 1 - currentCPU()
+		volatile.StoreUint32(&gcStopping, 0)
+		for volatile.LoadUint32(&gcStackPointers[other]) != 0 {
+		}
+	}
+	task.Unlock(gcSchedulerState)
+}
+
+// gcWaitUntilStarted is called on a core stopped by gcStopTheWorld, with all
+// its registers stored on the stack. It waits until gcStartTheWorld is called.
+func gcWaitUntilStarted(cpu uint32, sp uintptr) {
+	volatile.StoreUint32(&gcStackPointers[cpu], uint32(sp))
+	for volatile.LoadUint32(&gcStopping) != 0 {
+	}
+	volatile.StoreUint32(&gcStackPointers[cpu], 0)
+}
+
+// gcStoppedStackPointer returns the stack pointer of a core that was stopped
+// by gcStopTheWorld, or 0 if it isn't stopped.
+func gcStoppedStackPointer(cpu uint32) uintptr {
+	return uintptr(volatile.LoadUint32(&gcStackPointers[cpu]))
+}
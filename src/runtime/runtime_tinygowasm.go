@@ -57,6 +57,7 @@ func abort() {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	printMemStats()
 	proc_exit(uint32(code))
 }
 
@@ -119,6 +119,15 @@ func syscall_runtime_envs() []string {
 	return envs
 }
 
+// The syscall package keeps its own copy of the environment, so there is
+// nothing to update when it changes.
+
+//go:linkname syscall_runtimeSetenv syscall.runtimeSetenv
+func syscall_runtimeSetenv(key, value string) {}
+
+//go:linkname syscall_runtimeUnsetenv syscall.runtimeUnsetenv
+func syscall_runtimeUnsetenv(key string) {}
+
 func putchar(c byte) {
 	_putchar(int(c))
 }
@@ -153,6 +162,7 @@ func ticks() timeUnit {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
+	printMemStats()
 	exit(code)
 }
 
@@ -20,6 +20,15 @@ func syscall_runtime_envs() []string {
 	return nil
 }
 
+// The syscall package keeps its own copy of the environment, so there is
+// nothing to update when it changes.
+
+//go:linkname syscall_runtimeSetenv syscall.runtimeSetenv
+func syscall_runtimeSetenv(key, value string) {}
+
+//go:linkname syscall_runtimeUnsetenv syscall.runtimeUnsetenv
+func syscall_runtimeUnsetenv(key string) {}
+
 var handleEvent func()
 
 //go:linkname setEventHandler syscall/js.setEventHandler
@@ -32,12 +41,12 @@ func resume() {
 	go func() {
 		handleEvent()
 	}()
-	scheduler()
+	scheduler(false)
 }
 
 //export go_scheduler
 func go_scheduler() {
-	scheduler()
+	scheduler(false)
 }
 
 func ticksToNanoseconds(ticks timeUnit) int64 {
@@ -3,6 +3,7 @@
 package runtime
 
 import (
+	"internal/task"
 	"unsafe"
 )
 
@@ -12,15 +13,6 @@ type timeUnit int64
 //export __wasm_call_ctors
 func __wasm_call_ctors()
 
-//export _start
-func _start() {
-	// These need to be initialized early so that the heap can be initialized.
-	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
-	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
-	__wasm_call_ctors()
-	run()
-}
-
 // Read the command line arguments from WASI.
 // For example, they can be passed to a program with wasmtime like this:
 //
@@ -79,10 +71,158 @@ var (
 )
 
 func sleepTicks(d timeUnit) {
+	if fdQueue != nil {
+		// Also wake up when one of the file descriptors is ready.
+		pollFDs(true, d)
+		return
+	}
 	sleepTicksSubscription.u.u.timeout = uint64(d)
 	poll_oneoff(&sleepTicksSubscription, &sleepTicksResult, 1, &sleepTicksNEvents)
 }
 
+// Goroutines that wait until a file descriptor is ready for reading or
+// writing. They are linked through task.Next, and task.Data contains the file
+// descriptor and event type (see fdWaitData). The queue is only checked when
+// no other goroutine can run, from sleepTicks or waitForEvents.
+var (
+	fdQueue *task.Task
+
+	// Buffers for poll_oneoff, with room for the clock subscription and one
+	// subscription per waiting goroutine. They are allocated in waitFD, as the
+	// scheduler should not allocate memory.
+	pollSubscriptions []__wasi_subscription_t
+	pollEvents        []__wasi_event_t
+)
+
+func fdWaitData(fd int32, write bool) uint64 {
+	data := uint64(uint32(fd)) << 1
+	if write {
+		data |= 1
+	}
+	return data
+}
+
+// Wait until the given file descriptor is ready for reading or writing, so
+// that a read or write call doesn't block the whole program. It returns
+// immediately if the file descriptor is already ready (which is always the
+// case for regular files), and otherwise pauses the current goroutine until
+// the scheduler finds it ready.
+//go:linkname syscall_waitFD syscall.runtime_waitFD
+func syscall_waitFD(fd int32, write bool) {
+	if !hasScheduler {
+		// Nothing else can run while waiting, so just block until the file
+		// descriptor is ready.
+		var sub __wasi_subscription_t
+		var event __wasi_event_t
+		var nevents uint32
+		setFDSubscription(&sub, 0, fdWaitData(fd, write))
+		poll_oneoff(&sub, &event, 1, &nevents)
+		return
+	}
+	t := task.Current()
+	n := 2 // clock and this file descriptor
+	for q := fdQueue; q != nil; q = q.Next {
+		n++
+	}
+	if len(pollSubscriptions) < n {
+		pollSubscriptions = make([]__wasi_subscription_t, n*2)
+		pollEvents = make([]__wasi_event_t, n*2)
+	}
+
+	// Check whether the file descriptor is ready without blocking.
+	var nevents uint32
+	pollSubscriptions[0] = __wasi_subscription_t{
+		userData: 0,
+		u: __wasi_subscription_u_t{
+			tag: __wasi_eventtype_t_clock,
+			u: __wasi_subscription_clock_t{
+				timeout:   0,
+				precision: timePrecisionNanoseconds,
+			},
+		},
+	}
+	setFDSubscription(&pollSubscriptions[1], uint64(uintptr(unsafe.Pointer(t))), fdWaitData(fd, write))
+	poll_oneoff(&pollSubscriptions[0], &pollEvents[0], 2, &nevents)
+	for _, event := range pollEvents[:nevents] {
+		if event.eventType != __wasi_eventtype_t_clock {
+			return
+		}
+	}
+
+	// Not ready, so wait for the scheduler to wake this goroutine.
+	i := task.Lock()
+	t.Data = fdWaitData(fd, write)
+	t.Next = fdQueue
+	fdQueue = t
+	task.PauseLocked(i)
+}
+
+// setFDSubscription fills in a fd_read or fd_write subscription for the file
+// descriptor and event type in data (see fdWaitData).
+func setFDSubscription(sub *__wasi_subscription_t, userData, data uint64) {
+	sub.userData = userData
+	sub.u.tag = __wasi_eventtype_t_fd_read
+	if data&1 != 0 {
+		sub.u.tag = __wasi_eventtype_t_fd_write
+	}
+	// The subscription_fd_readwrite record only contains the file descriptor,
+	// at the same offset as the clock id.
+	sub.u.u = __wasi_subscription_clock_t{id: uint32(data >> 1)}
+}
+
+// pollFDs waits until at least one of the goroutines in fdQueue can continue,
+// and moves those goroutines to the runqueue. If hasTimeout is set, it returns
+// after the given time even if no file descriptor is ready.
+func pollFDs(hasTimeout bool, timeout timeUnit) {
+	n := 0
+	if hasTimeout {
+		pollSubscriptions[n] = __wasi_subscription_t{
+			userData: 0,
+			u: __wasi_subscription_u_t{
+				tag: __wasi_eventtype_t_clock,
+				u: __wasi_subscription_clock_t{
+					timeout:   uint64(timeout),
+					precision: timePrecisionNanoseconds,
+				},
+			},
+		}
+		n++
+	}
+	for t := fdQueue; t != nil; t = t.Next {
+		setFDSubscription(&pollSubscriptions[n], uint64(uintptr(unsafe.Pointer(t))), t.Data)
+		n++
+	}
+	var nevents uint32
+	errno := poll_oneoff(&pollSubscriptions[0], &pollEvents[0], uint32(n), &nevents)
+	if errno != 0 {
+		runtimePanic("poll_oneoff failed")
+	}
+
+	// Wake the goroutines whose file descriptor is ready. This includes
+	// events with an error, so that the read or write call returns it.
+	for _, event := range pollEvents[:nevents] {
+		if event.eventType == __wasi_eventtype_t_clock {
+			continue
+		}
+		for q := &fdQueue; *q != nil; q = &(*q).Next {
+			t := *q
+			if uint64(uintptr(unsafe.Pointer(t))) == event.userData {
+				*q = t.Next
+				t.Next = nil
+				runqueue.Push(t)
+				break
+			}
+		}
+	}
+}
+
+func waitForEvents() {
+	if fdQueue == nil {
+		runtimePanic("deadlocked: no event source")
+	}
+	pollFDs(false, 0)
+}
+
 func ticks() timeUnit {
 	var nano uint64
 	clock_time_get(0, timePrecisionNanoseconds, &nano)
@@ -110,9 +250,9 @@ func poll_oneoff(in *__wasi_subscription_t, out *__wasi_event_t, nsubscriptions
 type __wasi_eventtype_t = uint8
 
 const (
-	__wasi_eventtype_t_clock __wasi_eventtype_t = 0
-	// TODO: __wasi_eventtype_t_fd_read  __wasi_eventtype_t = 1
-	// TODO: __wasi_eventtype_t_fd_write __wasi_eventtype_t = 2
+	__wasi_eventtype_t_clock    __wasi_eventtype_t = 0
+	__wasi_eventtype_t_fd_read  __wasi_eventtype_t = 1
+	__wasi_eventtype_t_fd_write __wasi_eventtype_t = 2
 )
 
 type (
@@ -125,7 +265,8 @@ type (
 	__wasi_subscription_u_t struct {
 		tag __wasi_eventtype_t
 
-		// TODO: support fd_read/fd_write event
+		// This is a union: for fd_read and fd_write events, only the first
+		// field (the file descriptor) is used. See setFDSubscription.
 		u __wasi_subscription_clock_t
 	}
 
@@ -146,7 +287,6 @@ type (
 		eventType __wasi_eventtype_t
 
 		// only used for fd_read or fd_write events
-		// TODO: support fd_read/fd_write event
 		_ struct {
 			nBytes uint64
 			flags  uint16
@@ -0,0 +1,16 @@
+// +build tinygo.wasm,wasi,!tinygo.wasm.reactor
+
+package runtime
+
+import "unsafe"
+
+// Entry point of a WASI command: initialize all packages, call main.main and
+// exit.
+//export _start
+func _start() {
+	// These need to be initialized early so that the heap can be initialized.
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+	__wasm_call_ctors()
+	run()
+}
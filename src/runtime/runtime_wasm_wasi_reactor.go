@@ -0,0 +1,54 @@
+// +build tinygo.wasm,wasi,tinygo.wasm.reactor
+
+package runtime
+
+// This file implements WASI reactors (-buildmode=c-shared): modules that are
+// initialized once, after which the host calls exported functions as often as
+// it likes. The main function is never called.
+//
+// Goroutines keep running between calls: the compiler inserts a call to
+// wasmExportExit at the end of every exported function, which runs all
+// goroutines that can run before returning to the host. Goroutines that sleep
+// or wait for a file descriptor continue during a later call. Exported
+// functions themselves cannot block: this is reported by the compiler.
+
+import "unsafe"
+
+var (
+	// Set once _initialize has been called.
+	reactorInitialized bool
+
+	// Set while goroutines are running, so that exported functions called
+	// from a goroutine (through the host) don't start the scheduler again.
+	reactorScheduling bool
+)
+
+// Entry point of a WASI reactor: initialize all packages. It must be called
+// by the host before any other exported function, and does nothing when
+// called again.
+//export _initialize
+func _initialize() {
+	if reactorInitialized {
+		return
+	}
+	reactorInitialized = true
+
+	// These need to be initialized early so that the heap can be initialized.
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+	__wasm_call_ctors()
+	reactorScheduling = true
+	runInit()
+	reactorScheduling = false
+}
+
+// wasmExportExit is called by the compiler at the end of every exported
+// function (outside the runtime) to run the goroutines it started or woke up.
+func wasmExportExit() {
+	if reactorScheduling {
+		return
+	}
+	reactorScheduling = true
+	runScheduled()
+	reactorScheduling = false
+}
@@ -13,6 +13,9 @@ package runtime
 // to the bottom of the stack where some important fields are kept. In the case
 // of the coroutine-based scheduler, it is the coroutine pointer (a *i8 in
 // LLVM).
+//
+// The scheduler loop itself is implemented in scheduler_singlecore.go, or in
+// scheduler_cores.go when goroutines run on multiple cores at the same time.
 
 import (
 	"internal/task"
@@ -68,19 +71,21 @@ func deadlock() {
 
 // Goexit terminates the currently running goroutine. No other goroutines are affected.
 //
-// Unlike the main Go implementation, no deffered calls will be run.
+// Deferred calls are only run with -panic=unwind. Without it, unlike the main
+// Go implementation, no deferred calls will be run.
 //go:inline
 func Goexit() {
+	if startUnwinding(goexitPanic{}) {
+		// The deferred calls run while unwinding, like for a panic. The
+		// goroutine stops after the last of them, see _panic.
+		return
+	}
 	// its really just a deadlock
 	deadlock()
 }
 
-// Add this task to the end of the run queue.
-func runqueuePushBack(t *task.Task) {
-	runqueue.Push(t)
-}
-
 // Add this task to the sleep queue, assuming its state is set to sleeping.
+// The scheduler lock must be held, see task.Lock.
 func addSleepTask(t *task.Task, duration timeUnit) {
 	if schedulerDebug {
 		println("  set sleep:", t, duration)
@@ -116,63 +121,8 @@ func addSleepTask(t *task.Task, duration timeUnit) {
 	*q = t
 }
 
-// Run the scheduler until all tasks have finished.
-func scheduler() {
-	// Main scheduler loop.
-	var now timeUnit
-	for !schedulerDone {
-		scheduleLog("")
-		scheduleLog("  schedule")
-		if sleepQueue != nil {
-			now = ticks()
-		}
-
-		// Add tasks that are done sleeping to the end of the runqueue so they
-		// will be executed soon.
-		if sleepQueue != nil && now-sleepQueueBaseTime >= timeUnit(sleepQueue.Data) {
-			t := sleepQueue
-			scheduleLogTask("  awake:", t)
-			sleepQueueBaseTime += timeUnit(t.Data)
-			sleepQueue = t.Next
-			t.Next = nil
-			runqueue.Push(t)
-		}
-
-		t := runqueue.Pop()
-		if t == nil {
-			if sleepQueue == nil {
-				if asyncScheduler {
-					// JavaScript is treated specially, see below.
-					return
-				}
-				waitForEvents()
-				continue
-			}
-			timeLeft := timeUnit(sleepQueue.Data) - (now - sleepQueueBaseTime)
-			if schedulerDebug {
-				println("  sleeping...", sleepQueue, uint(timeLeft))
-				for t := sleepQueue; t != nil; t = t.Next {
-					println("    task sleeping:", t, timeUnit(t.Data))
-				}
-			}
-			sleepTicks(timeLeft)
-			if asyncScheduler {
-				// The sleepTicks function above only sets a timeout at which
-				// point the scheduler will be called again. It does not really
-				// sleep. So instead of sleeping, we return and expect to be
-				// called again.
-				break
-			}
-			continue
-		}
-
-		// Run the given task.
-		scheduleLogTask("  run:", t)
-		t.Resume()
-	}
-}
-
 func Gosched() {
+	i := task.Lock()
 	runqueue.Push(task.Current())
-	task.Pause()
+	task.PauseLocked(i)
 }
@@ -7,8 +7,9 @@ import "internal/task"
 // Pause the current task for a given time.
 //go:linkname sleep time.Sleep
 func sleep(duration int64) {
+	i := task.Lock()
 	addSleepTask(task.Current(), nanosecondsToTicks(duration))
-	task.Pause()
+	task.PauseLocked(i)
 }
 
 // run is called by the program entry point to execute the go program.
@@ -19,9 +20,30 @@ func run() {
 		initAll()
 		postinit()
 		callMain()
+		printMemStats()
 		schedulerDone = true
 	}()
-	scheduler()
+	scheduler(false)
+}
+
+// runInit is called by the entry point of a WASI reactor. It initializes all
+// packages without calling the main function. Goroutines that are still
+// running afterwards continue when runScheduled is called.
+func runInit() {
+	initHeap()
+	go func() {
+		initAll()
+		postinit()
+		schedulerDone = true
+	}()
+	scheduler(false)
+	schedulerDone = false
+}
+
+// runScheduled runs goroutines until none of them can run right now, without
+// waiting for sleeping or blocked goroutines.
+func runScheduled() {
+	scheduler(true)
 }
 
 const hasScheduler = true
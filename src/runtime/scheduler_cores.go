@@ -0,0 +1,152 @@
+// +build scheduler.cores
+
+package runtime
+
+// This file implements the scheduler loop for the "cores" scheduler, which runs
+// goroutines on all cores at the same time. Goroutines are still never
+// preempted: each core runs a goroutine until it pauses, and then picks the
+// next one from the shared runqueue.
+//
+// All scheduler state (the runqueue, the sleep queue, channels, etc) is
+// protected by a single recursive lock, see task.Lock. A goroutine that pauses
+// keeps holding this lock until it has switched back to the scheduler, so that
+// another core can't resume it while it is still running.
+//
+// The hardware specific parts (starting cores, hardware spinlocks, stopping
+// cores for a GC cycle) are implemented in runtime_rp2040_cores.go.
+
+import (
+	"internal/task"
+	"runtime/interrupt"
+)
+
+// Add this task to the end of the run queue, and wake up any cores that are
+// waiting for a task to run.
+func runqueuePushBack(t *task.Task) {
+	runqueue.Push(t)
+	wakeOtherCores()
+}
+
+// The scheduler lock is recursive: it may be acquired again by the core that
+// already holds it. These variables are only modified with interrupts
+// disabled, by the core that holds the hardware spinlock.
+var (
+	schedulerLockCore  uint32 // the core that holds the lock, plus one (0 means unlocked)
+	schedulerLockDepth uint32 // how often the lock has been acquired by this core
+)
+
+// schedulerTryLock tries to acquire the scheduler lock and reports whether it
+// succeeded. It is called from task.Lock, with interrupts disabled.
+func schedulerTryLock() bool {
+	core := currentCPU() + 1
+	if schedulerLockCore == core {
+		// Only this core can store its own number here, so it already holds
+		// the lock.
+		schedulerLockDepth++
+		return true
+	}
+	if !schedulerSpinlock.tryLock() {
+		return false
+	}
+	schedulerLockCore = core
+	schedulerLockDepth = 1
+	return true
+}
+
+// schedulerUnlock releases the scheduler lock. It is called from task.Unlock,
+// with interrupts disabled.
+func schedulerUnlock() {
+	schedulerLockDepth--
+	if schedulerLockDepth == 0 {
+		schedulerLockCore = 0
+		schedulerSpinlock.unlock()
+	}
+}
+
+// spinLock is a lock for runtime state that is shared between cores, such as
+// the heap. Unlike the scheduler lock, interrupts remain enabled while waiting
+// for the lock. It is not recursive.
+type spinLock struct {
+	locked bool
+}
+
+func (l *spinLock) Lock() {
+	for {
+		i := interrupt.Disable()
+		runtimeSpinlock.lock()
+		wasLocked := l.locked
+		l.locked = true
+		runtimeSpinlock.unlock()
+		interrupt.Restore(i)
+		if !wasLocked {
+			return
+		}
+	}
+}
+
+func (l *spinLock) Unlock() {
+	i := interrupt.Disable()
+	runtimeSpinlock.lock()
+	l.locked = false
+	runtimeSpinlock.unlock()
+	interrupt.Restore(i)
+}
+
+// Run the scheduler until all tasks have finished. This starts the other cores,
+// which run the scheduler as well. Returning when no goroutine can run
+// (returnAtDeadlock) is not supported.
+func scheduler(returnAtDeadlock bool) {
+	startSecondaryCores()
+	runScheduler()
+}
+
+// runScheduler is the scheduler loop that runs on every core.
+func runScheduler() {
+	for !schedulerDone {
+		scheduleLog("")
+		scheduleLog("  schedule")
+		i := task.Lock()
+
+		// Add tasks that are done sleeping to the end of the runqueue so they
+		// will be executed soon.
+		var now timeUnit
+		if sleepQueue != nil {
+			now = ticks()
+			if now-sleepQueueBaseTime >= timeUnit(sleepQueue.Data) {
+				t := sleepQueue
+				scheduleLogTask("  awake:", t)
+				sleepQueueBaseTime += timeUnit(t.Data)
+				sleepQueue = t.Next
+				t.Next = nil
+				runqueue.Push(t)
+			}
+		}
+
+		t := runqueue.Pop()
+		if t == nil {
+			if sleepQueue == nil {
+				task.Unlock(i)
+				waitForEvents()
+				continue
+			}
+			timeLeft := timeUnit(sleepQueue.Data) - (now - sleepQueueBaseTime)
+			task.Unlock(i)
+
+			// Wait until the first sleeping task should be woken up, unless
+			// another core makes a task runnable in the meantime.
+			for ticks()-now < timeLeft && runqueue.Empty() {
+			}
+			continue
+		}
+		task.Unlock(i)
+
+		// Run the given task. It returns while holding the scheduler lock,
+		// which can be released now that the task is no longer running.
+		scheduleLogTask("  run:", t)
+		t.Resume()
+		task.Unlock(i)
+	}
+
+	// Make sure the other cores notice that the program has exited.
+	wakeOtherCores()
+}
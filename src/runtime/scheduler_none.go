@@ -20,6 +20,18 @@ func run() {
 	initAll()
 	postinit()
 	callMain()
+	printMemStats()
 }
 
+// runInit is called by the entry point of a WASI reactor. It initializes all
+// packages without calling the main function.
+func runInit() {
+	initHeap()
+	initAll()
+	postinit()
+}
+
+// runScheduled does nothing, as there are no goroutines to run.
+func runScheduled() {}
+
 const hasScheduler = false
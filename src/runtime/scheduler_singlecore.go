@@ -0,0 +1,96 @@
+// +build !scheduler.cores
+
+package runtime
+
+// This file implements the scheduler loop for when goroutines only run on a
+// single core, which is the case for all schedulers except "cores".
+
+import "internal/task"
+
+// numCPU is the number of cores that run goroutines.
+const numCPU = 1
+
+// Add this task to the end of the run queue.
+func runqueuePushBack(t *task.Task) {
+	runqueue.Push(t)
+}
+
+// Run the scheduler until all tasks have finished. If returnAtDeadlock is set,
+// it also returns when no goroutine can run right now (because they are all
+// sleeping or blocked), instead of waiting for one to become runnable.
+func scheduler(returnAtDeadlock bool) {
+	// Main scheduler loop.
+	var now timeUnit
+	for !schedulerDone {
+		scheduleLog("")
+		scheduleLog("  schedule")
+		if sleepQueue != nil {
+			now = ticks()
+		}
+
+		// Add tasks that are done sleeping to the end of the runqueue so they
+		// will be executed soon.
+		if sleepQueue != nil && now-sleepQueueBaseTime >= timeUnit(sleepQueue.Data) {
+			t := sleepQueue
+			scheduleLogTask("  awake:", t)
+			sleepQueueBaseTime += timeUnit(t.Data)
+			sleepQueue = t.Next
+			t.Next = nil
+			runqueue.Push(t)
+		}
+
+		t := runqueue.Pop()
+		if t == nil {
+			if returnAtDeadlock {
+				return
+			}
+			if sleepQueue == nil {
+				if asyncScheduler {
+					// JavaScript is treated specially, see below.
+					return
+				}
+				waitForEvents()
+				continue
+			}
+			timeLeft := timeUnit(sleepQueue.Data) - (now - sleepQueueBaseTime)
+			if schedulerDebug {
+				println("  sleeping...", sleepQueue, uint(timeLeft))
+				for t := sleepQueue; t != nil; t = t.Next {
+					println("    task sleeping:", t, timeUnit(t.Data))
+				}
+			}
+			sleepTicks(timeLeft)
+			if asyncScheduler {
+				// The sleepTicks function above only sets a timeout at which
+				// point the scheduler will be called again. It does not really
+				// sleep. So instead of sleeping, we return and expect to be
+				// called again.
+				break
+			}
+			continue
+		}
+
+		// Run the given task.
+		scheduleLogTask("  run:", t)
+		restoreUnwindState(t)
+		restoreCallStack(t)
+		t.Resume()
+		saveCallStack(t)
+		saveUnwindState(t)
+	}
+}
+
+// spinLock is a lock that is only necessary when running on multiple cores at
+// the same time. With a single core, it does nothing.
+type spinLock struct{}
+
+func (l *spinLock) Lock() {}
+
+func (l *spinLock) Unlock() {}
+
+// gcStopTheWorld stops all other cores before a garbage collection cycle. There
+// are no other cores, so this does nothing.
+func gcStopTheWorld() {}
+
+// gcStartTheWorld resumes the cores stopped by gcStopTheWorld.
+func gcStartTheWorld() {}
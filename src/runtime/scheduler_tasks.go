@@ -1,4 +1,4 @@
-// +build scheduler.tasks
+// +build scheduler.tasks scheduler.cores
 
 package runtime
 
@@ -7,8 +7,9 @@ import (
 )
 
 // Builtin append(src, elements...) function: append elements to src and return
-// the modified (possibly expanded) slice.
-func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen uintptr, elemSize uintptr) (unsafe.Pointer, uintptr, uintptr) {
+// the modified (possibly expanded) slice. The layout is the object layout (see
+// alloc) of a single element, or nil if it is unknown.
+func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen uintptr, elemSize uintptr, layout unsafe.Pointer) (unsafe.Pointer, uintptr, uintptr) {
 	if elemsLen == 0 {
 		// Nothing to append, return the input slice.
 		return srcBuf, srcLen, srcCap
@@ -27,7 +28,7 @@ func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen uintp
 			// programs).
 			srcCap *= 2
 		}
-		buf := alloc(srcCap * elemSize)
+		buf := alloc(srcCap*elemSize, layout)
 
 		// Copy the old slice to the new slice.
 		if srcLen != 0 {
@@ -1,20 +1,121 @@
 package runtime
 
+import "internal/task"
+
+// Func describes a single function, for use in stack traces.
 type Func struct {
+	name string
+	file string
 }
 
+// FuncForPC returns the function that contains the given pc, or nil if the pc
+// is not known. Only pc values returned by Callers are known.
 func FuncForPC(pc uintptr) *Func {
-	return nil
+	site := findCallSite(pc)
+	if site == nil {
+		return nil
+	}
+	return site.function
 }
 
+// Name returns the name of the function, or an empty string if f is nil.
 func (f *Func) Name() string {
-	return ""
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
+// FileLine returns the file name and line number of the given pc, which must
+// be part of this function.
+func (f *Func) FileLine(pc uintptr) (file string, line int) {
+	if site := findCallSite(pc); site != nil && site.function == f {
+		return f.file, site.line
+	}
+	return f.file, 0
 }
 
 func Caller(skip int) (pc uintptr, file string, line int, ok bool) {
-	return 0, "", 0, false
+	// The first call site is the one in Caller itself.
+	var pcs [1]uintptr
+	if callers(skip+1, pcs[:]) == 0 {
+		return 0, "", 0, false
+	}
+	site := findCallSite(pcs[0])
+	return pcs[0], site.function.file, site.line, true
 }
 
+// Stack formats a stack trace of the calling goroutine into buf and returns
+// the number of bytes written to buf. If all is true, Stack formats the stack
+// traces of the goroutines that are waiting to run or sleeping after it.
+// Goroutines that are blocked, for example on a channel, are not known to the
+// scheduler and are not included.
 func Stack(buf []byte, all bool) int {
-	return 0
+	n := copy(buf, "goroutine [running]:\n")
+	var pcs [16]uintptr
+	for skip := 1; ; skip += len(pcs) {
+		// Skip the call site in Stack itself.
+		count := callers(skip, pcs[:])
+		n += formatStack(buf[n:], pcs[:count])
+		if count < len(pcs) {
+			break
+		}
+	}
+	if all {
+		for t := runqueue.Front(); t != nil; t = t.Next {
+			n += formatTaskStack(buf[n:], t, "runnable")
+		}
+		for t := sleepQueue; t != nil; t = t.Next {
+			n += formatTaskStack(buf[n:], t, "sleep")
+		}
+	}
+	return n
+}
+
+// formatTaskStack formats the header and stack trace of a goroutine that is
+// not running into buf, and returns the number of bytes written.
+func formatTaskStack(buf []byte, t *task.Task, status string) int {
+	n := copy(buf, "\ngoroutine [")
+	n += copy(buf[n:], status)
+	n += copy(buf[n:], "]:\n")
+	var pcs [16]uintptr
+	for skip := 0; ; skip += len(pcs) {
+		count := taskCallers(t, skip, pcs[:])
+		n += formatStack(buf[n:], pcs[:count])
+		if count < len(pcs) {
+			break
+		}
+	}
+	return n
+}
+
+// formatStack formats the given call sites into buf, and returns the number
+// of bytes written.
+func formatStack(buf []byte, pcs []uintptr) int {
+	n := 0
+	for _, pc := range pcs {
+		site := findCallSite(pc)
+		n += copy(buf[n:], site.function.name)
+		n += copy(buf[n:], "()\n\t")
+		n += copy(buf[n:], site.function.file)
+		n += copy(buf[n:], ":")
+		n += copy(buf[n:], itoa(site.line))
+		n += copy(buf[n:], "\n")
+	}
+	return n
+}
+
+// itoa converts a non-negative integer to a decimal string.
+func itoa(n int) string {
+	var buf [20]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+		if n == 0 {
+			break
+		}
+	}
+	return string(buf[i:])
 }
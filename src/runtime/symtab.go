@@ -1,10 +1,76 @@
 package runtime
 
+// This file implements stack traces, using the call site records emitted by
+// the compiler (see compiler/stacktrace.go). How the call sites of the current
+// call stack are found depends on the target, see symtab_framepointer.go and
+// symtab_callframes.go. Stack traces are only available on hosted and
+// WebAssembly targets when debug information is enabled: otherwise the call
+// stack is always empty.
+
+import (
+	"unsafe"
+)
+
+// callSite is a constant record emitted by the compiler for a call in a
+// function. The pc values returned by Callers are the addresses of these
+// records.
+type callSite struct {
+	function *Func // nil if the function has no debug information
+	line     int
+	parent   *callSite // call site of the function this call was inlined into
+}
+
+// The call site table is created by the compiler after linking.
+
+//go:extern runtime.callSitesStart
+var callSitesStart uintptr
+
+//go:extern runtime.callSitesLength
+var callSitesLength uintptr
+
+// findCallSite returns the call site record for the given pc, or nil if it
+// doesn't refer to a call site.
+func findCallSite(pc uintptr) *callSite {
+	size := unsafe.Sizeof(callSite{})
+	if pc < callSitesStart || pc >= callSitesStart+callSitesLength*size || (pc-callSitesStart)%size != 0 {
+		return nil
+	}
+	return (*callSite)(unsafe.Pointer(pc))
+}
+
+// printStackTrace prints a Go-style backtrace of the calling goroutine,
+// starting at the caller of printStackTrace. It prints nothing if no call
+// sites are known.
+func printStackTrace() {
+	var pcs [16]uintptr
+	for skip := 1; ; skip += len(pcs) {
+		n := callers(skip, pcs[:])
+		if skip == 1 && n != 0 {
+			printstring("\ngoroutine [running]:\n")
+		}
+		for _, pc := range pcs[:n] {
+			site := findCallSite(pc)
+			printstring(site.function.name)
+			printstring("()\n\t")
+			printstring(site.function.file)
+			putchar(':')
+			printint64(int64(site.line))
+			printnl()
+		}
+		if n < len(pcs) {
+			break
+		}
+	}
+}
+
 type Frames struct {
-	//
+	callers []uintptr
 }
 
 type Frame struct {
+	PC uintptr
+
+	Func     *Func
 	Function string
 
 	File string
@@ -12,9 +78,20 @@ type Frame struct {
 }
 
 func CallersFrames(callers []uintptr) *Frames {
-	return nil
+	return &Frames{callers: callers}
 }
 
 func (ci *Frames) Next() (frame Frame, more bool) {
-	return Frame{}, false
+	if len(ci.callers) == 0 {
+		return Frame{}, false
+	}
+	frame.PC = ci.callers[0]
+	ci.callers = ci.callers[1:]
+	if site := findCallSite(frame.PC); site != nil {
+		frame.Func = site.function
+		frame.Function = site.function.name
+		frame.File = site.function.file
+		frame.Line = site.line
+	}
+	return frame, len(ci.callers) != 0
 }
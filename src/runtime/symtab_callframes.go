@@ -0,0 +1,78 @@
+// +build baremetal tinygo.wasm windows
+
+package runtime
+
+// This file finds the call sites of a goroutine with a shadow call stack
+// maintained by the compiler. It is used on WebAssembly, where the call stack
+// cannot be inspected, and on Windows, where frame pointers do not form a
+// linked list. On baremetal targets, stack traces are disabled and the call
+// stack is always empty.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// callFrame is pushed by every function on entry when stack traces are
+// enabled, and popped again on return.
+type callFrame struct {
+	parent *callFrame
+	pc     uintptr // address of the callSite that is currently executing
+}
+
+// callStack is the innermost call frame of the currently running goroutine.
+var callStack *callFrame
+
+// schedulerCallStack is the call stack of the scheduler while a goroutine is
+// running.
+var schedulerCallStack *callFrame
+
+// callers stores the call sites of the calling goroutine in pcs, starting at
+// the call site in the caller of callers after skipping skip call sites. It
+// returns the number of entries written to pcs.
+func callers(skip int, pcs []uintptr) int {
+	if callStack == nil {
+		return 0
+	}
+	// The innermost call frame is the one of callers itself.
+	return walkCallStack(callStack.parent, skip, pcs)
+}
+
+// taskCallers is like callers, but for a goroutine that is not running.
+func taskCallers(t *task.Task, skip int, pcs []uintptr) int {
+	return walkCallStack((*callFrame)(t.CallStack), skip, pcs)
+}
+
+// walkCallStack stores the pc of each call frame starting at the given frame
+// in pcs, after skipping the first skip frames. It returns the number of
+// entries written to pcs.
+func walkCallStack(frame *callFrame, skip int, pcs []uintptr) int {
+	n := 0
+	for ; frame != nil && n < len(pcs); frame = frame.parent {
+		if frame.pc == 0 {
+			// This function hasn't called anything yet.
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		pcs[n] = frame.pc
+		n++
+	}
+	return n
+}
+
+// restoreCallStack is called by the scheduler right before a goroutine is
+// resumed.
+func restoreCallStack(t *task.Task) {
+	schedulerCallStack = callStack
+	callStack = (*callFrame)(t.CallStack)
+}
+
+// saveCallStack is called by the scheduler after a goroutine was paused. It
+// stores the call stack of the goroutine in its task.
+func saveCallStack(t *task.Task) {
+	t.CallStack = unsafe.Pointer(callStack)
+	callStack = schedulerCallStack
+}
@@ -0,0 +1,177 @@
+// +build !baremetal,!tinygo.wasm,!windows
+
+package runtime
+
+// This file finds the call sites of a goroutine by walking the frame pointers
+// of its call stack. The compiler keeps the frame pointer in every function
+// and creates a table of all call instructions in the program, so that every
+// return address on the call stack can be looked up in it (see
+// transform.CreatePCTable).
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// pcEntry is an entry in the PC table, which is created by the compiler.
+type pcEntry struct {
+	pc   uintptr   // start address of a call or of a function
+	site *callSite // innermost call site of the call, nil for a function
+}
+
+//go:extern runtime.pcTableStart
+var pcTableStart uintptr
+
+//go:extern runtime.pcTableLength
+var pcTableLength uintptr
+
+// pcTableSorted indicates whether the PC table has been sorted by address. The
+// compiler cannot do this, because the addresses are only known after linking.
+var pcTableSorted bool
+
+// stackBottom is used by the compiler as the parent of the outermost call site
+// of calls in a function at the bottom of the call stack, such as a goroutine
+// start wrapper or a function called from outside Go. The stack walk stops
+// there.
+var stackBottom callSite
+
+//export llvm.frameaddress.p0i8
+func frameAddress(level int32) unsafe.Pointer
+
+// callers stores the call sites of the calling goroutine in pcs, starting at
+// the call site in the caller of callers after skipping skip call sites. It
+// returns the number of entries written to pcs.
+//go:noinline
+func callers(skip int, pcs []uintptr) int {
+	// The frame record of this function contains the frame pointer and the
+	// return address of its caller.
+	fp := uintptr(frameAddress(0))
+	return walkStack(*(*uintptr)(unsafe.Pointer(fp)), *(*uintptr)(unsafe.Pointer(fp + unsafe.Sizeof(fp))), skip, pcs)
+}
+
+// taskCallers is like callers, but for a goroutine that is not running.
+func taskCallers(t *task.Task, skip int, pcs []uintptr) int {
+	fp, pc := t.SavedFrame()
+	if fp == 0 {
+		// The goroutine has not started yet.
+		return 0
+	}
+	return walkStack(fp, pc, skip, pcs)
+}
+
+// walkStack stores the call sites of a call stack in pcs, after skipping skip
+// call sites. The call stack starts at the given return address, in the
+// function with the given frame pointer. It returns the number of entries
+// written to pcs.
+func walkStack(fp, pc uintptr, skip int, pcs []uintptr) int {
+	n := 0
+	for {
+		entry := findPCEntry(pc)
+		if entry == nil || entry.site == nil {
+			// Not a return address of a known call.
+			return n
+		}
+		for site := entry.site; site != nil; site = site.parent {
+			if site == &stackBottom {
+				return n
+			}
+			if site.function == nil {
+				// Function without debug information.
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if n == len(pcs) {
+				return n
+			}
+			pcs[n] = uintptr(unsafe.Pointer(site))
+			n++
+		}
+
+		// Go to the frame record of the caller. It is stored higher up the
+		// stack, which guards against loops in a corrupted stack.
+		parent := *(*uintptr)(unsafe.Pointer(fp))
+		if parent <= fp {
+			return n
+		}
+		pc = *(*uintptr)(unsafe.Pointer(fp + unsafe.Sizeof(fp)))
+		fp = parent
+	}
+}
+
+// findPCEntry returns the entry of the PC table that contains the given return
+// address, or nil if the address is before the first entry.
+func findPCEntry(pc uintptr) *pcEntry {
+	if !pcTableSorted {
+		sortPCTable()
+		pcTableSorted = true
+	}
+
+	// Find the last entry that starts before the return address.
+	low, high := uintptr(0), pcTableLength
+	for low < high {
+		mid := (low + high) / 2
+		if pcTableEntry(mid).pc < pc {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	if low == 0 {
+		return nil
+	}
+	return pcTableEntry(low - 1)
+}
+
+// pcTableEntry returns the entry in the PC table at the given index.
+func pcTableEntry(index uintptr) *pcEntry {
+	return (*pcEntry)(unsafe.Pointer(pcTableStart + index*unsafe.Sizeof(pcEntry{})))
+}
+
+// sortPCTable sorts the PC table by address. It uses heapsort, which doesn't
+// need to allocate memory.
+func sortPCTable() {
+	for i := pcTableLength / 2; i > 0; i-- {
+		siftDownPCTable(i-1, pcTableLength)
+	}
+	for end := pcTableLength; end > 1; end-- {
+		// Move the largest entry to the end.
+		first, last := pcTableEntry(0), pcTableEntry(end-1)
+		*first, *last = *last, *first
+		siftDownPCTable(0, end-1)
+	}
+}
+
+// siftDownPCTable moves the entry at index root down the heap of the first end
+// entries, until it is larger than its children.
+func siftDownPCTable(root, end uintptr) {
+	for {
+		child := 2*root + 1
+		if child >= end {
+			return
+		}
+		if child+1 < end && pcTableEntry(child).pc < pcTableEntry(child+1).pc {
+			child++
+		}
+		parent, larger := pcTableEntry(root), pcTableEntry(child)
+		if parent.pc >= larger.pc {
+			return
+		}
+		*parent, *larger = *larger, *parent
+		root = child
+	}
+}
+
+// restoreCallStack is called by the scheduler right before a goroutine is
+// resumed. The call stack is part of the goroutine stack, so there is nothing
+// to do.
+//go:inline
+func restoreCallStack(t *task.Task) {
+}
+
+// saveCallStack is called by the scheduler after a goroutine was paused.
+//go:inline
+func saveCallStack(t *task.Task) {
+}
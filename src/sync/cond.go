@@ -40,21 +40,27 @@ func (c *Cond) trySignal() bool {
 }
 
 func (c *Cond) Signal() {
+	i := task.Lock()
 	c.trySignal()
+	task.Unlock(i)
 }
 
 func (c *Cond) Broadcast() {
 	// Signal everything.
+	i := task.Lock()
 	for c.trySignal() {
 	}
+	task.Unlock(i)
 }
 
 func (c *Cond) Wait() {
 	// Add an earlySignal frame to the stack so we can be signalled while unlocking.
+	i := task.Lock()
 	early := earlySignal{
 		next: c.unlocking,
 	}
 	c.unlocking = &early
+	task.Unlock(i)
 
 	// Temporarily unlock L.
 	c.L.Unlock()
@@ -63,7 +69,9 @@ func (c *Cond) Wait() {
 	defer c.L.Lock()
 
 	// If we were signaled while unlocking, immediately complete.
+	i = task.Lock()
 	if early.signaled {
+		task.Unlock(i)
 		return
 	}
 
@@ -80,5 +88,5 @@ func (c *Cond) Wait() {
 
 	// Wait for a signal.
 	c.blocked.Push(task.Current())
-	task.Pause()
+	task.PauseLocked(i)
 }
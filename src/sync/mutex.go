@@ -5,8 +5,9 @@ import (
 	_ "unsafe"
 )
 
-// These mutexes assume there is only one thread of operation: no goroutines,
-// interrupts or anything else.
+// These mutexes are protected by the scheduler lock (see task.Lock), so that
+// they also work when goroutines run on multiple cores at the same time. They
+// must not be used from interrupts.
 
 type Mutex struct {
 	locked  bool
@@ -17,18 +18,22 @@ type Mutex struct {
 func scheduleTask(*task.Task)
 
 func (m *Mutex) Lock() {
+	i := task.Lock()
 	if m.locked {
 		// Push self onto stack of blocked tasks, and wait to be resumed.
 		m.blocked.Push(task.Current())
-		task.Pause()
+		task.PauseLocked(i)
 		return
 	}
 
 	m.locked = true
+	task.Unlock(i)
 }
 
 func (m *Mutex) Unlock() {
+	i := task.Lock()
 	if !m.locked {
+		task.Unlock(i)
 		panic("sync: unlock of unlocked Mutex")
 	}
 
@@ -38,6 +43,7 @@ func (m *Mutex) Unlock() {
 	} else {
 		m.locked = false
 	}
+	task.Unlock(i)
 }
 
 type RWMutex struct {
@@ -54,20 +60,29 @@ func (rw *RWMutex) Unlock() {
 }
 
 func (rw *RWMutex) RLock() {
+	i := task.Lock()
 	if rw.readers == 0 {
+		// The first reader takes the lock on behalf of all readers. This may
+		// block, so release the scheduler lock while waiting.
+		task.Unlock(i)
 		rw.m.Lock()
+		i = task.Lock()
 	}
 	rw.readers++
+	task.Unlock(i)
 }
 
 func (rw *RWMutex) RUnlock() {
+	i := task.Lock()
 	if rw.readers == 0 {
+		task.Unlock(i)
 		panic("sync: unlock of unlocked RWMutex")
 	}
 	rw.readers--
 	if rw.readers == 0 {
 		rw.m.Unlock()
 	}
+	task.Unlock(i)
 }
 
 type Locker interface {
@@ -8,9 +8,11 @@ type WaitGroup struct {
 }
 
 func (wg *WaitGroup) Add(delta int) {
+	i := task.Lock()
 	if delta > 0 {
 		// Check for overflow.
 		if uint(delta) > (^uint(0))-wg.counter {
+			task.Unlock(i)
 			panic("sync: WaitGroup counter overflowed")
 		}
 
@@ -19,6 +21,7 @@ func (wg *WaitGroup) Add(delta int) {
 	} else {
 		// Check for underflow.
 		if uint(-delta) > wg.counter {
+			task.Unlock(i)
 			panic("sync: negative WaitGroup counter")
 		}
 
@@ -34,6 +37,7 @@ func (wg *WaitGroup) Add(delta int) {
 			}
 		}
 	}
+	task.Unlock(i)
 }
 
 func (wg *WaitGroup) Done() {
@@ -41,8 +45,10 @@ func (wg *WaitGroup) Done() {
 }
 
 func (wg *WaitGroup) Wait() {
+	i := task.Lock()
 	if wg.counter == 0 {
 		// Everything already finished.
+		task.Unlock(i)
 		return
 	}
 
@@ -50,5 +56,5 @@ func (wg *WaitGroup) Wait() {
 	wg.waiters.Push(task.Current())
 
 	// Pause until the waiters are awoken by Add/Done.
-	task.Pause()
+	task.PauseLocked(i)
 }
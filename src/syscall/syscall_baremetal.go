@@ -51,6 +51,14 @@ func Getenv(key string) (value string, found bool) {
 	return "", false // stub
 }
 
+func Setenv(key, value string) (err error) {
+	return ENOSYS
+}
+
+func Unsetenv(key string) (err error) {
+	return ENOSYS
+}
+
 func Open(path string, mode int, perm uint32) (fd int, err error) {
 	return 0, ENOSYS
 }
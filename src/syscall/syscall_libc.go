@@ -21,14 +21,24 @@ func Close(fd int) (err error) {
 
 func Write(fd int, p []byte) (n int, err error) {
 	buf, count := splitSlice(p)
-	n = libc_write(int32(fd), buf, uint(count))
-	if n < 0 {
+	for {
+		n = libc_write(int32(fd), buf, uint(count))
+		if n >= 0 {
+			return n, nil
+		}
 		err = getErrno()
+		// Writes rarely block, so only wait for a non-blocking file
+		// descriptor that isn't ready yet.
+		if err != EAGAIN || !waitFD(fd, true) {
+			return
+		}
 	}
-	return
 }
 
 func Read(fd int, p []byte) (n int, err error) {
+	// Wait before reading: file descriptors such as stdin are usually
+	// blocking, and a read call that blocks would stop all goroutines.
+	waitFD(fd, false)
 	buf, count := splitSlice(p)
 	n = libc_read(int32(fd), buf, uint(count))
 	if n < 0 {
@@ -37,8 +47,44 @@ func Read(fd int, p []byte) (n int, err error) {
 	return
 }
 
+func Pread(fd int, p []byte, offset int64) (n int, err error) {
+	buf, count := splitSlice(p)
+	n = libc_pread(int32(fd), buf, uint(count), offset)
+	if n < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Pwrite(fd int, p []byte, offset int64) (n int, err error) {
+	buf, count := splitSlice(p)
+	n = libc_pwrite(int32(fd), buf, uint(count), offset)
+	if n < 0 {
+		err = getErrno()
+	}
+	return
+}
+
 func Seek(fd int, offset int64, whence int) (off int64, err error) {
-	return 0, ENOSYS // TODO
+	off = libc_lseek(int32(fd), offset, int32(whence))
+	if off < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Ftruncate(fd int, length int64) (err error) {
+	if libc_ftruncate(int32(fd), length) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Fsync(fd int) (err error) {
+	if libc_fsync(int32(fd)) < 0 {
+		err = getErrno()
+	}
+	return
 }
 
 func Open(path string, flag int, mode uint32) (fd int, err error) {
@@ -51,11 +97,36 @@ func Open(path string, flag int, mode uint32) (fd int, err error) {
 }
 
 func Mkdir(path string, mode uint32) (err error) {
-	return ENOSYS // TODO
+	data := append([]byte(path), 0)
+	if libc_mkdir(&data[0], mode) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Rmdir(path string) (err error) {
+	data := append([]byte(path), 0)
+	if libc_rmdir(&data[0]) < 0 {
+		err = getErrno()
+	}
+	return
 }
 
 func Unlink(path string) (err error) {
-	return ENOSYS // TODO
+	data := append([]byte(path), 0)
+	if libc_unlink(&data[0]) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Rename(from, to string) (err error) {
+	fromData := append([]byte(from), 0)
+	toData := append([]byte(to), 0)
+	if libc_rename(&fromData[0], &toData[0]) < 0 {
+		err = getErrno()
+	}
+	return
 }
 
 func Kill(pid int, sig Signal) (err error) {
@@ -68,13 +139,47 @@ func Getenv(key string) (value string, found bool) {
 	if raw == nil {
 		return "", false
 	}
+	return gostring(raw), true
+}
 
+func Setenv(key, value string) (err error) {
+	if len(key) == 0 {
+		return EINVAL
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] == '=' || key[i] == 0 {
+			return EINVAL
+		}
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] == 0 {
+			return EINVAL
+		}
+	}
+	keyData := append([]byte(key), 0)
+	valueData := append([]byte(value), 0)
+	if libc_setenv(&keyData[0], &valueData[0], 1) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Unsetenv(key string) (err error) {
+	keyData := append([]byte(key), 0)
+	if libc_unsetenv(&keyData[0]) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+// gostring converts a NUL-terminated C string to a Go string.
+func gostring(raw *byte) string {
 	ptr := uintptr(unsafe.Pointer(raw))
 	for size := uintptr(0); ; size++ {
 		v := *(*byte)(unsafe.Pointer(ptr))
 		if v == 0 {
 			src := *(*[]byte)(unsafe.Pointer(&sliceHeader{buf: raw, len: size, cap: size}))
-			return string(src), true
+			return string(src)
 		}
 		ptr += unsafe.Sizeof(byte(0))
 	}
@@ -93,6 +198,14 @@ func libc_write(fd int32, buf *byte, count uint) int
 //export getenv
 func libc_getenv(name *byte) *byte
 
+// int setenv(const char *name, const char *value, int overwrite);
+//export setenv
+func libc_setenv(name, value *byte, overwrite int32) int32
+
+// int unsetenv(const char *name);
+//export unsetenv
+func libc_unsetenv(name *byte) int32
+
 // ssize_t read(int fd, void *buf, size_t count);
 //export read
 func libc_read(fd int32, buf *byte, count uint) int
@@ -104,3 +217,39 @@ func libc_open(pathname *byte, flags int32, mode uint32) int32
 // int close(int fd)
 //export close
 func libc_close(fd int32) int32
+
+// ssize_t pread(int fd, void *buf, size_t count, off_t offset);
+//export pread
+func libc_pread(fd int32, buf *byte, count uint, offset int64) int
+
+// ssize_t pwrite(int fd, const void *buf, size_t count, off_t offset);
+//export pwrite
+func libc_pwrite(fd int32, buf *byte, count uint, offset int64) int
+
+// off_t lseek(int fd, off_t offset, int whence);
+//export lseek
+func libc_lseek(fd int32, offset int64, whence int32) int64
+
+// int ftruncate(int fd, off_t length);
+//export ftruncate
+func libc_ftruncate(fd int32, length int64) int32
+
+// int fsync(int fd);
+//export fsync
+func libc_fsync(fd int32) int32
+
+// int mkdir(const char *pathname, mode_t mode);
+//export mkdir
+func libc_mkdir(pathname *byte, mode uint32) int32
+
+// int rmdir(const char *pathname);
+//export rmdir
+func libc_rmdir(pathname *byte) int32
+
+// int unlink(const char *pathname);
+//export unlink
+func libc_unlink(pathname *byte) int32
+
+// int rename(const char *from, const char *to);
+//export rename
+func libc_rename(from, to *byte) int32
@@ -5,6 +5,10 @@ package syscall
 // This file defines errno and constants to match the darwin libsystem ABI.
 // Values have been copied from src/syscall/zerrors_darwin_amd64.go.
 
+import (
+	"unsafe"
+)
+
 // This function returns the error location in the darwin ABI.
 // Discovered by compiling the following code using Clang:
 //
@@ -24,6 +28,10 @@ func getErrno() Errno {
 	return Errno(uintptr(*errptr))
 }
 
+func setErrno(errno Errno) {
+	*libc___error() = int32(errno)
+}
+
 func (e Errno) Is(target error) bool {
 	switch target.Error() {
 	case "permission denied":
@@ -39,11 +47,17 @@ func (e Errno) Is(target error) bool {
 const (
 	EPERM       Errno = 0x1
 	ENOENT      Errno = 0x2
+	EBADF       Errno = 0x9
 	EACCES      Errno = 0xd
 	EEXIST      Errno = 0x11
+	EXDEV       Errno = 0x12
 	EINTR       Errno = 0x4
 	ENOTDIR     Errno = 0x14
+	EISDIR      Errno = 0x15
+	EINVAL      Errno = 0x16
+	ENOTEMPTY   Errno = 0x42
 	EMFILE      Errno = 0x18
+	ENOSPC      Errno = 0x1c
 	EAGAIN      Errno = 0x23
 	ETIMEDOUT   Errno = 0x3c
 	ENOSYS      Errno = 0x4e
@@ -71,3 +85,67 @@ const (
 	O_TRUNC  = 0x400
 	O_EXCL   = 0x800
 )
+
+const (
+	S_IFBLK  = 0x6000
+	S_IFCHR  = 0x2000
+	S_IFDIR  = 0x4000
+	S_IFIFO  = 0x1000
+	S_IFLNK  = 0xa000
+	S_IFMT   = 0xf000
+	S_IFREG  = 0x8000
+	S_IFSOCK = 0xc000
+)
+
+type Timespec struct {
+	Sec  int64
+	Nsec int64
+}
+
+// Stat_t is struct stat with 64-bit inode numbers, as returned by the
+// $INODE64 variants of the stat functions.
+type Stat_t struct {
+	Dev           int32
+	Mode          uint16
+	Nlink         uint16
+	Ino           uint64
+	Uid           uint32
+	Gid           uint32
+	Rdev          int32
+	Pad_cgo_0     [4]byte
+	Atimespec     Timespec
+	Mtimespec     Timespec
+	Ctimespec     Timespec
+	Birthtimespec Timespec
+	Size          int64
+	Blocks        int64
+	Blksize       int32
+	Flags         uint32
+	Gen           uint32
+	Lspare        int32
+	Qspare        [2]int64
+}
+
+// The name of a directory entry follows d_ino, d_seekoff, d_reclen, d_namlen
+// and d_type in struct dirent (with 64-bit inode numbers).
+const direntNameOffset = 21
+
+// int stat(const char *path, struct stat *buf);
+//export stat$INODE64
+func libc_stat(pathname *byte, ptr unsafe.Pointer) int32
+
+// int lstat(const char *path, struct stat *buf);
+//export lstat$INODE64
+func libc_lstat(pathname *byte, ptr unsafe.Pointer) int32
+
+// int fstat(int fd, struct stat *buf);
+//export fstat$INODE64
+func libc_fstat(fd int32, ptr unsafe.Pointer) int32
+
+// DIR *fdopendir(int fd);
+//export fdopendir$INODE64
+func libc_fdopendir(fd int32) unsafe.Pointer
+
+// struct dirent *readdir(DIR *dirp);
+//export readdir$INODE64
+func libc_readdir(dirp unsafe.Pointer) unsafe.Pointer
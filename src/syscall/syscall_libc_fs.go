@@ -0,0 +1,81 @@
+// +build darwin wasi
+
+package syscall
+
+// This file implements file information and directory access on top of libc.
+// The struct layouts and symbol names differ between platforms, they are
+// defined in the platform specific files.
+
+import (
+	"unsafe"
+)
+
+func Stat(path string, st *Stat_t) (err error) {
+	data := append([]byte(path), 0)
+	if libc_stat(&data[0], unsafe.Pointer(st)) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Lstat(path string, st *Stat_t) (err error) {
+	data := append([]byte(path), 0)
+	if libc_lstat(&data[0], unsafe.Pointer(st)) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+func Fstat(fd int, st *Stat_t) (err error) {
+	if libc_fstat(int32(fd), unsafe.Pointer(st)) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+// Fdopendir opens a directory stream for the directory referred to by fd. On
+// success, fd is owned by the directory stream: it is closed by Closedir and
+// should not be used directly anymore.
+//
+// This function is specific to TinyGo, as libc doesn't provide the getdents
+// system call used by ReadDirent in the standard library.
+func Fdopendir(fd int) (dir uintptr, err error) {
+	d := libc_fdopendir(int32(fd))
+	if d == nil {
+		return 0, getErrno()
+	}
+	return uintptr(d), nil
+}
+
+// Readdir returns the name of the next entry in the directory stream, or an
+// empty string when the end of the directory has been reached.
+//
+// This function is specific to TinyGo, see Fdopendir.
+func Readdir(dir uintptr) (name string, err error) {
+	// readdir only sets errno on failure, so it must be cleared first to
+	// distinguish the end of the directory from an error.
+	setErrno(0)
+	entry := libc_readdir(unsafe.Pointer(dir))
+	if entry == nil {
+		if getErrno() != Errno(0) {
+			err = getErrno()
+		}
+		return
+	}
+	return gostring((*byte)(unsafe.Pointer(uintptr(entry) + direntNameOffset))), nil
+}
+
+// Closedir closes the directory stream, and the file descriptor it was opened
+// with.
+//
+// This function is specific to TinyGo, see Fdopendir.
+func Closedir(dir uintptr) (err error) {
+	if libc_closedir(unsafe.Pointer(dir)) < 0 {
+		err = getErrno()
+	}
+	return
+}
+
+// int closedir(DIR *dirp);
+//export closedir
+func libc_closedir(dirp unsafe.Pointer) int32
@@ -0,0 +1,10 @@
+// +build darwin nintendoswitch
+
+package syscall
+
+// waitFD is a no-op on these systems: a blocking read or write call simply
+// blocks the whole program. It returns false, as there is no way to wait until
+// a non-blocking file descriptor is ready.
+func waitFD(fd int, write bool) bool {
+	return false
+}
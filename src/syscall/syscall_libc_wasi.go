@@ -2,6 +2,10 @@
 
 package syscall
 
+import (
+	"unsafe"
+)
+
 // https://github.com/WebAssembly/wasi-libc/blob/main/expected/wasm32-wasi/predefined-macros.txt
 
 type Signal int
@@ -48,6 +52,21 @@ func getErrno() error {
 	return Errno(libcErrno)
 }
 
+func setErrno(errno Errno) {
+	libcErrno = uintptr(errno)
+}
+
+// waitFD waits until the file descriptor is ready for reading or writing
+// before a read or write call, so that other goroutines can run in the
+// meantime. It returns true, so that a write that failed with EAGAIN is
+// retried.
+func waitFD(fd int, write bool) bool {
+	runtime_waitFD(int32(fd), write)
+	return true
+}
+
+func runtime_waitFD(fd int32, write bool) // in package runtime
+
 func (e Errno) Is(target error) bool {
 	switch target.Error() {
 	case "permission denied":
@@ -139,3 +158,67 @@ const (
 	EXDEV           Errno = 75 /* Cross-device link */
 	ENOTCAPABLE     Errno = 76 /* Extension: Capabilities insufficient. */
 )
+
+// https://github.com/WebAssembly/wasi-libc/blob/main/libc-bottom-half/headers/public/__mode_t.h
+const (
+	S_IFBLK  = 0x6000
+	S_IFCHR  = 0x2000
+	S_IFDIR  = 0x4000
+	S_IFIFO  = 0x1000
+	S_IFLNK  = 0xa000
+	S_IFMT   = 0xf000
+	S_IFREG  = 0x8000
+	S_IFSOCK = 0xc000
+)
+
+// Timespec matches struct timespec in wasi-libc, where tv_nsec is a 32-bit
+// long. The struct is padded to 16 bytes.
+type Timespec struct {
+	Sec  int64
+	Nsec int32
+}
+
+// Stat_t matches struct stat in wasi-libc. The field names are the same as on
+// Linux.
+// https://github.com/WebAssembly/wasi-libc/blob/main/libc-bottom-half/headers/public/__struct_stat.h
+type Stat_t struct {
+	Dev         uint64
+	Ino         uint64
+	Nlink       uint64
+	Mode        uint32
+	Uid         uint32
+	Gid         uint32
+	X__pad0     uint32
+	Rdev        uint64
+	Size        int64
+	Blksize     int32
+	Blocks      int64
+	Atim        Timespec
+	Mtim        Timespec
+	Ctim        Timespec
+	X__reserved [3]int64
+}
+
+// The name of a directory entry follows the 64-bit inode number and the
+// 8-bit type in struct dirent.
+const direntNameOffset = 9
+
+// int stat(const char *path, struct stat *buf);
+//export stat
+func libc_stat(pathname *byte, ptr unsafe.Pointer) int32
+
+// int lstat(const char *path, struct stat *buf);
+//export lstat
+func libc_lstat(pathname *byte, ptr unsafe.Pointer) int32
+
+// int fstat(int fd, struct stat *buf);
+//export fstat
+func libc_fstat(fd int32, ptr unsafe.Pointer) int32
+
+// DIR *fdopendir(int fd);
+//export fdopendir
+func libc_fdopendir(fd int32) unsafe.Pointer
+
+// struct dirent *readdir(DIR *dirp);
+//export readdir
+func libc_readdir(dirp unsafe.Pointer) unsafe.Pointer
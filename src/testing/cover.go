@@ -0,0 +1,108 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// This file has been modified for use by the TinyGo compiler.
+// src: https://github.com/golang/go/blob/61bb56ad/src/testing/cover.go
+
+package testing
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// These lines surround the coverage profile when it is written to stdout, so
+// that tinygo test can extract it from the test output.
+const (
+	coverProfileStart = "=== COVERAGE PROFILE"
+	coverProfileEnd   = "=== END COVERAGE PROFILE"
+)
+
+// coverFile holds the coverage counters of a single instrumented source file.
+type coverFile struct {
+	name     string   // import path plus file name
+	counter  []uint32 // execution count of each block
+	pos      []uint32 // start line, end line and packed columns of each block
+	numStmts []uint16 // number of statements in each block
+}
+
+var (
+	coverMode  string
+	coverFiles []coverFile
+)
+
+// registerCover is called from the init function of packages that have been
+// instrumented for code coverage by tinygo test -cover. It is not imported by
+// these packages but called through go:linkname, because the instrumented
+// package may be a dependency of the testing package.
+func registerCover(mode, fileName string, counter, pos []uint32, numStmts []uint16) {
+	coverMode = mode
+	coverFiles = append(coverFiles, coverFile{fileName, counter, pos, numStmts})
+}
+
+// CoverMode reports what the test coverage mode is set to. The values are
+// "set" or "count". The return value will be empty if test coverage is not
+// enabled.
+func CoverMode() string {
+	return coverMode
+}
+
+// Coverage reports the current code coverage as a fraction in the range [0, 1].
+// If coverage is not enabled, Coverage returns 0.
+func Coverage() float64 {
+	var n, d int64
+	for _, file := range coverFiles {
+		for i, count := range file.counter {
+			if count > 0 {
+				n += int64(file.numStmts[i])
+			}
+			d += int64(file.numStmts[i])
+		}
+	}
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// coverReport writes the coverage profile, if requested with
+// -test.coverprofile, and prints the coverage percentage.
+func coverReport() {
+	if coverMode == "" {
+		return
+	}
+	if *coverProfile == "-" {
+		fmt.Println(coverProfileStart)
+		writeCoverProfile(os.Stdout)
+		fmt.Println(coverProfileEnd)
+	} else if *coverProfile != "" {
+		f, err := os.Create(*coverProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "testing: %s\n", err)
+			os.Exit(2)
+		}
+		writeCoverProfile(f)
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "testing: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	fmt.Printf("coverage: %.1f%% of statements\n", 100*Coverage())
+}
+
+// writeCoverProfile writes the coverage profile in the format expected by go
+// tool cover.
+func writeCoverProfile(w io.Writer) {
+	fmt.Fprintf(w, "mode: %s\n", coverMode)
+	for _, file := range coverFiles {
+		for i, count := range file.counter {
+			line0 := file.pos[3*i+0]
+			line1 := file.pos[3*i+1]
+			col0 := uint16(file.pos[3*i+2])
+			col1 := uint16(file.pos[3*i+2] >> 16)
+			fmt.Fprintf(w, "%s:%d.%d,%d.%d %d %d\n", file.name, line0, col0, line1, col1, file.numStmts[i], count)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// This file has been modified for use by the TinyGo compiler.
+// src: https://github.com/golang/go/blob/61bb56ad/src/testing/example.go
+
+package testing
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	_ "unsafe" // for go:linkname
+)
+
+type InternalExample struct {
+	Name      string
+	F         func()
+	Output    string
+	Unordered bool
+}
+
+// runExamples runs the examples that match the -test.run flag. It returns
+// whether any examples were run and whether they all passed.
+func runExamples(examples []InternalExample) (ran, ok bool) {
+	ok = true
+	exampleMatch := newMatcher(*match)
+	for _, eg := range examples {
+		if _, matched, _ := exampleMatch.fullName(nil, eg.Name); !matched {
+			continue
+		}
+		ran = true
+		if !runExample(eg) {
+			ok = false
+			if *failFast {
+				break
+			}
+		}
+		checkAlarm()
+	}
+	return ran, ok
+}
+
+// captureHandle is an os.FileHandle that stores everything written to it.
+type captureHandle struct {
+	bytes.Buffer
+}
+
+func (h *captureHandle) Close() error {
+	return nil
+}
+
+//go:linkname newFile os.newFile
+func newFile(handle os.FileHandle, name string) *os.File
+
+// runExample runs the example with os.Stdout redirected to a buffer, so that
+// the output can be compared with the expected output. Unlike upstream Go, no
+// pipe is used as pipes are not available on most targets.
+func runExample(eg InternalExample) (ok bool) {
+	if *chatty {
+		fmt.Printf("=== RUN   %s\n", eg.Name)
+	}
+
+	capture := &captureHandle{}
+	func() {
+		// Restore os.Stdout even if the example panics.
+		stdout := os.Stdout
+		os.Stdout = newFile(capture, stdout.Name())
+		defer func() {
+			os.Stdout = stdout
+		}()
+		eg.F()
+	}()
+
+	var fail string
+	got := strings.TrimSpace(capture.String())
+	want := strings.TrimSpace(eg.Output)
+	if eg.Unordered {
+		if sortLines(got) != sortLines(want) {
+			fail = fmt.Sprintf("got:\n%s\nwant (unordered):\n%s\n", got, want)
+		}
+	} else {
+		if got != want {
+			fail = fmt.Sprintf("got:\n%s\nwant:\n%s\n", got, want)
+		}
+	}
+	if fail != "" {
+		fmt.Printf("--- FAIL: %s\n%s", eg.Name, fail)
+		return false
+	}
+	if *chatty {
+		fmt.Printf("--- PASS: %s\n", eg.Name)
+	}
+	return true
+}
+
+// sortLines sorts the lines of the output, so that unordered output can be
+// compared.
+func sortLines(output string) string {
+	lines := strings.Split(output, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// This file has been modified for use by the TinyGo compiler.
+// src: https://github.com/golang/go/blob/61bb56ad/src/testing/match.go
+
+package testing
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matcher sanitizes and matches the names of benchmarks.
+type matcher struct {
+	filter []string
+}
+
+func newMatcher(patterns string) *matcher {
+	var filter []string
+	if patterns != "" {
+		filter = splitRegexp(patterns)
+		for i, s := range filter {
+			filter[i] = rewrite(s)
+		}
+		// Verify filters before doing any processing.
+		for i, s := range filter {
+			if _, err := regexp.Compile(s); err != nil {
+				fmt.Fprintf(os.Stderr, "testing: invalid regexp for element %d of %s: %s\n", i, patterns, err)
+				os.Exit(1)
+			}
+		}
+	}
+	return &matcher{
+		filter: filter,
+	}
+}
+
+// fullName returns the full name of a benchmark with the given parent (which
+// may be nil for top-level benchmarks). It also reports whether the name
+// matches the filter, and whether it only matches partially (and only
+// sub-benchmarks can fully match).
+func (m *matcher) fullName(c *common, subname string) (name string, ok, partial bool) {
+	name = subname
+	if c != nil && c.level > 0 {
+		name = c.name + "/" + rewrite(subname)
+	}
+
+	// We check the full array of paths each time to allow for the case that
+	// a pattern contains a '/'.
+	elem := strings.Split(name, "/")
+	ok, partial = m.matches(elem)
+	return name, ok, partial
+}
+
+func (m *matcher) matches(name []string) (ok, partial bool) {
+	for i, s := range name {
+		if i >= len(m.filter) {
+			break
+		}
+		if ok, _ := regexp.MatchString(m.filter[i], s); !ok {
+			return false, false
+		}
+	}
+	return true, len(name) < len(m.filter)
+}
+
+// splitRegexp splits a pattern at each '/' that is not inside brackets or
+// parentheses.
+func splitRegexp(s string) []string {
+	a := make([]string, 0, strings.Count(s, "/"))
+	cs := 0
+	cp := 0
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '[':
+			cs++
+		case ']':
+			if cs--; cs < 0 { // An unmatched ']' is legal.
+				cs = 0
+			}
+		case '(':
+			if cs == 0 {
+				cp++
+			}
+		case ')':
+			if cs == 0 {
+				cp--
+			}
+		case '\\':
+			i++
+		case '/':
+			if cs == 0 && cp == 0 {
+				a = append(a, s[:i])
+				s = s[i+1:]
+				i = 0
+				continue
+			}
+		}
+		i++
+	}
+	return append(a, s)
+}
+
+// rewrite rewrites a subname to having only printable characters and no white
+// space.
+func rewrite(s string) string {
+	b := []byte{}
+	for _, r := range s {
+		switch {
+		case r == ' ':
+			b = append(b, '_')
+		case !strconv.IsPrint(r):
+			s := strconv.QuoteRune(r)
+			b = append(b, s[1:len(s)-1]...)
+		default:
+			b = append(b, string(r)...)
+		}
+	}
+	return string(b)
+}
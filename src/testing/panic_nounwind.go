@@ -0,0 +1,7 @@
+// +build !panic.unwind
+
+package testing
+
+// goexitRunsDefers is false because runtime.Goexit doesn't run deferred calls
+// without -panic=unwind.
+const goexitRunsDefers = false
@@ -0,0 +1,7 @@
+// +build panic.unwind
+
+package testing
+
+// goexitRunsDefers is true because runtime.Goexit runs deferred calls with
+// -panic=unwind.
+const goexitRunsDefers = true
@@ -0,0 +1,32 @@
+// +build !scheduler.none
+
+package testing
+
+import (
+	"time"
+)
+
+// hasScheduler is true when tests can run in separate goroutines, which is
+// needed for parallel tests.
+const hasScheduler = true
+
+// start runs the test function in a new goroutine. It returns when the test
+// has finished or when it has called Parallel.
+func (t *T) start(f func(t *T)) {
+	t.signal = make(chan bool)
+	go func() {
+		tRunner(t, f)
+		t.signal <- true
+	}()
+	<-t.signal
+}
+
+// startAlarm starts a goroutine that panics once the -test.timeout duration
+// has passed. It can only run when the running test yields to the scheduler,
+// for example when it is blocked.
+func startAlarm() {
+	go func() {
+		time.Sleep(*timeout)
+		panic("test timed out after " + timeout.String())
+	}()
+}
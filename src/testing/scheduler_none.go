@@ -0,0 +1,16 @@
+// +build scheduler.none
+
+package testing
+
+// hasScheduler is false because goroutines cannot be started. Parallel tests
+// run sequentially.
+const hasScheduler = false
+
+// start runs the test function directly, as there are no goroutines.
+func (t *T) start(f func(t *T)) {
+	tRunner(t, f)
+}
+
+// startAlarm does nothing without a scheduler: the timeout is only checked
+// between tests by checkAlarm.
+func startAlarm() {}
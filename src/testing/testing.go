@@ -10,20 +10,79 @@ package testing
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var initRan bool
+
+// Flags for the test binary, registered by Init.
+var (
+	short    *bool
+	failFast *bool
+	match    *string
+	chatty   *bool
+	count    *uint
+	timeout  *time.Duration
+
+	coverProfile *string
+)
+
+// testFlags contains the test flags for targets that cannot receive command
+// line arguments, like microcontrollers running in an emulator. It is set by
+// tinygo test at link time, with a NUL byte between each flag.
+var testFlags string
+
+// Init registers testing flags. These flags are automatically registered by
+// the "go test" command before running test functions, so Init is only needed
+// when calling functions such as Benchmark without using "go test".
+//
+// Init has no effect if it was already called.
+func Init() {
+	if initRan {
+		return
+	}
+	initRan = true
+
+	short = flag.Bool("test.short", false, "run smaller test suite to save time")
+	failFast = flag.Bool("test.failfast", false, "do not start new tests after the first test failure")
+	match = flag.String("test.run", "", "run only tests matching `regexp`")
+	chatty = flag.Bool("test.v", false, "verbose: print additional output")
+	count = flag.Uint("test.count", 1, "run tests and benchmarks `n` times")
+	timeout = flag.Duration("test.timeout", 0, "panic test binary after duration `d` (default 0, timeout disabled)")
+	coverProfile = flag.String("test.coverprofile", "", "write a coverage profile to `file` (- for stdout)")
+
+	matchBenchmarks = flag.String("test.bench", "", "run only benchmarks matching `regexp`")
+	benchmarkMemory = flag.Bool("test.benchmem", false, "print memory allocations for benchmarks")
+	flag.Var(&benchTime, "test.benchtime", "run each benchmark for duration `d`")
+}
+
 // common holds the elements common between T and B and
 // captures common methods such as Errorf.
 type common struct {
 	output io.Writer
 
-	failed   bool   // Test or benchmark has failed.
-	skipped  bool   // Test of benchmark has been skipped.
-	finished bool   // Test function has completed.
-	name     string // Name of test or benchmark.
+	mu       sync.Mutex // Guards failed, which subtests may set concurrently.
+	failed   bool       // Test or benchmark has failed.
+	skipped  bool       // Test of benchmark has been skipped.
+	finished bool       // Test function has completed.
+	name     string     // Name of test or benchmark.
+	level    int        // Nesting depth of test or benchmark.
+
+	cleanups   []func() // Functions to call when the test has finished.
+	tempDir    string   // Parent directory of the directories returned by TempDir.
+	tempDirErr error
+	tempDirSeq int
+
+	goexit    func() // Finishes the test and stops its goroutine, see FailNow.
+	goexiting bool   // goexit has been called.
 }
 
 // TB is the interface common to T and B.
@@ -43,6 +102,9 @@ type TB interface {
 	Skipf(format string, args ...interface{})
 	Skipped() bool
 	Helper()
+	Cleanup(func())
+	Setenv(key, value string)
+	TempDir() string
 }
 
 var _ TB = (*T)(nil)
@@ -50,10 +112,17 @@ var _ TB = (*B)(nil)
 
 // T is a type passed to Test functions to manage test state and support formatted test logs.
 // Logs are accumulated during execution and dumped to standard output when done.
-//
 type T struct {
 	common
-	indent string
+	parent *T
+
+	isParallel bool // Parallel has been called.
+	isEnvSet   bool // Setenv has been called.
+	ran        bool // A subtest has been run.
+
+	signal  chan bool // Signals that the test has finished or called Parallel.
+	barrier chan bool // Closed when the test function returns, to release parallel subtests.
+	sub     []*T      // Parallel subtests.
 }
 
 // Name returns the name of the running test or benchmark.
@@ -63,23 +132,38 @@ func (c *common) Name() string {
 
 // Fail marks the function as having failed but continues execution.
 func (c *common) Fail() {
+	c.mu.Lock()
 	c.failed = true
+	c.mu.Unlock()
 }
 
 // Failed reports whether the function has failed.
 func (c *common) Failed() bool {
+	c.mu.Lock()
 	failed := c.failed
+	c.mu.Unlock()
 	return failed
 }
 
 // FailNow marks the function as having failed and stops its execution
-// by calling runtime.Goexit (which then runs all deferred calls in the
-// current goroutine).
+// by calling runtime.Goexit. It must be called from the goroutine running the
+// test, not from other goroutines created during the test.
+//
+// Unlike the main Go implementation, deferred calls in the test function are
+// only run when built with -panic=unwind. Benchmarks and tests built without a
+// scheduler continue to run after FailNow, as they don't run in a goroutine of
+// their own.
 func (c *common) FailNow() {
 	c.Fail()
+	c.exit()
+}
 
+// exit stops the test after FailNow or SkipNow. Cleanup functions still run.
+func (c *common) exit() {
 	c.finished = true
-	c.Error("FailNow is incomplete, requires runtime.Goexit()")
+	if c.goexit != nil {
+		c.goexit()
+	}
 }
 
 // log generates the output.
@@ -139,11 +223,10 @@ func (c *common) Skipf(format string, args ...interface{}) {
 }
 
 // SkipNow marks the test as having been skipped and stops its execution
-// by calling runtime.Goexit.
+// by calling runtime.Goexit. The same limitations as for FailNow apply.
 func (c *common) SkipNow() {
 	c.skip()
-	c.finished = true
-	c.Error("SkipNow is incomplete, requires runtime.Goexit()")
+	c.exit()
 }
 
 func (c *common) skip() {
@@ -160,31 +243,248 @@ func (c *common) Helper() {
 	// Unimplemented.
 }
 
-// Run runs a subtest of f t called name. It waits until the subtest is finished
-// and returns whether the subtest succeeded.
+// Cleanup registers a function to be called when the test (or subtest) and all
+// its subtests complete. Cleanup functions will be called in last added, first
+// called order.
+func (c *common) Cleanup(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+
+// runCleanup calls the cleanup functions registered with Cleanup.
+func (c *common) runCleanup() {
+	for len(c.cleanups) != 0 {
+		f := c.cleanups[len(c.cleanups)-1]
+		c.cleanups = c.cleanups[:len(c.cleanups)-1]
+		f()
+	}
+}
+
+// TempDir returns a temporary directory for the test to use. The directory is
+// automatically removed by Cleanup when the test and all its subtests
+// complete. Each subsequent call to t.TempDir returns a unique directory; if
+// the directory creation fails, TempDir terminates the test by calling Fatal.
+func (c *common) TempDir() string {
+	if c.tempDir == "" && c.tempDirErr == nil {
+		// Use the test name as a prefix, without characters that are not
+		// allowed (or are awkward) in file names.
+		pattern := strings.Map(func(r rune) rune {
+			if '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || strings.ContainsRune("-._", r) {
+				return r
+			}
+			return '_'
+		}, c.name)
+		if len(pattern) > 64 {
+			pattern = pattern[:64]
+		}
+		c.tempDir, c.tempDirErr = os.MkdirTemp("", pattern)
+		if c.tempDirErr == nil {
+			c.Cleanup(func() {
+				if err := os.RemoveAll(c.tempDir); err != nil {
+					c.Errorf("TempDir RemoveAll cleanup: %v", err)
+				}
+			})
+		}
+	}
+	if c.tempDirErr != nil {
+		c.Fatalf("TempDir: %v", c.tempDirErr)
+		return ""
+	}
+
+	c.tempDirSeq++
+	dir := fmt.Sprintf("%s%c%03d", c.tempDir, os.PathSeparator, c.tempDirSeq)
+	if err := os.Mkdir(dir, 0777); err != nil {
+		c.Fatalf("TempDir: %v", err)
+	}
+	return dir
+}
+
+// Setenv calls os.Setenv(key, value) and uses Cleanup to restore the
+// environment variable to its original value after the test.
+func (c *common) Setenv(key, value string) {
+	prevValue, ok := os.LookupEnv(key)
+
+	if err := os.Setenv(key, value); err != nil {
+		c.Fatalf("cannot set environment variable: %v", err)
+		return
+	}
+
+	if ok {
+		c.Cleanup(func() {
+			os.Setenv(key, prevValue)
+		})
+	} else {
+		c.Cleanup(func() {
+			os.Unsetenv(key)
+		})
+	}
+}
+
+// Setenv calls os.Setenv(key, value) and uses Cleanup to restore the
+// environment variable to its original value after the test.
+//
+// Because Setenv affects the whole process, it cannot be used in parallel
+// tests or tests with parallel ancestors.
+func (t *T) Setenv(key, value string) {
+	for p := t; p != nil; p = p.parent {
+		if p.isParallel {
+			panic("testing: t.Setenv called after t.Parallel; cannot set environment variables in parallel tests")
+		}
+	}
+	t.isEnvSet = true
+	t.common.Setenv(key, value)
+}
+
+// Parallel signals that this test is to be run in parallel with (and only
+// with) other parallel tests. The test is paused until the function of its
+// parent test returns, and then runs concurrently with the other parallel
+// subtests of its parent.
+//
+// Parallel tests only run concurrently when there is a scheduler. With
+// -scheduler=none, they run sequentially like other tests.
+func (t *T) Parallel() {
+	if t.isParallel {
+		panic("testing: t.Parallel called multiple times")
+	}
+	if t.isEnvSet {
+		panic("testing: t.Parallel called after t.Setenv; cannot set environment variables in parallel tests")
+	}
+	t.isParallel = true
+	if !hasScheduler || t.parent == nil {
+		return
+	}
+
+	if *chatty {
+		fmt.Printf("=== PAUSE %s\n", t.name)
+	}
+	if t.parent.barrier == nil {
+		t.parent.barrier = make(chan bool)
+	}
+	t.parent.sub = append(t.parent.sub, t)
+	t.signal <- true   // Release the parent, which continues as if the test finished.
+	<-t.parent.barrier // Wait until the parent test function has returned.
+	if *chatty {
+		fmt.Printf("=== CONT  %s\n", t.name)
+	}
+}
+
+// Run runs f as a subtest of t called name. It runs f in a separate goroutine
+// (if there is a scheduler) and blocks until f returns or calls t.Parallel to
+// become a parallel test. Run reports whether f succeeded (or at least did not
+// fail before calling t.Parallel).
 func (t *T) Run(name string, f func(t *T)) bool {
+	name, ok, _ := testMatch.fullName(&t.common, name)
+	if !ok || (*failFast && atomic.LoadUint32(&numFailed) > 0) {
+		return true
+	}
+	t.ran = true
+
 	// Create a subtest.
-	sub := T{
-		indent: t.indent + "    ",
+	sub := &T{
 		common: common{
-			name:   t.name + "/" + name,
+			name:   name,
 			output: &bytes.Buffer{},
+			level:  t.level + 1,
 		},
+		parent: t,
 	}
 
 	// Run the test.
-	fmt.Printf("=== RUN   %s\n", sub.name)
-	f(&sub)
+	if *chatty {
+		fmt.Printf("=== RUN   %s\n", sub.name)
+	}
+	sub.start(f)
+	checkAlarm()
+	return !sub.Failed()
+}
 
-	// Process the result (pass or fail).
-	if sub.failed {
-		t.failed = true
-		fmt.Printf(sub.indent+"--- FAIL: %s\n", sub.name)
-	} else {
-		fmt.Printf(sub.indent+"--- PASS: %s\n", sub.name)
+// tRunner runs the test function of t, and then finishes the test.
+func tRunner(t *T, f func(t *T)) {
+	if hasScheduler && t.parent != nil {
+		// The test runs in its own goroutine (see start), which FailNow and
+		// SkipNow can stop.
+		if goexitRunsDefers {
+			// runtime.Goexit runs the deferred calls of the test function,
+			// and then this one which finishes the test.
+			defer func() {
+				if t.goexiting {
+					t.finish()
+					t.signal <- true
+				}
+			}()
+			t.goexit = func() {
+				t.goexiting = true
+				runtime.Goexit()
+			}
+		} else {
+			// runtime.Goexit doesn't run deferred calls, so the test is
+			// finished and the parent signalled before.
+			t.goexit = func() {
+				t.finish()
+				t.signal <- true
+				runtime.Goexit()
+			}
+		}
 	}
-	fmt.Print(sub.output)
-	return !sub.failed
+	f(t)
+	t.finish()
+}
+
+// finish is called once the test function has returned or stopped. It starts
+// the parallel subtests of t and waits for them to finish. It then calls the
+// cleanup functions and reports the result.
+func (t *T) finish() {
+	if len(t.sub) != 0 {
+		close(t.barrier)
+		for _, sub := range t.sub {
+			<-sub.signal
+		}
+	}
+	t.finished = true
+	t.runCleanup()
+
+	// Process the result (pass or fail). Parallel subtests may report to
+	// their parent at the same time.
+	if t.Failed() && t.parent != nil {
+		atomic.AddUint32(&numFailed, 1)
+		t.parent.Fail()
+	}
+	t.report()
+}
+
+// report prints the result of a test. Failures are always printed, passed and
+// skipped tests only in verbose mode.
+func (t *T) report() {
+	if t.parent == nil {
+		return // the root of all tests, which is not a test itself
+	}
+	indent := strings.Repeat("    ", t.level-1)
+	switch {
+	case t.Failed():
+		fmt.Printf(indent+"--- FAIL: %s\n", t.name)
+	case !*chatty:
+		return
+	case t.skipped:
+		fmt.Printf(indent+"--- SKIP: %s\n", t.name)
+	default:
+		fmt.Printf(indent+"--- PASS: %s\n", t.name)
+	}
+	fmt.Print(t.output)
+}
+
+// Short reports whether the -test.short flag is set.
+func Short() bool {
+	if short == nil {
+		panic("testing: Short called before Init")
+	}
+	return *short
+}
+
+// Verbose reports whether the -test.v flag is set.
+func Verbose() bool {
+	if chatty == nil {
+		panic("testing: Verbose called before Init")
+	}
+	return *chatty
 }
 
 // InternalTest is a reference to a test that should be called during a test suite run.
@@ -196,45 +496,93 @@ type InternalTest struct {
 // M is a test suite.
 type M struct {
 	// tests is a list of the test names to execute
-	Tests []InternalTest
+	Tests      []InternalTest
+	Benchmarks []InternalBenchmark
+	Examples   []InternalExample
 }
 
 // Run the test suite.
 func (m *M) Run() int {
-	if len(m.Tests) == 0 {
-		fmt.Fprintln(os.Stderr, "testing: warning: no tests to run")
+	// Some tests may call flag.Parse themselves in TestMain.
+	if !flag.Parsed() {
+		args := os.Args[1:]
+		if testFlags != "" {
+			args = append(strings.Split(testFlags, "\x00"), args...)
+		}
+		flag.CommandLine.Parse(args)
 	}
 
-	failures := 0
-	for _, test := range m.Tests {
-		t := &T{
+	if *timeout > 0 {
+		deadline = time.Now().Add(*timeout)
+		startAlarm()
+	}
+
+	// Run all tests as subtests of a root test, so that top-level tests can
+	// be parallel tests too.
+	testMatch = newMatcher(*match)
+	ran := false
+	for i := uint(0); i < *count; i++ {
+		root := &T{
 			common: common{
-				name:   test.Name,
 				output: &bytes.Buffer{},
 			},
 		}
-
-		fmt.Printf("=== RUN   %s\n", test.Name)
-		test.F(t)
-
-		if t.failed {
-			fmt.Printf("--- FAIL: %s\n", test.Name)
-		} else {
-			fmt.Printf("--- PASS: %s\n", test.Name)
-		}
-		fmt.Print(t.output)
-
-		if t.failed {
+		tRunner(root, func(t *T) {
+			for _, test := range m.Tests {
+				t.Run(test.Name, test.F)
+			}
+		})
+		ran = ran || root.ran
+	}
+	failures := int(atomic.LoadUint32(&numFailed))
+	if failures == 0 || !*failFast {
+		examplesRan, ok := runExamples(m.Examples)
+		ran = ran || examplesRan
+		if !ok {
 			failures++
 		}
 	}
+	if !ran && len(m.Benchmarks) == 0 {
+		fmt.Fprintln(os.Stderr, "testing: warning: no tests to run")
+	}
+
+	if (failures == 0 || !*failFast) && !runBenchmarks(m.Benchmarks) {
+		failures++
+	}
 
+	// The coverage is printed before the result, so that the output still
+	// ends with PASS or FAIL: that is how tinygo test checks the result of
+	// tests that run in an emulator.
+	coverReport()
 	if failures > 0 {
 		fmt.Println("FAIL")
-	} else {
-		fmt.Println("PASS")
+		// This is the exit code (see TestMain), which is truncated to 8
+		// bits: returning the number of failures would make 256 failures
+		// look like success.
+		return 1
+	}
+	fmt.Println("PASS")
+	return 0
+}
+
+// testMatch is the matcher for the -test.run flag.
+var testMatch *matcher
+
+// numFailed is the number of tests (including subtests) that have failed. It
+// is updated atomically, as parallel tests may fail at the same time.
+var numFailed uint32
+
+// deadline is the time at which the test binary times out, if -test.timeout is
+// set.
+var deadline time.Time
+
+// checkAlarm panics if the test binary has been running for longer than
+// allowed by -test.timeout. It is called between tests, so that a timeout is
+// also detected when the alarm set by startAlarm cannot run.
+func checkAlarm() {
+	if *timeout > 0 && time.Now().After(deadline) {
+		panic("test timed out after " + timeout.String())
 	}
-	return failures
 }
 
 func TestMain(m *M) {
@@ -242,14 +590,10 @@ func TestMain(m *M) {
 }
 
 func MainStart(deps interface{}, tests []InternalTest, benchmarks []InternalBenchmark, examples []InternalExample) *M {
+	Init()
 	return &M{
-		Tests: tests,
+		Tests:      tests,
+		Benchmarks: benchmarks,
+		Examples:   examples,
 	}
 }
-
-type InternalExample struct {
-	Name      string
-	F         func()
-	Output    string
-	Unordered bool
-}
@@ -229,6 +229,31 @@ func main() {
 	}
 	wg.Wait()
 	println("blocking select sum:", sum)
+
+	// Test that select picks one of the ready cases at random, instead of
+	// always picking the first one.
+	fch1 := make(chan int, 1)
+	fch2 := make(chan int, 1)
+	fch3 := make(chan int, 1)
+	var counts [3]int
+	for i := 0; i < 300; i++ {
+		fch1 <- 1
+		fch2 <- 2
+		select {
+		case <-fch1:
+			counts[0]++
+			<-fch2
+		case <-fch2:
+			counts[1]++
+			<-fch1
+		case fch3 <- 3:
+			counts[2]++
+			<-fch1
+			<-fch2
+			<-fch3
+		}
+	}
+	println("select fairness:", counts[0] >= 50, counts[1] >= 50, counts[2] >= 50)
 }
 
 func send(ch chan<- int) {
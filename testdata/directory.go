@@ -0,0 +1,187 @@
+package main
+
+// This test reads the directory tree in testdata/directory, and modifies files
+// in a temporary directory next to it.
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	testStat()
+	testReadDir()
+	testSeek()
+	testWalk()
+	testDirFS()
+	testWrite()
+	testTemp()
+}
+
+func testStat() {
+	info, err := os.Stat("testdata/directory/a.txt")
+	check(err)
+	println("stat:", info.Name(), info.Size(), info.IsDir(), info.Mode().IsRegular())
+
+	info, err = os.Stat("testdata/directory/sub")
+	check(err)
+	println("stat:", info.Name(), info.IsDir(), info.Mode().IsRegular())
+
+	info, err = os.Lstat("testdata/directory/b.txt")
+	check(err)
+	println("lstat:", info.Name(), info.Size())
+
+	_, err = os.Stat("testdata/directory/nonexistent")
+	println("stat nonexistent:", os.IsNotExist(err))
+}
+
+func testReadDir() {
+	entries, err := os.ReadDir("testdata/directory")
+	check(err)
+	for _, entry := range entries {
+		println("entry:", entry.Name(), entry.IsDir())
+	}
+
+	// Read the directory one entry at a time.
+	f, err := os.Open("testdata/directory")
+	check(err)
+	count := 0
+	for {
+		names, err := f.Readdirnames(1)
+		if err == io.EOF {
+			break
+		}
+		check(err)
+		count += len(names)
+	}
+	check(f.Close())
+	println("readdirnames:", count)
+}
+
+func testSeek() {
+	f, err := os.Open("testdata/directory/b.txt")
+	check(err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	check(err)
+	println("file stat:", info.Name(), info.Size())
+
+	buf := make([]byte, 3)
+	n, err := f.ReadAt(buf, 2)
+	check(err)
+	println("readat:", string(buf[:n]))
+	n, err = f.ReadAt(buf, 4)
+	println("readat at end:", string(buf[:n]), err == io.EOF)
+
+	pos, err := f.Seek(1, io.SeekStart)
+	check(err)
+	n, err = f.Read(buf)
+	check(err)
+	println("seek:", pos, string(buf[:n]))
+
+	pos, err = f.Seek(-2, io.SeekEnd)
+	check(err)
+	n, err = f.Read(buf)
+	check(err)
+	println("seek from end:", pos, string(buf[:n]))
+}
+
+func testWalk() {
+	err := filepath.Walk("testdata/directory", func(path string, info os.FileInfo, err error) error {
+		check(err)
+		println("walk:", path, info.IsDir())
+		return nil
+	})
+	check(err)
+}
+
+func testDirFS() {
+	fsys := os.DirFS("testdata/directory")
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		check(err)
+		println("walkdir:", path, d.IsDir())
+		return nil
+	})
+	check(err)
+
+	data, err := fs.ReadFile(fsys, "sub/c.txt")
+	check(err)
+	println("readfile:", string(data))
+}
+
+func testWrite() {
+	// Use a new directory, as the test may run for several targets at once.
+	dir, err := os.MkdirTemp("testdata", "directory-*")
+	check(err)
+	defer func() {
+		check(os.RemoveAll(dir))
+		_, err := os.Stat(dir)
+		println("removeall:", os.IsNotExist(err))
+	}()
+
+	name := dir + "/file.txt"
+	f, err := os.Create(name)
+	check(err)
+	_, err = f.Write([]byte("hello world"))
+	check(err)
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	check(err)
+	check(f.Sync())
+	check(f.Close())
+	data, err := os.ReadFile(name)
+	check(err)
+	println("writeat:", string(data))
+
+	check(os.Truncate(name, 5))
+	info, err := os.Stat(name)
+	check(err)
+	println("truncate:", info.Size())
+
+	newName := dir + "/renamed.txt"
+	check(os.Rename(name, newName))
+	_, err = os.Stat(name)
+	println("rename: old name exists:", !os.IsNotExist(err))
+	data, err = os.ReadFile(newName)
+	check(err)
+	println("rename: new name:", string(data))
+
+	// Directories can be renamed, and only be removed when they are empty.
+	check(os.Mkdir(dir+"/sub", 0755))
+	check(os.Rename(dir+"/sub", dir+"/sub2"))
+	info, err = os.Stat(dir + "/sub2")
+	check(err)
+	println("rename directory:", info.IsDir())
+	err = os.Remove(dir)
+	println("remove non-empty directory fails:", err != nil)
+	check(os.Remove(dir + "/sub2"))
+	_, err = os.Stat(dir + "/sub2")
+	println("remove empty directory:", os.IsNotExist(err))
+}
+
+func testTemp() {
+	dir, err := os.MkdirTemp("testdata", "directory-*.tmp")
+	check(err)
+	println("mkdirtemp pattern:", strings.HasPrefix(dir, "testdata/directory-") && strings.HasSuffix(dir, ".tmp"))
+
+	// RemoveAll removes nested directories, and ignores missing paths.
+	check(os.Mkdir(dir+"/a", 0755))
+	check(os.Mkdir(dir+"/a/b", 0755))
+	check(os.WriteFile(dir+"/a/b/c.txt", []byte("charlie"), 0644))
+	check(os.RemoveAll(dir))
+	_, err = os.Stat(dir)
+	println("removeall nested:", os.IsNotExist(err))
+	println("removeall missing:", os.RemoveAll(dir) == nil)
+
+	_, err = os.MkdirTemp("testdata", "invalid/pattern")
+	println("mkdirtemp with separator fails:", err != nil)
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
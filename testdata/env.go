@@ -22,10 +22,36 @@ func main() {
 		println("arg:", arg)
 	}
 
+	// Check for changing environment variables.
+	checkSetenv()
+
 	// Check for crypto/rand support.
 	checkRand()
 }
 
+func checkSetenv() {
+	if err := os.Setenv("ENV3", "VALUE3"); err != nil {
+		println("could not set ENV3:", err.Error())
+	}
+	println("ENV3:", os.Getenv("ENV3"))
+	if err := os.Setenv("ENV1", "CHANGED"); err != nil {
+		println("could not change ENV1:", err.Error())
+	}
+	println("ENV1:", os.Getenv("ENV1"))
+	if err := os.Unsetenv("ENV1"); err != nil {
+		println("could not unset ENV1:", err.Error())
+	}
+	_, ok := os.LookupEnv("ENV1")
+	println("ENV1 found after unsetenv:", ok)
+
+	err := os.Setenv("INVALID=KEY", "value")
+	_, isSyscallError := err.(*os.SyscallError)
+	println("setenv with invalid key:", isSyscallError)
+	err = os.Setenv("", "value")
+	_, isSyscallError = err.(*os.SyscallError)
+	println("setenv with empty key:", isSyscallError)
+}
+
 func checkRand() {
 	buf := make([]byte, 500)
 	n, err := rand.Read(buf)
@@ -0,0 +1,11 @@
+package main
+
+//export divmod
+func divmod(a, b int32) (int32, int32) {
+	return a / b, a % b
+}
+
+func main() {
+}
+
+// ERROR: testdata/errors/multivalue.go:4:6: exported function divmod returns 2 values, which requires the WebAssembly multi-value feature (-llvm-features=+multivalue)
@@ -1,5 +1,11 @@
 package main
 
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
 var xorshift32State uint32 = 1
 
 func xorshift32(x uint32) uint32 {
@@ -17,6 +23,8 @@ func randuint32() uint32 {
 
 func main() {
 	testNonPointerHeap()
+	testFinalizers()
+	testMemStats()
 }
 
 var scalarSlices [4][]byte
@@ -64,3 +72,125 @@ func testNonPointerHeap() {
 	}
 	println("ok")
 }
+
+type finalizedObject struct {
+	value int
+	next  *finalizedObject
+}
+
+var (
+	finalized          uint32
+	reachableFinalized uint32
+	clearedFinalized   uint32
+	reachableObject    *finalizedObject
+)
+
+func testFinalizers() {
+	allocFinalized(100)
+
+	// The finalizer of an object that is still reachable must not run.
+	reachableObject = &finalizedObject{value: 1}
+	runtime.SetFinalizer(reachableObject, func(obj *finalizedObject) {
+		atomic.StoreUint32(&reachableFinalized, 1)
+	})
+
+	// Finalizers that have been cleared must not run.
+	allocClearedFinalizers(10)
+
+	// Finalizers run in a separate goroutine after the GC cycle.
+	for i := 0; i < 10 && atomic.LoadUint32(&finalized) == 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	println("finalizers ran:", atomic.LoadUint32(&finalized) != 0)
+	println("finalizer of reachable object ran:", atomic.LoadUint32(&reachableFinalized) != 0)
+	println("cleared finalizers ran:", atomic.LoadUint32(&clearedFinalized) != 0)
+
+	testResurrection()
+}
+
+//go:noinline
+func allocFinalized(n int) {
+	var list *finalizedObject
+	for i := 0; i < n; i++ {
+		obj := &finalizedObject{value: i, next: list}
+		runtime.SetFinalizer(obj, func(obj *finalizedObject) {
+			atomic.AddUint32(&finalized, 1)
+		})
+		if i%10 == 0 {
+			// Start a new list, so that most lists are unreachable.
+			list = nil
+		} else {
+			list = obj
+		}
+	}
+}
+
+//go:noinline
+func allocClearedFinalizers(n int) {
+	for i := 0; i < n; i++ {
+		obj := &finalizedObject{value: i}
+		runtime.SetFinalizer(obj, func(obj *finalizedObject) {
+			atomic.StoreUint32(&clearedFinalized, 1)
+		})
+		runtime.SetFinalizer(obj, nil)
+	}
+}
+
+// resurrected receives the objects that were made reachable again by their
+// finalizer.
+var resurrected = make(chan *finalizedObject, 10)
+
+var resurrectionSink *finalizedObject
+
+func testResurrection() {
+	allocResurrected(10)
+	var obj *finalizedObject
+	for i := 0; i < 10 && obj == nil; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		select {
+		case obj = <-resurrected:
+		default:
+		}
+	}
+	if obj == nil {
+		println("no object was resurrected")
+		return
+	}
+
+	// The object (and the object it references) must have been kept alive
+	// until the finalizer ran, and is now reachable again. Allocate objects of
+	// the same size, which would overwrite it if it had been freed.
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		for j := 0; j < 10; j++ {
+			resurrectionSink = &finalizedObject{value: -1}
+		}
+	}
+	println("resurrected object intact:", obj.value >= 1000 && obj.next != nil && obj.next.value == obj.value+1)
+}
+
+//go:noinline
+func allocResurrected(n int) {
+	for i := 0; i < n; i++ {
+		obj := &finalizedObject{
+			value: 1000 + 2*i,
+			next:  &finalizedObject{value: 1000 + 2*i + 1},
+		}
+		runtime.SetFinalizer(obj, func(obj *finalizedObject) {
+			resurrected <- obj
+		})
+	}
+}
+
+var memStatsSink []byte
+
+func testMemStats() {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	memStatsSink = make([]byte, 100)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	println("memstats:", after.Mallocs > before.Mallocs, after.TotalAlloc >= before.TotalAlloc+100, after.NumGC > before.NumGC)
+}
@@ -0,0 +1,73 @@
+package main
+
+// This test only passes with the precise GC (-gc=precise): an integer that
+// happens to contain the address of a heap object must not keep that object
+// alive when it is stored in an object without pointers.
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// addressHolder has no pointer fields, so its layout has no pointers and the
+// GC must not scan it.
+type addressHolder struct {
+	address uintptr
+	index   int
+}
+
+type target struct {
+	next  *target
+	value int
+}
+
+var holders [10]*addressHolder
+
+// addresses is grown by append, so its buffer is allocated by the runtime and
+// must also be allocated without pointers.
+var addresses []uintptr
+
+var finalized, appendFinalized uint32
+
+func main() {
+	allocTargets()
+
+	// Finalizers run in a separate goroutine after the GC cycle.
+	for i := 0; i < 10 && (atomic.LoadUint32(&finalized) == 0 || atomic.LoadUint32(&appendFinalized) == 0); i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	// Some of these objects might still be referenced from a register or the
+	// stack, which are scanned conservatively. But not all of them.
+	println("objects only referenced by an integer were collected:", atomic.LoadUint32(&finalized) != 0)
+	println("objects only referenced by an appended integer were collected:", atomic.LoadUint32(&appendFinalized) != 0)
+
+	// The integers themselves must not have been modified.
+	for i, holder := range holders {
+		if holder.index != i || holder.address == 0 {
+			println("holder", i, "was overwritten")
+		}
+	}
+}
+
+//go:noinline
+func allocTargets() {
+	for i := range holders {
+		obj := &target{value: i}
+		runtime.SetFinalizer(obj, func(obj *target) {
+			atomic.AddUint32(&finalized, 1)
+		})
+		holders[i] = &addressHolder{
+			address: uintptr(unsafe.Pointer(obj)),
+			index:   i,
+		}
+
+		appended := &target{value: i}
+		runtime.SetFinalizer(appended, func(obj *target) {
+			atomic.AddUint32(&appendFinalized, 1)
+		})
+		addresses = append(addresses, uintptr(unsafe.Pointer(appended)))
+	}
+}
@@ -109,6 +109,9 @@ func main() {
 	squares = make(map[int]int, 20)
 	testBigMap(squares, 40)
 	println("tested growing of a map")
+
+	// test shrinking maps, and modifying a map while iterating over it
+	testGrowShrink(500)
 }
 
 func readMap(m map[string]int, key string) {
@@ -148,3 +151,39 @@ func testBigMap(squares map[int]int, n int) {
 		}
 	}
 }
+
+func testGrowShrink(n int) {
+	m := make(map[int]int)
+	for i := 0; i < n; i++ {
+		m[i] = i * 2
+	}
+
+	// Insert new keys while iterating, which resizes the map. All keys that
+	// were in the map before must be seen exactly once.
+	seen := 0
+	for k, v := range m {
+		if k >= n {
+			// Inserted while iterating, may or may not be seen.
+			continue
+		}
+		if v != k*2 {
+			println("unexpected value while iterating:", k, v)
+		}
+		seen++
+		m[k+n] = k
+	}
+	println("map length after iterating:", len(m), seen)
+
+	// Delete most keys, so that the map shrinks again.
+	for i := 0; i < 2*n; i++ {
+		if i%100 != 0 {
+			delete(m, i)
+		}
+	}
+	for k, v := range m {
+		if v != k*2 && v != k-n {
+			println("unexpected value after deleting:", k, v)
+		}
+	}
+	println("map length after deleting:", len(m), m[100], m[n+100], m[n+101])
+}
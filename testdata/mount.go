@@ -0,0 +1,134 @@
+package main
+
+// This test mounts the in-memory and flash filesystems in the os package.
+
+import (
+	"io"
+	"os"
+	"os/flashfs"
+	"os/ramfs"
+	"strconv"
+)
+
+func main() {
+	os.Mount("/ram/", ramfs.New())
+	testFilesystem("/ram")
+
+	testFlash()
+}
+
+func testFilesystem(dir string) {
+	check(os.Mkdir(dir+"/sub", 0777))
+	check(os.WriteFile(dir+"/sub/a.txt", []byte("alpha"), 0666))
+	check(os.WriteFile(dir+"/b.txt", []byte("bravo"), 0666))
+
+	data, err := os.ReadFile(dir + "/sub/a.txt")
+	check(err)
+	println("read:", string(data))
+
+	// Append to a file.
+	f, err := os.OpenFile(dir+"/b.txt", os.O_WRONLY|os.O_APPEND, 0)
+	check(err)
+	_, err = f.Write([]byte(" charlie"))
+	check(err)
+	check(f.Close())
+	data, err = os.ReadFile(dir + "/b.txt")
+	check(err)
+	println("append:", string(data))
+
+	// Seek, truncate and stat.
+	f, err = os.OpenFile(dir+"/b.txt", os.O_RDWR, 0)
+	check(err)
+	_, err = f.Seek(2, io.SeekStart)
+	check(err)
+	buf := make([]byte, 3)
+	n, err := f.Read(buf)
+	check(err)
+	println("seek:", string(buf[:n]))
+	check(f.Truncate(5))
+	info, err := f.Stat()
+	check(err)
+	println("stat:", info.Name(), info.Size(), info.IsDir())
+	check(f.Close())
+
+	// Rename a file between directories.
+	check(os.Rename(dir+"/b.txt", dir+"/sub/b.txt"))
+	_, err = os.Stat(dir + "/b.txt")
+	println("renamed:", os.IsNotExist(err))
+
+	listDir(dir + "/sub")
+
+	// Remove files and directories.
+	err = os.Remove(dir + "/sub")
+	println("remove non-empty:", err != nil)
+	check(os.Remove(dir + "/sub/a.txt"))
+	check(os.Remove(dir + "/sub/b.txt"))
+	check(os.Remove(dir + "/sub"))
+	listDir(dir + "/")
+}
+
+func testFlash() {
+	name := os.TempDir() + "/tinygo-flashfs-" + strconv.Itoa(os.Getpid()) + ".img"
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	check(err)
+	defer os.Remove(name)
+	defer f.Close()
+	dev, err := flashfs.NewFileDevice(f, 64*1024, 4, 4096)
+	check(err)
+
+	fs := flashfs.New(dev)
+	println("mount empty device:", fs.Mount() != nil)
+	check(fs.Format())
+	os.Mount("/flash/", fs)
+	testFilesystem("/flash")
+
+	check(os.WriteFile("/flash/persistent.txt", []byte("still here"), 0666))
+	check(fs.Unmount())
+
+	// Mount the same device a second time, as if the system was restarted.
+	fs = flashfs.New(dev)
+	check(fs.Mount())
+	os.Mount("/flash2/", fs)
+	data, err := os.ReadFile("/flash2/persistent.txt")
+	check(err)
+	println("remount:", string(data))
+
+	// Fill the filesystem until it runs out of space.
+	big := make([]byte, 16*1024)
+	count := 0
+	for {
+		err := os.WriteFile("/flash2/big"+strconv.Itoa(count)+".bin", big, 0666)
+		count++
+		if err != nil {
+			println("full:", count > 1)
+			break
+		}
+	}
+	data, err = os.ReadFile("/flash2/persistent.txt")
+	check(err)
+	println("after full:", string(data))
+
+	// Removing files makes space available again.
+	for i := 0; i < count; i++ {
+		check(os.Remove("/flash2/big" + strconv.Itoa(i) + ".bin"))
+	}
+	check(os.WriteFile("/flash2/big.bin", big, 0666))
+	info, err := os.Stat("/flash2/big.bin")
+	check(err)
+	println("after remove:", info.Size())
+}
+
+func listDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	check(err)
+	println("list:", dir, len(entries))
+	for _, entry := range entries {
+		println("entry:", entry.Name(), entry.IsDir())
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+// This test is built with the WebAssembly multi-value feature, which allows
+// exported and imported functions to return more than one value. It is run by
+// testdata/multivalue.js, which implements the imported function.
+
+//export divmod
+func divmod(a, b int32) (int32, int64) {
+	return a / b, int64(a%b) << 40
+}
+
+//export greeting
+func greeting(name string) string {
+	return "hello, " + name
+}
+
+type point struct {
+	x, y int32
+	z    float64
+}
+
+//export makePoint
+func makePoint(x, y int32) point {
+	return point{x, y, float64(x) / 2}
+}
+
+// pair is implemented by the host.
+//go:wasm-module multivalue
+//export pair
+func pair(a int32) (int32, int64)
+
+//export sumPair
+func sumPair(a int32) int64 {
+	x, y := pair(a)
+	return int64(x) + y
+}
+
+func main() {
+	q, r := divmod(17, 5)
+	println("divmod:", q, r>>40)
+	println("greeting:", greeting("multi-value"))
+	p := makePoint(3, 4)
+	println("point:", p.x, p.y, p.z == 1.5)
+	println("sumPair:", sumPair(3))
+}
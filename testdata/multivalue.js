@@ -0,0 +1,25 @@
+// This is the host for testdata/multivalue.go. It implements the imported
+// function, runs main, and then calls the exported functions that return more
+// than one value.
+'use strict';
+
+const fs = require('fs');
+const { WASI } = require('wasi');
+
+const print = (line) => fs.writeSync(1, line + '\n');
+
+const wasi = new WASI({ version: 'preview1', returnOnExit: true });
+const wasmModule = new WebAssembly.Module(fs.readFileSync(process.argv[2]));
+const instance = new WebAssembly.Instance(wasmModule, {
+	wasi_snapshot_preview1: wasi.wasiImport,
+	multivalue: {
+		pair: (a) => [a * 2, BigInt(a) << 40n],
+	},
+});
+wasi.start(instance);
+
+const [q, r] = instance.exports.divmod(17, 5);
+print('host divmod: ' + q + ' ' + (r >> 40n));
+const [x, y, z] = instance.exports.makePoint(3, 4);
+print('host makePoint: ' + x + ' ' + y + ' ' + z);
+print('host sumPair: ' + instance.exports.sumPair(3));
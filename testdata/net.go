@@ -0,0 +1,182 @@
+package main
+
+// This test uses the network stack of the host, and then replaces it with a
+// fake stack to check what the net package passes to it.
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+func main() {
+	testTCP()
+	testUDP()
+	testDeadline()
+	testRefused()
+	testLookup()
+	testFakeStack()
+}
+
+func testTCP() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	check(err)
+	done := make(chan struct{})
+	go func() {
+		c, err := ln.Accept()
+		check(err)
+		buf := make([]byte, 64)
+		for {
+			n, err := c.Read(buf)
+			if err == io.EOF {
+				break
+			}
+			check(err)
+			_, err = c.Write(buf[:n])
+			check(err)
+		}
+		check(c.Close())
+		close(done)
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	check(err)
+	println("tcp remote address matches:", c.RemoteAddr().String() == ln.Addr().String())
+	_, err = c.Write([]byte("hello over TCP"))
+	check(err)
+	check(c.(*net.TCPConn).CloseWrite())
+	data, err := io.ReadAll(c)
+	check(err)
+	println("tcp echo:", string(data))
+	check(c.Close())
+	<-done
+	check(ln.Close())
+
+	_, err = c.Write([]byte("x"))
+	println("write after close fails:", err != nil)
+}
+
+func testUDP() {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	check(err)
+	c, err := net.Dial("udp", server.LocalAddr().String())
+	check(err)
+	_, err = c.Write([]byte("ping"))
+	check(err)
+
+	buf := make([]byte, 64)
+	n, addr, err := server.ReadFrom(buf)
+	check(err)
+	println("udp received:", string(buf[:n]))
+	println("udp sender matches:", addr.String() == c.LocalAddr().String())
+	_, err = server.WriteTo([]byte("pong"), addr)
+	check(err)
+
+	n, err = c.Read(buf)
+	check(err)
+	println("udp reply:", string(buf[:n]))
+	check(c.Close())
+	check(server.Close())
+}
+
+func testDeadline() {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	check(err)
+	check(c.SetReadDeadline(time.Now().Add(10 * time.Millisecond)))
+	_, _, err = c.ReadFrom(make([]byte, 1))
+	var neterr net.Error
+	println("timeout:", errors.As(err, &neterr) && neterr.Timeout())
+	println("deadline exceeded:", errors.Is(err, os.ErrDeadlineExceeded))
+	check(c.Close())
+}
+
+func testRefused() {
+	// Find a port that is not in use by listening on it and closing it again.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	check(err)
+	addr := ln.Addr().String()
+	check(ln.Close())
+
+	_, err = net.Dial("tcp", addr)
+	_, isOpError := err.(*net.OpError)
+	println("dial closed port:", isOpError)
+}
+
+func testLookup() {
+	port, err := net.LookupPort("tcp", "https")
+	check(err)
+	println("https port:", port)
+
+	addrs, err := net.LookupHost("localhost")
+	check(err)
+	found := false
+	for _, addr := range addrs {
+		if addr == "127.0.0.1" {
+			found = true
+		}
+	}
+	println("localhost is 127.0.0.1:", found)
+
+	ips, err := net.LookupIP("192.0.2.1")
+	check(err)
+	println("literal address:", ips[0].String())
+}
+
+func testFakeStack() {
+	net.UseStack(fakeStack{})
+
+	c, err := net.Dial("tcp", "example.test:http")
+	check(err)
+	println("fake dial:", c.RemoteAddr().String())
+	n, err := c.Read(make([]byte, 4))
+	println("fake read:", n, err == io.EOF)
+
+	_, err = net.Dial("tcp", "unknown.test:80")
+	var dnsErr *net.DNSError
+	println("unknown host:", errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+
+	_, err = net.Listen("tcp", ":1234")
+	println("fake listen:", err.Error())
+}
+
+// fakeStack resolves example.test to 192.0.2.10 and creates fake connections.
+type fakeStack struct{}
+
+func (fakeStack) DialTCP(laddr, raddr *net.TCPAddr, deadline time.Time) (net.ConnHandle, error) {
+	return &fakeConn{raddr: raddr}, nil
+}
+
+func (fakeStack) ListenTCP(laddr *net.TCPAddr) (net.ListenerHandle, error) {
+	return nil, errors.New("cannot listen on " + laddr.String())
+}
+
+func (fakeStack) ListenUDP(laddr, raddr *net.UDPAddr) (net.PacketHandle, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeStack) LookupIP(network, host string) ([]net.IP, error) {
+	if host == "example.test" {
+		return []net.IP{net.IPv4(192, 0, 2, 10)}, nil
+	}
+	return nil, net.ErrNoSuchHost
+}
+
+type fakeConn struct {
+	raddr *net.TCPAddr
+}
+
+func (c *fakeConn) Read(b []byte) (int, error)         { return 0, io.EOF }
+func (c *fakeConn) Write(b []byte) (int, error)        { return len(b), nil }
+func (c *fakeConn) Close() error                       { return nil }
+func (c *fakeConn) LocalAddr() net.Addr                { return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000} }
+func (c *fakeConn) RemoteAddr() net.Addr               { return c.raddr }
+func (c *fakeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fakeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+// This test is built as a WASI reactor (-buildmode=c-shared). The host calls
+// _initialize and then the exported run function, but never main.
+
+func init() {
+	println("initialized")
+}
+
+func main() {
+	println("main should not be called in a reactor")
+}
+
+//export run
+func run() {
+	println("run called")
+	go func() {
+		println("goroutine started by run")
+	}()
+}
@@ -0,0 +1,138 @@
+package main
+
+// This test is only run with -panic=unwind.
+
+func main() {
+	println("# simple recover")
+	recoverSimple()
+
+	println("\n# recover with result")
+	println("result:", recoverResult())
+
+	println("\n# runtime error")
+	recoverRuntimeError()
+
+	println("\n# nested panic")
+	recoverNested()
+
+	println("\n# recover in caller")
+	recoverCaller()
+
+	println("\n# panic in deferred function")
+	recoverPanicInDefer()
+
+	println("\n# not panicking")
+	notPanicking()
+
+	println("\n# indirect recover")
+	recoverIndirect()
+
+	println("\n# deferred function value")
+	recoverFunctionValue()
+}
+
+func recoverSimple() {
+	defer func() {
+		println("recovered:", recover().(string))
+	}()
+	println("before panic")
+	panic("foo")
+	println("after panic (unreachable)")
+}
+
+func recoverResult() (result int) {
+	defer func() {
+		if r := recover(); r != nil {
+			println("recovered:", r.(string))
+			result = 3
+		}
+	}()
+	result = 1
+	panic("set result")
+}
+
+func recoverRuntimeError() {
+	defer func() {
+		r := recover()
+		if err, ok := r.(error); ok {
+			println("recovered:", err.Error())
+		}
+	}()
+	var s []int
+	index := 5
+	println(s[index])
+}
+
+func recoverNested() {
+	defer func() {
+		println("recovered:", recover().(string))
+	}()
+	defer func() {
+		println("deferred call runs while panicking")
+	}()
+	nestedPanic(3)
+}
+
+func nestedPanic(depth int) int {
+	if depth == 0 {
+		panic("deep")
+	}
+	return nestedPanic(depth-1) + 1
+}
+
+func recoverCaller() {
+	defer func() {
+		println("recovered in caller:", recover().(string))
+	}()
+	noRecover()
+	println("after noRecover (unreachable)")
+}
+
+func noRecover() {
+	defer func() {
+		println("deferred call without recover")
+	}()
+	panic("bar")
+}
+
+func recoverPanicInDefer() {
+	defer func() {
+		println("recovered:", recover().(string))
+	}()
+	defer func() {
+		panic("second")
+	}()
+	panic("first")
+}
+
+func notPanicking() {
+	defer func() {
+		println("recover returns nil:", recover() == nil)
+	}()
+}
+
+// recover() must only stop a panic when it is called directly by a deferred
+// function.
+func recoverIndirect() {
+	defer func() {
+		println("recovered:", recover().(string))
+	}()
+	defer func() {
+		println("indirect recover returns nil:", callRecover() == nil)
+	}()
+	panic("indirect")
+}
+
+func callRecover() interface{} {
+	return recover()
+}
+
+func recoverFunctionValue() {
+	handler := printRecovered
+	defer handler()
+	panic("function value")
+}
+
+func printRecovered() {
+	println("recovered:", recover().(string))
+}
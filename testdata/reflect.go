@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"math"
 	"reflect"
 	"unsafe"
 )
@@ -281,6 +282,10 @@ func main() {
 	}
 
 	testAppendSlice()
+	testMaps()
+	testDeepEqual()
+	testFuncs()
+	testMethods()
 
 	// Test types that are created in reflect and never created elsewhere in a
 	// value-to-interface conversion.
@@ -375,7 +380,7 @@ func showValue(rv reflect.Value, indent string) {
 		println(indent + "  interface")
 		println(indent+"  nil:", rv.IsNil())
 	case reflect.Map:
-		println(indent + "  map")
+		println(indent+"  map:", rt.Key().Kind().String(), rt.Elem().Kind().String(), rv.Len())
 		println(indent+"  nil:", rv.IsNil())
 	case reflect.Ptr:
 		println(indent+"  pointer:", rv.Pointer() != 0, rt.Elem().Kind().String())
@@ -425,6 +430,261 @@ func testAppendSlice() {
 	}
 }
 
+// Test reading and modifying maps through reflection, for all the ways the
+// runtime hashmap stores keys (binary, string and interface keys).
+func testMaps() {
+	println("\nmaps:")
+
+	// Map with string keys.
+	m := map[string]int{"one": 1, "two": 2, "three": 3}
+	rv := reflect.ValueOf(m)
+	println("MapIndex:", rv.MapIndex(reflect.ValueOf("two")).Int(), rv.MapIndex(reflect.ValueOf("four")).IsValid())
+	sum := 0
+	keys := rv.MapKeys()
+	for _, key := range keys {
+		sum += m[key.String()]
+	}
+	println("MapKeys:", len(keys), sum)
+	rv.SetMapIndex(reflect.ValueOf("four"), reflect.ValueOf(4))
+	rv.SetMapIndex(reflect.ValueOf("one"), reflect.Value{})
+	_, ok := m["one"]
+	println("SetMapIndex:", len(m), m["four"], ok)
+
+	// Map with binary keys, created through reflection.
+	rv = reflect.MakeMap(reflect.TypeOf(map[int]string{}))
+	rv.SetMapIndex(reflect.ValueOf(5), reflect.ValueOf("five"))
+	rv.SetMapIndex(reflect.ValueOf(7), reflect.ValueOf("seven"))
+	intMap := rv.Interface().(map[int]string)
+	println("MakeMap:", len(intMap), intMap[5], intMap[7])
+	n := 0
+	iter := rv.MapRange()
+	for iter.Next() {
+		if intMap[int(iter.Key().Int())] != iter.Value().String() {
+			println("MapRange: mismatch for key", iter.Key().Int())
+		}
+		n++
+	}
+	println("MapRange:", n)
+
+	// Map with keys that are stored as interfaces.
+	type point struct {
+		Name string
+		X, Y int
+	}
+	pointMap := map[point]int{{"a", 1, 2}: 3}
+	rv = reflect.ValueOf(pointMap)
+	rv.SetMapIndex(reflect.ValueOf(point{"b", 3, 4}), reflect.ValueOf(7))
+	println("struct keys:", len(pointMap), pointMap[point{"b", 3, 4}], rv.MapIndex(reflect.ValueOf(point{"a", 1, 2})).Int())
+	itfMap := map[interface{}]interface{}{}
+	rv = reflect.ValueOf(itfMap)
+	rv.SetMapIndex(reflect.ValueOf(3), reflect.ValueOf("three"))
+	rv.SetMapIndex(reflect.ValueOf("four"), reflect.ValueOf(4))
+	println("interface keys:", len(itfMap), itfMap[3].(string), itfMap["four"].(int))
+
+	// Maps that are not stored directly in the reflect.Value.
+	s := struct{ M map[string]int }{m}
+	rv = reflect.ValueOf(&s).Elem().Field(0)
+	println("indirect map:", rv.Len(), rv.MapIndex(reflect.ValueOf("three")).Int())
+
+	// Lookups in a nil map.
+	var nilMap map[string]int
+	println("nil map:", reflect.ValueOf(nilMap).MapIndex(reflect.ValueOf("one")).IsValid(), len(reflect.ValueOf(nilMap).MapKeys()))
+}
+
+// Test reflect.DeepEqual for all kinds of values, including cyclic data
+// structures.
+func testDeepEqual() {
+	println("\ndeep equal:")
+	type point struct {
+		X, Y int
+		name string
+	}
+	a := &linkedList{foo: 1}
+	a.next = a
+	b := &linkedList{foo: 1}
+	b.next = b
+	c := &linkedList{foo: 2}
+	c.next = c
+	var nilFunc func()
+	for _, tc := range []struct {
+		name string
+		x, y interface{}
+	}{
+		{"int", 3, 3},
+		{"int/uint", 3, uint(3)},
+		{"string", "foo", "foo"},
+		{"float NaN", math.NaN(), math.NaN()},
+		{"array", [3]int{1, 2, 3}, [3]int{1, 2, 3}},
+		{"array diff", [3]int{1, 2, 3}, [3]int{1, 2, 4}},
+		{"slice", []string{"a", "b"}, []string{"a", "b"}},
+		{"slice len", []string{"a", "b"}, []string{"a"}},
+		{"slice nil", []int{}, []int(nil)},
+		{"struct", point{1, 2, "p"}, point{1, 2, "p"}},
+		{"struct unexported", point{1, 2, "p"}, point{1, 2, "q"}},
+		{"map", map[string]int{"a": 1, "b": 2}, map[string]int{"b": 2, "a": 1}},
+		{"map diff", map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1, "c": 2}},
+		{"map nested", map[int][]int{1: {2}}, map[int][]int{1: {2}}},
+		{"pointer", &point{1, 2, "p"}, &point{1, 2, "p"}},
+		{"interface", []interface{}{1, "a", nil}, []interface{}{1, "a", nil}},
+		{"interface diff", []interface{}{1, "a", nil}, []interface{}{1, "a", 0}},
+		{"cycle", a, b},
+		{"cycle diff", a, c},
+		{"func nil", nilFunc, nilFunc},
+		{"func", emptyFunc, emptyFunc},
+	} {
+		println(tc.name+":", reflect.DeepEqual(tc.x, tc.y))
+	}
+}
+
+func testFuncs() {
+	println("\nfuncs:")
+
+	// Multiple parameters and results.
+	divmod := func(a, b int) (int, int) {
+		return a / b, a % b
+	}
+	rv := reflect.ValueOf(divmod)
+	rt := rv.Type()
+	println("divmod type:", rt.NumIn(), rt.NumOut(), rt.IsVariadic(), rt.In(0).Kind().String(), rt.Out(1).Kind().String())
+	results := rv.Call([]reflect.Value{reflect.ValueOf(17), reflect.ValueOf(5)})
+	println("divmod:", len(results), results[0].Int(), results[1].Int())
+
+	// Variadic functions, with values that are bigger than a pointer.
+	concat := func(a string, b ...string) string {
+		for _, s := range b {
+			a += s
+		}
+		return a
+	}
+	rv = reflect.ValueOf(concat)
+	println("concat type:", rv.Type().IsVariadic(), rv.Type().In(1).Kind().String())
+	println("concat:", rv.Call([]reflect.Value{reflect.ValueOf("a"), reflect.ValueOf("b"), reflect.ValueOf("c")})[0].String())
+	println("concat none:", rv.Call([]reflect.Value{reflect.ValueOf("x")})[0].String())
+	println("concat slice:", rv.CallSlice([]reflect.Value{reflect.ValueOf("d"), reflect.ValueOf([]string{"e", "f"})})[0].String())
+
+	// Closures with an interface parameter and no results.
+	var stored interface{}
+	store := func(v interface{}) {
+		stored = v
+	}
+	results = reflect.ValueOf(store).Call([]reflect.Value{reflect.ValueOf(5)})
+	println("store:", len(results), stored.(int))
+
+	// Functions created with MakeFunc, called directly and using reflect.
+	var swap func(int, string) (string, int)
+	swapValue := reflect.MakeFunc(reflect.TypeOf(swap), func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{args[1], args[0]}
+	})
+	swap = swapValue.Interface().(func(int, string) (string, int))
+	s, n := swap(3, "three")
+	println("swap:", s, n)
+	results = swapValue.Call([]reflect.Value{reflect.ValueOf(4), reflect.ValueOf("four")})
+	println("swap call:", results[0].String(), results[1].Int())
+
+	// The func type may also be taken from a pointer to a func variable.
+	var double func(int) int
+	doubleValue := reflect.ValueOf(&double).Elem()
+	doubleValue.Set(reflect.MakeFunc(doubleValue.Type(), func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(int(args[0].Int() * 2))}
+	}))
+	println("double:", double(21))
+
+	// The func type may also be passed around, and parameters and results
+	// only need to be assignable: a concrete value may be used for an
+	// interface type, and a named type for an unnamed type.
+	describeType := reflect.TypeOf(struct {
+		Describe func(interface{}) error
+	}{}).Field(0).Type
+	describe := makeFunc(describeType, func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(errorValue)}
+	}).Interface().(func(interface{}) error)
+	println("describe:", describe(5).Error())
+	length := func(b []byte) int {
+		return len(b)
+	}
+	results = reflect.ValueOf(length).Call([]reflect.Value{reflect.ValueOf(myslice{1, 2, 3})})
+	println("length:", results[0].Int())
+}
+
+// makeFunc calls reflect.MakeFunc with a func type that is not known at
+// compile time.
+func makeFunc(typ reflect.Type, fn func([]reflect.Value) []reflect.Value) reflect.Value {
+	return reflect.MakeFunc(typ, fn)
+}
+
+type counter struct {
+	n int
+}
+
+func (c counter) Get() int {
+	return c.n
+}
+
+func (c *counter) Add(n int) {
+	c.n += n
+}
+
+func (c counter) Sum(values ...int) int {
+	sum := c.n
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
+
+func (c counter) reset() {
+	c.n = 0
+}
+
+type namedCounter struct {
+	counter
+	Name string
+}
+
+type getter interface {
+	Get() int
+}
+
+func testMethods() {
+	println("\nmethods:")
+	c := &counter{n: 3}
+	t := reflect.TypeOf(c)
+	println("num methods:", t.NumMethod(), t.Elem().NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		println("method:", m.Name, m.Index, m.Type.NumIn(), m.Type.NumOut(), m.Type.In(0) == t)
+	}
+	m, ok := t.MethodByName("Add")
+	println("Add found:", ok, m.Index)
+	m.Func.Call([]reflect.Value{reflect.ValueOf(c), reflect.ValueOf(4)})
+	println("after Add:", c.n)
+	_, ok = t.MethodByName("reset")
+	println("reset found:", ok)
+
+	// Call methods bound to a value.
+	v := reflect.ValueOf(c)
+	v.MethodByName("Add").Call([]reflect.Value{reflect.ValueOf(5)})
+	get := v.Elem().MethodByName("Get")
+	println("get type:", get.Kind() == reflect.Func, get.Type().NumIn(), get.Type().NumOut())
+	println("get:", get.Call(nil)[0].Int())
+	sum := v.MethodByName("Sum").Call([]reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2)})
+	println("sum:", sum[0].Int())
+	println("missing:", v.MethodByName("Missing").IsValid())
+
+	// Methods promoted from an embedded field.
+	nv := reflect.ValueOf(namedCounter{counter: counter{n: 7}})
+	println("promoted:", nv.NumMethod(), nv.Method(0).Call(nil)[0].Int())
+	getFunc := nv.MethodByName("Get").Interface().(func() int)
+	println("get func:", getFunc())
+
+	// Methods of interface types.
+	var g getter = counter{n: 9}
+	gv := reflect.ValueOf(&g).Elem()
+	gm := gv.Type().Method(0)
+	println("interface method:", gv.Type().NumMethod(), gm.Name, gm.Type.NumIn(), gm.Type.NumOut(), gm.Func.IsValid())
+	println("interface call:", gv.NumMethod(), gv.Method(0).Call(nil)[0].Int(), gv.MethodByName("Get").Call(nil)[0].Int())
+}
+
 func makeRandomSlice(max int) []uint32 {
 	cap := randuint32() % uint32(max+1)
 	len := randuint32() % (cap + 1)
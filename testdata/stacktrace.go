@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type T struct{}
+
+func main() {
+	printCaller()
+	printCallers()
+	t := &T{}
+	t.method()
+	func() {
+		printCaller()
+	}()
+
+	done := make(chan bool)
+	go func() {
+		printCaller()
+		done <- true
+	}()
+	<-done
+
+	_, file, line, ok := runtime.Caller(0)
+	println("Caller(0):", filepath.Base(file), line, ok)
+
+	// Stack prints other goroutines too when all is set.
+	go func() {
+		time.Sleep(time.Hour)
+	}()
+	runtime.Gosched()
+	buf := make([]byte, 4096)
+	stack := string(buf[:runtime.Stack(buf, false)])
+	println("running goroutine:", strings.Contains(stack, "main.main()"), strings.Contains(stack, "main.main.func3"))
+	stack = string(buf[:runtime.Stack(buf, true)])
+	println("sleeping goroutine:", strings.Contains(stack, "main.main.func3"))
+}
+
+func (t *T) method() {
+	printCaller()
+}
+
+func printCaller() {
+	pc, file, line, ok := runtime.Caller(1)
+	println("caller:", runtime.FuncForPC(pc).Name(), filepath.Base(file), line, ok)
+}
+
+func printCallers() {
+	pcs := make([]uintptr, 10)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		println("frame:", frame.Function, filepath.Base(frame.File), frame.Line)
+		if !more || frame.Function == "main.main" {
+			break
+		}
+	}
+}
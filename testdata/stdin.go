@@ -0,0 +1,32 @@
+package main
+
+// This test reads from stdin in a goroutine while a timer keeps running. The
+// test runner keeps stdin open for a while before writing to it, so the timer
+// must fire while the read is still waiting.
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+func main() {
+	done := make(chan string)
+	go func() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			println("error:", err.Error())
+		}
+		done <- string(data)
+	}()
+
+	timer := time.NewTimer(10 * time.Millisecond)
+	select {
+	case <-timer.C:
+		println("timer fired before the read completed")
+	case data := <-done:
+		println("read completed before the timer fired:", data)
+		return
+	}
+	print("read from stdin: ", <-done)
+}
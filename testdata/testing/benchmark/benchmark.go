@@ -0,0 +1,10 @@
+package benchmark
+
+// Sum returns the sum of the given numbers.
+func Sum(values ...int) int {
+	sum := 0
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
@@ -0,0 +1,17 @@
+package benchmark
+
+import "testing"
+
+func BenchmarkPass(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum(1, 2, 3)
+	}
+}
+
+// BenchmarkFail only fails in a sub-benchmark, which must still make the test
+// binary fail.
+func BenchmarkFail(b *testing.B) {
+	b.Run("sub", func(b *testing.B) {
+		b.Errorf("sum is %d", Sum(1, 2))
+	})
+}
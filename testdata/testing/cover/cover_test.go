@@ -0,0 +1,9 @@
+package cover
+
+import "testing"
+
+func TestAbs(t *testing.T) {
+	if Abs(-3) != 3 {
+		t.Error("Abs(-3) != 3")
+	}
+}
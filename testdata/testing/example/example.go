@@ -0,0 +1,6 @@
+package example
+
+// Greet returns a greeting for the given name.
+func Greet(name string) string {
+	return "hello, " + name
+}
@@ -0,0 +1,24 @@
+package example
+
+import "fmt"
+
+func ExampleGreet() {
+	fmt.Println(Greet("gopher"))
+	// Output: hello, gopher
+}
+
+func ExampleGreet_unordered() {
+	for _, name := range []string{"b", "c", "a"} {
+		fmt.Println(Greet(name))
+	}
+	// Unordered output:
+	// hello, a
+	// hello, b
+	// hello, c
+}
+
+// This example has the wrong output, so it fails.
+func ExampleGreet_fail() {
+	fmt.Println(Greet("world"))
+	// Output: hello, gopher
+}
@@ -0,0 +1,31 @@
+package flags
+
+import "testing"
+
+func TestPass(t *testing.T) {
+	t.Log("double:", Double(2))
+}
+
+func TestFail(t *testing.T) {
+	t.Errorf("double is %d", Double(3))
+}
+
+func TestFailToo(t *testing.T) {
+	t.Error("also failed")
+}
+
+func TestShort(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipped in short mode")
+	}
+	t.Log("not short")
+}
+
+func TestSub(t *testing.T) {
+	t.Run("one", func(t *testing.T) {
+		t.Log("in one")
+	})
+	t.Run("two", func(t *testing.T) {
+		t.Log("in two")
+	})
+}
@@ -0,0 +1,3 @@
+// Package goexit is used to test that FailNow runs the deferred calls of a test
+// with -panic=unwind.
+package goexit
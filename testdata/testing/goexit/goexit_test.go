@@ -0,0 +1,13 @@
+package goexit
+
+import "testing"
+
+func TestFatalDefer(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		t.Cleanup(func() { t.Log("cleanup ran") })
+		defer t.Log("deferred call ran")
+		t.Fatal("stop")
+		t.Error("not reached")
+	})
+	t.Log("parent continues")
+}
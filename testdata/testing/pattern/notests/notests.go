@@ -0,0 +1,4 @@
+package notests
+
+// Answer has no tests.
+const Answer = 42
@@ -0,0 +1,6 @@
+package one
+
+// Name returns the name of this package.
+func Name() string {
+	return "one"
+}
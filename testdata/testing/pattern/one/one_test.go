@@ -0,0 +1,9 @@
+package one
+
+import "testing"
+
+func TestName(t *testing.T) {
+	if Name() != "one" {
+		t.Error("unexpected name:", Name())
+	}
+}
@@ -0,0 +1,6 @@
+package two
+
+// Name returns the name of this package.
+func Name() string {
+	return "two"
+}
@@ -0,0 +1,9 @@
+package two
+
+import "testing"
+
+func TestName(t *testing.T) {
+	if Name() != "two" {
+		t.Error("unexpected name:", Name())
+	}
+}
@@ -0,0 +1,26 @@
+// Package subtests is used to test subtests, parallel tests and the functions
+// that clean up after a test.
+package subtests
+
+import "sync"
+
+var (
+	eventsLock sync.Mutex
+	events     []string
+)
+
+// Record appends an event to the list of events.
+func Record(event string) {
+	eventsLock.Lock()
+	events = append(events, event)
+	eventsLock.Unlock()
+}
+
+// Events returns the recorded events and clears the list.
+func Events() []string {
+	eventsLock.Lock()
+	defer eventsLock.Unlock()
+	recorded := events
+	events = nil
+	return recorded
+}
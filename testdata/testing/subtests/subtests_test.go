@@ -0,0 +1,85 @@
+package subtests
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanup(t *testing.T) {
+	Events()
+	t.Cleanup(func() { Record("cleanup 1") })
+	t.Cleanup(func() { Record("cleanup 2") })
+	t.Run("sub", func(t *testing.T) {
+		t.Cleanup(func() { Record("sub cleanup") })
+		Record("sub")
+	})
+	Record("test")
+}
+
+// TestCleanupOrder prints the events of TestCleanup, which has finished by now.
+func TestCleanupOrder(t *testing.T) {
+	t.Log(strings.Join(Events(), ", "))
+}
+
+func TestParallel(t *testing.T) {
+	Events()
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				Record("start")
+				time.Sleep(10 * time.Millisecond)
+				Record("end")
+			})
+		}
+		Record("group returned")
+	})
+
+	// The parallel subtests only start when the group function returns, and
+	// Run waits for them to finish. They all start before any of them ends.
+	events := strings.Join(Events(), ", ")
+	if events != "group returned, start, start, start, end, end, end" {
+		t.Error("unexpected events:", events)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		t.Cleanup(func() { t.Log("cleanup ran") })
+		t.Fatal("stop")
+		t.Error("not reached")
+	})
+	t.Log("parent continues")
+}
+
+var tempDir string
+
+func TestTempDir(t *testing.T) {
+	t.Cleanup(func() {
+		if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+			t.Error("temporary directory was not removed:", err)
+		}
+	})
+	tempDir = t.TempDir()
+	if err := os.WriteFile(tempDir+"/file.txt", []byte("data"), 0666); err != nil {
+		t.Error("could not write to temporary directory:", err)
+	}
+	if tempDir == t.TempDir() {
+		t.Error("TempDir returned the same directory twice")
+	}
+}
+
+func TestSetenv(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		t.Setenv("TINYGO_SUBTESTS_ENV", "value")
+		if value := os.Getenv("TINYGO_SUBTESTS_ENV"); value != "value" {
+			t.Errorf("expected value, got %q", value)
+		}
+	})
+	if _, ok := os.LookupEnv("TINYGO_SUBTESTS_ENV"); ok {
+		t.Error("environment variable was not restored")
+	}
+}
@@ -0,0 +1,58 @@
+package runtime_test
+
+// Tests for the garbage collector when multiple goroutines allocate at the
+// same time. With -scheduler=cores, these goroutines run on different cores
+// and GC cycles need to stop the other core, for example:
+//
+//     tinygo test -target=pico -scheduler=cores ./tests/runtime
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// node is an element of a linked list, which is checked after every GC cycle.
+type node struct {
+	next  *node
+	value int
+}
+
+func TestGCParallelAlloc(t *testing.T) {
+	const numGoroutines = 4
+	results := make(chan error, numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(seed int) {
+			results <- allocLists(seed)
+		}(i)
+	}
+	for i := 0; i < numGoroutines; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// allocLists repeatedly builds a linked list while running GC cycles, and
+// checks that the list wasn't corrupted by a GC cycle (possibly running on
+// another core).
+func allocLists(seed int) error {
+	const length = 200
+	for round := 0; round < 20; round++ {
+		var list *node
+		for i := 0; i < length; i++ {
+			list = &node{next: list, value: seed*length + i}
+			if i%64 == 0 {
+				runtime.GC()
+			}
+		}
+		for i := length - 1; i >= 0; i-- {
+			if list == nil || list.value != seed*length+i {
+				return fmt.Errorf("goroutine %d: list corrupted at element %d in round %d", seed, i, round)
+			}
+			list = list.next
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
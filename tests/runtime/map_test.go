@@ -0,0 +1,75 @@
+package runtime_test
+
+// Benchmarks for the runtime hashmap implementation. Run them with:
+//
+//     tinygo test -bench=. ./tests/runtime
+//
+// The time per lookup should stay roughly the same as the map grows.
+
+import (
+	"strconv"
+	"testing"
+)
+
+var mapSizes = []int{100, 1000, 10000, 100000}
+
+func BenchmarkMapLookupInt(b *testing.B) {
+	for _, size := range mapSizes {
+		m := make(map[int]int)
+		for i := 0; i < size; i++ {
+			m[i] = i
+		}
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if m[i%size] != i%size {
+					b.Fatal("unexpected value")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMapLookupString(b *testing.B) {
+	for _, size := range mapSizes {
+		keys := make([]string, size)
+		m := make(map[string]int)
+		for i := range keys {
+			keys[i] = "key" + strconv.Itoa(i)
+			m[keys[i]] = i
+		}
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if m[keys[i%size]] != i%size {
+					b.Fatal("unexpected value")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMapInsert(b *testing.B) {
+	for _, size := range mapSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := make(map[int]int)
+				for j := 0; j < size; j++ {
+					m[j] = j
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMapDelete(b *testing.B) {
+	for _, size := range mapSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			m := make(map[int]int)
+			for i := 0; i < b.N; i++ {
+				// Keep the map at the given size, while deleting and inserting
+				// keys.
+				m[i] = i
+				delete(m, i-size)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"testing" // This is the tinygo testing package
 )
 
@@ -23,3 +24,35 @@ func TestPass(t *testing.T) {
 
 func BenchmarkNotImplemented(b *testing.B) {
 }
+
+func ExampleThing() {
+	Thing()
+	// Output: THING
+}
+
+func TestSubtests(t *testing.T) {
+	var order []string
+	t.Cleanup(func() {
+		if len(order) != 3 {
+			t.Errorf("expected all subtests to have run, got %v", order)
+		}
+	})
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if dir == "" {
+				t.Error("empty temp dir")
+			}
+			order = append(order, name)
+		})
+	}
+}
+
+func TestSetenv(t *testing.T) {
+	t.Setenv("TINYGO_TEST_VAR", "value")
+	if v := os.Getenv("TINYGO_TEST_VAR"); v != "value" {
+		t.Errorf("unexpected value for TINYGO_TEST_VAR: %q", v)
+	}
+}
@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// CreateCallSiteTable bundles all call site records created by the compiler
+// for stack traces into a single table, runtime.callSites. Every use of a call
+// site record is replaced with a pointer into this table. The table location is
+// stored in runtime.callSitesStart and runtime.callSitesLength, so that the
+// runtime can check whether a given pc value refers to a call site.
+//
+// The call site records are recognized by their name, which starts with
+// "runtime.callsite:". This pass should be run late in the pipeline, after all
+// dead code has been removed, to avoid keeping unused call sites in the table.
+func CreateCallSiteTable(mod llvm.Module) bool {
+	callSitesStart := mod.NamedGlobal("runtime.callSitesStart")
+	callSitesLength := mod.NamedGlobal("runtime.callSitesLength")
+	if callSitesStart.IsNil() || callSitesLength.IsNil() {
+		return false // nothing to do: stack traces are not used
+	}
+
+	ctx := mod.Context()
+	targetData := llvm.NewTargetData(mod.DataLayout())
+	uintptrType := ctx.IntType(targetData.PointerSize() * 8)
+
+	// Collect all call site records.
+	var callSites []llvm.Value
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if global.IsDeclaration() || !strings.HasPrefix(global.Name(), "runtime.callsite:") {
+			continue
+		}
+		callSites = append(callSites, global)
+	}
+
+	callSitesStart.SetLinkage(llvm.InternalLinkage)
+	callSitesLength.SetLinkage(llvm.InternalLinkage)
+	callSitesLength.SetInitializer(llvm.ConstInt(uintptrType, uint64(len(callSites)), false))
+	if len(callSites) == 0 {
+		// No stack traces were emitted, so the table is empty.
+		callSitesStart.SetInitializer(llvm.ConstInt(uintptrType, 0, false))
+		return true
+	}
+
+	// Create the call site table and replace all existing call site records
+	// with a pointer into this table.
+	elementType := callSites[0].Type().ElementType()
+	values := make([]llvm.Value, len(callSites))
+	for i, site := range callSites {
+		values[i] = site.Initializer()
+	}
+	tableValue := llvm.ConstArray(elementType, values)
+	table := llvm.AddGlobal(mod, tableValue.Type(), "runtime.callSites")
+	table.SetInitializer(tableValue)
+	table.SetLinkage(llvm.InternalLinkage)
+	table.SetGlobalConstant(true)
+	for i, site := range callSites {
+		gep := llvm.ConstGEP(table, []llvm.Value{
+			llvm.ConstInt(ctx.Int32Type(), 0, false),
+			llvm.ConstInt(ctx.Int32Type(), uint64(i), false),
+		})
+		site.ReplaceAllUsesWith(gep)
+		site.EraseFromParentAsGlobal()
+	}
+	callSitesStart.SetInitializer(llvm.ConstPtrToInt(table, uintptrType))
+
+	return true
+}
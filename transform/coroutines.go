@@ -185,6 +185,9 @@ type coroutineLoweringPass struct {
 	setState, setRetPtr, getRetPtr, returnTo, returnCurrent llvm.Value
 	createTask                                              llvm.Value
 
+	// call stack of the current goroutine, only used for stack traces
+	callStack, setCallStack llvm.Value
+
 	// llvm.coro intrinsics
 	coroId, coroSize, coroBegin, coroSuspend, coroEnd, coroFree, coroSave llvm.Value
 
@@ -341,6 +344,10 @@ func (c *coroutineLoweringPass) load() error {
 		return ErrMissingIntrinsic{"internal/task.createTask"}
 	}
 
+	// These are only present when stack traces are used.
+	c.callStack = c.mod.NamedGlobal("runtime.callStack")
+	c.setCallStack = c.mod.NamedFunction("(*internal/task.Task).setCallStack")
+
 	if c.needStackSlots {
 		c.trackPointer = c.mod.NamedFunction("runtime.trackPointer")
 		if c.trackPointer.IsNil() {
@@ -627,7 +634,8 @@ func (async *asyncFunc) hasValueStoreReturn() bool {
 func (c *coroutineLoweringPass) heapAlloc(t llvm.Type, name string) llvm.Value {
 	sizeT := c.alloc.FirstParam().Type()
 	size := llvm.ConstInt(sizeT, c.target.TypeAllocSize(t), false)
-	return c.builder.CreateCall(c.alloc, []llvm.Value{size, llvm.Undef(c.i8ptr), llvm.Undef(c.i8ptr)}, name)
+	layout := llvmutil.CreateObjectLayout(c.mod, t)
+	return c.builder.CreateCall(c.alloc, []llvm.Value{size, layout, llvm.Undef(c.i8ptr), llvm.Undef(c.i8ptr)}, name)
 }
 
 // lowerFuncFast lowers an async function that has no suspend points.
@@ -776,8 +784,10 @@ func (c *coroutineLoweringPass) lowerFuncCoro(fn *asyncFunc) {
 	}, "coro.id")
 	// %coro.size = call i32 @llvm.coro.size.i32()
 	coroSize := c.builder.CreateCall(c.coroSize, []llvm.Value{}, "coro.size")
-	// %coro.alloc = call i8* runtime.alloc(i32 %coro.size)
-	coroAlloc := c.builder.CreateCall(c.alloc, []llvm.Value{coroSize, llvm.Undef(c.i8ptr), llvm.Undef(c.i8ptr)}, "coro.alloc")
+	// %coro.alloc = call i8* runtime.alloc(i32 %coro.size, i8* null)
+	// The layout of the coroutine frame is not known, so it is scanned
+	// conservatively.
+	coroAlloc := c.builder.CreateCall(c.alloc, []llvm.Value{coroSize, llvm.ConstPointerNull(c.i8ptr), llvm.Undef(c.i8ptr), llvm.Undef(c.i8ptr)}, "coro.alloc")
 	// %coro.state = call noalias i8* @llvm.coro.begin(token %coro.id, i8* %coro.alloc)
 	coroState := c.builder.CreateCall(c.coroBegin, []llvm.Value{coroId, coroAlloc}, "coro.state")
 	c.track(coroState)
@@ -1002,6 +1012,15 @@ func (c *coroutineLoweringPass) lowerStart(start llvm.Value) {
 	// Generate call to function.
 	c.builder.CreateCall(fn, params, "")
 
+	if !c.callStack.IsNil() && !c.setCallStack.IsNil() {
+		// The goroutine has paused or exited. Store its call stack in the
+		// task, so that the scheduler can restore it when the goroutine is
+		// resumed.
+		frame := c.builder.CreateLoad(c.callStack, "start.callstack")
+		frame = c.builder.CreateBitCast(frame, c.i8ptr, "")
+		c.builder.CreateCall(c.setCallStack, []llvm.Value{task, frame, llvm.Undef(c.i8ptr), llvm.Undef(c.i8ptr)}, "")
+	}
+
 	// Erase start call.
 	start.EraseFromParentAsInstruction()
 }
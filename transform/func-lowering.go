@@ -52,6 +52,13 @@ func LowerFuncValues(mod llvm.Module) {
 	builder := ctx.NewBuilder()
 	uintptrType := ctx.IntType(llvm.NewTargetData(mod.DataLayout()).PointerSize() * 8)
 
+	// The reflect trampolines of func types and the functions implementing
+	// methods (for reflect) are only referenced as func values. Remove them
+	// first if they're not used, otherwise they would be included as possible
+	// callees of these func types.
+	removeUnusedFuncTrampolines(mod)
+	removeUnusedMethods(mod)
+
 	// Find all func values used in the program with their signatures.
 	signatures := map[string]*funcSignatureInfo{}
 	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
@@ -2,6 +2,7 @@ package transform
 
 import (
 	"math/big"
+	"strings"
 
 	"tinygo.org/x/go-llvm"
 )
@@ -304,6 +305,9 @@ func AddGlobalsBitmap(mod llvm.Module) bool {
 		if global.IsDeclaration() || global.IsGlobalConstant() {
 			continue
 		}
+		if strings.HasPrefix(global.Name(), "llvm.") {
+			continue // special globals like llvm.used, not visible at runtime
+		}
 		typ := global.Type().ElementType()
 		ptrs := getPointerBitmap(targetData, typ, global.Name())
 		if ptrs.BitLen() == 0 {
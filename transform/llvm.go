@@ -75,6 +75,24 @@ func replaceGlobalIntWithArray(mod llvm.Module, name string, buf interface{}) ll
 	return global
 }
 
+// replaceGlobalWithConstArray is like replaceGlobalIntWithArray, but takes a
+// list of constants (of the global type) as array elements instead of a
+// slice of integers.
+func replaceGlobalWithConstArray(mod llvm.Module, name string, values []llvm.Value) llvm.Value {
+	oldGlobal := mod.NamedGlobal(name)
+	value := llvm.ConstArray(oldGlobal.Type().ElementType(), values)
+	global := llvm.AddGlobal(mod, value.Type(), name+".tmp")
+	global.SetInitializer(value)
+	gep := llvm.ConstGEP(global, []llvm.Value{
+		llvm.ConstInt(mod.Context().Int32Type(), 0, false),
+		llvm.ConstInt(mod.Context().Int32Type(), 0, false),
+	})
+	oldGlobal.ReplaceAllUsesWith(gep)
+	oldGlobal.EraseFromParentAsGlobal()
+	global.SetName(name)
+	return global
+}
+
 // typeHasPointers returns whether this type is a pointer or contains pointers.
 // If the type is an aggregate type, it will check whether there is a pointer
 // inside.
@@ -125,7 +125,7 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 		if err != nil {
 			return []error{err}
 		}
-	case "tasks":
+	case "tasks", "cores":
 		// No transformations necessary.
 	case "none":
 		// Check for any goroutine starts.
@@ -175,6 +175,9 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 	builder.Populate(modPasses)
 	modPasses.Run(mod)
 
+	CreatePCTable(mod, config.StackTraces())
+	CreateCallSiteTable(mod)
+
 	hasGCPass := AddGlobalsBitmap(mod)
 	hasGCPass = MakeGCStackSlots(mod) || hasGCPass
 	if hasGCPass {
@@ -210,6 +213,7 @@ var coroFunctionsUsedInTransforms = []string{
 	"(*internal/task.Task).returnCurrent",
 	"(*internal/task.Task).setReturnPtr",
 	"(*internal/task.Task).getReturnPtr",
+	"(*internal/task.Task).setCallStack",
 }
 
 // getFunctionsUsedInTransforms gets a list of all special functions that should be preserved during transforms and optimization.
@@ -219,7 +223,7 @@ func getFunctionsUsedInTransforms(config *compileopts.Config) []string {
 	case "none":
 	case "coroutines":
 		fnused = append(append([]string{}, fnused...), coroFunctionsUsedInTransforms...)
-	case "tasks":
+	case "tasks", "cores":
 		fnused = append(append([]string{}, fnused...), taskFunctionsUsedInTransforms...)
 	default:
 		panic(fmt.Errorf("invalid scheduler %q", config.Scheduler()))
@@ -0,0 +1,235 @@
+package transform
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/compiler/llvmutil"
+	"tinygo.org/x/go-llvm"
+)
+
+// CreatePCTable creates the table that the runtime uses to find the call site
+// of a return address on the call stack, runtime.pcTable. It contains an entry
+// for the start of every function and for every call instruction, sorted by
+// address when the runtime first uses it. The table location is stored in
+// runtime.pcTableStart and runtime.pcTableLength.
+//
+// Each call gets its own basic block so that its address can be stored in the
+// table, and a chain of call site records: one for the innermost function and
+// one for each function it was inlined into, as found in the debug location of
+// the call. The function of each call site is found by the file and line of
+// its DISubprogram: the compiler creates a runtime.Func object named after this
+// location for every function (see compiler/stacktrace.go). Calls in functions
+// at the bottom of the call stack, such as goroutine start wrappers and
+// functions called from outside Go, get runtime.stackBottom as the parent of
+// the outermost call site, so that the runtime stops walking the stack there.
+//
+// Additionally, all functions are marked to keep their frame pointer and to
+// not do tail calls, so that the runtime can walk the call stack.
+//
+// If stackTraces is false, the table is left empty. This pass should be run
+// after all optimizations and before CreateCallSiteTable, which bundles the
+// call site records created here.
+func CreatePCTable(mod llvm.Module, stackTraces bool) bool {
+	// The function information is only needed to create the call site
+	// records. Remove the unused objects afterwards.
+	hasFuncInfo := false
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if strings.HasPrefix(global.Name(), "runtime.funcinfo:") && global.Linkage() == llvm.WeakODRLinkage {
+			global.SetLinkage(llvm.InternalLinkage)
+			hasFuncInfo = true
+		}
+	}
+	if hasFuncInfo {
+		defer func() {
+			pm := llvm.NewPassManager()
+			defer pm.Dispose()
+			pm.AddGlobalDCEPass()
+			pm.Run(mod)
+		}()
+	}
+
+	pcTableStart := mod.NamedGlobal("runtime.pcTableStart")
+	pcTableLength := mod.NamedGlobal("runtime.pcTableLength")
+	if pcTableStart.IsNil() || pcTableLength.IsNil() {
+		return false // nothing to do: stack traces are not used
+	}
+
+	ctx := mod.Context()
+	targetData := llvm.NewTargetData(mod.DataLayout())
+	uintptrType := ctx.IntType(targetData.PointerSize() * 8)
+	i8ptrType := llvm.PointerType(ctx.Int8Type(), 0)
+	builder := ctx.NewBuilder()
+	defer builder.Dispose()
+
+	pcTableStart.SetLinkage(llvm.InternalLinkage)
+	pcTableLength.SetLinkage(llvm.InternalLinkage)
+	if !stackTraces {
+		pcTableStart.SetInitializer(llvm.ConstInt(uintptrType, 0, false))
+		pcTableLength.SetInitializer(llvm.ConstInt(uintptrType, 0, false))
+		return true
+	}
+
+	// These types match runtime.pcEntry and runtime.callSite.
+	entryType := ctx.StructType([]llvm.Type{uintptrType, i8ptrType}, false)
+	siteType := ctx.StructType([]llvm.Type{i8ptrType, uintptrType, i8ptrType}, false)
+	nullPtr := llvm.ConstPointerNull(i8ptrType)
+
+	// Return the call site record with the given contents, creating it if
+	// needed.
+	type siteKey struct {
+		function llvm.Value
+		line     uint
+		parent   llvm.Value
+	}
+	sites := make(map[siteKey]llvm.Value)
+	getSite := func(key siteKey, fn llvm.Value) llvm.Value {
+		if site, ok := sites[key]; ok {
+			return site
+		}
+		global := llvm.AddGlobal(mod, siteType, "runtime.callsite:"+fn.Name())
+		global.SetInitializer(llvm.ConstStruct([]llvm.Value{
+			key.function,
+			llvm.ConstInt(uintptrType, uint64(key.line), false),
+			key.parent,
+		}, false))
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetGlobalConstant(true)
+		site := llvm.ConstBitCast(global, i8ptrType)
+		sites[key] = site
+		return site
+	}
+
+	// Return the runtime.Func object for the given DISubprogram, or nil if it
+	// is not known.
+	getFunc := func(scope llvm.Metadata) llvm.Value {
+		if scope.Kind() != llvm.DISubprogramMetadataKind {
+			return nullPtr
+		}
+		file := scope.ScopeFile()
+		name := "runtime.funcinfo:" + filepath.Join(file.FileDirectory(), file.FileFilename()) + ":" + strconv.Itoa(int(scope.SubprogramLine()))
+		function := mod.NamedGlobal(name)
+		if function.IsNil() {
+			return nullPtr
+		}
+		return llvm.ConstBitCast(function, i8ptrType)
+	}
+
+	bottom := nullPtr
+	if stackBottom := mod.NamedGlobal("runtime.stackBottom"); !stackBottom.IsNil() {
+		bottom = llvm.ConstBitCast(stackBottom, i8ptrType)
+	}
+	frameAttrs := []llvm.Attribute{
+		ctx.CreateStringAttribute("frame-pointer", "all"),
+		ctx.CreateStringAttribute("disable-tail-calls", "true"),
+	}
+
+	var entries []llvm.Value
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() {
+			continue
+		}
+		for _, attr := range frameAttrs {
+			fn.AddFunctionAttr(attr)
+		}
+
+		// Mark the start of the function, so that a return address is never
+		// attributed to the previous function.
+		entries = append(entries, llvm.ConstStruct([]llvm.Value{
+			llvm.ConstPtrToInt(fn, uintptrType),
+			nullPtr,
+		}, false))
+
+		// Calls in a goroutine start wrapper or in a function that may be
+		// called from outside Go are at the bottom of the call stack.
+		outerParent := nullPtr
+		linkage := fn.Linkage()
+		isGoWrapper := !fn.GetStringAttributeAtIndex(-1, "tinygo-gowrapper").IsNil()
+		if isGoWrapper || (linkage != llvm.InternalLinkage && linkage != llvm.PrivateLinkage) {
+			outerParent = bottom
+		}
+
+		// The debug information of wrapper functions points to the go
+		// statement or to the wrapped method, but they should not show up in
+		// a stack trace.
+		isWrapper := isGoWrapper || strings.HasSuffix(fn.Name(), "$invoke")
+
+		var calls []llvm.Value
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				if inst.IsACallInst().IsNil() || !inst.IsAIntrinsicInst().IsNil() || !inst.CalledValue().IsAInlineAsm().IsNil() {
+					continue
+				}
+				calls = append(calls, inst)
+			}
+		}
+
+		for _, call := range calls {
+			// Create the call site records from the outermost to the innermost
+			// function. A call without debug information (in a
+			// compiler-generated function) gets a call site without function,
+			// which is skipped by the runtime.
+			var locations []llvm.Metadata
+			for loc := call.InstructionDebugLoc(); !loc.IsNil(); loc = loc.LocationInlinedAt() {
+				locations = append(locations, loc)
+			}
+			functions := make([]llvm.Value, len(locations))
+			for i, loc := range locations {
+				functions[i] = getFunc(loc.LocationScope())
+				if isWrapper && loc.LocationScope() == fn.Subprogram() {
+					functions[i] = nullPtr
+				}
+			}
+			site := outerParent
+			for i := len(locations) - 1; i >= 0; i-- {
+				if i > 0 && functions[i] == functions[i-1] && functions[i] != nullPtr {
+					// An inlined wrapper function with the same debug
+					// information as the function it wraps.
+					continue
+				}
+				site = getSite(siteKey{functions[i], locations[i].LocationLine(), site}, fn)
+			}
+			if len(locations) == 0 {
+				site = getSite(siteKey{nullPtr, 0, site}, fn)
+			}
+
+			// Put the call at the start of a new basic block, so that its
+			// address can be taken.
+			bb := call.InstructionParent()
+			prev := llvm.PrevInstruction(call)
+			var placeholder llvm.Value
+			if prev.IsNil() && bb == fn.EntryBasicBlock() {
+				// The address of the entry block cannot be taken, so split it
+				// using a temporary instruction.
+				builder.SetInsertPointBefore(call)
+				placeholder = builder.CreateAlloca(ctx.Int8Type(), "")
+				prev = placeholder
+			}
+			if !prev.IsNil() {
+				callBlock := llvmutil.SplitBasicBlock(builder, prev, bb, "callsite")
+				callBlock.MoveAfter(bb)
+				builder.SetInsertPointAtEnd(bb)
+				builder.CreateBr(callBlock)
+				bb = callBlock
+			}
+			if !placeholder.IsNil() {
+				placeholder.EraseFromParentAsInstruction()
+			}
+			entries = append(entries, llvm.ConstStruct([]llvm.Value{
+				llvm.ConstPtrToInt(llvm.BlockAddress(fn, bb), uintptrType),
+				site,
+			}, false))
+		}
+	}
+
+	// Create the table. It is writable, because the runtime sorts it.
+	tableValue := llvm.ConstArray(entryType, entries)
+	table := llvm.AddGlobal(mod, tableValue.Type(), "runtime.pcTable")
+	table.SetInitializer(tableValue)
+	table.SetLinkage(llvm.InternalLinkage)
+	pcTableStart.SetInitializer(llvm.ConstPtrToInt(table, uintptrType))
+	pcTableLength.SetInitializer(llvm.ConstInt(uintptrType, uint64(len(entries)), false))
+
+	return true
+}
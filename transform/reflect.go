@@ -95,6 +95,32 @@ type typeCodeAssignmentState struct {
 	arrayTypesSidetable      []byte
 	needsArrayTypesSidetable bool
 
+	// Map of map types to their type code.
+	mapTypes               map[string]int
+	mapTypesSidetable      []byte
+	needsMapTypesSidetable bool
+
+	// Map of func types to their type code.
+	funcTypes               map[string]int
+	funcTypesSidetable      []byte
+	needsFuncTypesSidetable bool
+
+	// Trampolines of func types, used by reflect.Value.Call and
+	// reflect.MakeFunc. Both lists are indexed by the trampoline index stored
+	// in the func types sidetable.
+	funcCallTrampolines      []llvm.Value
+	makeFuncTrampolines      []llvm.Value
+	needsFuncCallTrampolines bool
+	needsMakeFuncTrampolines bool
+
+	// Exported methods of types, used by reflect.Type.Method and
+	// reflect.Value.Method. The method sets sidetable maps type codes to an
+	// offset in the methods sidetable, and the method funcs sidetable contains
+	// the functions implementing these methods.
+	methodsSidetable         []byte
+	methodFuncs              []llvm.Value
+	needsMethodSetsSidetable bool
+
 	// Map of struct types to their type code.
 	structTypes               map[string]int
 	structTypesSidetable      []byte
@@ -130,6 +156,11 @@ func LowerReflect(mod llvm.Module) {
 	// if reflect were not used, we could skip generating the sidetable
 	// this does not help in practice, and is difficult to do correctly
 
+	// Remove the reflect trampolines of func types when they won't be used, so
+	// that they won't be referenced from the func trampoline sidetables.
+	removeUnusedFuncTrampolines(mod)
+	removeUnusedMethods(mod)
+
 	// Obtain slice of all types in the program.
 	type typeInfo struct {
 		typecode llvm.Value
@@ -168,13 +199,28 @@ func LowerReflect(mod llvm.Module) {
 		namedBasicTypes:                  make(map[string]int),
 		namedNonBasicTypes:               make(map[string]int),
 		arrayTypes:                       make(map[string]int),
+		mapTypes:                         make(map[string]int),
+		funcTypes:                        make(map[string]int),
 		structTypes:                      make(map[string]int),
 		structNames:                      make(map[string]int),
 		needsNamedNonBasicTypesSidetable: len(getUses(mod.NamedGlobal("reflect.namedNonBasicTypesSidetable"))) != 0,
 		needsStructTypesSidetable:        len(getUses(mod.NamedGlobal("reflect.structTypesSidetable"))) != 0,
 		needsStructNamesSidetable:        len(getUses(mod.NamedGlobal("reflect.structNamesSidetable"))) != 0,
 		needsArrayTypesSidetable:         len(getUses(mod.NamedGlobal("reflect.arrayTypesSidetable"))) != 0,
+		needsMapTypesSidetable:           len(getUses(mod.NamedGlobal("reflect.mapTypesSidetable"))) != 0,
+		needsFuncCallTrampolines:         len(getUses(mod.NamedGlobal("reflect.funcCallTrampolinesSidetable"))) != 0,
+		needsMakeFuncTrampolines:         len(getUses(mod.NamedGlobal("reflect.makeFuncTrampolinesSidetable"))) != 0,
+		needsMethodSetsSidetable:         len(getUses(mod.NamedGlobal("reflect.methodSetsSidetable"))) != 0,
 	}
+	// Method names are stored in the struct names sidetable.
+	state.needsStructNamesSidetable = state.needsStructNamesSidetable || state.needsMethodSetsSidetable
+	// The trampolines can only be found using the func types sidetable.
+	state.needsFuncTypesSidetable = len(getUses(mod.NamedGlobal("reflect.funcTypesSidetable"))) != 0 || state.needsFuncCallTrampolines || state.needsMakeFuncTrampolines
+	type methodSetInfo struct {
+		typecode uint64
+		methods  llvm.Value
+	}
+	var methodSets []methodSetInfo
 	for _, t := range types {
 		num := state.getTypeCodeNum(t.typecode)
 		if num.BitLen() > state.uintptrLen || !num.IsUint64() {
@@ -203,6 +249,40 @@ func LowerReflect(mod llvm.Module) {
 			}
 			use.ReplaceAllUsesWith(typecode)
 		}
+
+		// Remember the exported methods of this type, if there are any.
+		if state.needsMethodSetsSidetable {
+			methods := llvm.ConstExtractValue(t.typecode.Initializer(), []uint32{4})
+			if !methods.IsNull() {
+				methodSets = append(methodSets, methodSetInfo{num.Uint64(), methods.Operand(0)})
+			}
+		}
+	}
+
+	// The method sets sidetable starts with the number of entries, followed
+	// by {type code, methods sidetable offset} pairs sorted by type code so
+	// that they can be found using a binary search.
+	if state.needsMethodSetsSidetable {
+		sort.Slice(methodSets, func(i, j int) bool {
+			return methodSets[i].typecode < methodSets[j].typecode
+		})
+		methodSetsSidetable := []uint64{uint64(len(methodSets))}
+		for _, methodSet := range methodSets {
+			index := state.getMethodsNum(methodSet.methods)
+			methodSetsSidetable = append(methodSetsSidetable, methodSet.typecode, uint64(index))
+		}
+		global := replaceGlobalIntWithArray(mod, "reflect.methodSetsSidetable", methodSetsSidetable)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+		global = replaceGlobalIntWithArray(mod, "reflect.methodsSidetable", state.methodsSidetable)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+		global = replaceGlobalWithConstArray(mod, "reflect.methodFuncsSidetable", state.methodFuncs)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
 	}
 
 	// Only create this sidetable when it is necessary.
@@ -218,6 +298,30 @@ func LowerReflect(mod llvm.Module) {
 		global.SetUnnamedAddr(true)
 		global.SetGlobalConstant(true)
 	}
+	if state.needsMapTypesSidetable {
+		global := replaceGlobalIntWithArray(mod, "reflect.mapTypesSidetable", state.mapTypesSidetable)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+	}
+	if state.needsFuncTypesSidetable {
+		global := replaceGlobalIntWithArray(mod, "reflect.funcTypesSidetable", state.funcTypesSidetable)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+	}
+	if state.needsFuncCallTrampolines {
+		global := replaceGlobalWithConstArray(mod, "reflect.funcCallTrampolinesSidetable", state.funcCallTrampolines)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+	}
+	if state.needsMakeFuncTrampolines {
+		global := replaceGlobalWithConstArray(mod, "reflect.makeFuncTrampolinesSidetable", state.makeFuncTrampolines)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+	}
 	if state.needsStructTypesSidetable {
 		global := replaceGlobalIntWithArray(mod, "reflect.structTypesSidetable", state.structTypesSidetable)
 		global.SetLinkage(llvm.InternalLinkage)
@@ -238,15 +342,20 @@ func LowerReflect(mod llvm.Module) {
 		initializer := typ.typecode.Initializer()
 		references := llvm.ConstExtractValue(initializer, []uint32{0})
 		typ.typecode.SetInitializer(llvm.ConstNull(initializer.Type()))
-		if strings.HasPrefix(typ.name, "reflect/types.type:struct:") {
-			// Structs have a 'references' field that is not a typecode but
-			// a pointer to a runtime.structField array and therefore a
-			// bitcast. This global should be erased separately, otherwise
-			// typecode objects cannot be erased.
-			structFields := references.Operand(0)
-			structFields.EraseFromParentAsGlobal()
+		if strings.HasPrefix(typ.name, "reflect/types.type:struct:") || strings.HasPrefix(typ.name, "reflect/types.type:map:") || strings.HasPrefix(typ.name, "reflect/types.type:func:") {
+			// Structs, maps and funcs have a 'references' field that is not a
+			// typecode but a pointer to some other type information (an
+			// array of runtime.structField, the key/elem typecodes, or the
+			// func type information) and therefore a bitcast. This global
+			// should be erased separately, otherwise typecode objects cannot
+			// be erased.
+			referencesGlobal := references.Operand(0)
+			referencesGlobal.EraseFromParentAsGlobal()
 		}
 	}
+	for _, methodSet := range methodSets {
+		methodSet.methods.EraseFromParentAsGlobal()
+	}
 }
 
 // getTypeCodeNum returns the typecode for a given type as expected by the
@@ -348,6 +457,14 @@ func (state *typeCodeAssignmentState) getNonBasicTypeCode(class string, typecode
 		// An array is basically a pair of (typecode, length) stored in a
 		// sidetable.
 		return big.NewInt(int64(state.getArrayTypeNum(typecode)))
+	case "map":
+		// A map is a pair of (key typecode, elem typecode) stored in a
+		// sidetable.
+		return big.NewInt(int64(state.getMapTypeNum(typecode)))
+	case "func":
+		// A func type has a list of parameter and result types, and some
+		// trampolines, stored in a sidetable.
+		return big.NewInt(int64(state.getFuncTypeNum(typecode)))
 	case "struct":
 		// More complicated type kind. The upper bits contain the index to the
 		// struct type in the struct types sidetable.
@@ -429,6 +546,203 @@ func (state *typeCodeAssignmentState) getArrayTypeNum(typecode llvm.Value) int {
 	return index
 }
 
+// getMapTypeNum returns the map type number, which is an index into the
+// reflect.mapTypesSidetable or a unique number for this type if this table is
+// not used.
+func (state *typeCodeAssignmentState) getMapTypeNum(typecode llvm.Value) int {
+	name := typecode.Name()
+	if num, ok := state.mapTypes[name]; ok {
+		// This map type already has an entry in the sidetable. Don't store it
+		// twice.
+		return num
+	}
+
+	if !state.needsMapTypesSidetable {
+		// We don't need map sidetables, so we can just assign monotonically
+		// increasing numbers to each map type.
+		num := len(state.mapTypes)
+		state.mapTypes[name] = num
+		return num
+	}
+
+	// The map side table is a sequence of {key type, elem type}.
+	keyElem := llvm.ConstExtractValue(typecode.Initializer(), []uint32{0}).Operand(0).Initializer()
+	var buf []byte
+	for i := uint32(0); i < 2; i++ {
+		typeNum := state.getTypeCodeNum(llvm.ConstExtractValue(keyElem, []uint32{i}))
+		if typeNum.BitLen() > state.uintptrLen || !typeNum.IsUint64() {
+			// TODO: make this a regular error
+			panic("map key or element type has a type code that is too big")
+		}
+		buf = append(buf, makeVarint(typeNum.Uint64())...)
+	}
+
+	index := len(state.mapTypesSidetable)
+	state.mapTypes[name] = index
+	state.mapTypesSidetable = append(state.mapTypesSidetable, buf...)
+	return index
+}
+
+// getFuncTypeNum returns the func type number, which is an index into the
+// reflect.funcTypesSidetable or a unique number for this type if this table is
+// not used.
+func (state *typeCodeAssignmentState) getFuncTypeNum(typecode llvm.Value) int {
+	name := typecode.Name()
+	if num, ok := state.funcTypes[name]; ok {
+		// This func type already has an entry in the sidetable. Don't store
+		// it twice.
+		return num
+	}
+
+	if !state.needsFuncTypesSidetable {
+		// We don't need func sidetables, so we can just assign monotonically
+		// increasing numbers to each func type.
+		num := len(state.funcTypes)
+		state.funcTypes[name] = num
+		return num
+	}
+
+	// The func type information is a struct of {call trampoline, variadic,
+	// [params], [results]}, see compiler/reflect.go.
+	funcInfo := llvm.ConstExtractValue(typecode.Initializer(), []uint32{0}).Operand(0).Initializer()
+
+	// Store the trampolines in the trampoline sidetables, if they're needed.
+	// The MakeFunc trampoline is stored in a separate global, see
+	// removeUnusedFuncTrampolines.
+	trampolineIndex := 0
+	if state.needsFuncCallTrampolines || state.needsMakeFuncTrampolines {
+		callCode := llvm.ConstExtractValue(funcInfo, []uint32{0})
+		makeFuncCode := llvm.ConstNull(callCode.Type())
+		if global := typecode.GlobalParent().NamedGlobal("reflect/makefunc.code:" + strings.TrimPrefix(name, "reflect/types.type:")); !global.IsNil() {
+			makeFuncCode = global.Initializer()
+		}
+		trampolineIndex = len(state.funcCallTrampolines)
+		state.funcCallTrampolines = append(state.funcCallTrampolines, callCode)
+		state.makeFuncTrampolines = append(state.makeFuncTrampolines, makeFuncCode)
+	}
+
+	// The func side table is a sequence of {trampoline index, number of
+	// params with the variadic flag in the lowest bit, number of results,
+	// param types..., result types...}.
+	variadic := llvm.ConstExtractValue(funcInfo, []uint32{1}).ZExtValue()
+	params := llvm.ConstExtractValue(funcInfo, []uint32{2})
+	results := llvm.ConstExtractValue(funcInfo, []uint32{3})
+	numParams := params.Type().ArrayLength()
+	numResults := results.Type().ArrayLength()
+	buf := makeVarint(uint64(trampolineIndex))
+	buf = append(buf, makeVarint(uint64(numParams)<<1|variadic)...)
+	buf = append(buf, makeVarint(uint64(numResults))...)
+	for _, list := range []llvm.Value{params, results} {
+		for i := 0; i < list.Type().ArrayLength(); i++ {
+			typeNum := state.getTypeCodeNum(llvm.ConstExtractValue(list, []uint32{uint32(i)}))
+			if typeNum.BitLen() > state.uintptrLen || !typeNum.IsUint64() {
+				// TODO: make this a regular error
+				panic("func param or result type has a type code that is too big")
+			}
+			buf = append(buf, makeVarint(typeNum.Uint64())...)
+		}
+	}
+
+	index := len(state.funcTypesSidetable)
+	state.funcTypes[name] = index
+	state.funcTypesSidetable = append(state.funcTypesSidetable, buf...)
+	return index
+}
+
+// removeUnusedFuncTrampolines removes the trampolines for reflect.Value.Call
+// and reflect.MakeFunc created by the compiler when the reflect package doesn't
+// use them. This avoids adding them as possible targets of func values in the
+// func lowering pass.
+func removeUnusedFuncTrampolines(mod llvm.Module) {
+	needsCall := len(getUses(mod.NamedGlobal("reflect.funcCallTrampolinesSidetable"))) != 0
+	needsMakeFunc := len(getUses(mod.NamedGlobal("reflect.makeFuncTrampolinesSidetable"))) != 0
+	if needsCall && needsMakeFunc {
+		return
+	}
+	var makeFuncCodes []llvm.Value
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if !needsCall && strings.HasPrefix(global.Name(), "reflect/types.funcInfo") {
+			// The call trampoline is stored in the func type information.
+			removeFuncTrampoline(llvm.ConstExtractValue(global.Initializer(), []uint32{0}))
+		}
+		if !needsMakeFunc && strings.HasPrefix(global.Name(), "reflect/makefunc.code:") {
+			// The MakeFunc trampoline is stored in a separate global, which
+			// is only used by the reflect lowering pass.
+			makeFuncCodes = append(makeFuncCodes, global)
+		}
+	}
+	for _, global := range makeFuncCodes {
+		removeFuncTrampoline(global.Initializer())
+		global.EraseFromParentAsGlobal()
+	}
+}
+
+// removeFuncTrampoline removes the trampoline referenced by the given code
+// value. The trampoline is referenced with a ptrtoint of the function itself or
+// of a runtime.funcValueWithSignature global. These are only used in func type
+// information, so they can be removed entirely.
+func removeFuncTrampoline(code llvm.Value) {
+	if code.IsAConstantExpr().IsNil() || code.Opcode() != llvm.PtrToInt {
+		return // already removed
+	}
+	trampoline := code.Operand(0)
+	trampoline.ReplaceAllUsesWith(llvm.ConstNull(trampoline.Type()))
+	if trampoline.IsAFunction().IsNil() {
+		trampoline.EraseFromParentAsGlobal()
+	} else {
+		trampoline.EraseFromParentAsFunction()
+	}
+}
+
+// getMethodsNum stores the exported methods of a type (see makeTypeMethods in
+// the compiler) in the methods sidetable and returns the index into this
+// sidetable.
+func (state *typeCodeAssignmentState) getMethodsNum(methodsGlobal llvm.Value) int {
+	// The methods sidetable contains the number of methods, followed by
+	// {name, method type, func type, func index} for each method. The name is
+	// an index into the struct names sidetable and the func index is an index
+	// into the method funcs sidetable.
+	methods := methodsGlobal.Initializer()
+	numMethods := methods.Type().ArrayLength()
+	buf := makeVarint(uint64(numMethods))
+	for i := 0; i < numMethods; i++ {
+		method := llvm.ConstExtractValue(methods, []uint32{uint32(i)})
+		nameBytes := getGlobalBytes(llvm.ConstExtractValue(method, []uint32{0}).Operand(0))
+		buf = append(buf, makeVarint(uint64(state.getStructNameNumber(nameBytes)))...)
+		for _, field := range []uint32{1, 2} {
+			typeNum := state.getTypeCodeNum(llvm.ConstExtractValue(method, []uint32{field}))
+			if typeNum.BitLen() > state.uintptrLen || !typeNum.IsUint64() {
+				// TODO: make this a regular error
+				panic("method has a type code that is too big")
+			}
+			buf = append(buf, makeVarint(typeNum.Uint64())...)
+		}
+		buf = append(buf, makeVarint(uint64(len(state.methodFuncs)))...)
+		state.methodFuncs = append(state.methodFuncs, llvm.ConstExtractValue(method, []uint32{3}))
+	}
+
+	index := len(state.methodsSidetable)
+	state.methodsSidetable = append(state.methodsSidetable, buf...)
+	return index
+}
+
+// removeUnusedMethods removes the exported methods of types (for reflect) from
+// the type codes when the reflect package doesn't use them. This avoids keeping
+// all these methods alive.
+func removeUnusedMethods(mod llvm.Module) {
+	if len(getUses(mod.NamedGlobal("reflect.methodSetsSidetable"))) != 0 {
+		return
+	}
+	for global := mod.FirstGlobal(); !global.IsNil(); {
+		next := llvm.NextGlobal(global)
+		if strings.HasPrefix(global.Name(), "reflect/types.methods") {
+			global.ReplaceAllUsesWith(llvm.ConstNull(global.Type()))
+			global.EraseFromParentAsGlobal()
+		}
+		global = next
+	}
+}
+
 // getStructTypeNum returns the struct type number, which is an index into
 // reflect.structTypesSidetable or an unique number for every struct if this
 // sidetable is not needed in the to-be-compiled program.
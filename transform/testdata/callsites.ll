@@ -0,0 +1,25 @@
+target datalayout = "e-m:e-p:32:32-i64:64-n32:64-S128"
+target triple = "wasm32-unknown-unknown-wasm"
+
+%runtime._string = type { i8*, i32 }
+%runtime.Func = type { %runtime._string, %runtime._string }
+%runtime.callSite = type { %runtime.Func*, i32, %runtime.callSite* }
+%runtime.callFrame = type { %runtime.callFrame*, i32 }
+
+@runtime.callSitesStart = external global i32
+@runtime.callSitesLength = external global i32
+@"runtime.funcinfo:main.main" = internal constant %runtime.Func zeroinitializer
+@"runtime.callsite:main.main:10" = internal constant %runtime.callSite { %runtime.Func* @"runtime.funcinfo:main.main", i32 10, %runtime.callSite* null }
+@"runtime.callsite:main.main:12" = internal constant %runtime.callSite { %runtime.Func* @"runtime.funcinfo:main.main", i32 12, %runtime.callSite* null }
+
+declare void @foo()
+
+define void @main.main() {
+  %callFrame = alloca %runtime.callFrame
+  %pc = getelementptr inbounds %runtime.callFrame, %runtime.callFrame* %callFrame, i32 0, i32 1
+  store i32 ptrtoint (%runtime.callSite* @"runtime.callsite:main.main:10" to i32), i32* %pc
+  call void @foo()
+  store i32 ptrtoint (%runtime.callSite* @"runtime.callsite:main.main:12" to i32), i32* %pc
+  call void @foo()
+  ret void
+}
@@ -0,0 +1,24 @@
+target datalayout = "e-m:e-p:32:32-i64:64-n32:64-S128"
+target triple = "wasm32-unknown-unknown-wasm"
+
+%runtime.callSite = type { %runtime.Func*, i32, %runtime.callSite* }
+%runtime.Func = type { %runtime._string, %runtime._string }
+%runtime._string = type { i8*, i32 }
+%runtime.callFrame = type { %runtime.callFrame*, i32 }
+
+@runtime.callSitesStart = internal global i32 ptrtoint ([2 x %runtime.callSite]* @runtime.callSites to i32)
+@runtime.callSitesLength = internal global i32 2
+@"runtime.funcinfo:main.main" = internal constant %runtime.Func zeroinitializer
+@runtime.callSites = internal constant [2 x %runtime.callSite] [%runtime.callSite { %runtime.Func* @"runtime.funcinfo:main.main", i32 10, %runtime.callSite* null }, %runtime.callSite { %runtime.Func* @"runtime.funcinfo:main.main", i32 12, %runtime.callSite* null }]
+
+declare void @foo()
+
+define void @main.main() {
+  %callFrame = alloca %runtime.callFrame
+  %pc = getelementptr inbounds %runtime.callFrame, %runtime.callFrame* %callFrame, i32 0, i32 1
+  store i32 ptrtoint (%runtime.callSite* getelementptr inbounds ([2 x %runtime.callSite], [2 x %runtime.callSite]* @runtime.callSites, i32 0, i32 0) to i32), i32* %pc
+  call void @foo()
+  store i32 ptrtoint (%runtime.callSite* getelementptr inbounds ([2 x %runtime.callSite], [2 x %runtime.callSite]* @runtime.callSites, i32 0, i32 1) to i32), i32* %pc
+  call void @foo()
+  ret void
+}
@@ -0,0 +1,57 @@
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux"
+
+%runtime.callSite = type { %runtime.Func*, i64, %runtime.callSite* }
+%runtime.Func = type { %runtime._string, %runtime._string }
+%runtime._string = type { i8*, i64 }
+
+@runtime.pcTableStart = external global i64
+@runtime.pcTableLength = external global i64
+@runtime.stackBottom = internal global %runtime.callSite zeroinitializer
+@"runtime.funcinfo:/src/main.go:5" = weak_odr constant %runtime.Func zeroinitializer
+@"runtime.funcinfo:/src/main.go:9" = weak_odr constant %runtime.Func zeroinitializer
+@"runtime.funcinfo:/src/main.go:20" = weak_odr constant %runtime.Func zeroinitializer
+
+declare void @foo()
+
+declare void @use(i64, i64)
+
+; Exported function, at the bottom of the call stack.
+define %runtime.callSite* @runtime.walkStack() {
+entry:
+  %start = load i64, i64* @runtime.pcTableStart
+  %length = load i64, i64* @runtime.pcTableLength
+  call void @use(i64 %start, i64 %length)
+  ret %runtime.callSite* @runtime.stackBottom
+}
+
+; The second call was inlined from a function declared at line 9.
+define internal void @main.main() !dbg !3 {
+entry:
+  call void @foo(), !dbg !6
+  call void @foo(), !dbg !7
+  ret void
+}
+
+; Goroutine start wrapper without debug information.
+define internal void @"main.main$gowrapper"(i8* %0) #0 {
+entry:
+  call void @foo()
+  ret void
+}
+
+attributes #0 = { "tinygo-gowrapper" }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!2}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "TinyGo", isOptimized: true, runtimeVersion: 0, emissionKind: FullDebug)
+!1 = !DIFile(filename: "main.go", directory: "/src")
+!2 = !{i32 2, !"Debug Info Version", i32 3}
+!3 = distinct !DISubprogram(name: "main.main", scope: !1, file: !1, line: 5, type: !4, spFlags: DISPFlagDefinition, unit: !0)
+!4 = !DISubroutineType(types: !5)
+!5 = !{}
+!6 = !DILocation(line: 6, column: 2, scope: !3)
+!7 = !DILocation(line: 10, column: 2, scope: !8, inlinedAt: !9)
+!8 = distinct !DISubprogram(name: "main.helper", scope: !1, file: !1, line: 9, type: !4, spFlags: DISPFlagDefinition, unit: !0)
+!9 = !DILocation(line: 7, column: 2, scope: !3)
@@ -0,0 +1,71 @@
+target datalayout = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux"
+
+%runtime.callSite = type { %runtime.Func*, i64, %runtime.callSite* }
+%runtime.Func = type { %runtime._string, %runtime._string }
+%runtime._string = type { i8*, i64 }
+
+@runtime.pcTableStart = internal global i64 ptrtoint ([7 x { i64, i8* }]* @runtime.pcTable to i64)
+@runtime.pcTableLength = internal global i64 7
+@runtime.stackBottom = internal global %runtime.callSite zeroinitializer
+@"runtime.funcinfo:/src/main.go:5" = internal constant %runtime.Func zeroinitializer
+@"runtime.funcinfo:/src/main.go:9" = internal constant %runtime.Func zeroinitializer
+@"runtime.callsite:runtime.walkStack" = internal constant { i8*, i64, i8* } { i8* null, i64 0, i8* bitcast (%runtime.callSite* @runtime.stackBottom to i8*) }
+@"runtime.callsite:main.main" = internal constant { i8*, i64, i8* } { i8* bitcast (%runtime.Func* @"runtime.funcinfo:/src/main.go:5" to i8*), i64 6, i8* null }
+@"runtime.callsite:main.main.1" = internal constant { i8*, i64, i8* } { i8* bitcast (%runtime.Func* @"runtime.funcinfo:/src/main.go:5" to i8*), i64 7, i8* null }
+@"runtime.callsite:main.main.2" = internal constant { i8*, i64, i8* } { i8* bitcast (%runtime.Func* @"runtime.funcinfo:/src/main.go:9" to i8*), i64 10, i8* bitcast ({ i8*, i64, i8* }* @"runtime.callsite:main.main.1" to i8*) }
+@runtime.pcTable = internal global [7 x { i64, i8* }] [{ i64, i8* } { i64 ptrtoint (%runtime.callSite* ()* @runtime.walkStack to i64), i8* null }, { i64, i8* } { i64 ptrtoint (i8* blockaddress(@runtime.walkStack, %callsite) to i64), i8* bitcast ({ i8*, i64, i8* }* @"runtime.callsite:runtime.walkStack" to i8*) }, { i64, i8* } { i64 ptrtoint (void ()* @main.main to i64), i8* null }, { i64, i8* } { i64 ptrtoint (i8* blockaddress(@main.main, %callsite) to i64), i8* bitcast ({ i8*, i64, i8* }* @"runtime.callsite:main.main" to i8*) }, { i64, i8* } { i64 ptrtoint (i8* blockaddress(@main.main, %callsite1) to i64), i8* bitcast ({ i8*, i64, i8* }* @"runtime.callsite:main.main.2" to i8*) }, { i64, i8* } { i64 ptrtoint (void (i8*)* @"main.main$gowrapper" to i64), i8* null }, { i64, i8* } { i64 ptrtoint (i8* blockaddress(@"main.main$gowrapper", %callsite) to i64), i8* bitcast ({ i8*, i64, i8* }* @"runtime.callsite:runtime.walkStack" to i8*) }]
+
+declare void @foo()
+
+declare void @use(i64, i64)
+
+define %runtime.callSite* @runtime.walkStack() #0 {
+entry:
+  %start = load i64, i64* @runtime.pcTableStart
+  %length = load i64, i64* @runtime.pcTableLength
+  br label %callsite
+
+callsite:                                         ; preds = %entry
+  call void @use(i64 %start, i64 %length)
+  ret %runtime.callSite* @runtime.stackBottom
+}
+
+define internal void @main.main() #0 !dbg !3 {
+entry:
+  br label %callsite
+
+callsite:                                         ; preds = %entry
+  call void @foo(), !dbg !6
+  br label %callsite1
+
+callsite1:                                        ; preds = %callsite
+  call void @foo(), !dbg !7
+  ret void
+}
+
+define internal void @"main.main$gowrapper"(i8* %0) #1 {
+entry:
+  br label %callsite
+
+callsite:                                         ; preds = %entry
+  call void @foo()
+  ret void
+}
+
+attributes #0 = { "disable-tail-calls"="true" "frame-pointer"="all" }
+attributes #1 = { "disable-tail-calls"="true" "frame-pointer"="all" "tinygo-gowrapper" }
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!2}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "TinyGo", isOptimized: true, runtimeVersion: 0, emissionKind: FullDebug)
+!1 = !DIFile(filename: "main.go", directory: "/src")
+!2 = !{i32 2, !"Debug Info Version", i32 3}
+!3 = distinct !DISubprogram(name: "main.main", scope: !1, file: !1, line: 5, type: !4, spFlags: DISPFlagDefinition, unit: !0)
+!4 = !DISubroutineType(types: !5)
+!5 = !{}
+!6 = !DILocation(line: 6, column: 2, scope: !3)
+!7 = !DILocation(line: 10, column: 2, scope: !8, inlinedAt: !9)
+!8 = distinct !DISubprogram(name: "main.helper", scope: !1, file: !1, line: 9, type: !4, spFlags: DISPFlagDefinition, unit: !0)
+!9 = !DILocation(line: 7, column: 2, scope: !3)
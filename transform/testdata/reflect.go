@@ -42,6 +42,13 @@ func main() {
 	assertType(make(chan int), (intNum<<5)|prefixChan)
 	assertType(new(int), (intNum<<5)|prefixPtr)
 	assertType([]int{}, (intNum<<5)|prefixSlice)
+
+	// Check for map types, which are numbered in order when the sidetable is
+	// not used.
+	assertType(map[string]int{}, prefixMap)
+
+	// Same for func types.
+	assertType(func(int) {}, prefixFunc)
 }
 
 type (
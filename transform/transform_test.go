@@ -154,6 +154,7 @@ func compileGoFileForTesting(t *testing.T, filename string) llvm.Module {
 		GOARCH:             config.GOARCH(),
 		CodeModel:          config.CodeModel(),
 		RelocationModel:    config.RelocationModel(),
+		BuildMode:          config.BuildMode(),
 		Scheduler:          config.Scheduler(),
 		FuncImplementation: config.FuncImplementation(),
 		AutomaticStackSize: config.AutomaticStackSize(),
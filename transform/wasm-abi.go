@@ -49,6 +49,11 @@ func ExternalInt64AsPtr(mod llvm.Module) error {
 			hasInt64 = true
 			paramTypes = append(paramTypes, int64PtrType)
 			returnType = ctx.VoidType()
+		} else if returnType.TypeKind() == llvm.StructTypeKind && typeHasInt64(returnType) {
+			// Multiple return values (with the multi-value feature) can't be
+			// passed by reference as easily.
+			return errors.New("not supported: function " + fn.Name() + " returns multiple values including an i64 with -wasm-abi=js; " +
+				"use -wasm-abi=generic instead")
 		}
 
 		// Check param types for 64-bit integers.
@@ -163,3 +168,20 @@ func ExternalInt64AsPtr(mod llvm.Module) error {
 
 	return nil
 }
+
+// typeHasInt64 returns whether the given type is or contains a 64-bit integer.
+func typeHasInt64(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.IntegerTypeKind:
+		return t.IntTypeWidth() == 64
+	case llvm.StructTypeKind:
+		for _, elementType := range t.StructElementTypes() {
+			if typeHasInt64(elementType) {
+				return true
+			}
+		}
+	case llvm.ArrayTypeKind:
+		return typeHasInt64(t.ElementType())
+	}
+	return false
+}
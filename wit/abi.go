@@ -0,0 +1,252 @@
+package wit
+
+// This file implements the parts of the canonical ABI that are needed to
+// generate bindings: how values are flattened to core WebAssembly values and
+// how they are laid out in linear memory.
+// For details, see:
+// https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md
+
+// Limits on the number of flattened values, above which values are passed
+// through linear memory instead.
+const (
+	maxFlatParams  = 16
+	maxFlatResults = 1
+)
+
+// Core WebAssembly value types.
+const (
+	coreI32 = "i32"
+	coreI64 = "i64"
+	coreF32 = "f32"
+	coreF64 = "f64"
+)
+
+// flatten returns the core WebAssembly types that a value of the given type
+// is lowered to when passed as a parameter or result.
+func flatten(t Type) []string {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case S64, U64:
+			return []string{coreI64}
+		case Float32:
+			return []string{coreF32}
+		case Float64:
+			return []string{coreF64}
+		case String:
+			return []string{coreI32, coreI32}
+		default:
+			return []string{coreI32}
+		}
+	case *List:
+		return []string{coreI32, coreI32}
+	case *Option:
+		return flattenVariant([]Type{nil, t.Elem})
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			var flat []string
+			for _, field := range t.Fields {
+				flat = append(flat, flatten(field.Type)...)
+			}
+			return flat
+		case "variant":
+			return flattenVariant(t.caseTypes())
+		default: // enum
+			return []string{coreI32}
+		}
+	}
+	panic("unknown type: " + t.String())
+}
+
+// flattenVariant returns the flattened discriminant followed by the joined
+// payload types of all cases.
+func flattenVariant(cases []Type) []string {
+	var flat []string
+	for _, c := range cases {
+		if c == nil {
+			continue
+		}
+		for i, ft := range flatten(c) {
+			if i < len(flat) {
+				flat[i] = joinCoreTypes(flat[i], ft)
+			} else {
+				flat = append(flat, ft)
+			}
+		}
+	}
+	return append([]string{coreI32}, flat...)
+}
+
+// joinCoreTypes returns the core type that can hold values of both a and b,
+// for variant payloads that share the same flattened position.
+func joinCoreTypes(a, b string) string {
+	if a == b {
+		return a
+	}
+	if (a == coreI32 && b == coreF32) || (a == coreF32 && b == coreI32) {
+		return coreI32
+	}
+	return coreI64
+}
+
+// caseTypes returns the payload types of all cases, nil for cases without a
+// payload.
+func (t *TypeDef) caseTypes() []Type {
+	types := make([]Type, len(t.Cases))
+	for i, c := range t.Cases {
+		types[i] = c.Type
+	}
+	return types
+}
+
+// sizeAlign returns the size and alignment of the given type when stored in
+// linear memory.
+func sizeAlign(t Type) (size, align uint32) {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case Bool, S8, U8:
+			return 1, 1
+		case S16, U16:
+			return 2, 2
+		case S64, U64, Float64:
+			return 8, 8
+		case String:
+			return 8, 4
+		default:
+			return 4, 4
+		}
+	case *List:
+		return 8, 4
+	case *Option:
+		layout := variantLayout([]Type{nil, t.Elem})
+		return layout.size, layout.align
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			_, size, align := recordLayout(t.Fields)
+			return size, align
+		case "variant":
+			layout := variantLayout(t.caseTypes())
+			return layout.size, layout.align
+		default: // enum
+			n := discriminantSize(len(t.Cases))
+			return n, n
+		}
+	}
+	panic("unknown type: " + t.String())
+}
+
+// recordLayout returns the offset of each field, and the size and alignment
+// of the record as a whole. It is also used for function parameters that are
+// passed in linear memory.
+func recordLayout(fields []*Field) (offsets []uint32, size, align uint32) {
+	align = 1
+	for _, field := range fields {
+		fieldSize, fieldAlign := sizeAlign(field.Type)
+		size = alignTo(size, fieldAlign)
+		offsets = append(offsets, size)
+		size += fieldSize
+		if fieldAlign > align {
+			align = fieldAlign
+		}
+	}
+	return offsets, alignTo(size, align), align
+}
+
+// memVariantLayout describes how a variant is stored in linear memory.
+type memVariantLayout struct {
+	discSize      uint32 // size of the discriminant: 1, 2 or 4 bytes
+	payloadOffset uint32
+	size, align   uint32
+}
+
+func variantLayout(cases []Type) memVariantLayout {
+	layout := memVariantLayout{discSize: discriminantSize(len(cases))}
+	layout.align = layout.discSize
+	var payloadSize uint32
+	for _, c := range cases {
+		if c == nil {
+			continue
+		}
+		size, align := sizeAlign(c)
+		if size > payloadSize {
+			payloadSize = size
+		}
+		if align > layout.align {
+			layout.align = align
+		}
+	}
+	layout.payloadOffset = alignTo(layout.discSize, layout.align)
+	layout.size = alignTo(layout.payloadOffset+payloadSize, layout.align)
+	return layout
+}
+
+// discriminantSize returns the number of bytes needed to store the case index
+// of a variant or enum.
+func discriminantSize(numCases int) uint32 {
+	switch {
+	case numCases <= 1<<8:
+		return 1
+	case numCases <= 1<<16:
+		return 2
+	default:
+		return 4
+	}
+}
+
+func alignTo(n, align uint32) uint32 {
+	return (n + align - 1) / align * align
+}
+
+// coreSignature returns the core WebAssembly parameter and result types of a
+// function. Imports (lowered functions) return large results through a
+// pointer passed as an extra parameter, exports (lifted functions) return a
+// pointer to the results instead.
+func coreSignature(fn *Function, export bool) (params, results []string) {
+	for _, param := range fn.Params {
+		params = append(params, flatten(param.Type)...)
+	}
+	if len(params) > maxFlatParams {
+		params = []string{coreI32}
+	}
+	if fn.Result != nil {
+		results = flatten(fn.Result)
+	}
+	if len(results) > maxFlatResults {
+		if export {
+			results = []string{coreI32}
+		} else {
+			params = append(params, coreI32)
+			results = nil
+		}
+	}
+	return params, results
+}
+
+// usesMemory returns whether values of this type refer to other parts of
+// linear memory, so that a function using them needs cabi_realloc and
+// post-return cleanup.
+func usesMemory(t Type) bool {
+	switch t := t.(type) {
+	case Primitive:
+		return t == String
+	case *List:
+		return true
+	case *Option:
+		return usesMemory(t.Elem)
+	case *TypeDef:
+		for _, field := range t.Fields {
+			if usesMemory(field.Type) {
+				return true
+			}
+		}
+		for _, c := range t.Cases {
+			if c.Type != nil && usesMemory(c.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package wit
+
+import (
+	"strings"
+)
+
+// keywords are the WIT keywords that must be escaped with a % when used as
+// identifier.
+var keywords = map[string]bool{
+	"package": true, "interface": true, "world": true, "import": true,
+	"export": true, "func": true, "record": true, "variant": true,
+	"enum": true, "flags": true, "resource": true, "type": true, "use": true,
+	"include": true, "list": true, "option": true, "result": true,
+	"tuple": true, "borrow": true, "own": true, "future": true, "stream": true,
+	"static": true, "constructor": true,
+}
+
+// Format returns a self-contained WIT document describing the given world:
+// the package declaration, the interfaces it references and the world itself.
+// Doc comments are not included.
+func Format(doc *Document, world *World) string {
+	var b strings.Builder
+	if doc.Package != "" {
+		b.WriteString("package " + doc.Package + ";\n\n")
+	}
+	seen := make(map[*Interface]bool)
+	for _, iface := range append(world.Imports[:len(world.Imports):len(world.Imports)], world.Exports...) {
+		if iface.inline || seen[iface] {
+			continue
+		}
+		seen[iface] = true
+		b.WriteString("interface " + formatIdent(iface.Name) + " ")
+		formatInterfaceBody(&b, iface, "")
+		b.WriteString("\n\n")
+	}
+	b.WriteString("world " + formatIdent(world.Name) + " {\n")
+	for _, typ := range world.Types {
+		formatTypeDef(&b, typ, "\t")
+	}
+	for _, item := range []struct {
+		keyword    string
+		interfaces []*Interface
+		funcs      []*Function
+	}{
+		{"import", world.Imports, world.ImportFuncs},
+		{"export", world.Exports, world.ExportFuncs},
+	} {
+		for _, iface := range item.interfaces {
+			b.WriteString("\t" + item.keyword + " " + formatIdent(iface.Name))
+			if iface.inline {
+				b.WriteString(": interface ")
+				formatInterfaceBody(&b, iface, "\t")
+				b.WriteString("\n")
+			} else {
+				b.WriteString(";\n")
+			}
+		}
+		for _, fn := range item.funcs {
+			b.WriteString("\t" + item.keyword + " ")
+			formatFunc(&b, fn)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func formatInterfaceBody(b *strings.Builder, iface *Interface, indent string) {
+	b.WriteString("{\n")
+	for _, typ := range iface.Types {
+		formatTypeDef(b, typ, indent+"\t")
+	}
+	for _, fn := range iface.Funcs {
+		b.WriteString(indent + "\t")
+		formatFunc(b, fn)
+	}
+	b.WriteString(indent + "}")
+}
+
+func formatTypeDef(b *strings.Builder, typ *TypeDef, indent string) {
+	b.WriteString(indent + typ.Kind + " " + formatIdent(typ.Name) + " {\n")
+	for _, field := range typ.Fields {
+		b.WriteString(indent + "\t" + formatIdent(field.Name) + ": " + formatType(field.Type) + ",\n")
+	}
+	for _, c := range typ.Cases {
+		b.WriteString(indent + "\t" + formatIdent(c.Name))
+		if c.Type != nil {
+			b.WriteString("(" + formatType(c.Type) + ")")
+		}
+		b.WriteString(",\n")
+	}
+	b.WriteString(indent + "}\n")
+}
+
+func formatFunc(b *strings.Builder, fn *Function) {
+	b.WriteString(formatIdent(fn.Name) + ": func(")
+	for i, param := range fn.Params {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(formatIdent(param.Name) + ": " + formatType(param.Type))
+	}
+	b.WriteString(")")
+	if fn.Result != nil {
+		b.WriteString(" -> " + formatType(fn.Result))
+	}
+	b.WriteString(";\n")
+}
+
+func formatType(t Type) string {
+	switch t := t.(type) {
+	case *List:
+		return "list<" + formatType(t.Elem) + ">"
+	case *Option:
+		return "option<" + formatType(t.Elem) + ">"
+	case *TypeDef:
+		return formatIdent(t.Name)
+	default:
+		return t.String()
+	}
+}
+
+// formatIdent returns the identifier, escaped if it is a keyword.
+func formatIdent(name string) string {
+	if _, ok := primitives[name]; ok || keywords[name] {
+		return "%" + name
+	}
+	return name
+}
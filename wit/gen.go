@@ -0,0 +1,986 @@
+package wit
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// SectionPrefix is the prefix of the name of the custom section that contains
+// the interface definition of a world. The rest of the name is the world name.
+// The section contents is a self-contained WIT document as returned by Format.
+const SectionPrefix = "wit-world:"
+
+// Generate returns Go source code implementing the given world. The resulting
+// package contains a Go function for each imported function, an interface
+// type (and a function to register an implementation) for each group of
+// exported functions, and Go types for all records, variants and enums.
+// The filename is only used in the header comment of the generated file.
+func Generate(doc *Document, world *World, pkgName, filename string) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		world:   world,
+		goNames: make(map[string]string),
+		typeIDs: make(map[*TypeDef]string),
+	}
+	err := g.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the header now that it is known which packages are used.
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tinygo wit-bindgen from %s. DO NOT EDIT.\n\n", filename)
+	for _, line := range docLines(world.Doc) {
+		fmt.Fprintf(&out, "// %s\n", line)
+	}
+	fmt.Fprintf(&out, "package %s\n\n", pkgName)
+	out.WriteString("import (\n")
+	if g.useMath {
+		out.WriteString("\t\"math\"\n")
+	}
+	out.WriteString("\t\"unsafe\"\n)\n\n")
+	out.Write(g.out.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		// Should not happen: this is a bug in the generator.
+		return nil, fmt.Errorf("could not format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// generator holds the state while generating Go code for a single world.
+type generator struct {
+	doc     *Document
+	world   *World
+	out     bytes.Buffer
+	tmp     int                 // counter for temporary variables
+	goNames map[string]string   // package level Go identifiers, to detect conflicts
+	typeIDs map[*TypeDef]string // Go type names of type definitions
+	useMath bool                // whether the math package is used
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.out, format, args...)
+}
+
+// newTemp returns a new unique temporary variable name.
+func (g *generator) newTemp() string {
+	g.tmp++
+	return "t" + strconv.Itoa(g.tmp)
+}
+
+// declare registers a package level Go identifier, returning an error if it
+// conflicts with an identifier declared before.
+func (g *generator) declare(goName, witName string) error {
+	if prev, ok := g.goNames[goName]; ok {
+		return fmt.Errorf("%s and %s both map to Go identifier %s", prev, witName, goName)
+	}
+	g.goNames[goName] = witName
+	return nil
+}
+
+// helperNames are the identifiers declared by genHelpers and genSection.
+var helperNames = []string{"pinned", "unpin", "alloc", "wasmPtr", "cabiRealloc", "stringHeader", "lowerString", "liftString", "witSection"}
+
+// generate writes the body of the Go file to g.out.
+func (g *generator) generate() error {
+	for _, name := range helperNames {
+		g.goNames[name] = "(helper)"
+	}
+
+	// Type definitions.
+	var typeDefs []*TypeDef
+	typeDefs = append(typeDefs, g.world.Types...)
+	for _, iface := range g.interfaces() {
+		typeDefs = append(typeDefs, iface.Types...)
+	}
+	for _, typ := range typeDefs {
+		goName := goIdentifier(typ.Name, true)
+		err := g.declare(goName, "type "+typ.Name)
+		if err != nil {
+			return err
+		}
+		g.typeIDs[typ] = goName
+	}
+	for _, typ := range typeDefs {
+		err := g.genTypeDef(typ)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Imported functions.
+	for _, iface := range g.world.Imports {
+		for _, fn := range iface.Funcs {
+			err := g.genImport(fn, goIdentifier(iface.Name, true)+goIdentifier(fn.Name, true))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, fn := range g.world.ImportFuncs {
+		err := g.genImport(fn, goIdentifier(fn.Name, true))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Exported functions.
+	for _, iface := range g.world.Exports {
+		err := g.genExports(iface.Funcs, goIdentifier(iface.Name, true), "interface "+iface.Name, iface.Doc)
+		if err != nil {
+			return err
+		}
+	}
+	if len(g.world.ExportFuncs) != 0 {
+		err := g.genExports(g.world.ExportFuncs, "", "world "+g.world.Name, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	g.genHelpers()
+	g.genSection()
+	return nil
+}
+
+// interfaces returns all interfaces imported or exported by the world.
+func (g *generator) interfaces() []*Interface {
+	var interfaces []*Interface
+	interfaces = append(interfaces, g.world.Imports...)
+	return append(interfaces, g.world.Exports...)
+}
+
+// printComment prints a doc comment, if there is one.
+func (g *generator) printComment(doc string) {
+	for _, line := range docLines(doc) {
+		g.printf("// %s\n", line)
+	}
+}
+
+func docLines(doc string) []string {
+	if doc == "" {
+		return nil
+	}
+	return strings.Split(doc, "\n")
+}
+
+// genTypeDef writes the Go type for a WIT record, variant or enum.
+func (g *generator) genTypeDef(typ *TypeDef) error {
+	goName := g.typeIDs[typ]
+	g.printComment(typ.Doc)
+	switch typ.Kind {
+	case "record":
+		g.printf("type %s struct {\n", goName)
+		for _, field := range typ.Fields {
+			g.printf("\t%s %s\n", goIdentifier(field.Name, true), g.goType(field.Type))
+		}
+		g.printf("}\n\n")
+	case "enum":
+		g.printf("type %s %s\n\n", goName, discriminantType(len(typ.Cases)))
+		err := g.genCaseConstants(typ, goName, goName)
+		if err != nil {
+			return err
+		}
+	case "variant":
+		// Variants are represented as a struct with a tag and a field for
+		// each case with a payload. Only the field of the case selected by
+		// the tag is used.
+		tagName := goName + "Tag"
+		err := g.declare(tagName, "variant "+typ.Name)
+		if err != nil {
+			return err
+		}
+		g.printf("type %s struct {\n", goName)
+		g.printf("\tTag %s\n", tagName)
+		for _, c := range typ.Cases {
+			if c.Type != nil {
+				g.printf("\t%s %s\n", goIdentifier(c.Name, true), g.goType(c.Type))
+			}
+		}
+		g.printf("}\n\n")
+		g.printf("// %s selects the case of a %s.\n", tagName, goName)
+		g.printf("type %s %s\n\n", tagName, discriminantType(len(typ.Cases)))
+		err = g.genCaseConstants(typ, goName, tagName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genCaseConstants declares a constant for each case of an enum or variant.
+func (g *generator) genCaseConstants(typ *TypeDef, prefix, goType string) error {
+	g.printf("const (\n")
+	for i, c := range typ.Cases {
+		name := prefix + goIdentifier(c.Name, true)
+		err := g.declare(name, typ.Kind+" case "+typ.Name+"."+c.Name)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			g.printf("\t%s %s = iota\n", name, goType)
+		} else {
+			g.printf("\t%s\n", name)
+		}
+	}
+	g.printf(")\n\n")
+	return nil
+}
+
+// discriminantType returns the Go type used to store the case index of an
+// enum or variant with the given number of cases.
+func discriminantType(numCases int) string {
+	return "uint" + strconv.Itoa(int(discriminantSize(numCases))*8)
+}
+
+// goType returns the Go type for a WIT type.
+func (g *generator) goType(t Type) string {
+	switch t := t.(type) {
+	case Primitive:
+		return goPrimitives[t]
+	case *List:
+		return "[]" + g.goType(t.Elem)
+	case *Option:
+		return "*" + g.goType(t.Elem)
+	case *TypeDef:
+		return g.typeIDs[t]
+	}
+	panic("unknown type: " + t.String())
+}
+
+var goPrimitives = map[Primitive]string{
+	Bool:    "bool",
+	S8:      "int8",
+	U8:      "uint8",
+	S16:     "int16",
+	U16:     "uint16",
+	S32:     "int32",
+	U32:     "uint32",
+	S64:     "int64",
+	U64:     "uint64",
+	Float32: "float32",
+	Float64: "float64",
+	Char:    "rune",
+	String:  "string",
+}
+
+// goCoreTypes are the Go types used for core WebAssembly values.
+var goCoreTypes = map[string]string{
+	coreI32: "uint32",
+	coreI64: "uint64",
+	coreF32: "float32",
+	coreF64: "float64",
+}
+
+// goIdentifier converts a kebab-case WIT name to a Go identifier, either
+// exported (FooBar) or unexported (fooBar).
+func goIdentifier(name string, exported bool) string {
+	var b strings.Builder
+	for i, part := range strings.Split(name, "-") {
+		if part == "" {
+			continue
+		}
+		if i != 0 || exported {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+		b.WriteString(part)
+	}
+	ident := b.String()
+	if ident == "" || ident[0] >= '0' && ident[0] <= '9' {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// paramName returns the Go name of a function parameter. It avoids Go
+// keywords and the names of temporary variables.
+func paramName(name string) string {
+	ident := goIdentifier(name, false)
+	if token.Lookup(ident).IsKeyword() || types.Universe.Lookup(ident) != nil || isTempName(ident) {
+		return ident + "_"
+	}
+	switch ident {
+	case "math", "unsafe":
+		return ident + "_"
+	}
+	for _, helper := range helperNames {
+		if ident == helper {
+			return ident + "_"
+		}
+	}
+	return ident
+}
+
+func isTempName(name string) bool {
+	if len(name) < 2 || name[0] != 't' {
+		return false
+	}
+	_, err := strconv.Atoi(name[1:])
+	return err == nil
+}
+
+// signature returns the Go parameter list and result type of a function.
+func (g *generator) signature(fn *Function) (params []string, result string) {
+	for _, param := range fn.Params {
+		params = append(params, paramName(param.Name)+" "+g.goType(param.Type))
+	}
+	if fn.Result != nil {
+		result = " " + g.goType(fn.Result)
+	}
+	return params, result
+}
+
+// coreParams returns the parameter list of a core WebAssembly function with
+// parameters named p0, p1, etc.
+func coreParams(types []string) (params, names []string) {
+	for i, t := range types {
+		name := "p" + strconv.Itoa(i)
+		names = append(names, name)
+		params = append(params, name+" "+goCoreTypes[t])
+	}
+	return params, names
+}
+
+// genImport writes the import declaration of a core WebAssembly function and
+// a Go wrapper that lowers the parameters and lifts the result.
+func (g *generator) genImport(fn *Function, goName string) error {
+	err := g.declare(goName, "imported function "+fn.Name)
+	if err != nil {
+		return err
+	}
+	coreName := "wasmImport" + goName
+	err = g.declare(coreName, "imported function "+fn.Name)
+	if err != nil {
+		return err
+	}
+	g.tmp = 0
+	coreParamTypes, coreResultTypes := coreSignature(fn, false)
+	params, _ := coreParams(coreParamTypes)
+	coreResult := ""
+	if len(coreResultTypes) != 0 {
+		coreResult = " " + goCoreTypes[coreResultTypes[0]]
+	}
+	g.printf("//go:wasm-module %s\n", g.doc.importModule(fn))
+	g.printf("//export %s\n", fn.Name)
+	g.printf("func %s(%s)%s\n\n", coreName, strings.Join(params, ", "), coreResult)
+
+	goParams, goResult := g.signature(fn)
+	g.printComment(fn.Doc)
+	g.printf("func %s(%s)%s {\n", goName, strings.Join(goParams, ", "), goResult)
+
+	// Lower the parameters, either as flat values or in linear memory.
+	var args []string
+	var flatCount int
+	for _, param := range fn.Params {
+		flatCount += len(flatten(param.Type))
+	}
+	if flatCount > maxFlatParams {
+		offsets, size, align := recordLayout(fn.Params)
+		area := g.newTemp()
+		g.printf("%s := alloc(%d, %d)\n", area, size, align)
+		for i, param := range fn.Params {
+			g.store(param.Type, addOffset(area, offsets[i]), paramName(param.Name))
+		}
+		args = []string{area}
+	} else {
+		for _, param := range fn.Params {
+			args = append(args, g.lowerFlat(param.Type, paramName(param.Name))...)
+		}
+	}
+
+	// Call the imported function and lift the result.
+	call := coreName + "(" + strings.Join(args, ", ")
+	switch {
+	case fn.Result == nil:
+		g.printf("%s)\n", call)
+		g.printf("unpin()\n")
+	case len(coreResultTypes) == 0:
+		// The result is written to a return area passed as the last
+		// parameter.
+		size, align := sizeAlign(fn.Result)
+		area := g.newTemp()
+		g.printf("%s := alloc(%d, %d)\n", area, size, align)
+		if len(args) != 0 {
+			call += ", "
+		}
+		g.printf("%s%s)\n", call, area)
+		result := g.load(fn.Result, area)
+		g.printf("unpin()\n")
+		g.printf("return %s\n", result)
+	default:
+		r := g.newTemp()
+		g.printf("%s := %s)\n", r, call)
+		result := g.liftFlat(fn.Result, []string{r})
+		g.printf("unpin()\n")
+		g.printf("return %s\n", result)
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+// genExports writes an interface type for a group of exported functions, a
+// function to register the implementation, and the exported core WebAssembly
+// functions that lift the parameters and lower the results.
+func (g *generator) genExports(funcs []*Function, prefix, witName, doc string) error {
+	ifaceName := prefix + "Exports"
+	implName := strings.ToLower(ifaceName[:1]) + ifaceName[1:]
+	setName := "Set" + ifaceName
+	for _, name := range []string{ifaceName, implName, setName} {
+		err := g.declare(name, witName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if doc != "" {
+		g.printComment(doc)
+	} else {
+		g.printf("// %s is implemented by the program to export the functions of %s.\n", ifaceName, witName)
+	}
+	g.printf("type %s interface {\n", ifaceName)
+	for _, fn := range funcs {
+		g.printComment(fn.Doc)
+		params, result := g.signature(fn)
+		g.printf("%s(%s)%s\n", goIdentifier(fn.Name, true), strings.Join(params, ", "), result)
+	}
+	g.printf("}\n\n")
+	g.printf("var %s %s\n\n", implName, ifaceName)
+	g.printf("// %s registers the implementation of the functions exported by %s.\n", setName, witName)
+	g.printf("// It must be called before any of them are called by the host.\n")
+	g.printf("func %s(impl %s) {\n", setName, ifaceName)
+	g.printf("%s = impl\n", implName)
+	g.printf("}\n\n")
+
+	for _, fn := range funcs {
+		err := g.genExport(fn, prefix, implName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) genExport(fn *Function, prefix, implName string) error {
+	coreName := "wasmExport" + prefix + goIdentifier(fn.Name, true)
+	err := g.declare(coreName, "exported function "+fn.Name)
+	if err != nil {
+		return err
+	}
+	g.tmp = 0
+	exportName := g.doc.exportName(fn)
+	coreParamTypes, coreResultTypes := coreSignature(fn, true)
+	params, names := coreParams(coreParamTypes)
+	coreResult := ""
+	if len(coreResultTypes) != 0 {
+		coreResult = " " + goCoreTypes[coreResultTypes[0]]
+	}
+	g.printf("//export %s\n", exportName)
+	g.printf("func %s(%s)%s {\n", coreName, strings.Join(params, ", "), coreResult)
+
+	// Lift the parameters.
+	var args []string
+	var flatCount int
+	for _, param := range fn.Params {
+		flatCount += len(flatten(param.Type))
+	}
+	if flatCount > maxFlatParams {
+		offsets, _, _ := recordLayout(fn.Params)
+		for i, param := range fn.Params {
+			args = append(args, g.assign(g.load(param.Type, addOffset(names[0], offsets[i]))))
+		}
+	} else {
+		for _, param := range fn.Params {
+			n := len(flatten(param.Type))
+			args = append(args, g.assign(g.liftFlat(param.Type, names[:n])))
+			names = names[n:]
+		}
+	}
+	// The lifted values now keep the memory allocated by the host alive.
+	g.printf("unpin()\n")
+
+	// Call the implementation and lower the result.
+	call := implName + "." + goIdentifier(fn.Name, true) + "(" + strings.Join(args, ", ") + ")"
+	switch {
+	case fn.Result == nil:
+		g.printf("%s\n", call)
+	case len(coreResultTypes) == 1 && len(flatten(fn.Result)) == 1:
+		r := g.newTemp()
+		g.printf("%s := %s\n", r, call)
+		g.printf("return %s\n", g.lowerFlat(fn.Result, r)[0])
+	default:
+		// The result is returned in linear memory, which is kept alive until
+		// the post-return function is called.
+		r := g.newTemp()
+		g.printf("%s := %s\n", r, call)
+		size, align := sizeAlign(fn.Result)
+		area := g.newTemp()
+		g.printf("%s := alloc(%d, %d)\n", area, size, align)
+		g.store(fn.Result, area, r)
+		g.printf("return %s\n", area)
+	}
+	g.printf("}\n\n")
+
+	if fn.Result != nil && (usesMemory(fn.Result) || len(flatten(fn.Result)) > maxFlatResults) {
+		postParams, _ := coreParams(coreResultTypes)
+		g.printf("//export cabi_post_%s\n", exportName)
+		g.printf("func %sPost(%s) {\n", coreName, strings.Join(postParams, ", "))
+		g.printf("unpin()\n")
+		g.printf("}\n\n")
+	}
+	return nil
+}
+
+// assign stores the result of the expression in a new temporary variable
+// and returns its name, unless the expression is a variable already.
+func (g *generator) assign(expr string) string {
+	if token.IsIdentifier(expr) {
+		return expr
+	}
+	v := g.newTemp()
+	g.printf("%s := %s\n", v, expr)
+	return v
+}
+
+// addOffset returns an expression for an address plus a constant offset.
+func addOffset(addr string, offset uint32) string {
+	if offset == 0 {
+		return addr
+	}
+	return addr + "+" + strconv.FormatUint(uint64(offset), 10)
+}
+
+// lowerFlat writes code to lower the Go value v to core WebAssembly values and
+// returns expressions for these values, one for each type returned by
+// flatten.
+func (g *generator) lowerFlat(t Type, v string) []string {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case Bool:
+			r := g.newTemp()
+			g.printf("var %s uint32\n", r)
+			g.printf("if %s {\n%s = 1\n}\n", v, r)
+			return []string{r}
+		case S8, S16:
+			return []string{"uint32(int32(" + v + "))"}
+		case S64:
+			return []string{"uint64(" + v + ")"}
+		case U64, Float32, Float64:
+			return []string{v}
+		case String:
+			ptr, length := g.newTemp(), g.newTemp()
+			g.printf("%s, %s := lowerString(%s)\n", ptr, length, v)
+			return []string{ptr, length}
+		default:
+			return []string{"uint32(" + v + ")"}
+		}
+	case *List:
+		return g.lowerList(t, v)
+	case *Option:
+		return g.lowerFlatVariant(flatten(t), "", []variantCase{
+			{cond: v + " == nil"},
+			{cond: v + " != nil", typ: t.Elem, value: "(*" + v + ")"},
+		})
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			var values []string
+			for _, field := range t.Fields {
+				values = append(values, g.lowerFlat(field.Type, v+"."+goIdentifier(field.Name, true))...)
+			}
+			return values
+		case "variant":
+			cases := make([]variantCase, len(t.Cases))
+			for i, c := range t.Cases {
+				cases[i] = variantCase{
+					cond:  g.typeIDs[t] + goIdentifier(c.Name, true),
+					typ:   c.Type,
+					value: v + "." + goIdentifier(c.Name, true),
+				}
+			}
+			return g.lowerFlatVariant(flatten(t), v+".Tag", cases)
+		default: // enum
+			return []string{"uint32(" + v + ")"}
+		}
+	}
+	panic("unknown type: " + t.String())
+}
+
+// variantCase describes a single case of a variant or option for the code
+// generator.
+type variantCase struct {
+	cond  string // case expression in the switch statement
+	typ   Type   // payload type, or nil
+	value string // expression for the payload value
+}
+
+// lowerFlatVariant lowers a variant or option using a switch statement over
+// the given tag expression. If tag is empty, the cases contain boolean
+// conditions instead.
+func (g *generator) lowerFlatVariant(flat []string, tag string, cases []variantCase) []string {
+	values := make([]string, len(flat))
+	for i, t := range flat {
+		values[i] = g.newTemp()
+		g.printf("var %s %s\n", values[i], goCoreTypes[t])
+	}
+	g.printf("switch %s {\n", tag)
+	for i, c := range cases {
+		if i == 0 && c.typ == nil {
+			continue // all zero
+		}
+		g.printf("case %s:\n", c.cond)
+		if i != 0 {
+			g.printf("%s = %d\n", values[0], i)
+		}
+		if c.typ == nil {
+			continue
+		}
+		for j, payload := range g.lowerFlat(c.typ, c.value) {
+			g.printf("%s = %s\n", values[j+1], g.convertCore(payload, flatten(c.typ)[j], flat[j+1]))
+		}
+	}
+	g.printf("}\n")
+	return values
+}
+
+// convertCore converts a core value to the joined type of a variant payload.
+func (g *generator) convertCore(v, from, to string) string {
+	switch {
+	case from == to:
+		return v
+	case from == coreF32 && to == coreI32:
+		g.useMath = true
+		return "math.Float32bits(" + v + ")"
+	case from == coreI32 && to == coreI64:
+		return "uint64(" + v + ")"
+	case from == coreF32 && to == coreI64:
+		g.useMath = true
+		return "uint64(math.Float32bits(" + v + "))"
+	case from == coreF64 && to == coreI64:
+		g.useMath = true
+		return "math.Float64bits(" + v + ")"
+	}
+	panic("cannot convert " + from + " to " + to)
+}
+
+// convertCoreBack is the inverse of convertCore.
+func (g *generator) convertCoreBack(v, from, to string) string {
+	switch {
+	case from == to:
+		return v
+	case from == coreI32 && to == coreF32:
+		g.useMath = true
+		return "math.Float32frombits(" + v + ")"
+	case from == coreI64 && to == coreI32:
+		return "uint32(" + v + ")"
+	case from == coreI64 && to == coreF32:
+		g.useMath = true
+		return "math.Float32frombits(uint32(" + v + "))"
+	case from == coreI64 && to == coreF64:
+		g.useMath = true
+		return "math.Float64frombits(" + v + ")"
+	}
+	panic("cannot convert " + from + " to " + to)
+}
+
+// lowerList copies the elements of a Go slice to linear memory and returns
+// the pointer and length.
+func (g *generator) lowerList(t *List, v string) []string {
+	size, align := sizeAlign(t.Elem)
+	ptr := g.newTemp()
+	g.printf("%s := alloc(uint32(len(%s))*%d, %d)\n", ptr, v, size, align)
+	i, elem := g.newTemp(), g.newTemp()
+	g.printf("for %s, %s := range %s {\n", i, elem, v)
+	g.store(t.Elem, ptr+"+uint32("+i+")*"+strconv.Itoa(int(size)), elem)
+	g.printf("}\n")
+	return []string{ptr, "uint32(len(" + v + "))"}
+}
+
+// liftFlat writes code to lift core WebAssembly values to a Go value of the
+// given type and returns an expression for it.
+func (g *generator) liftFlat(t Type, values []string) string {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case Bool:
+			return values[0] + " != 0"
+		case U32, U64, Float32, Float64:
+			return values[0]
+		case String:
+			return "liftString(" + values[0] + ", " + values[1] + ")"
+		default:
+			return goPrimitives[t] + "(" + values[0] + ")"
+		}
+	case *List:
+		return g.liftList(t, values[0], values[1])
+	case *Option:
+		r := g.newTemp()
+		g.printf("var %s %s\n", r, g.goType(t))
+		g.printf("if %s != 0 {\n", values[0])
+		g.printf("%s = new(%s)\n", r, g.goType(t.Elem))
+		g.printf("*%s = %s\n", r, g.liftFlat(t.Elem, g.payloadValues(t.Elem, flatten(t), values)))
+		g.printf("}\n")
+		return r
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			var fields []string
+			for _, field := range t.Fields {
+				n := len(flatten(field.Type))
+				fields = append(fields, goIdentifier(field.Name, true)+": "+g.assign(g.liftFlat(field.Type, values[:n])))
+				values = values[n:]
+			}
+			return g.typeIDs[t] + "{" + strings.Join(fields, ", ") + "}"
+		case "variant":
+			flat := flatten(t)
+			r := g.newTemp()
+			g.printf("var %s %s\n", r, g.typeIDs[t])
+			g.printf("%s.Tag = %sTag(%s)\n", r, g.typeIDs[t], values[0])
+			g.liftVariantPayload(t, r, func(c *Case) string {
+				return g.liftFlat(c.Type, g.payloadValues(c.Type, flat, values))
+			})
+			return r
+		default: // enum
+			return g.typeIDs[t] + "(" + values[0] + ")"
+		}
+	}
+	panic("unknown type: " + t.String())
+}
+
+// liftVariantPayload writes a switch statement that sets the payload field of
+// the variant stored in variable v, using the lift function for the value.
+func (g *generator) liftVariantPayload(t *TypeDef, v string, lift func(c *Case) string) {
+	g.printf("switch %s.Tag {\n", v)
+	for _, c := range t.Cases {
+		if c.Type == nil {
+			continue
+		}
+		g.printf("case %s%s:\n", g.typeIDs[t], goIdentifier(c.Name, true))
+		g.printf("%s.%s = %s\n", v, goIdentifier(c.Name, true), lift(c))
+	}
+	g.printf("}\n")
+}
+
+// payloadValues converts the joined flat values of a variant back to the flat
+// values of a single case.
+func (g *generator) payloadValues(payload Type, flat, values []string) []string {
+	var result []string
+	for i, t := range flatten(payload) {
+		result = append(result, g.convertCoreBack(values[i+1], flat[i+1], t))
+	}
+	return result
+}
+
+// liftList copies a list from linear memory to a new Go slice.
+func (g *generator) liftList(t *List, ptr, length string) string {
+	size, _ := sizeAlign(t.Elem)
+	r := g.newTemp()
+	g.printf("%s := make(%s, %s)\n", r, g.goType(t), length)
+	i := g.newTemp()
+	g.printf("for %s := range %s {\n", i, r)
+	g.printf("%s[%s] = %s\n", r, i, g.load(t.Elem, ptr+"+uint32("+i+")*"+strconv.Itoa(int(size))))
+	g.printf("}\n")
+	return r
+}
+
+// store writes code to store the Go value v at the given address in linear
+// memory.
+func (g *generator) store(t Type, addr, v string) {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case String:
+			g.storeFlat(addr, g.lowerFlat(t, v))
+		default:
+			g.printf("*(*%s)(wasmPtr(%s)) = %s\n", goPrimitives[t], addr, v)
+		}
+	case *List:
+		g.storeFlat(addr, g.lowerFlat(t, v))
+	case *Option:
+		layout := variantLayout([]Type{nil, t.Elem})
+		g.printf("if %s != nil {\n", v)
+		g.printf("*(*uint8)(wasmPtr(%s)) = 1\n", addr)
+		g.store(t.Elem, addOffset(addr, layout.payloadOffset), "(*"+v+")")
+		g.printf("} else {\n")
+		g.printf("*(*uint8)(wasmPtr(%s)) = 0\n", addr)
+		g.printf("}\n")
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			offsets, _, _ := recordLayout(t.Fields)
+			for i, field := range t.Fields {
+				g.store(field.Type, addOffset(addr, offsets[i]), v+"."+goIdentifier(field.Name, true))
+			}
+		case "variant":
+			layout := variantLayout(t.caseTypes())
+			g.printf("*(*%s)(wasmPtr(%s)) = %s(%s.Tag)\n", discriminantType(len(t.Cases)), addr, discriminantType(len(t.Cases)), v)
+			g.printf("switch %s.Tag {\n", v)
+			for _, c := range t.Cases {
+				if c.Type == nil {
+					continue
+				}
+				g.printf("case %s%s:\n", g.typeIDs[t], goIdentifier(c.Name, true))
+				g.store(c.Type, addOffset(addr, layout.payloadOffset), v+"."+goIdentifier(c.Name, true))
+			}
+			g.printf("}\n")
+		default: // enum
+			g.printf("*(*%s)(wasmPtr(%s)) = %s(%s)\n", discriminantType(len(t.Cases)), addr, discriminantType(len(t.Cases)), v)
+		}
+	default:
+		panic("unknown type: " + t.String())
+	}
+}
+
+// storeFlat stores a pointer and length pair at the given address.
+func (g *generator) storeFlat(addr string, values []string) {
+	g.printf("*(*uint32)(wasmPtr(%s)) = %s\n", addr, values[0])
+	g.printf("*(*uint32)(wasmPtr(%s)) = %s\n", addOffset(addr, 4), values[1])
+}
+
+// load writes code to load a value of the given type from linear memory and
+// returns an expression for the resulting Go value.
+func (g *generator) load(t Type, addr string) string {
+	switch t := t.(type) {
+	case Primitive:
+		switch t {
+		case Bool:
+			return "*(*uint8)(wasmPtr(" + addr + ")) != 0"
+		case String:
+			return g.liftFlat(t, g.loadFlat(addr))
+		default:
+			return "*(*" + goPrimitives[t] + ")(wasmPtr(" + addr + "))"
+		}
+	case *List:
+		return g.liftFlat(t, g.loadFlat(addr))
+	case *Option:
+		layout := variantLayout([]Type{nil, t.Elem})
+		r := g.newTemp()
+		g.printf("var %s %s\n", r, g.goType(t))
+		g.printf("if *(*uint8)(wasmPtr(%s)) != 0 {\n", addr)
+		g.printf("%s = new(%s)\n", r, g.goType(t.Elem))
+		g.printf("*%s = %s\n", r, g.load(t.Elem, addOffset(addr, layout.payloadOffset)))
+		g.printf("}\n")
+		return r
+	case *TypeDef:
+		switch t.Kind {
+		case "record":
+			offsets, _, _ := recordLayout(t.Fields)
+			var fields []string
+			for i, field := range t.Fields {
+				fields = append(fields, goIdentifier(field.Name, true)+": "+g.assign(g.load(field.Type, addOffset(addr, offsets[i]))))
+			}
+			return g.typeIDs[t] + "{" + strings.Join(fields, ", ") + "}"
+		case "variant":
+			layout := variantLayout(t.caseTypes())
+			r := g.newTemp()
+			g.printf("var %s %s\n", r, g.typeIDs[t])
+			g.printf("%s.Tag = %sTag(*(*%s)(wasmPtr(%s)))\n", r, g.typeIDs[t], discriminantType(len(t.Cases)), addr)
+			g.liftVariantPayload(t, r, func(c *Case) string {
+				return g.load(c.Type, addOffset(addr, layout.payloadOffset))
+			})
+			return r
+		default: // enum
+			return g.typeIDs[t] + "(*(*" + discriminantType(len(t.Cases)) + ")(wasmPtr(" + addr + ")))"
+		}
+	}
+	panic("unknown type: " + t.String())
+}
+
+// loadFlat loads a pointer and length pair from the given address.
+func (g *generator) loadFlat(addr string) []string {
+	ptr, length := g.newTemp(), g.newTemp()
+	g.printf("%s := *(*uint32)(wasmPtr(%s))\n", ptr, addr)
+	g.printf("%s := *(*uint32)(wasmPtr(%s))\n", length, addOffset(addr, 4))
+	return []string{ptr, length}
+}
+
+// genHelpers writes the functions used by the generated bindings to manage
+// memory that is shared with the host.
+func (g *generator) genHelpers() {
+	g.out.WriteString(`// pinned keeps memory alive that is only referenced from the host, until the
+// host is done with it.
+var pinned []unsafe.Pointer
+
+func unpin() {
+	pinned = nil
+}
+
+// wasmPtr converts an address in linear memory to a pointer.
+func wasmPtr(addr uint32) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(addr))
+}
+
+// alloc allocates memory that stays alive until the next call to unpin.
+func alloc(size, align uint32) uint32 {
+	if size == 0 {
+		return align
+	}
+	buf := make([]uint64, (size+7)/8) // aligned to 8 bytes, the maximum alignment
+	ptr := unsafe.Pointer(&buf[0])
+	pinned = append(pinned, ptr)
+	return uint32(uintptr(ptr))
+}
+
+// cabiRealloc is called by the host to allocate memory for values passed to
+// the program, such as strings.
+//
+//export cabi_realloc
+func cabiRealloc(ptr, oldSize, align, newSize uint32) uint32 {
+	newPtr := alloc(newSize, align)
+	for i := uint32(0); i < oldSize && i < newSize; i++ {
+		*(*byte)(wasmPtr(newPtr + i)) = *(*byte)(wasmPtr(ptr + i))
+	}
+	return newPtr
+}
+
+type stringHeader struct {
+	data unsafe.Pointer
+	len  uintptr
+}
+
+func lowerString(s string) (uint32, uint32) {
+	if len(s) == 0 {
+		return 1, 0
+	}
+	ptr := (*stringHeader)(unsafe.Pointer(&s)).data
+	pinned = append(pinned, ptr)
+	return uint32(uintptr(ptr)), uint32(len(s))
+}
+
+func liftString(ptr, length uint32) string {
+	var s string
+	if length != 0 {
+		header := (*stringHeader)(unsafe.Pointer(&s))
+		header.data = wasmPtr(ptr)
+		header.len = uintptr(length)
+	}
+	return s
+}
+
+`)
+}
+
+// genSection writes the global containing the custom section with the WIT
+// definition of the world, so that the resulting module can be checked
+// against it with Validate.
+func (g *generator) genSection() {
+	data := Format(g.doc, g.world)
+	g.printf("// witSection is stored in a custom section of the WebAssembly module.\n")
+	g.printf("//\n")
+	g.printf("//go:section .custom_section.%s%s\n", SectionPrefix, g.world.Name)
+	g.printf("var witSection = [%d]byte{", len(data))
+	for i, c := range []byte(data) {
+		if i%16 == 0 {
+			g.printf("\n")
+		}
+		g.printf("0x%02x, ", c)
+	}
+	g.printf("\n}\n")
+}
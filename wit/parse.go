@@ -0,0 +1,768 @@
+package wit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Position is a location in a WIT file.
+type Position struct {
+	Filename string
+	Line     int // 1-based
+	Column   int // 1-based, in bytes
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+// Error is a syntax or type error in a WIT file.
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ParseFile reads and parses the WIT file at the given path.
+func ParseFile(path string) (*Document, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse parses a WIT document. The filename is only used in error messages.
+func Parse(filename string, src []byte) (*Document, error) {
+	p := &parser{
+		scanner: scanner{filename: filename, src: src, line: 1, col: 1},
+		doc:     &Document{},
+	}
+	err := p.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+	err = p.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return p.doc, nil
+}
+
+// Token kinds returned by the scanner. Punctuation is returned as the
+// punctuation itself.
+const (
+	tokenEOF   = "EOF"
+	tokenIdent = "identifier"
+	tokenArrow = "->"
+)
+
+type witToken struct {
+	kind  string
+	value string // identifier name, without a leading %
+	doc   string // doc comment (///) preceding this token
+	pos   Position
+}
+
+// scanner splits a WIT file in tokens.
+type scanner struct {
+	filename  string
+	src       []byte
+	offset    int
+	line, col int
+}
+
+func (s *scanner) pos() Position {
+	return Position{Filename: s.filename, Line: s.line, Column: s.col}
+}
+
+func (s *scanner) peekByte(n int) byte {
+	if s.offset+n >= len(s.src) {
+		return 0
+	}
+	return s.src[s.offset+n]
+}
+
+func (s *scanner) advance() {
+	if s.src[s.offset] == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	s.offset++
+}
+
+// skipSpace skips whitespace and comments, and returns the doc comments it
+// found on the way.
+func (s *scanner) skipSpace() (string, error) {
+	var doc []string
+	for s.offset < len(s.src) {
+		c := s.src[s.offset]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			s.advance()
+		case c == '/' && s.peekByte(1) == '/':
+			start := s.offset
+			for s.offset < len(s.src) && s.src[s.offset] != '\n' {
+				s.advance()
+			}
+			line := string(s.src[start:s.offset])
+			if strings.HasPrefix(line, "///") {
+				doc = append(doc, strings.TrimSpace(line[3:]))
+			}
+		case c == '/' && s.peekByte(1) == '*':
+			pos := s.pos()
+			s.advance()
+			s.advance()
+			for {
+				if s.offset >= len(s.src) {
+					return "", &Error{pos, "unterminated block comment"}
+				}
+				if s.src[s.offset] == '*' && s.peekByte(1) == '/' {
+					s.advance()
+					s.advance()
+					break
+				}
+				s.advance()
+			}
+		default:
+			return strings.Join(doc, "\n"), nil
+		}
+	}
+	return strings.Join(doc, "\n"), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// next returns the next token in the input.
+func (s *scanner) next() (witToken, error) {
+	doc, err := s.skipSpace()
+	if err != nil {
+		return witToken{}, err
+	}
+	tok := witToken{doc: doc, pos: s.pos()}
+	if s.offset >= len(s.src) {
+		tok.kind = tokenEOF
+		return tok, nil
+	}
+	c := s.src[s.offset]
+	switch {
+	case c == '-' && s.peekByte(1) == '>':
+		s.advance()
+		s.advance()
+		tok.kind = tokenArrow
+	case c == '%' || isIdentByte(c):
+		if c == '%' {
+			s.advance()
+		}
+		start := s.offset
+		for s.offset < len(s.src) && isIdentByte(s.src[s.offset]) {
+			s.advance()
+		}
+		tok.kind = tokenIdent
+		tok.value = string(s.src[start:s.offset])
+		if tok.value == "" {
+			return tok, &Error{tok.pos, "expected identifier after %"}
+		}
+	case strings.IndexByte(":;,.{}()<>=@/", c) >= 0:
+		s.advance()
+		tok.kind = string(c)
+	default:
+		return tok, &Error{tok.pos, fmt.Sprintf("unexpected character %q", c)}
+	}
+	return tok, nil
+}
+
+// parser builds a Document from the tokens returned by the scanner.
+type parser struct {
+	scanner
+	tok witToken
+	doc *Document
+}
+
+// nextToken reads the next token into p.tok.
+func (p *parser) nextToken() error {
+	tok, err := p.scanner.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// expect checks that the current token is of the given kind and moves on to
+// the next token.
+func (p *parser) expect(kind string) (witToken, error) {
+	tok := p.tok
+	if tok.kind != kind {
+		return tok, p.unexpected("expected " + kind)
+	}
+	return tok, p.nextToken()
+}
+
+// expectIdent is like expect(tokenIdent) but returns the identifier.
+func (p *parser) expectIdent() (string, error) {
+	tok, err := p.expect(tokenIdent)
+	return tok.value, err
+}
+
+// unexpected returns an error for the current token.
+func (p *parser) unexpected(msg string) error {
+	found := p.tok.kind
+	if p.tok.kind == tokenIdent {
+		found = p.tok.value
+	}
+	return &Error{p.tok.pos, fmt.Sprintf("%s, found %s", msg, found)}
+}
+
+func (p *parser) parseDocument() error {
+	err := p.nextToken()
+	if err != nil {
+		return err
+	}
+	if p.tok.kind == tokenIdent && p.tok.value == "package" {
+		err := p.parsePackage()
+		if err != nil {
+			return err
+		}
+	}
+	for p.tok.kind != tokenEOF {
+		doc := p.tok.doc
+		keyword, err := p.expectIdent()
+		if err != nil {
+			return err
+		}
+		switch keyword {
+		case "interface":
+			iface, err := p.parseInterface(doc)
+			if err != nil {
+				return err
+			}
+			p.doc.Interfaces = append(p.doc.Interfaces, iface)
+		case "world":
+			world, err := p.parseWorld(doc)
+			if err != nil {
+				return err
+			}
+			p.doc.Worlds = append(p.doc.Worlds, world)
+		default:
+			return &Error{p.tok.pos, "expected interface or world, found " + keyword}
+		}
+	}
+	return nil
+}
+
+// parsePackage parses a package declaration like this:
+//
+//	package example:host@0.1.0;
+func (p *parser) parsePackage() error {
+	// Package names contain version numbers which don't fit the identifier
+	// token, so read the raw source up to the semicolon.
+	pos := p.tok.pos
+	start := p.offset
+	for p.offset < len(p.src) && p.src[p.offset] != ';' {
+		p.advance()
+	}
+	if p.offset >= len(p.src) {
+		return &Error{pos, "expected ; after package name"}
+	}
+	name := strings.TrimSpace(string(p.src[start:p.offset]))
+	if !strings.Contains(name, ":") {
+		return &Error{pos, "package name must be of the form namespace:name, found " + name}
+	}
+	p.doc.Package = name
+	p.advance() // skip ;
+	return p.nextToken()
+}
+
+// parseInterface parses an interface body, starting with the name. The
+// interface keyword has already been consumed.
+func (p *parser) parseInterface(doc string) (*Interface, error) {
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	iface := &Interface{Name: name, Doc: doc}
+	return iface, p.parseInterfaceBody(iface)
+}
+
+func (p *parser) parseInterfaceBody(iface *Interface) error {
+	_, err := p.expect("{")
+	if err != nil {
+		return err
+	}
+	for p.tok.kind != "}" {
+		doc := p.tok.doc
+		pos := p.tok.pos
+		name, err := p.expectIdent()
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "record", "variant", "enum":
+			typ, err := p.parseTypeDef(name, doc)
+			if err != nil {
+				return err
+			}
+			iface.Types = append(iface.Types, typ)
+		case "resource", "flags", "type", "use":
+			return &Error{pos, name + " is not supported"}
+		default:
+			fn, err := p.parseFunc(name, doc)
+			if err != nil {
+				return err
+			}
+			fn.iface = iface
+			iface.Funcs = append(iface.Funcs, fn)
+		}
+	}
+	return p.nextToken()
+}
+
+// parseWorld parses a world, starting with the name. The world keyword has
+// already been consumed.
+func (p *parser) parseWorld(doc string) (*World, error) {
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	world := &World{Name: name, Doc: doc}
+	_, err = p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind != "}" {
+		doc := p.tok.doc
+		pos := p.tok.pos
+		keyword, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		switch keyword {
+		case "record", "variant", "enum":
+			typ, err := p.parseTypeDef(keyword, doc)
+			if err != nil {
+				return nil, err
+			}
+			world.Types = append(world.Types, typ)
+		case "import", "export":
+			doc = p.tok.doc
+			itemPos := p.tok.pos
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			var iface *Interface
+			var fn *Function
+			switch p.tok.kind {
+			case ";":
+				// Reference to an interface defined at the top level. It is
+				// resolved later as it may be defined after this world.
+				iface = &Interface{Name: name, ref: true, pos: itemPos}
+				err = p.nextToken()
+			case ":":
+				err = p.nextToken()
+				if err != nil {
+					return nil, err
+				}
+				if p.tok.kind == tokenIdent && p.tok.value == "interface" {
+					err = p.nextToken()
+					if err != nil {
+						return nil, err
+					}
+					iface = &Interface{Name: name, Doc: doc, inline: true}
+					err = p.parseInterfaceBody(iface)
+				} else {
+					fn, err = p.parseFuncSignature(name, doc)
+				}
+			default:
+				return nil, p.unexpected("expected ; or :")
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case keyword == "import" && iface != nil:
+				world.Imports = append(world.Imports, iface)
+			case keyword == "export" && iface != nil:
+				world.Exports = append(world.Exports, iface)
+			case keyword == "import":
+				world.ImportFuncs = append(world.ImportFuncs, fn)
+			default:
+				world.ExportFuncs = append(world.ExportFuncs, fn)
+			}
+		case "resource", "flags", "type", "use", "include":
+			return nil, &Error{pos, keyword + " is not supported"}
+		default:
+			return nil, &Error{pos, "expected import, export or type definition, found " + keyword}
+		}
+	}
+	return world, p.nextToken()
+}
+
+// parseTypeDef parses a record, variant or enum starting with the name.
+func (p *parser) parseTypeDef(kind, doc string) (*TypeDef, error) {
+	pos := p.tok.pos
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	typ := &TypeDef{Name: name, Doc: doc, Kind: kind}
+	_, err = p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind != "}" {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "record":
+			_, err = p.expect(":")
+			if err != nil {
+				return nil, err
+			}
+			fieldType, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			typ.Fields = append(typ.Fields, &Field{Name: name, Type: fieldType})
+		case "variant":
+			c := &Case{Name: name}
+			if p.tok.kind == "(" {
+				err = p.nextToken()
+				if err != nil {
+					return nil, err
+				}
+				c.Type, err = p.parseType()
+				if err != nil {
+					return nil, err
+				}
+				_, err = p.expect(")")
+				if err != nil {
+					return nil, err
+				}
+			}
+			typ.Cases = append(typ.Cases, c)
+		case "enum":
+			typ.Cases = append(typ.Cases, &Case{Name: name})
+		}
+		if p.tok.kind != "," {
+			break
+		}
+		err = p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect("}")
+	if err != nil {
+		return nil, err
+	}
+	if len(typ.Fields) == 0 && len(typ.Cases) == 0 {
+		return nil, &Error{pos, kind + " " + name + " must not be empty"}
+	}
+	return typ, nil
+}
+
+// parseFunc parses a function inside an interface, starting after the name.
+func (p *parser) parseFunc(name, doc string) (*Function, error) {
+	_, err := p.expect(":")
+	if err != nil {
+		return nil, err
+	}
+	return p.parseFuncSignature(name, doc)
+}
+
+// parseFuncSignature parses a function signature like this, including the
+// trailing semicolon:
+//
+//	func(a: u32, b: string) -> string;
+func (p *parser) parseFuncSignature(name, doc string) (*Function, error) {
+	if p.tok.kind != tokenIdent || p.tok.value != "func" {
+		return nil, p.unexpected("expected func")
+	}
+	err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	fn := &Function{Name: name, Doc: doc}
+	_, err = p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind != ")" {
+		paramName, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+		paramType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		fn.Params = append(fn.Params, &Field{Name: paramName, Type: paramType})
+		if p.tok.kind != "," {
+			break
+		}
+		err = p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect(")")
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenArrow {
+		err = p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind == "(" {
+			return nil, &Error{p.tok.pos, "named results are not supported"}
+		}
+		fn.Result, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect(";")
+	return fn, err
+}
+
+// parseType parses a type expression: a primitive type, list<T>, option<T>
+// or the name of a type definition.
+func (p *parser) parseType() (Type, error) {
+	pos := p.tok.pos
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	if typ, ok := primitives[name]; ok {
+		return typ, nil
+	}
+	switch name {
+	case "list", "option":
+		_, err := p.expect("<")
+		if err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(">")
+		if err != nil {
+			return nil, err
+		}
+		if name == "list" {
+			return &List{Elem: elem}, nil
+		}
+		return &Option{Elem: elem}, nil
+	case "tuple", "result", "borrow", "own", "future", "stream":
+		return nil, &Error{pos, name + " types are not supported"}
+	}
+	return &typeRef{name: name, pos: pos}, nil
+}
+
+// resolve replaces interface references in worlds with the interfaces they
+// refer to, and type references with the type definitions they name.
+func (p *parser) resolve() error {
+	interfaces := map[string]*Interface{}
+	for _, iface := range p.doc.Interfaces {
+		if interfaces[iface.Name] != nil {
+			return fmt.Errorf("%s: interface %s defined twice", p.filename, iface.Name)
+		}
+		interfaces[iface.Name] = iface
+		err := resolveInterface(iface, nil)
+		if err != nil {
+			return err
+		}
+	}
+	for _, world := range p.doc.Worlds {
+		for _, list := range [][]*Interface{world.Imports, world.Exports} {
+			for i, iface := range list {
+				if !iface.ref {
+					err := resolveInterface(iface, world.Types)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				list[i] = interfaces[iface.Name]
+				if list[i] == nil {
+					return &Error{iface.pos, "undefined interface: " + iface.Name}
+				}
+			}
+		}
+		scope := makeScope(world.Types)
+		for _, typ := range world.Types {
+			err := resolveTypeDef(typ, scope)
+			if err != nil {
+				return err
+			}
+		}
+		for _, fn := range append(world.ImportFuncs[:len(world.ImportFuncs):len(world.ImportFuncs)], world.ExportFuncs...) {
+			err := resolveFunc(fn, scope)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return p.checkRecursion()
+}
+
+// checkRecursion returns an error if a type definition contains itself,
+// which is not allowed in the component model.
+func (p *parser) checkRecursion() error {
+	var defs []*TypeDef
+	for _, iface := range p.doc.Interfaces {
+		defs = append(defs, iface.Types...)
+	}
+	for _, world := range p.doc.Worlds {
+		defs = append(defs, world.Types...)
+		for _, iface := range append(world.Imports[:len(world.Imports):len(world.Imports)], world.Exports...) {
+			if iface.inline {
+				defs = append(defs, iface.Types...)
+			}
+		}
+	}
+	for _, def := range defs {
+		if containsType(def, def, map[*TypeDef]bool{}) {
+			return fmt.Errorf("%s: type %s is recursive", p.filename, def.Name)
+		}
+	}
+	return nil
+}
+
+// containsType returns whether t (indirectly) contains the type definition
+// def.
+func containsType(t Type, def *TypeDef, visited map[*TypeDef]bool) bool {
+	switch t := t.(type) {
+	case *List:
+		return containsType(t.Elem, def, visited)
+	case *Option:
+		return containsType(t.Elem, def, visited)
+	case *TypeDef:
+		if visited[t] {
+			return t == def
+		}
+		visited[t] = true
+		for _, field := range t.Fields {
+			if containsType(field.Type, def, visited) {
+				return true
+			}
+		}
+		for _, c := range t.Cases {
+			if c.Type != nil && containsType(c.Type, def, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveInterface resolves all type references in an interface. Inline
+// interfaces may also refer to types defined in the world (outer).
+func resolveInterface(iface *Interface, outer []*TypeDef) error {
+	scope := makeScope(append(outer[:len(outer):len(outer)], iface.Types...))
+	for _, typ := range iface.Types {
+		err := resolveTypeDef(typ, scope)
+		if err != nil {
+			return err
+		}
+	}
+	for _, fn := range iface.Funcs {
+		err := resolveFunc(fn, scope)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeScope returns a map of type names to type definitions. Types defined
+// later in the list shadow earlier types.
+func makeScope(types []*TypeDef) map[string]*TypeDef {
+	scope := make(map[string]*TypeDef, len(types))
+	for _, typ := range types {
+		scope[typ.Name] = typ
+	}
+	return scope
+}
+
+func resolveTypeDef(typ *TypeDef, scope map[string]*TypeDef) error {
+	for _, field := range typ.Fields {
+		t, err := resolveType(field.Type, scope)
+		if err != nil {
+			return err
+		}
+		field.Type = t
+	}
+	for _, c := range typ.Cases {
+		if c.Type == nil {
+			continue
+		}
+		t, err := resolveType(c.Type, scope)
+		if err != nil {
+			return err
+		}
+		c.Type = t
+	}
+	return nil
+}
+
+func resolveFunc(fn *Function, scope map[string]*TypeDef) error {
+	for _, param := range fn.Params {
+		t, err := resolveType(param.Type, scope)
+		if err != nil {
+			return err
+		}
+		param.Type = t
+	}
+	if fn.Result != nil {
+		t, err := resolveType(fn.Result, scope)
+		if err != nil {
+			return err
+		}
+		fn.Result = t
+	}
+	return nil
+}
+
+// resolveType returns the type with all type references replaced.
+func resolveType(t Type, scope map[string]*TypeDef) (Type, error) {
+	switch t := t.(type) {
+	case *typeRef:
+		def := scope[t.name]
+		if def == nil {
+			return nil, &Error{t.pos, "undefined type: " + t.name}
+		}
+		return def, nil
+	case *List:
+		elem, err := resolveType(t.Elem, scope)
+		if err != nil {
+			return nil, err
+		}
+		t.Elem = elem
+	case *Option:
+		elem, err := resolveType(t.Elem, scope)
+		if err != nil {
+			return nil, err
+		}
+		t.Elem = elem
+	}
+	return t, nil
+}
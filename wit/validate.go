@@ -0,0 +1,340 @@
+package wit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError is returned by Validate when a module doesn't match the
+// world it claims to implement.
+type ValidationError struct {
+	World    string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "module does not match world " + e.World + ":\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// Validate checks a WebAssembly module against the WIT worlds stored in its
+// custom sections (see SectionPrefix). It checks that all exports of the world
+// are present with the correct core signature, and that all imports from the
+// interfaces of the world exist in the world with a matching signature.
+// Imports that aren't used by the program may be missing from the module.
+func Validate(module []byte) error {
+	m, err := parseModule(module)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range m.customSections {
+		if strings.HasPrefix(name, SectionPrefix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("module does not contain a WIT world")
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		worldName := name[len(SectionPrefix):]
+		doc, err := Parse(name, m.customSections[name])
+		if err != nil {
+			return fmt.Errorf("could not parse custom section: %w", err)
+		}
+		world, err := doc.World(worldName)
+		if err != nil {
+			return fmt.Errorf("custom section %s: %w", name, err)
+		}
+		err = validateWorld(m, doc, world)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWorld(m *wasmModule, doc *Document, world *World) error {
+	verr := &ValidationError{World: world.Name}
+	needsMemory := false
+
+	// Check imports.
+	type importKey struct{ module, name string }
+	expectedImports := make(map[importKey]*Function)
+	worldModules := make(map[string]bool)
+	var importFuncs []*Function
+	for _, iface := range world.Imports {
+		importFuncs = append(importFuncs, iface.Funcs...)
+	}
+	importFuncs = append(importFuncs, world.ImportFuncs...)
+	for _, fn := range importFuncs {
+		module := doc.importModule(fn)
+		worldModules[module] = true
+		expectedImports[importKey{module, fn.Name}] = fn
+	}
+	for _, imp := range m.imports {
+		if !worldModules[imp.module] {
+			continue // not part of this world, for example WASI
+		}
+		fn := expectedImports[importKey{imp.module, imp.name}]
+		if fn == nil {
+			verr.Problems = append(verr.Problems, fmt.Sprintf("import %s from %s is not part of the world", imp.name, imp.module))
+			continue
+		}
+		params, results := coreSignature(fn, false)
+		if sig := formatSignature(params, results); imp.signature != sig {
+			verr.Problems = append(verr.Problems, fmt.Sprintf("import %s from %s has signature %s, expected %s", imp.name, imp.module, imp.signature, sig))
+		}
+		needsMemory = needsMemory || funcUsesMemory(fn)
+	}
+
+	// Check exports.
+	var exportFuncs []*Function
+	for _, iface := range world.Exports {
+		exportFuncs = append(exportFuncs, iface.Funcs...)
+	}
+	exportFuncs = append(exportFuncs, world.ExportFuncs...)
+	for _, fn := range exportFuncs {
+		name := doc.exportName(fn)
+		params, results := coreSignature(fn, true)
+		sig, ok := m.exports[name]
+		if !ok {
+			verr.Problems = append(verr.Problems, "missing export "+name)
+		} else if expected := formatSignature(params, results); sig != expected {
+			verr.Problems = append(verr.Problems, fmt.Sprintf("export %s has signature %s, expected %s", name, sig, expected))
+		}
+		if sig, ok := m.exports["cabi_post_"+name]; ok {
+			if expected := formatSignature(results, nil); sig != expected {
+				verr.Problems = append(verr.Problems, fmt.Sprintf("export cabi_post_%s has signature %s, expected %s", name, sig, expected))
+			}
+		}
+		needsMemory = needsMemory || funcUsesMemory(fn)
+	}
+
+	// Values in linear memory require the memory and an allocator to be
+	// exported.
+	if needsMemory {
+		if _, ok := m.exports["memory"]; !ok {
+			verr.Problems = append(verr.Problems, "missing export memory")
+		}
+		sig, ok := m.exports["cabi_realloc"]
+		if !ok {
+			verr.Problems = append(verr.Problems, "missing export cabi_realloc")
+		} else if expected := "(i32, i32, i32, i32) -> (i32)"; sig != expected {
+			verr.Problems = append(verr.Problems, fmt.Sprintf("export cabi_realloc has signature %s, expected %s", sig, expected))
+		}
+	}
+
+	if len(verr.Problems) != 0 {
+		return verr
+	}
+	return nil
+}
+
+// funcUsesMemory returns whether calling this function involves values stored
+// in linear memory.
+func funcUsesMemory(fn *Function) bool {
+	var flatCount int
+	for _, param := range fn.Params {
+		if usesMemory(param.Type) {
+			return true
+		}
+		flatCount += len(flatten(param.Type))
+	}
+	if flatCount > maxFlatParams {
+		return true
+	}
+	return fn.Result != nil && (usesMemory(fn.Result) || len(flatten(fn.Result)) > maxFlatResults)
+}
+
+// formatSignature returns a core function signature in the same format as
+// used in wasmModule.
+func formatSignature(params, results []string) string {
+	return "(" + strings.Join(params, ", ") + ") -> (" + strings.Join(results, ", ") + ")"
+}
+
+// wasmModule contains the parts of a WebAssembly module that are needed for
+// validation. Function signatures are stored as strings like
+// "(i32, i32) -> (i64)". Exports other than functions have an empty signature.
+type wasmModule struct {
+	imports        []wasmImport
+	exports        map[string]string
+	customSections map[string][]byte
+}
+
+type wasmImport struct {
+	module, name string
+	signature    string
+}
+
+// parseModule reads the sections of a WebAssembly module binary that are
+// needed for validation.
+func parseModule(data []byte) (*wasmModule, error) {
+	if !bytes.HasPrefix(data, []byte("\x00asm\x01\x00\x00\x00")) {
+		return nil, errors.New("not a WebAssembly module")
+	}
+	m := &wasmModule{
+		exports:        make(map[string]string),
+		customSections: make(map[string][]byte),
+	}
+	var types []string
+	var funcs []string // signatures of all functions, including imports
+	r := &wasmReader{data: data[8:]}
+	for len(r.data) != 0 && r.err == nil {
+		id := r.byte()
+		section := &wasmReader{data: r.bytes(int(r.u32()))}
+		switch id {
+		case 0: // custom
+			name := section.name()
+			m.customSections[name] = section.data
+		case 1: // type
+			for n := section.u32(); n != 0 && section.err == nil; n-- {
+				if section.byte() != 0x60 {
+					section.err = errors.New("invalid function type")
+				}
+				params := section.valTypes()
+				results := section.valTypes()
+				types = append(types, formatSignature(params, results))
+			}
+		case 2: // import
+			for n := section.u32(); n != 0 && section.err == nil; n-- {
+				imp := wasmImport{module: section.name(), name: section.name()}
+				switch section.byte() {
+				case 0: // func
+					imp.signature = section.typeIndex(types)
+					funcs = append(funcs, imp.signature)
+					m.imports = append(m.imports, imp)
+				case 1: // table
+					section.byte()
+					section.limits()
+				case 2: // memory
+					section.limits()
+				case 3: // global
+					section.byte()
+					section.byte()
+				case 4: // tag
+					section.byte()
+					section.u32()
+				default:
+					section.err = errors.New("unknown import kind")
+				}
+			}
+		case 3: // function
+			for n := section.u32(); n != 0 && section.err == nil; n-- {
+				funcs = append(funcs, section.typeIndex(types))
+			}
+		case 7: // export
+			for n := section.u32(); n != 0 && section.err == nil; n-- {
+				name := section.name()
+				kind := section.byte()
+				index := section.u32()
+				m.exports[name] = ""
+				if kind == 0 {
+					if int(index) >= len(funcs) {
+						section.err = errors.New("invalid function index")
+						break
+					}
+					m.exports[name] = funcs[index]
+				}
+			}
+		}
+		if section.err != nil {
+			r.err = section.err
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("could not parse WebAssembly module: %w", r.err)
+	}
+	return m, nil
+}
+
+// wasmReader reads values from a WebAssembly binary. After the first error,
+// all reads return zero values.
+type wasmReader struct {
+	data []byte
+	err  error
+}
+
+var errUnexpectedEOF = errors.New("unexpected end of data")
+
+func (r *wasmReader) byte() byte {
+	if r.err != nil || len(r.data) == 0 {
+		r.err = errUnexpectedEOF
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *wasmReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = errUnexpectedEOF
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// u32 reads an unsigned LEB128 encoded integer.
+func (r *wasmReader) u32() uint32 {
+	var result uint32
+	for shift := uint(0); shift < 35; shift += 7 {
+		b := r.byte()
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result
+		}
+	}
+	if r.err == nil {
+		r.err = errors.New("invalid integer")
+	}
+	return 0
+}
+
+func (r *wasmReader) name() string {
+	return string(r.bytes(int(r.u32())))
+}
+
+func (r *wasmReader) valTypes() []string {
+	var types []string
+	for n := r.u32(); n != 0 && r.err == nil; n-- {
+		switch b := r.byte(); b {
+		case 0x7f:
+			types = append(types, coreI32)
+		case 0x7e:
+			types = append(types, coreI64)
+		case 0x7d:
+			types = append(types, coreF32)
+		case 0x7c:
+			types = append(types, coreF64)
+		default:
+			types = append(types, fmt.Sprintf("0x%02x", b))
+		}
+	}
+	return types
+}
+
+func (r *wasmReader) typeIndex(types []string) string {
+	index := r.u32()
+	if r.err == nil && int(index) >= len(types) {
+		r.err = errors.New("invalid type index")
+		return ""
+	}
+	if r.err != nil {
+		return ""
+	}
+	return types[index]
+}
+
+func (r *wasmReader) limits() {
+	flags := r.byte()
+	r.u32()
+	if flags&1 != 0 {
+		r.u32()
+	}
+}
@@ -0,0 +1,224 @@
+// Package wit reads WebAssembly interface definitions (WIT files) and
+// generates Go bindings for them that follow the canonical ABI of the
+// component model.
+//
+// Only a subset of WIT is supported: interfaces and worlds containing
+// functions and record, variant, enum and option types, strings and lists.
+// Resources, flags, tuples, result types and use statements are not
+// supported.
+//
+// The generated bindings store the WIT definition of the world in a custom
+// section of the WebAssembly module, so that Validate can check a module
+// against it without access to the original WIT file. They pass 64-bit
+// integers directly, so they must be built with -wasm-abi=generic (the default
+// for WASI).
+package wit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document is a parsed WIT file.
+type Document struct {
+	Package    string // for example "example:host", may be empty
+	Interfaces []*Interface
+	Worlds     []*World
+}
+
+// Interface is a named collection of types and functions. Interfaces are
+// either defined at the top level of a document, or inline in a world in
+// which case they are only used in that world.
+type Interface struct {
+	Name  string
+	Doc   string
+	Types []*TypeDef
+	Funcs []*Function
+
+	inline bool     // defined inside a world, not part of the package
+	ref    bool     // placeholder for a top-level interface, before resolving
+	pos    Position // position of the reference, for error messages
+}
+
+// World describes the imports and exports of a component.
+type World struct {
+	Name    string
+	Doc     string
+	Types   []*TypeDef   // types defined directly in the world
+	Imports []*Interface // imported interfaces
+	Exports []*Interface // exported interfaces
+
+	// Functions imported or exported directly by the world, not as part of
+	// an interface.
+	ImportFuncs []*Function
+	ExportFuncs []*Function
+}
+
+// Function is a single WIT function.
+type Function struct {
+	Name   string
+	Doc    string
+	Params []*Field
+	Result Type // nil if the function doesn't return a value
+
+	iface *Interface // nil for functions defined directly in a world
+}
+
+// Field is a record field or function parameter.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Case is a single case of a variant or enum. Type is nil for cases without
+// a payload and always nil for enums.
+type Case struct {
+	Name string
+	Type Type
+}
+
+// Type is a WIT type: a Primitive, *List, *Option or *TypeDef.
+type Type interface {
+	String() string
+}
+
+// Primitive is a builtin type like u32 or string.
+type Primitive string
+
+// All primitive types, as they're named in WIT.
+const (
+	Bool    Primitive = "bool"
+	S8      Primitive = "s8"
+	U8      Primitive = "u8"
+	S16     Primitive = "s16"
+	U16     Primitive = "u16"
+	S32     Primitive = "s32"
+	U32     Primitive = "u32"
+	S64     Primitive = "s64"
+	U64     Primitive = "u64"
+	Float32 Primitive = "float32"
+	Float64 Primitive = "float64"
+	Char    Primitive = "char"
+	String  Primitive = "string"
+)
+
+var primitives = map[string]Primitive{
+	"bool":    Bool,
+	"s8":      S8,
+	"u8":      U8,
+	"s16":     S16,
+	"u16":     U16,
+	"s32":     S32,
+	"u32":     U32,
+	"s64":     S64,
+	"u64":     U64,
+	"float32": Float32,
+	"f32":     Float32,
+	"float64": Float64,
+	"f64":     Float64,
+	"char":    Char,
+	"string":  String,
+}
+
+func (t Primitive) String() string {
+	return string(t)
+}
+
+// List is a list<T> type.
+type List struct {
+	Elem Type
+}
+
+func (t *List) String() string {
+	return "list<" + t.Elem.String() + ">"
+}
+
+// Option is an option<T> type.
+type Option struct {
+	Elem Type
+}
+
+func (t *Option) String() string {
+	return "option<" + t.Elem.String() + ">"
+}
+
+// TypeDef is a named record, variant or enum type.
+type TypeDef struct {
+	Name   string
+	Doc    string
+	Kind   string   // "record", "variant" or "enum"
+	Fields []*Field // for records
+	Cases  []*Case  // for variants and enums
+}
+
+func (t *TypeDef) String() string {
+	return t.Name
+}
+
+// typeRef is a reference to a named type that hasn't been resolved yet.
+type typeRef struct {
+	name string
+	pos  Position
+}
+
+func (t *typeRef) String() string {
+	return t.name
+}
+
+// World returns the world with the given name. If name is empty and there is
+// exactly one world in the document, that world is returned.
+func (doc *Document) World(name string) (*World, error) {
+	if name == "" {
+		switch len(doc.Worlds) {
+		case 0:
+			return nil, fmt.Errorf("no world defined")
+		case 1:
+			return doc.Worlds[0], nil
+		default:
+			var names []string
+			for _, world := range doc.Worlds {
+				names = append(names, world.Name)
+			}
+			return nil, fmt.Errorf("multiple worlds defined, select one of: %s", strings.Join(names, ", "))
+		}
+	}
+	for _, world := range doc.Worlds {
+		if world.Name == name {
+			return world, nil
+		}
+	}
+	return nil, fmt.Errorf("world %s not defined", name)
+}
+
+// interfaceName returns the name an interface is known by to the host: the
+// fully qualified name for interfaces that are part of a package, or just the
+// name for inline interfaces and documents without a package.
+func (doc *Document) interfaceName(iface *Interface) string {
+	if iface.inline || doc.Package == "" {
+		return iface.Name
+	}
+	pkg := doc.Package
+	version := ""
+	if i := strings.IndexByte(pkg, '@'); i >= 0 {
+		pkg, version = pkg[:i], pkg[i:]
+	}
+	return pkg + "/" + iface.Name + version
+}
+
+// importModule returns the module name of an imported function, as used in
+// the WebAssembly import section.
+func (doc *Document) importModule(fn *Function) string {
+	if fn.iface == nil {
+		return "$root"
+	}
+	return doc.interfaceName(fn.iface)
+}
+
+// exportName returns the name under which a function must be exported from
+// the WebAssembly module.
+func (doc *Document) exportName(fn *Function) string {
+	if fn.iface == nil {
+		return fn.Name
+	}
+	return doc.interfaceName(fn.iface) + "#" + fn.Name
+}
@@ -0,0 +1,301 @@
+package wit
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Pass -update to go test to update the output of the test files.
+var flagUpdate = flag.Bool("update", false, "update tests based on test output")
+
+// Generate bindings for the WIT files in testdata and compare them with the
+// expected output. The generated code must also type check.
+func TestGenerate(t *testing.T) {
+	for _, name := range []string{"host", "abi"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			doc, err := ParseFile("testdata/" + name + ".wit")
+			if err != nil {
+				t.Fatal("could not parse WIT file:", err)
+			}
+			world, err := doc.World("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := Generate(doc, world, name, name+".wit")
+			if err != nil {
+				t.Fatal("could not generate bindings:", err)
+			}
+			checkGoSource(t, name, src)
+
+			outPath := filepath.Join("testdata", name+".go")
+			if *flagUpdate {
+				err := ioutil.WriteFile(outPath, src, 0666)
+				if err != nil {
+					t.Error("failed to write updated output file:", err)
+				}
+				return
+			}
+			expected, err := ioutil.ReadFile(outPath)
+			if err != nil {
+				t.Fatal("failed to read golden file:", err)
+			}
+			if !bytes.Equal(src, expected) {
+				t.Errorf("output does not match expected output in %s", outPath)
+			}
+		})
+	}
+}
+
+// checkGoSource type checks the generated source code.
+func checkGoSource(t *testing.T, name string, src []byte) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, name+".go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal("could not parse generated code:", err)
+	}
+	config := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Sizes:    types.SizesFor("gc", "arm"), // 32-bit, like WebAssembly
+	}
+	_, err = config.Check(name, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Error("generated code does not type check:", err)
+	}
+}
+
+// Formatting a world must result in a document that parses to the same world.
+func TestFormat(t *testing.T) {
+	for _, name := range []string{"host", "abi"} {
+		doc, err := ParseFile("testdata/" + name + ".wit")
+		if err != nil {
+			t.Fatal("could not parse WIT file:", err)
+		}
+		world, err := doc.World("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted := Format(doc, world)
+		doc2, err := Parse(name+".wit", []byte(formatted))
+		if err != nil {
+			t.Fatalf("could not parse formatted world %s: %v\n%s", name, err, formatted)
+		}
+		world2, err := doc2.World(world.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatted2 := Format(doc2, world2); formatted2 != formatted {
+			t.Errorf("formatting world %s is not stable:\n%s\n%s", name, formatted, formatted2)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src string
+		err string
+	}{
+		{"world w { import f: func(x: foo); }", "test.wit:1:29: undefined type: foo"},
+		{"world w { import foo; }", "test.wit:1:18: undefined interface: foo"},
+		{"interface i { f: func() }", "test.wit:1:25: expected ;, found }"},
+		{"interface i { resource r; }", "test.wit:1:15: resource is not supported"},
+		{"interface i { f: func() -> tuple<u32, u32>; }", "test.wit:1:28: tuple types are not supported"},
+		{"interface i { record r { next: option<r> } }", "test.wit: type r is recursive"},
+		{"interface i { enum e {} }", "test.wit:1:20: enum e must not be empty"},
+		{"world w { import f: func(); }\nworld v {}\n", ""},
+		{"/* comment", "test.wit:1:1: unterminated block comment"},
+	} {
+		doc, err := Parse("test.wit", []byte(tc.src))
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %q: %v", tc.src, err)
+			}
+			if _, err := doc.World(""); err == nil || err.Error() != "multiple worlds defined, select one of: w, v" {
+				t.Errorf("unexpected error selecting world: %v", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected error %q for %q", tc.err, tc.src)
+		} else if err.Error() != tc.err {
+			t.Errorf("expected error %q for %q, got %q", tc.err, tc.src, err.Error())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc, err := ParseFile("testdata/host.wit")
+	if err != nil {
+		t.Fatal("could not parse WIT file:", err)
+	}
+	world, err := doc.World("plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := customSection{SectionPrefix + "plugin", Format(doc, world)}
+
+	// All exports present, only some imports used.
+	valid := testModule{
+		imports: []testFunc{
+			{"example:host/logging@0.1.0", "log", "(i32, i32, i32) -> ()"},
+			{"wasi_snapshot_preview1", "fd_write", "(i32, i32, i32, i32) -> (i32)"},
+		},
+		exports: []testFunc{
+			{"", "example:host/shapes@0.1.0#area", "(i32, i32, i32) -> (f64)"},
+			{"", "example:host/shapes@0.1.0#center", "(i32, i32, i32) -> (i32)"},
+			{"", "cabi_post_example:host/shapes@0.1.0#center", "(i32) -> ()"},
+			{"", "run", "(i32, i32, i32) -> (i32)"},
+			{"", "name", "() -> (i32)"},
+			{"", "cabi_realloc", "(i32, i32, i32, i32) -> (i32)"},
+		},
+		memory:   true,
+		sections: []customSection{section},
+	}
+	err = Validate(valid.encode())
+	if err != nil {
+		t.Error("unexpected validation error:", err)
+	}
+
+	// Wrong signatures, unknown imports and missing exports.
+	invalid := valid
+	invalid.imports = []testFunc{
+		{"example:host/logging@0.1.0", "log", "(i32, i32) -> ()"},
+		{"$root", "get-config", "(i32, i32, i32) -> ()"},
+		{"$root", "set-config", "(i32, i32) -> ()"},
+	}
+	invalid.exports = invalid.exports[1:5]
+	invalid.memory = false
+	err = Validate(invalid.encode())
+	expected := `module does not match world plugin:
+	import log from example:host/logging@0.1.0 has signature (i32, i32) -> (), expected (i32, i32, i32) -> ()
+	import set-config from $root is not part of the world
+	missing export example:host/shapes@0.1.0#area
+	missing export memory
+	missing export cabi_realloc`
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected validation error:\n%v\nexpected:\n%s", err, expected)
+	}
+
+	// A module without a world cannot be validated.
+	err = Validate(testModule{}.encode())
+	if err == nil || err.Error() != "module does not contain a WIT world" {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+// testModule describes a WebAssembly module for TestValidate.
+type testModule struct {
+	imports  []testFunc
+	exports  []testFunc
+	memory   bool
+	sections []customSection
+}
+
+type testFunc struct {
+	module, name string
+	signature    string // for example "(i32, i32) -> (i64)"
+}
+
+type customSection struct {
+	name, data string
+}
+
+// encode returns the binary encoding of the module. All functions consist of
+// an unreachable instruction.
+func (m testModule) encode() []byte {
+	var types []string
+	typeIndex := func(signature string) int {
+		for i, t := range types {
+			if t == signature {
+				return i
+			}
+		}
+		types = append(types, signature)
+		return len(types) - 1
+	}
+	var importSection, funcSection, exportSection, codeSection []byte
+	for _, fn := range m.imports {
+		importSection = append(importSection, encodeName(fn.module)...)
+		importSection = append(importSection, encodeName(fn.name)...)
+		importSection = append(importSection, 0, byte(typeIndex(fn.signature)))
+	}
+	for i, fn := range m.exports {
+		funcSection = append(funcSection, byte(typeIndex(fn.signature)))
+		exportSection = append(exportSection, encodeName(fn.name)...)
+		exportSection = append(exportSection, 0, byte(len(m.imports)+i))
+		codeSection = append(codeSection, 3, 0, 0x00, 0x0b) // unreachable
+	}
+	if m.memory {
+		exportSection = append(exportSection, encodeName("memory")...)
+		exportSection = append(exportSection, 2, 0)
+	}
+	var typeSection []byte
+	for _, signature := range types {
+		typeSection = append(typeSection, 0x60)
+		parts := strings.Split(signature, " -> ")
+		for _, part := range parts {
+			part = strings.Trim(part, "()")
+			var valTypes []byte
+			if part != "" {
+				for _, t := range strings.Split(part, ", ") {
+					valTypes = append(valTypes, map[string]byte{"i32": 0x7f, "i64": 0x7e, "f32": 0x7d, "f64": 0x7c}[t])
+				}
+			}
+			typeSection = append(typeSection, byte(len(valTypes)))
+			typeSection = append(typeSection, valTypes...)
+		}
+	}
+
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = appendSection(out, 1, len(types), typeSection)
+	out = appendSection(out, 2, len(m.imports), importSection)
+	out = appendSection(out, 3, len(m.exports), funcSection)
+	if m.memory {
+		out = appendSection(out, 5, 1, []byte{0, 1})
+	}
+	numExports := len(m.exports)
+	if m.memory {
+		numExports++
+	}
+	out = appendSection(out, 7, numExports, exportSection)
+	out = appendSection(out, 10, len(m.exports), codeSection)
+	for _, section := range m.sections {
+		data := append(encodeName(section.name), section.data...)
+		out = append(out, 0)
+		out = append(out, encodeU32(uint32(len(data)))...)
+		out = append(out, data...)
+	}
+	return out
+}
+
+func appendSection(out []byte, id byte, count int, data []byte) []byte {
+	data = append(encodeU32(uint32(count)), data...)
+	out = append(out, id)
+	out = append(out, encodeU32(uint32(len(data)))...)
+	return append(out, data...)
+}
+
+func encodeName(name string) []byte {
+	return append(encodeU32(uint32(len(name))), name...)
+}
+
+func encodeU32(n uint32) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}